@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lukepeterson/go8080cpu/pkg/bench"
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+)
+
+// runBench runs the benchmark workloads and reports the emulated instructions
+// per second and effective clock speed of each.
+//
+// Usage:
+//
+//	cpu bench [-duration 2s] [-workload name] [-variant 8080|8085|z80]
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	duration := flags.Duration("duration", 2*time.Second, "how long to run each workload for")
+	workloadName := flags.String("workload", "", "run only the named workload")
+	variantName := flags.String("variant", "8080", "processor variant to emulate: 8080, 8085 or z80")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	variant, err := parseVariant(*variantName)
+	if err != nil {
+		return err
+	}
+
+	workloads := bench.Workloads
+	if *workloadName != "" {
+		workload, err := bench.Find(*workloadName)
+		if err != nil {
+			return err
+		}
+		workloads = []bench.Workload{workload}
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "WORKLOAD\tINSTRUCTIONS\tMIPS\tEFFECTIVE MHz\tVS 2 MHz 8080\t")
+	for _, workload := range workloads {
+		result, err := bench.Run(variant, workload, *duration)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(writer, "%s\t%d\t%.2f\t%.2f\t%.1fx\t\n",
+			result.Workload, result.Instructions, result.InstructionsPerSecond()/1e6, result.EffectiveHz()/1e6, result.SpeedUp())
+	}
+
+	return writer.Flush()
+}
+
+// parseVariant returns the processor variant named by name.
+func parseVariant(name string) (cpu.Variant, error) {
+	for _, variant := range []cpu.Variant{cpu.Intel8080, cpu.Intel8085, cpu.ZilogZ80} {
+		if strings.EqualFold(name, variant.String()) {
+			return variant, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown variant %q", name)
+}
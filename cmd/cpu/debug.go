@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/debugger"
+	"github.com/lukepeterson/go8080cpu/pkg/loader"
+	"github.com/lukepeterson/go8080cpu/pkg/symbols"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// redrawInterval is how often the screen is redrawn while the CPU runs.
+const redrawInterval = time.Second / 30
+
+// runDebug runs a program in the full-screen debugger.  The program is read
+// as by cpu run, or is the sample program if no file is given.  Breakpoints
+// may be set at labels of an assembly language program or read with -symbols,
+// or at addresses.  Its console is on two ports: status on the one given, and
+// data on the next.
+//
+// Usage:
+//
+//	cpu debug [-format auto|asm|bin|hex|com] [-load 0x0000] [-symbols file,...]
+//		[-break LOOP,0x0010] [-console 0x00] [-variant 8080|8085|z80] [file]
+func runDebug(args []string) error {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	formatName := flags.String("format", "auto", "program format: auto, asm, bin, hex or com")
+	loadAddress := flags.String("load", "0x0000", "address to load asm and bin programs at")
+	symbolPaths := flags.String("symbols", "", "comma separated .sym, .map or .lst files to read labels from")
+	breakpoints := flags.String("break", "", "comma separated labels or addresses to set breakpoints at")
+	consolePort := flags.String("console", "0x00", "console status port, with data on the next port")
+	variantName := flags.String("variant", "8080", "processor variant to emulate: 8080, 8085 or z80")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("too many arguments: %v", flags.Args())
+	}
+
+	format, err := loader.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	address, err := parseAddress(*loadAddress)
+	if err != nil {
+		return err
+	}
+	statusPort, err := strconv.ParseUint(*consolePort, 0, 8)
+	if err != nil || statusPort == 0xFF {
+		return fmt.Errorf("invalid console port %q", *consolePort)
+	}
+	variant, err := parseVariant(*variantName)
+	if err != nil {
+		return err
+	}
+
+	var program *loader.Program
+	if flags.NArg() == 0 {
+		program, err = loader.Parse("sample.asm", []byte(sampleProgram), loader.Assembly, address)
+	} else {
+		program, err = loader.Read(flags.Arg(0), format, address)
+	}
+	if err != nil {
+		return err
+	}
+
+	processor := cpu.NewWithVariant(variant)
+	err = program.Load(processor.Bus)
+	if err != nil {
+		return err
+	}
+	processor.SetProgramCounter(program.Entry)
+	processor.CallStack = cpu.NewCallStack()
+	table, err := readSymbols(program, *symbolPaths)
+	if err != nil {
+		return err
+	}
+	if table != nil {
+		processor.Symbols = table
+	}
+
+	console := debugger.NewConsole(byte(statusPort), byte(statusPort)+1)
+	processor.Attach(console, byte(statusPort), byte(statusPort)+1)
+	session := debugger.New(processor, console)
+
+	if *breakpoints != "" {
+		for _, location := range strings.Split(*breakpoints, ",") {
+			address, err := resolve(table, strings.TrimSpace(location))
+			if err != nil {
+				return err
+			}
+			session.ToggleBreakpoint(address)
+		}
+	}
+
+	return debug(session)
+}
+
+// readSymbols returns the program's symbols with those in the comma separated
+// files in paths, or nil if there are none.
+func readSymbols(program *loader.Program, paths string) (*symbols.Table, error) {
+	table := program.Symbols
+	if paths == "" {
+		return table, nil
+	}
+
+	if table == nil {
+		table = symbols.New()
+	}
+	for _, path := range strings.Split(paths, ",") {
+		file, err := symbols.Read(strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		table.Merge(file)
+	}
+
+	return table, nil
+}
+
+// resolve returns the address of a label in table, or of a number.
+func resolve(table *symbols.Table, location string) (types.Word, error) {
+	if table != nil {
+		if address, ok := table.Address(location); ok {
+			return address, nil
+		}
+	}
+
+	address, err := parseAddress(location)
+	if err != nil {
+		return 0, fmt.Errorf("unknown label or invalid address %q", location)
+	}
+
+	return address, nil
+}
+
+// debug runs the debugger on the terminal until the user quits.
+func debug(debugger *debugger.Debugger) error {
+	state, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer state.restore()
+
+	fmt.Print("\033[?1049h\033[?25l") // Switch to the alternate screen and hide the cursor
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	input := make(chan []byte)
+	go readInput(os.Stdin, input)
+
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+
+	// Errors stepping the CPU stop it, and are shown in the status line
+	for !debugger.Quit() {
+		width, height := terminalSize(int(os.Stdout.Fd()))
+		err := debugger.Draw(os.Stdout, width, height)
+		if err != nil {
+			return err
+		}
+
+		if debugger.Running() {
+			deadline := time.Now().Add(redrawInterval)
+			for debugger.Running() && time.Now().Before(deadline) {
+				debugger.Run(1000)
+				select {
+				case keys := <-input:
+					debugger.HandleInput(keys)
+				default:
+				}
+			}
+			continue
+		}
+
+		select {
+		case keys, ok := <-input:
+			if !ok {
+				return nil
+			}
+			debugger.HandleInput(keys)
+		case <-ticker.C: // Redraw in case the terminal was resized
+		}
+	}
+
+	return nil
+}
+
+// readInput sends what's read from reader to input, closing it at the end of
+// the input.
+func readInput(reader io.Reader, input chan<- []byte) {
+	defer close(input)
+
+	buffer := make([]byte, 256)
+	for {
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			input <- append([]byte(nil), buffer[:n]...)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
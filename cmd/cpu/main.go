@@ -3,17 +3,14 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/lukepeterson/go8080assembler/pkg/assembler"
 	"github.com/lukepeterson/go8080cpu/pkg/cpu"
 )
 
-func main() {
-
-	goCPU := cpu.New()
-	goCPU.DebugMode = true
-
-	input := `
+// sampleProgram is run when no subcommand is given, and debugged when no file is.
+const sampleProgram = `
 		INR A
 		DCR H
 		INR B
@@ -24,7 +21,29 @@ func main() {
 		HLT
 		`
 
-	asm := assembler.New(input)
+func main() {
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "bench":
+			err = runBench(os.Args[2:])
+		case "debug":
+			err = runDebug(os.Args[2:])
+		case "run":
+			err = runProgram(os.Args[2:])
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	goCPU := cpu.New()
+	goCPU.DebugMode = true
+
+	asm := assembler.New(sampleProgram)
 	bytecode, err := asm.Assemble()
 	if err != nil {
 		fmt.Println(err)
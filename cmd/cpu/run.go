@@ -0,0 +1,572 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lukepeterson/go8080cpu/pkg/clock"
+	"github.com/lukepeterson/go8080cpu/pkg/coverage"
+	"github.com/lukepeterson/go8080cpu/pkg/cpm"
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/heatmap"
+	"github.com/lukepeterson/go8080cpu/pkg/loader"
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+	"github.com/lukepeterson/go8080cpu/pkg/profile"
+	"github.com/lukepeterson/go8080cpu/pkg/symbols"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// runProgram runs a program from a file until it halts, then dumps the
+// registers and any memory asked for.  The file's format is detected from its
+// extension or contents unless it's given.  CP/M .com programs run with a BDOS
+// for console I/O, ending when they return to CP/M.  If the program fails, the
+// calls it was in are written to stderr, as are returns that didn't match
+// their calls.  Labels read with -symbols
+// from other assemblers' files are shown in traces and errors, as are those of
+// assembly language programs.  A profile of where the program spent its time
+// can be written for go tool pprof with -profile, or as text with
+// -profile-report, and the instructions and branches it exercised as an lcov
+// file with -coverage, or as an annotated listing with -coverage-listing.  Its
+// memory accesses can be drawn as a heatmap with -heatmap, and summarized with
+// -memory-report.  It runs as fast as it can unless -clock paces it to a clock
+// speed, reporting the speed it actually ran at to stderr; -turbo starts it
+// unpaced, and on Unix SIGUSR1 toggles between the two while it runs.
+//
+// Usage:
+//
+//	cpu run [-format auto|asm|bin|hex|com] [-load 0x0000] [-entry address]
+//		[-symbols file,...] [-memory 64K] [-max n] [-trace file|-] [-cpm]
+//		[-stack start:end] [-profile file] [-profile-report file|-]
+//		[-coverage file] [-coverage-listing file|-] [-heatmap file.png]
+//		[-memory-report file|-] [-clock hz] [-turbo]
+//		[-variant 8080|8085|z80] [-registers text|json|none]
+//		[-dump start:end] [-dump-format hex|json|bin] file
+func runProgram(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	formatName := flags.String("format", "auto", "program format: auto, asm, bin, hex or com")
+	loadAddress := flags.String("load", "0x0000", "address to load asm and bin programs at")
+	entryAddress := flags.String("entry", "", "address to start at, instead of the program's entry point")
+	symbolPaths := flags.String("symbols", "", "comma separated .sym, .map or .lst files to read labels from")
+	memorySize := flags.String("memory", "64K", "memory size in bytes, or with a K suffix")
+	maxInstructions := flags.Uint64("max", 0, "stop with an error after this many instructions, or 0 for no limit")
+	tracePath := flags.String("trace", "", "write a trace of each instruction to a file, or - for stderr")
+	stackRange := flags.String("stack", "", "guard the stack to start:end with end exclusive, reporting its use to stderr")
+	profilePath := flags.String("profile", "", "write a profile of the run to a file for go tool pprof")
+	reportPath := flags.String("profile-report", "", "write flat and call graph profiles of the run to a file, or - for stderr")
+	coveragePath := flags.String("coverage", "", "write the source lines and branches the run covered to an lcov file")
+	listingPath := flags.String("coverage-listing", "", "write a listing of the program annotated with its coverage to a file, or - for stderr")
+	heatmapPath := flags.String("heatmap", "", "write a PNG heatmap of the run's memory accesses to a file")
+	memoryReportPath := flags.String("memory-report", "", "write the run's hottest memory pages and its code and data to a file, or - for stderr")
+	clockSpeed := flags.String("clock", "", "pace the run to a clock speed such as 2MHz, 3.125MHz or 2000000")
+	turbo := flags.Bool("turbo", false, "start the run in turbo mode, unpaced until SIGUSR1 toggles it")
+	bdos := flags.Bool("cpm", false, "provide a CP/M BDOS for console I/O, as .com programs always have")
+	variantName := flags.String("variant", "8080", "processor variant to emulate: 8080, 8085 or z80")
+	registersFormat := flags.String("registers", "text", "format of the final register dump: text, json or none")
+	dumpRange := flags.String("dump", "", "memory to dump when the program ends, as start:end with end exclusive")
+	dumpFormat := flags.String("dump-format", "hex", "format of the memory dump: hex, json or bin")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one program file, got %d", flags.NArg())
+	}
+
+	format, err := loader.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	address, err := parseAddress(*loadAddress)
+	if err != nil {
+		return err
+	}
+	size, err := parseMemorySize(*memorySize)
+	if err != nil {
+		return err
+	}
+	variant, err := parseVariant(*variantName)
+	if err != nil {
+		return err
+	}
+	if *registersFormat != "text" && *registersFormat != "json" && *registersFormat != "none" {
+		return fmt.Errorf("unknown register dump format %q (must be text, json or none)", *registersFormat)
+	}
+	var hz float64
+	if *clockSpeed != "" {
+		hz, err = clock.ParseHz(*clockSpeed)
+		if err != nil {
+			return err
+		}
+	} else if *turbo {
+		return fmt.Errorf("-turbo needs a clock speed to toggle, given with -clock")
+	}
+	var guard *cpu.StackGuard
+	if *stackRange != "" {
+		start, end, err := parseRange(*stackRange)
+		if err != nil {
+			return err
+		}
+		guard = cpu.NewStackGuard(types.Word(start), types.Word(end))
+	}
+	var dumpStart, dumpEnd int
+	if *dumpRange != "" {
+		dumpStart, dumpEnd, err = parseRange(*dumpRange)
+		if err != nil {
+			return err
+		}
+		if *dumpFormat != "hex" && *dumpFormat != "json" && *dumpFormat != "bin" {
+			return fmt.Errorf("unknown memory dump format %q (must be hex, json or bin)", *dumpFormat)
+		}
+	}
+
+	program, err := loader.Read(flags.Arg(0), format, address)
+	if err != nil {
+		return err
+	}
+
+	processor := cpu.NewWithVariant(variant)
+	processor.Bus = &memory.Memory{Data: make([]byte, size)}
+	err = program.Load(processor.Bus)
+	if err != nil {
+		return err
+	}
+
+	entry := program.Entry
+	if *entryAddress != "" {
+		entry, err = parseAddress(*entryAddress)
+		if err != nil {
+			return err
+		}
+	}
+	processor.SetProgramCounter(entry)
+	table, err := readSymbols(program, *symbolPaths)
+	if err != nil {
+		return err
+	}
+	if table != nil {
+		processor.Symbols = table
+	}
+
+	var system *cpm.BDOS
+	if *bdos || program.Format == loader.CPM {
+		if size < 0x0200 {
+			return fmt.Errorf("memory size %d is too small for CP/M", size)
+		}
+		system, err = cpm.Install(processor, types.Word((size-1)&^0xFF), os.Stdin, os.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *tracePath != "" {
+		trace := io.Writer(os.Stderr)
+		if *tracePath != "-" {
+			file, err := os.Create(*tracePath)
+			if err != nil {
+				return fmt.Errorf("could not create trace: %v", err)
+			}
+			defer file.Close()
+			trace = file
+		}
+		processor.Trace = trace
+	}
+
+	if guard != nil {
+		processor.StackGuard = guard
+	}
+	processor.CallStack = cpu.NewCallStack()
+	var profilers cpu.Profilers
+	var profiler *profile.Profiler
+	if *profilePath != "" || *reportPath != "" {
+		profiler = profile.New(processor)
+		profilers = append(profilers, profiler)
+	}
+	var cover *coverage.Coverage
+	if *coveragePath != "" || *listingPath != "" {
+		if *coveragePath != "" && (table == nil || len(table.Instructions()) == 0) {
+			return fmt.Errorf("no source lines for coverage: run an assembly language program, or read a listing with -symbols")
+		}
+		cover = coverage.New(processor)
+		profilers = append(profilers, cover)
+	}
+	if len(profilers) > 0 {
+		processor.Profiler = profilers
+	}
+
+	var heat *heatmap.Heatmap
+	if *heatmapPath != "" || *memoryReportPath != "" {
+		heat = heatmap.New(processor.Bus)
+		processor.Bus = heat
+	}
+
+	var throttle *clock.Throttle
+	if hz > 0 {
+		throttle = clock.New(hz)
+		throttle.SetTurbo(*turbo)
+		stop := toggleTurboOnSignal(throttle)
+		defer stop()
+	}
+
+	instructions, runErr := run(processor, *maxInstructions, throttle, hz)
+	if heat != nil {
+		processor.Bus = heat.Bus // Leave dumps and reports out of the heatmap
+	}
+	if runErr == nil && system != nil {
+		runErr = system.Err()
+	}
+
+	err = dumpRegisters(os.Stdout, processor, instructions, *registersFormat)
+	if err != nil {
+		return err
+	}
+	if *dumpRange != "" {
+		err = dumpMemory(os.Stdout, processor.Bus, dumpStart, dumpEnd, *dumpFormat)
+		if err != nil {
+			return err
+		}
+	}
+	if throttle != nil {
+		fmt.Fprintf(os.Stderr, "Clock: %v\n", throttle.Stats())
+	}
+	if guard != nil {
+		dumpStack(os.Stderr, processor, guard)
+	}
+	dumpCalls(os.Stderr, processor, runErr != nil)
+	if profiler != nil {
+		err = writeProfile(profiler, *profilePath, *reportPath)
+		if err != nil {
+			return err
+		}
+	}
+	if heat != nil {
+		err = writeHeatmap(heat, *heatmapPath, *memoryReportPath)
+		if err != nil {
+			return err
+		}
+	}
+	if cover != nil {
+		err = writeCoverage(cover, table, program, *coveragePath, *listingPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return runErr
+}
+
+// dumpCalls writes how many returns didn't match their calls, and if failed is
+// true, the calls the program was in, innermost first.
+func dumpCalls(w io.Writer, processor *cpu.CPU, failed bool) {
+	count, mismatches := processor.CallStack.Mismatches()
+	if count > 0 {
+		last := mismatches[len(mismatches)-1]
+		fmt.Fprintf(w, "%d returns didn't match their calls, the last at %s to %s\n", count, processor.Describe(last.PC), processor.Describe(last.To))
+	}
+	if !failed {
+		return
+	}
+
+	calls := processor.CallStack.Calls()
+	for i := len(calls) - 1; i >= 0; i-- {
+		from := "called from"
+		if calls[i].Interrupt {
+			from = "interrupting"
+		}
+		fmt.Fprintf(w, "  in %s, %s %s\n", processor.Describe(calls[i].To), from, processor.Describe(calls[i].From))
+	}
+}
+
+// writeProfile writes the profile for pprof to profilePath, and the flat and
+// call graph profiles to reportPath or stderr, if they're given.
+func writeProfile(profiler *profile.Profiler, profilePath, reportPath string) error {
+	if profilePath != "" {
+		file, err := os.Create(profilePath)
+		if err != nil {
+			return fmt.Errorf("could not create profile: %w", err)
+		}
+		defer file.Close()
+		err = profiler.WritePprof(file)
+		if err != nil {
+			return fmt.Errorf("could not write profile: %w", err)
+		}
+	}
+
+	if reportPath != "" {
+		report := io.Writer(os.Stderr)
+		if reportPath != "-" {
+			file, err := os.Create(reportPath)
+			if err != nil {
+				return fmt.Errorf("could not create profile report: %w", err)
+			}
+			defer file.Close()
+			report = file
+		}
+		err := profiler.WriteFlat(report)
+		if err == nil {
+			_, err = fmt.Fprintln(report)
+		}
+		if err == nil {
+			err = profiler.WriteCallGraph(report)
+		}
+		if err != nil {
+			return fmt.Errorf("could not write profile report: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeHeatmap writes the heatmap as a PNG to heatmapPath, and a report of
+// the memory accesses to reportPath or stderr, if they're given.
+func writeHeatmap(heat *heatmap.Heatmap, heatmapPath, reportPath string) error {
+	if heatmapPath != "" {
+		file, err := os.Create(heatmapPath)
+		if err != nil {
+			return fmt.Errorf("could not create heatmap: %w", err)
+		}
+		defer file.Close()
+		err = heat.WritePNG(file, 2)
+		if err != nil {
+			return fmt.Errorf("could not write heatmap: %w", err)
+		}
+	}
+
+	if reportPath != "" {
+		report := io.Writer(os.Stderr)
+		if reportPath != "-" {
+			file, err := os.Create(reportPath)
+			if err != nil {
+				return fmt.Errorf("could not create memory report: %w", err)
+			}
+			defer file.Close()
+			report = file
+		}
+		err := heat.WriteReport(report, 10)
+		if err != nil {
+			return fmt.Errorf("could not write memory report: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeCoverage writes the coverage as an lcov file to coveragePath, and as a
+// listing of the program's segments to listingPath or stderr, if they're given.
+func writeCoverage(cover *coverage.Coverage, table *symbols.Table, program *loader.Program, coveragePath, listingPath string) error {
+	if coveragePath != "" {
+		file, err := os.Create(coveragePath)
+		if err != nil {
+			return fmt.Errorf("could not create coverage: %w", err)
+		}
+		defer file.Close()
+		err = cover.WriteLcov(file, table)
+		if err != nil {
+			return fmt.Errorf("could not write coverage: %w", err)
+		}
+	}
+
+	if listingPath != "" {
+		listing := io.Writer(os.Stderr)
+		if listingPath != "-" {
+			file, err := os.Create(listingPath)
+			if err != nil {
+				return fmt.Errorf("could not create coverage listing: %w", err)
+			}
+			defer file.Close()
+			listing = file
+		}
+		for i, segment := range program.Segments {
+			if i > 0 {
+				fmt.Fprintln(listing)
+			}
+			end := types.Word(int(segment.Address) + len(segment.Data))
+			err := cover.WriteListing(listing, segment.Address, end)
+			if err != nil {
+				return fmt.Errorf("could not write coverage listing: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// dumpStack writes how much of the guarded stack was used, and the deepest
+// chain of calls.
+func dumpStack(w io.Writer, processor *cpu.CPU, guard *cpu.StackGuard) {
+	calls := []string{"none"}
+	if chain := guard.DeepestChain(); len(chain) > 0 {
+		calls = calls[:0]
+		for _, address := range chain {
+			calls = append(calls, processor.Describe(address))
+		}
+	}
+
+	fmt.Fprintf(w, "Stack: %d bytes used at most, deepest calls: %s\n", guard.HighWater(), strings.Join(calls, " > "))
+}
+
+// run runs the CPU until it halts, returning the number of instructions
+// executed.  If max isn't 0, it stops with an error after max instructions.
+// If throttle isn't nil, it paces the CPU to hz, syncing with the throttle
+// after each millisecond of emulated time.
+func run(processor *cpu.CPU, max uint64, throttle *clock.Throttle, hz float64) (uint64, error) {
+	if throttle != nil {
+		throttle.Sync(0)
+	}
+	budget := uint64(hz / 1000)
+	if budget == 0 {
+		budget = 1
+	}
+	synced := processor.Cycles()
+	sync := func() {
+		if throttle != nil {
+			throttle.Sync(processor.Cycles() - synced)
+			synced = processor.Cycles()
+		}
+	}
+	defer sync()
+
+	var instructions uint64
+	for !processor.Halted() || processor.InterruptWaiting() {
+		if max != 0 && instructions == max {
+			return instructions, fmt.Errorf("stopped after %d instructions at %s", instructions, processor.Describe(processor.ProgramCounter()))
+		}
+
+		address := processor.ProgramCounter()
+		err := processor.Step()
+		if err != nil {
+			return instructions, fmt.Errorf("could not step cpu at %s: %w", processor.Describe(address), err)
+		}
+		instructions++
+		if processor.Cycles()-synced >= budget {
+			sync()
+		}
+	}
+
+	return instructions, nil
+}
+
+// dumpRegisters writes the registers and flags, and the number of cycles and
+// instructions run, as text or JSON.
+func dumpRegisters(w io.Writer, processor *cpu.CPU, instructions uint64, format string) error {
+	flags := processor.Flags()
+
+	var err error
+	switch format {
+	case "text":
+		_, err = fmt.Fprintf(w, "A=%02X BC=%02X%02X DE=%02X%02X HL=%02X%02X SP=%04X PC=%04X S=%d Z=%d AC=%d P=%d C=%d CYC=%d INSTR=%d\n",
+			processor.A, processor.B, processor.C, processor.D, processor.E, processor.H, processor.L,
+			processor.StackPointer(), processor.ProgramCounter(),
+			boolToInt(flags.Sign), boolToInt(flags.Zero), boolToInt(flags.AuxCarry), boolToInt(flags.Parity), boolToInt(flags.Carry),
+			processor.Cycles(), instructions)
+	case "json":
+		err = json.NewEncoder(w).Encode(map[string]any{
+			"A": processor.A, "B": processor.B, "C": processor.C, "D": processor.D,
+			"E": processor.E, "H": processor.H, "L": processor.L,
+			"SP": processor.StackPointer(), "PC": processor.ProgramCounter(),
+			"Flags": map[string]bool{
+				"S": flags.Sign, "Z": flags.Zero, "AC": flags.AuxCarry, "P": flags.Parity, "C": flags.Carry,
+			},
+			"Halted":       processor.Halted(),
+			"Cycles":       processor.Cycles(),
+			"Instructions": instructions,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("could not dump registers: %v", err)
+	}
+
+	return nil
+}
+
+// dumpMemory writes memory from start up to end as a hex dump, JSON or binary.
+func dumpMemory(w io.Writer, bus cpu.Bus, start, end int, format string) error {
+	data := make([]byte, end-start)
+	for i := range data {
+		value, err := bus.ReadByteAt(types.Word(start + i))
+		if err != nil {
+			return fmt.Errorf("could not dump memory: %v", err)
+		}
+		data[i] = value
+	}
+
+	var err error
+	switch format {
+	case "hex":
+		for offset := 0; offset < len(data); offset += 16 {
+			line := data[offset:min(offset+16, len(data))]
+			text := make([]byte, len(line))
+			for i, value := range line {
+				text[i] = '.'
+				if value >= 0x20 && value < 0x7F {
+					text[i] = value
+				}
+			}
+			_, err = fmt.Fprintf(w, "%04X  % -47X  %s\n", start+offset, line, text)
+			if err != nil {
+				break
+			}
+		}
+	case "json":
+		err = json.NewEncoder(w).Encode(map[string]any{"Start": start, "End": end, "Data": hex.EncodeToString(data)})
+	case "bin":
+		_, err = w.Write(data)
+	}
+	if err != nil {
+		return fmt.Errorf("could not dump memory: %v", err)
+	}
+
+	return nil
+}
+
+// parseAddress parses a 16-bit address, in decimal or with a 0x prefix in hex.
+func parseAddress(text string) (types.Word, error) {
+	address, err := strconv.ParseUint(text, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", text)
+	}
+
+	return types.Word(address), nil
+}
+
+// parseMemorySize parses a memory size in bytes, or in K with a K suffix.
+func parseMemorySize(text string) (int, error) {
+	multiplier := 1
+	number := text
+	if trimmed, found := strings.CutSuffix(strings.ToUpper(text), "K"); found {
+		multiplier, number = 1024, trimmed
+	}
+
+	size, err := strconv.ParseUint(number, 0, 32)
+	if err != nil || size == 0 || int(size)*multiplier > 0x10000 {
+		return 0, fmt.Errorf("invalid memory size %q (must be 1 byte to 64K)", text)
+	}
+
+	return int(size) * multiplier, nil
+}
+
+// parseRange parses a range of addresses given as start:end, with end
+// exclusive so that 0x0000:0x10000 is the whole of memory.
+func parseRange(text string) (int, int, error) {
+	startText, endText, found := strings.Cut(text, ":")
+	start, startErr := strconv.ParseUint(startText, 0, 16)
+	end, endErr := strconv.ParseUint(endText, 0, 32)
+	if !found || startErr != nil || endErr != nil || end <= start || end > 0x10000 {
+		return 0, 0, fmt.Errorf("invalid memory range %q (must be start:end)", text)
+	}
+
+	return int(start), int(end), nil
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import "github.com/lukepeterson/go8080cpu/pkg/clock"
+
+// toggleTurboOnSignal does nothing, as there's no SIGUSR1 on this platform.
+func toggleTurboOnSignal(throttle *clock.Throttle) func() {
+	return func() {}
+}
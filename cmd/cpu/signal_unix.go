@@ -0,0 +1,28 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lukepeterson/go8080cpu/pkg/clock"
+)
+
+// toggleTurboOnSignal turns the throttle's turbo mode on or off each time the
+// process receives SIGUSR1, until the returned function is called.
+func toggleTurboOnSignal(throttle *clock.Throttle) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			throttle.SetTurbo(!throttle.Turbo())
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
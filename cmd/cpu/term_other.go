@@ -0,0 +1,25 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// terminalState is a terminal's settings, saved to restore it from raw mode.
+type terminalState struct{}
+
+// makeRaw fails, as raw mode isn't supported on this platform.
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, fmt.Errorf("the debugger isn't supported on %s", runtime.GOOS)
+}
+
+func (state *terminalState) restore() error {
+	return nil
+}
+
+// terminalSize returns 80x24, as the size can't be read on this platform.
+func terminalSize(fd int) (int, int) {
+	return 80, 24
+}
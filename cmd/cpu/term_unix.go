@@ -0,0 +1,75 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// terminalState is a terminal's settings, saved to restore it from raw mode.
+type terminalState struct {
+	fd      int
+	termios syscall.Termios
+}
+
+// makeRaw puts the terminal on fd into raw mode, where each key is read as
+// it's pressed, without echo or signals, and output is written unchanged.
+func makeRaw(fd int) (*terminalState, error) {
+	state := &terminalState{fd: fd}
+	err := ioctl(fd, ioctlGetTermios, unsafe.Pointer(&state.termios))
+	if err != nil {
+		return nil, fmt.Errorf("could not read terminal settings (is stdin a terminal?): %v", err)
+	}
+
+	raw := state.termios
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	err = ioctl(fd, ioctlSetTermios, unsafe.Pointer(&raw))
+	if err != nil {
+		return nil, fmt.Errorf("could not put terminal into raw mode: %v", err)
+	}
+
+	return state, nil
+}
+
+// restore restores the terminal's settings from before it was put into raw
+// mode.
+func (state *terminalState) restore() error {
+	err := ioctl(state.fd, ioctlSetTermios, unsafe.Pointer(&state.termios))
+	if err != nil {
+		return fmt.Errorf("could not restore terminal settings: %v", err)
+	}
+
+	return nil
+}
+
+// terminalSize returns the width and height of the terminal on fd, or 80x24
+// if it can't be read.
+func terminalSize(fd int) (int, int) {
+	var size struct {
+		rows, columns, x, y uint16
+	}
+	err := ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size))
+	if err != nil || size.columns == 0 || size.rows == 0 {
+		return 80, 24
+	}
+
+	return int(size.columns), int(size.rows)
+}
+
+func ioctl(fd int, request uintptr, argument unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(argument))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package altair
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lukepeterson/go8080cpu/pkg/clock"
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Standard I/O ports of the Altair's boards.
+const (
+	SIOStatusPort   = 0x00 // 88-SIO status
+	SIODataPort     = 0x01 // 88-SIO data
+	SIO2AControl    = 0x10 // 88-2SIO port A control and status
+	SIO2AData       = 0x11 // 88-2SIO port A data
+	SIO2BControl    = 0x12 // 88-2SIO port B control and status
+	SIO2BData       = 0x13 // 88-2SIO port B data
+	SenseSwitchPort = 0xFF // Front panel sense switches A15 to A8
+)
+
+// Console selects which serial board is connected to the host terminal.
+type Console int
+
+const (
+	Console2SIO Console = iota // 88-2SIO port A, as used by later versions of BASIC and CP/M
+	ConsoleSIO                 // 88-SIO, as used by 4K and 8K BASIC
+)
+
+// MaxMemorySize is the largest amount of RAM the Altair can address.
+const MaxMemorySize = 64 * 1024
+
+// batch is the number of instructions run between checks for the STOP switch.
+const batch = 1000
+
+// Config describes an Altair 8800's boards.
+type Config struct {
+	// MemorySize is the amount of RAM, from address 0x0000, in multiples of
+	// 1K.  Addresses above it read as 0xFF and ignore writes.  It defaults to
+	// 64K.
+	MemorySize int
+
+	// Console selects the serial board connected to the host terminal.  Both
+	// boards write to the terminal, but only the console reads from it.
+	Console Console
+
+	// ClockHz paces the CPU to a clock speed, such as clock.Intel8080Hz.  It
+	// defaults to 0, which runs the CPU as fast as the host allows.
+	ClockHz float64
+}
+
+// Machine is an Altair 8800: an 8080 CPU with RAM, an 88-SIO and an 88-2SIO
+// serial board and an 88-DCDD disk controller on their standard ports, and a
+// front panel.
+//
+// Example:
+//
+//	machine, err := altair.New(altair.Config{MemorySize: 16 * 1024})
+//	machine.ConnectTerminal(os.Stdin, os.Stdout)
+//	machine.Load(0x0000, basic)
+//	err = machine.Run()
+type Machine struct {
+	// CPU is the machine's processor.  It mustn't be used while the machine is
+	// running, except from the goroutine calling Run.
+	CPU *cpu.CPU
+
+	SIO  *SIO
+	SIO2 [2]*ACIA
+	Disk *DiskController
+
+	config        Config
+	memory        *Memory
+	terminal      *terminal
+	senseSwitches atomic.Uint32
+
+	mutex   sync.Mutex // Guards the CPU and memory between Run and the front panel
+	running atomic.Bool
+	stop    atomic.Bool
+}
+
+// New returns an Altair 8800 configured by config, with its program counter at
+// 0x0000.
+func New(config Config) (*Machine, error) {
+	if config.MemorySize == 0 {
+		config.MemorySize = MaxMemorySize
+	}
+	if config.MemorySize < 0 || config.MemorySize > MaxMemorySize || config.MemorySize%1024 != 0 {
+		return nil, fmt.Errorf("invalid memory size %d (must be a multiple of 1K up to 64K)", config.MemorySize)
+	}
+
+	machine := &Machine{
+		CPU:      cpu.New(),
+		config:   config,
+		memory:   NewMemory(config.MemorySize),
+		terminal: &terminal{},
+	}
+	machine.CPU.Bus = machine.memory
+
+	machine.SIO = newSIO(machine.terminal, config.Console == ConsoleSIO)
+	machine.SIO2[0] = newACIA(machine.terminal, config.Console == Console2SIO)
+	machine.SIO2[1] = newACIA(machine.terminal, false)
+	machine.Disk = NewDiskController()
+
+	machine.CPU.Attach(machine.SIO, SIOStatusPort, SIODataPort)
+	machine.CPU.Attach(machine.SIO2[0], SIO2AControl, SIO2AData)
+	machine.CPU.Attach(machine.SIO2[1], SIO2BControl, SIO2BData)
+	machine.CPU.Attach(machine.Disk, DiskSelectPort, DiskControlPort, DiskDataPort)
+	machine.CPU.Attach(senseSwitches{machine}, SenseSwitchPort)
+
+	return machine, nil
+}
+
+// Memory returns the machine's memory, to map in ROM or to inspect it while
+// the machine is stopped.
+func (machine *Machine) Memory() *Memory {
+	return machine.memory
+}
+
+// Close unmounts the disk drives, closing their images.
+func (machine *Machine) Close() error {
+	return machine.Disk.Close()
+}
+
+// ConnectTerminal connects the serial boards to the host terminal: input is
+// read from reader by the console board, and output from either board is
+// written to writer.  Either may be nil.
+func (machine *Machine) ConnectTerminal(reader io.Reader, writer io.Writer) {
+	machine.terminal.connect(reader, writer)
+}
+
+// Load deposits data into RAM starting at address, as if toggled in from the
+// front panel.
+func (machine *Machine) Load(address types.Word, data []byte) error {
+	machine.mutex.Lock()
+	defer machine.mutex.Unlock()
+
+	if int(address)+len(data) > MaxMemorySize {
+		return fmt.Errorf("could not load %d bytes at address 0x%04X (past the end of memory)", len(data), address)
+	}
+	for i, value := range data {
+		current := address + types.Word(i)
+		if machine.memory.pages[current>>8] != ram {
+			return fmt.Errorf("could not load byte at address 0x%04X (not RAM)", current)
+		}
+		machine.memory.data[current] = value
+	}
+
+	return nil
+}
+
+// Run runs the CPU from the current program counter until it halts, Stop is
+// called or an error occurs.  It's the front panel's RUN switch, and is
+// normally called from its own goroutine, so a Stop made before it starts
+// still stops it.
+func (machine *Machine) Run() error {
+	if !machine.running.CompareAndSwap(false, true) {
+		return fmt.Errorf("machine is already running")
+	}
+	defer machine.running.Store(false)
+
+	var throttle *clock.Throttle
+	if machine.config.ClockHz > 0 {
+		throttle = clock.New(machine.config.ClockHz)
+		throttle.Sync(0)
+	}
+
+	for !machine.stop.CompareAndSwap(true, false) {
+		machine.mutex.Lock()
+		start := machine.CPU.Cycles()
+		halted, err := machine.runBatch()
+		cycles := machine.CPU.Cycles() - start
+		machine.mutex.Unlock()
+
+		if err != nil || halted {
+			return err
+		}
+		if throttle != nil {
+			throttle.Sync(cycles)
+		}
+	}
+
+	return nil
+}
+
+// runBatch steps the CPU for a batch of instructions, returning whether it
+// halted.  A CPU halted with interrupts enabled is still stepped, idling until
+// a device interrupts it.
+func (machine *Machine) runBatch() (bool, error) {
+	for i := 0; i < batch; i++ {
+		if machine.CPU.Halted() && !machine.CPU.InterruptsEnabled() {
+			return true, nil
+		}
+
+		err := machine.CPU.Step()
+		if err != nil {
+			return true, fmt.Errorf("could not step cpu at 0x%04X: %w", machine.CPU.ProgramCounter(), err)
+		}
+	}
+
+	return false, nil
+}
+
+// Stop asks a running machine to stop after the current batch of
+// instructions, as the front panel's STOP switch does.  If the machine isn't
+// running yet, the next Run stops straight away unless Reset is called first.
+// It's safe to call from any goroutine.
+func (machine *Machine) Stop() {
+	machine.stop.Store(true)
+}
+
+// Running reports whether the machine is running.
+func (machine *Machine) Running() bool {
+	return machine.running.Load()
+}
+
+// SetSenseSwitches sets the front panel's sense switches (A15 to A8), which
+// programs read with IN 0xFF.  It's safe to call while the machine is running.
+func (machine *Machine) SetSenseSwitches(value byte) {
+	machine.senseSwitches.Store(uint32(value))
+}
+
+// senseSwitches is the device read by IN 0xFF.
+type senseSwitches struct {
+	machine *Machine
+}
+
+func (switches senseSwitches) ReadPort(port byte) byte {
+	return byte(switches.machine.senseSwitches.Load())
+}
+
+func (switches senseSwitches) WritePort(port byte, value byte) {}
+
+// page says what occupies a 256 byte page of the address space.
+type page byte
+
+const (
+	unpopulated page = iota
+	ram
+	rom
+)
+
+// Memory is the Altair's memory: RAM from address 0x0000, and optionally ROM,
+// each occupying whole 256 byte pages, with unpopulated addresses reading as
+// 0xFF as they do on the real bus.
+type Memory struct {
+	data  [MaxMemorySize]byte
+	pages [256]page
+}
+
+// NewMemory returns memory with size bytes of RAM, rounded up to a whole page.
+func NewMemory(size int) *Memory {
+	memory := &Memory{}
+	for i := 0; i*256 < size && i < len(memory.pages); i++ {
+		memory.pages[i] = ram
+	}
+
+	return memory
+}
+
+// MapROM maps data as ROM starting at address, in place of any RAM in the
+// pages it occupies.
+func (memory *Memory) MapROM(address types.Word, data []byte) {
+	for i, value := range data {
+		memory.data[address+types.Word(i)] = value
+		memory.pages[(address+types.Word(i))>>8] = rom
+	}
+}
+
+// ReadByteAt reads a byte from ROM or RAM, or 0xFF from an unpopulated address.
+func (memory *Memory) ReadByteAt(address types.Word) (byte, error) {
+	if memory.pages[address>>8] == unpopulated {
+		return 0xFF, nil
+	}
+
+	return memory.data[address], nil
+}
+
+// WriteByteAt writes a byte to RAM.  Writes to ROM or unpopulated addresses
+// are ignored.
+func (memory *Memory) WriteByteAt(address types.Word, data byte) error {
+	if memory.pages[address>>8] == ram {
+		memory.data[address] = data
+	}
+
+	return nil
+}
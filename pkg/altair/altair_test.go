@@ -0,0 +1,273 @@
+package altair
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		memorySize int
+		wantErr    bool
+	}{
+		{memorySize: 0, wantErr: false},
+		{memorySize: 1024, wantErr: false},
+		{memorySize: 16 * 1024, wantErr: false},
+		{memorySize: MaxMemorySize, wantErr: false},
+		{memorySize: 1000, wantErr: true},
+		{memorySize: -1024, wantErr: true},
+		{memorySize: MaxMemorySize + 1024, wantErr: true},
+	}
+
+	for _, test := range tests {
+		_, err := New(Config{MemorySize: test.memorySize})
+		if test.wantErr && err == nil {
+			t.Errorf("expected an error for memory size %d, but got none", test.memorySize)
+		}
+		if !test.wantErr && err != nil {
+			t.Errorf("did not expect an error for memory size %d, but got: %v", test.memorySize, err)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	memory := NewMemory(4 * 1024)
+	memory.MapROM(0xFF00, []byte{0x11, 0x22})
+
+	tests := []struct {
+		address types.Word
+		write   byte
+		want    byte
+	}{
+		{address: 0x0000, write: 0x55, want: 0x55},
+		{address: 0x0FFF, write: 0x66, want: 0x66},
+		{address: 0x1000, write: 0x77, want: 0xFF}, // Unpopulated
+		{address: 0xFF00, write: 0x88, want: 0x11}, // ROM
+		{address: 0xFF01, write: 0x99, want: 0x22},
+		{address: 0xFF02, write: 0xAA, want: 0x00}, // In a ROM page, but not part of the ROM
+	}
+
+	for _, test := range tests {
+		memory.WriteByteAt(test.address, test.write)
+		got, err := memory.ReadByteAt(test.address)
+		if err != nil {
+			t.Fatalf("error reading address 0x%04X: %v", test.address, err)
+		}
+		if got != test.want {
+			t.Errorf("address 0x%04X = 0x%02X after writing 0x%02X, want 0x%02X", test.address, got, test.write, test.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	machine, _ := New(Config{MemorySize: 1024})
+
+	err := machine.Load(0x03FE, []byte{0x01, 0x02})
+	if err != nil {
+		t.Errorf("did not expect an error loading into RAM, but got: %v", err)
+	}
+
+	err = machine.Load(0x03FF, []byte{0x01, 0x02})
+	if err == nil {
+		t.Errorf("expected an error loading past the end of RAM, but got none")
+	}
+}
+
+func TestFrontPanel(t *testing.T) {
+	machine, _ := New(Config{})
+	machine.SetSenseSwitches(0xA5)
+
+	// Toggle in IN 0xFF, STA 0x0100, HLT
+	program := []byte{0xDB, 0xFF, 0x32, 0x00, 0x01, 0x76}
+	machine.Examine(0x0000)
+	machine.Deposit(program[0])
+	for _, value := range program[1:] {
+		machine.DepositNext(value)
+	}
+
+	if lights := machine.Lights(); lights.Address != 0x0005 || lights.Data != 0x76 || !lights.WAIT {
+		t.Errorf("lights = %+v after depositing, want address 0x0005, data 0x76 and WAIT", lights)
+	}
+
+	machine.Examine(0x0000)
+	machine.SingleStep()
+	if machine.CPU.A != 0xA5 {
+		t.Errorf("A = 0x%02X after a single step, want the sense switches 0xA5", machine.CPU.A)
+	}
+
+	err := machine.Run()
+	if err != nil {
+		t.Fatalf("error running machine: %v", err)
+	}
+
+	if value, _ := machine.Memory().ReadByteAt(0x0100); value != 0xA5 {
+		t.Errorf("memory at 0x0100 = 0x%02X, want 0xA5", value)
+	}
+	if lights := machine.Lights(); lights.Address != 0x0006 || !lights.HLTA || !lights.WAIT {
+		t.Errorf("lights = %+v after halting, want address 0x0006, HLTA and WAIT", lights)
+	}
+
+	machine.Reset()
+	if lights := machine.Lights(); lights.Address != 0x0000 || lights.HLTA {
+		t.Errorf("lights = %+v after reset, want address 0x0000 and HLTA clear", lights)
+	}
+}
+
+func TestRunStop(t *testing.T) {
+	machine, _ := New(Config{})
+	machine.Load(0x0000, []byte{0xC3, 0x00, 0x00}) // JMP 0x0000
+
+	done := make(chan error)
+	go func() { done <- machine.Run() }()
+	for !machine.Running() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := machine.Examine(0x1234); err == nil {
+		t.Errorf("expected an error examining while running, but got none")
+	}
+	if err := machine.Run(); err == nil {
+		t.Errorf("expected an error running while already running, but got none")
+	}
+
+	machine.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("error running machine: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("machine still running 5 seconds after STOP")
+	}
+
+	if machine.Running() {
+		t.Errorf("Running() = true after stopping, want false")
+	}
+}
+
+func TestStopBeforeRun(t *testing.T) {
+	machine, _ := New(Config{})
+	machine.Load(0x0000, []byte{0xC3, 0x00, 0x00}) // JMP 0x0000
+
+	// run runs the machine, returning whether it stopped by itself within wait,
+	// and otherwise stopping it.
+	run := func(wait time.Duration) bool {
+		done := make(chan error)
+		go func() { done <- machine.Run() }()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("error running machine: %v", err)
+			}
+			return true
+		case <-time.After(wait):
+		}
+
+		machine.Stop()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("error running machine: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("machine still running 5 seconds after STOP")
+		}
+		return false
+	}
+
+	machine.Stop()
+	if !run(5 * time.Second) {
+		t.Errorf("machine ran after STOP made before RUN")
+	}
+	if run(50 * time.Millisecond) {
+		t.Errorf("machine stopped straight away, after STOP was already acted on")
+	}
+
+	machine.Stop()
+	machine.Reset()
+	if run(50 * time.Millisecond) {
+		t.Errorf("machine stopped straight away, after RESET cancelled STOP")
+	}
+}
+
+func TestConsole(t *testing.T) {
+	tests := []struct {
+		name    string
+		console Console
+		program []byte
+	}{
+		{
+			name:    "88-SIO",
+			console: ConsoleSIO,
+			program: []byte{
+				0xDB, SIOStatusPort, // 0000 IN SIOStatusPort
+				0x0F,             // 0002 RRC
+				0xDA, 0x00, 0x00, // 0003 JC 0x0000
+				0xDB, SIODataPort, // 0006 IN SIODataPort
+				0xD3, SIODataPort, // 0008 OUT SIODataPort
+				0xFE, 0x0D, // 000A CPI 0x0D
+				0xC2, 0x00, 0x00, // 000C JNZ 0x0000
+				0x76, // 000F HLT
+			},
+		},
+		{
+			name:    "88-2SIO",
+			console: Console2SIO,
+			program: []byte{
+				0x3E, 0x03, // 0000 MVI A,0x03 (master reset)
+				0xD3, SIO2AControl, // 0002 OUT SIO2AControl
+				0x3E, 0x15, // 0004 MVI A,0x15 (8N1, divide by 16)
+				0xD3, SIO2AControl, // 0006 OUT SIO2AControl
+				0xDB, SIO2AControl, // 0008 IN SIO2AControl
+				0x0F,             // 000A RRC
+				0xD2, 0x08, 0x00, // 000B JNC 0x0008
+				0xDB, SIO2AData, // 000E IN SIO2AData
+				0xD3, SIO2AData, // 0010 OUT SIO2AData
+				0xFE, 0x0D, // 0012 CPI 0x0D
+				0xC2, 0x08, 0x00, // 0014 JNZ 0x0008
+				0x76, // 0017 HLT
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			machine, _ := New(Config{Console: test.console})
+			var output bytes.Buffer
+			machine.ConnectTerminal(strings.NewReader("hi\n"), &output)
+			machine.Load(0x0000, test.program)
+
+			err := machine.Run()
+			if err != nil {
+				t.Fatalf("error running machine: %v", err)
+			}
+
+			if output.String() != "hi\r" {
+				t.Errorf("output = %q, want %q", output.String(), "hi\r")
+			}
+		})
+	}
+}
+
+func TestSerialStatus(t *testing.T) {
+	machine, _ := New(Config{Console: ConsoleSIO})
+	machine.ConnectTerminal(nil, nil)
+	machine.terminal.incoming = []byte{'x'}
+
+	if got := machine.SIO.ReadPort(SIOStatusPort); got != 0 {
+		t.Errorf("88-SIO status = 0b%08b with a character waiting, want 0 (both ready)", got)
+	}
+	if got := machine.SIO2[0].ReadPort(SIO2AControl); got != ACIATransmitEmpty {
+		t.Errorf("88-2SIO status = 0b%08b when not the console, want only TDRE", got)
+	}
+
+	machine.SIO.ReadPort(SIODataPort)
+	if got := machine.SIO.ReadPort(SIOStatusPort); got != SIOInputReady {
+		t.Errorf("88-SIO status = 0b%08b after reading, want input not ready", got)
+	}
+}
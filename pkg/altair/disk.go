@@ -0,0 +1,322 @@
+package altair
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// 88-DCDD controller ports.
+const (
+	DiskSelectPort  = 0x08 // OUT selects a drive, IN reads the status
+	DiskControlPort = 0x09 // OUT controls the selected drive, IN reads the sector position
+	DiskDataPort    = 0x0A // Reads and writes sector data
+)
+
+// 88-DCDD disk geometry.  Each sector is stored in a .dsk image as the 137
+// bytes the controller reads and writes, in track then sector order.
+const (
+	Tracks          = 77
+	SectorsPerTrack = 32
+	SectorSize      = 137
+	DiskImageSize   = Tracks * SectorsPerTrack * SectorSize
+	MaxDrives       = 16
+)
+
+// Disk status bits, as read from the select port.  Each is active low.
+const (
+	DiskEnterWriteData   = 1 << 0 // ENWD - the controller is ready for the next byte to write
+	DiskMoveHeadOK       = 1 << 1 // The head can be stepped
+	DiskHeadStatus       = 1 << 2 // The head is loaded
+	DiskInterruptEnabled = 1 << 5 // INTE - sector interrupts are enabled
+	DiskTrack0           = 1 << 6 // The head is at track 0
+	DiskReadDataReady    = 1 << 7 // NRDA - a byte is ready to read
+)
+
+// Disk control bits, as written to the control port.
+const (
+	diskStepIn           = 1 << 0
+	diskStepOut          = 1 << 1
+	diskHeadLoad         = 1 << 2
+	diskHeadUnload       = 1 << 3
+	diskInterruptEnable  = 1 << 4
+	diskInterruptDisable = 1 << 5
+	diskWriteEnable      = 1 << 7 // Starts a write sequence at the current sector
+)
+
+const diskDeselect = 1 << 7 // Select port bit that deselects every drive
+
+// DiskImage is the storage behind a drive, such as an *os.File holding a .dsk
+// image.
+type DiskImage interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// drive is one of the drives attached to the controller.
+type drive struct {
+	image          DiskImage
+	writeProtected bool
+	track          int
+	sector         int
+	headLoaded     bool
+}
+
+// DiskController emulates the MITS 88-DCDD floppy disk controller, with up to
+// 16 drives of 77 tracks of 32 sectors of 137 bytes.
+//
+// The sector under the head advances each time the sector position is read,
+// so software polling for a sector finds it without waiting for the disk to
+// turn.  The controller's sector interrupts aren't emulated, although their
+// enable is shown in the status.
+//
+// Example:
+//
+//	machine.Disk.MountFile(0, "cpm.dsk", false)
+//	machine.Memory().MapROM(0xFF00, bootLoader) // The disk boot loader ROM
+//	machine.Examine(0xFF00)
+//	err := machine.Run()
+type DiskController struct {
+	drives   [MaxDrives]*drive
+	selected *drive
+
+	interruptsEnabled bool
+	buffer            [SectorSize]byte
+	position          int
+	bufferLoaded      bool // The buffer holds the current sector, ready to read
+	writing           bool
+
+	err error
+}
+
+// NewDiskController returns a controller with no drives mounted.
+func NewDiskController() *DiskController {
+	return &DiskController{}
+}
+
+// Mount attaches image to a drive, in place of any image already mounted.
+// Writes to a write protected drive are discarded.  Drives should only be
+// mounted while the machine is stopped.
+func (controller *DiskController) Mount(number int, image DiskImage, writeProtected bool) error {
+	if number < 0 || number >= MaxDrives {
+		return fmt.Errorf("invalid drive number %d (must be 0 to %d)", number, MaxDrives-1)
+	}
+
+	err := controller.Unmount(number)
+	if err != nil {
+		return err
+	}
+	controller.drives[number] = &drive{image: image, writeProtected: writeProtected}
+
+	return nil
+}
+
+// MountFile opens the .dsk image at path and mounts it on a drive.  A write
+// protected image is opened read only.
+func (controller *DiskController) MountFile(number int, path string, writeProtected bool) error {
+	flag := os.O_RDWR
+	if writeProtected {
+		flag = os.O_RDONLY
+	}
+
+	file, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return fmt.Errorf("could not open disk image: %v", err)
+	}
+
+	err = controller.Mount(number, file, writeProtected)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Unmount detaches the image from a drive, closing it if it's an io.Closer.
+func (controller *DiskController) Unmount(number int) error {
+	if number < 0 || number >= MaxDrives {
+		return fmt.Errorf("invalid drive number %d (must be 0 to %d)", number, MaxDrives-1)
+	}
+
+	drive := controller.drives[number]
+	if drive == nil {
+		return nil
+	}
+	if controller.selected == drive {
+		controller.selected = nil
+	}
+	controller.drives[number] = nil
+
+	if closer, ok := drive.image.(io.Closer); ok {
+		err := closer.Close()
+		if err != nil {
+			return fmt.Errorf("could not close disk image: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Close unmounts every drive.
+func (controller *DiskController) Close() error {
+	for number := range controller.drives {
+		err := controller.Unmount(number)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Err returns the last error reading or writing a disk image.  The 88-DCDD
+// has no way to report one to the CPU, so the sector reads as zeros, or the
+// write is lost.
+func (controller *DiskController) Err() error {
+	return controller.err
+}
+
+// ReadPort reads the status, the sector position or the next byte of the
+// current sector.  With no drive selected, every port reads 0xFF.
+func (controller *DiskController) ReadPort(port byte) byte {
+	drive := controller.selected
+	if drive == nil {
+		return 0xFF
+	}
+
+	switch port {
+	case DiskSelectPort:
+		return controller.status()
+	case DiskControlPort:
+		if !drive.headLoaded {
+			return 0xFF
+		}
+		drive.sector = (drive.sector + 1) % SectorsPerTrack
+		controller.position = 0
+		controller.bufferLoaded, controller.writing = false, false
+		return 0b1100_0000 | byte(drive.sector)<<1 // Bit 0 clear is sector true
+	default:
+		if !controller.bufferLoaded {
+			controller.readSector()
+		}
+		if controller.position >= SectorSize {
+			return 0xFF
+		}
+		value := controller.buffer[controller.position]
+		controller.position++
+		return value
+	}
+}
+
+// WritePort selects a drive, controls the selected drive or writes the next
+// byte of a write sequence.  Stepping or unloading the head abandons a write
+// sequence.
+func (controller *DiskController) WritePort(port byte, value byte) {
+	if port == DiskSelectPort {
+		controller.selected = nil
+		if value&diskDeselect == 0 {
+			controller.selected = controller.drives[value&0x0F]
+		}
+		controller.bufferLoaded, controller.writing = false, false
+		return
+	}
+
+	drive := controller.selected
+	if drive == nil {
+		return
+	}
+
+	if port == DiskDataPort {
+		if !controller.writing {
+			return
+		}
+		controller.buffer[controller.position] = value
+		controller.position++
+		if controller.position == SectorSize {
+			controller.writeSector()
+			controller.writing = false
+		}
+		return
+	}
+
+	if value&diskStepIn != 0 && drive.track < Tracks-1 {
+		drive.track++
+		controller.bufferLoaded, controller.writing = false, false
+	}
+	if value&diskStepOut != 0 && drive.track > 0 {
+		drive.track--
+		controller.bufferLoaded, controller.writing = false, false
+	}
+	if value&diskHeadLoad != 0 {
+		drive.headLoaded = true
+	}
+	if value&diskHeadUnload != 0 {
+		drive.headLoaded = false
+		controller.writing = false
+	}
+	if value&diskInterruptEnable != 0 {
+		controller.interruptsEnabled = true
+	}
+	if value&diskInterruptDisable != 0 {
+		controller.interruptsEnabled = false
+	}
+	if value&diskWriteEnable != 0 {
+		controller.writing = true
+		controller.bufferLoaded = false
+		controller.position = 0
+	}
+}
+
+// status returns the status of the selected drive, with each active bit clear.
+func (controller *DiskController) status() byte {
+	drive := controller.selected
+	status := byte(DiskEnterWriteData | DiskHeadStatus | DiskInterruptEnabled | DiskTrack0 | DiskReadDataReady)
+
+	if controller.writing {
+		status &^= DiskEnterWriteData
+	}
+	if drive.headLoaded {
+		status &^= DiskHeadStatus | DiskReadDataReady
+	}
+	if controller.interruptsEnabled {
+		status &^= DiskInterruptEnabled
+	}
+	if drive.track == 0 {
+		status &^= DiskTrack0
+	}
+
+	return status // DiskMoveHeadOK is always clear, as the head steps instantly
+}
+
+// offset returns the position of the selected drive's current sector in its image.
+func (controller *DiskController) offset() int64 {
+	drive := controller.selected
+	return int64((drive.track*SectorsPerTrack + drive.sector) * SectorSize)
+}
+
+// readSector reads the current sector into the buffer.  The part of a sector
+// past the end of a short image reads as zeros.
+func (controller *DiskController) readSector() {
+	controller.buffer = [SectorSize]byte{}
+	controller.position = 0
+	controller.bufferLoaded = true
+
+	_, err := controller.selected.image.ReadAt(controller.buffer[:], controller.offset())
+	if err != nil && err != io.EOF {
+		controller.err = fmt.Errorf("could not read track %d sector %d: %v", controller.selected.track, controller.selected.sector, err)
+	}
+}
+
+// writeSector writes the buffer to the current sector, unless the drive is
+// write protected.
+func (controller *DiskController) writeSector() {
+	if controller.selected.writeProtected {
+		return
+	}
+
+	_, err := controller.selected.image.WriteAt(controller.buffer[:], controller.offset())
+	if err != nil {
+		controller.err = fmt.Errorf("could not write track %d sector %d: %v", controller.selected.track, controller.selected.sector, err)
+	}
+}
@@ -0,0 +1,208 @@
+package altair
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// memoryImage is a disk image held in memory.
+type memoryImage struct {
+	data []byte
+}
+
+func newMemoryImage() *memoryImage {
+	return &memoryImage{data: make([]byte, DiskImageSize)}
+}
+
+func (image *memoryImage) ReadAt(p []byte, offset int64) (int, error) {
+	return copy(p, image.data[offset:]), nil
+}
+
+func (image *memoryImage) WriteAt(p []byte, offset int64) (int, error) {
+	return copy(image.data[offset:], p), nil
+}
+
+// sectorOffset returns the offset of a sector in a disk image.
+func sectorOffset(track, sector int) int {
+	return (track*SectorsPerTrack + sector) * SectorSize
+}
+
+// seekSector reads the sector position until sector is under the head.
+func seekSector(controller *DiskController, sector int) {
+	for int(controller.ReadPort(DiskControlPort)>>1&0x1F) != sector {
+	}
+}
+
+func TestDiskStatus(t *testing.T) {
+	controller := NewDiskController()
+	controller.Mount(1, newMemoryImage(), false)
+
+	tests := []struct {
+		name  string
+		port  byte
+		value byte
+		want  byte
+	}{
+		{"no drive selected", DiskSelectPort, 0x80, 0xFF},
+		{"empty drive selected", DiskSelectPort, 0x00, 0xFF},
+		{"drive selected", DiskSelectPort, 0x01, 0b1010_0101},
+		{"head loaded", DiskControlPort, diskHeadLoad, 0b0010_0001},
+		{"stepped in", DiskControlPort, diskStepIn, 0b0110_0001},
+		{"interrupts enabled", DiskControlPort, diskInterruptEnable, 0b0100_0001},
+		{"write enabled", DiskControlPort, diskWriteEnable, 0b0100_0000},
+		{"stepped out and head unloaded", DiskControlPort, diskStepOut | diskHeadUnload | diskInterruptDisable, 0b1010_0101},
+	}
+
+	for _, test := range tests {
+		controller.WritePort(test.port, test.value)
+		if got := controller.ReadPort(DiskSelectPort); got != test.want {
+			t.Errorf("%s: status = 0b%08b, want 0b%08b", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDiskStepping(t *testing.T) {
+	controller := NewDiskController()
+	controller.Mount(0, newMemoryImage(), false)
+	controller.WritePort(DiskSelectPort, 0)
+
+	controller.WritePort(DiskControlPort, diskStepOut)
+	if track := controller.selected.track; track != 0 {
+		t.Errorf("track = %d after stepping out from track 0, want 0", track)
+	}
+
+	for i := 0; i < 100; i++ {
+		controller.WritePort(DiskControlPort, diskStepIn)
+	}
+	if track := controller.selected.track; track != Tracks-1 {
+		t.Errorf("track = %d after stepping in 100 times, want %d", track, Tracks-1)
+	}
+}
+
+func TestDiskRead(t *testing.T) {
+	image := newMemoryImage()
+	for i := 0; i < SectorSize; i++ {
+		image.data[sectorOffset(1, 3)+i] = byte(i + 1)
+	}
+
+	machine, _ := New(Config{})
+	machine.Disk.Mount(0, image, true)
+	machine.Load(0x0000, []byte{
+		0x3E, 0x00, // 0000 MVI A,0x00
+		0xD3, DiskSelectPort, // 0002 OUT DiskSelectPort
+		0x3E, diskStepIn | diskHeadLoad, // 0004 MVI A,0x05
+		0xD3, DiskControlPort, // 0006 OUT DiskControlPort
+		0xDB, DiskControlPort, // 0008 IN DiskControlPort
+		0x1F,             // 000A RAR
+		0xDA, 0x08, 0x00, // 000B JC 0x0008
+		0xE6, 0x1F, // 000E ANI 0x1F
+		0xFE, 0x03, // 0010 CPI 0x03
+		0xC2, 0x08, 0x00, // 0012 JNZ 0x0008
+		0x21, 0x00, 0x10, // 0015 LXI H,0x1000
+		0x0E, SectorSize, // 0018 MVI C,137
+		0xDB, DiskDataPort, // 001A IN DiskDataPort
+		0x77,             // 001C MOV M,A
+		0x23,             // 001D INX H
+		0x0D,             // 001E DCR C
+		0xC2, 0x1A, 0x00, // 001F JNZ 0x001A
+		0x76, // 0022 HLT
+	})
+
+	err := machine.Run()
+	if err != nil {
+		t.Fatalf("error running machine: %v", err)
+	}
+
+	for i := 0; i < SectorSize; i++ {
+		value, _ := machine.Memory().ReadByteAt(0x1000 + types.Word(i))
+		if value != byte(i+1) {
+			t.Fatalf("byte %d of the sector = 0x%02X, want 0x%02X", i, value, byte(i+1))
+		}
+	}
+}
+
+func TestDiskWrite(t *testing.T) {
+	sector := bytes.Repeat([]byte{0xA5}, SectorSize)
+
+	tests := []struct {
+		name           string
+		writeProtected bool
+		want           []byte
+	}{
+		{"writable", false, sector},
+		{"write protected", true, make([]byte, SectorSize)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := newMemoryImage()
+			controller := NewDiskController()
+			controller.Mount(2, image, test.writeProtected)
+
+			controller.WritePort(DiskSelectPort, 2)
+			controller.WritePort(DiskControlPort, diskHeadLoad)
+			seekSector(controller, 5)
+			controller.WritePort(DiskControlPort, diskWriteEnable)
+			for _, value := range sector {
+				controller.WritePort(DiskDataPort, value)
+			}
+
+			if status := controller.ReadPort(DiskSelectPort); status&DiskEnterWriteData == 0 {
+				t.Errorf("status = 0b%08b after a whole sector, want ENWD inactive", status)
+			}
+			got := image.data[sectorOffset(0, 5) : sectorOffset(0, 5)+SectorSize]
+			if !bytes.Equal(got, test.want) {
+				t.Errorf("sector = % X, want % X", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMountFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.dsk")
+	err := os.WriteFile(path, nil, 0o644) // A short, unformatted image
+	if err != nil {
+		t.Fatalf("error creating disk image: %v", err)
+	}
+
+	controller := NewDiskController()
+	if err := controller.MountFile(MaxDrives, path, false); err == nil {
+		t.Errorf("expected an error for drive %d, but got none", MaxDrives)
+	}
+	if err := controller.MountFile(0, filepath.Join(t.TempDir(), "missing.dsk"), false); err == nil {
+		t.Errorf("expected an error for a missing image, but got none")
+	}
+
+	err = controller.MountFile(0, path, false)
+	if err != nil {
+		t.Fatalf("error mounting disk image: %v", err)
+	}
+	controller.WritePort(DiskSelectPort, 0)
+	controller.WritePort(DiskControlPort, diskHeadLoad)
+	seekSector(controller, 1)
+	if value := controller.ReadPort(DiskDataPort); value != 0 {
+		t.Errorf("first byte of an unformatted sector = 0x%02X, want 0", value)
+	}
+
+	seekSector(controller, 1)
+	controller.WritePort(DiskControlPort, diskWriteEnable)
+	for i := 0; i < SectorSize; i++ {
+		controller.WritePort(DiskDataPort, 0xE5)
+	}
+	if err := controller.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+
+	err = controller.Close()
+	if err != nil {
+		t.Fatalf("error closing disk image: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if len(data) != sectorOffset(0, 2) || data[sectorOffset(0, 1)] != 0xE5 {
+		t.Errorf("image is %d bytes, want %d with sector 1 written", len(data), sectorOffset(0, 2))
+	}
+}
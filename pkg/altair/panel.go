@@ -0,0 +1,106 @@
+package altair
+
+import (
+	"fmt"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Lights holds the state of the front panel's LEDs.
+type Lights struct {
+	Address types.Word // A15 to A0: the program counter
+	Data    byte       // D7 to D0: the byte at the program counter
+	INTE    bool       // Interrupts are enabled
+	HLTA    bool       // The CPU has halted
+	WAIT    bool       // The machine is stopped
+}
+
+// Lights returns the state of the front panel's LEDs.  While the machine is
+// running they're sampled between batches of instructions, so a UI can poll
+// them to render the panel.  It's safe to call from any goroutine.
+func (machine *Machine) Lights() Lights {
+	machine.mutex.Lock()
+	defer machine.mutex.Unlock()
+
+	address := machine.CPU.ProgramCounter()
+	data, _ := machine.memory.ReadByteAt(address)
+
+	return Lights{
+		Address: address,
+		Data:    data,
+		INTE:    machine.CPU.InterruptsEnabled(),
+		HLTA:    machine.CPU.Halted(),
+		WAIT:    !machine.Running(),
+	}
+}
+
+// Examine sets the program counter to address, showing it and the byte there
+// on the LEDs.
+func (machine *Machine) Examine(address types.Word) error {
+	return machine.whileStopped("examine", func() error {
+		machine.CPU.SetProgramCounter(address)
+		return nil
+	})
+}
+
+// ExamineNext moves the program counter on to the next address.
+func (machine *Machine) ExamineNext() error {
+	return machine.whileStopped("examine next", func() error {
+		machine.CPU.SetProgramCounter(machine.CPU.ProgramCounter() + 1)
+		return nil
+	})
+}
+
+// Deposit writes value to memory at the program counter.
+func (machine *Machine) Deposit(value byte) error {
+	return machine.whileStopped("deposit", func() error {
+		return machine.memory.WriteByteAt(machine.CPU.ProgramCounter(), value)
+	})
+}
+
+// DepositNext moves the program counter on to the next address, then writes
+// value there, so that a program can be toggled in byte by byte.
+func (machine *Machine) DepositNext(value byte) error {
+	return machine.whileStopped("deposit next", func() error {
+		machine.CPU.SetProgramCounter(machine.CPU.ProgramCounter() + 1)
+		return machine.memory.WriteByteAt(machine.CPU.ProgramCounter(), value)
+	})
+}
+
+// SingleStep executes one instruction.
+func (machine *Machine) SingleStep() error {
+	return machine.whileStopped("single step", func() error {
+		err := machine.CPU.Step()
+		if err != nil {
+			return fmt.Errorf("could not step cpu: %w", err)
+		}
+		return nil
+	})
+}
+
+// Reset resets the CPU, clearing the program counter and leaving the halt
+// state.  Like the front panel's RESET switch, it works while running.  While
+// stopped, it also cancels a Stop made since the machine last ran.
+func (machine *Machine) Reset() {
+	machine.mutex.Lock()
+	defer machine.mutex.Unlock()
+
+	machine.CPU.Reset()
+	if !machine.Running() {
+		machine.stop.Store(false)
+	}
+}
+
+// whileStopped operates a front panel switch, which only works while the
+// machine is stopped.  The state is checked holding the lock, so a Run
+// starting meanwhile waits for the switch to finish.
+func (machine *Machine) whileStopped(name string, operate func() error) error {
+	machine.mutex.Lock()
+	defer machine.mutex.Unlock()
+
+	if machine.Running() {
+		return fmt.Errorf("could not %s while running", name)
+	}
+
+	return operate()
+}
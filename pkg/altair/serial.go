@@ -0,0 +1,180 @@
+package altair
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// terminal is the host terminal shared by the serial boards.  Input is read
+// from a separate goroutine, so the reader can block without stalling the CPU.
+type terminal struct {
+	mutex    sync.Mutex
+	incoming []byte
+	writer   io.Writer
+}
+
+// connect connects the terminal to reader and writer.  Line feeds read from the
+// host are passed to the Altair as carriage returns, which is what its
+// software expects at the end of a line.
+func (terminal *terminal) connect(reader io.Reader, writer io.Writer) {
+	terminal.mutex.Lock()
+	terminal.writer = writer
+	terminal.mutex.Unlock()
+
+	if reader == nil {
+		return
+	}
+	go func() {
+		buffered := bufio.NewReader(reader)
+		for {
+			value, err := buffered.ReadByte()
+			if err != nil {
+				return
+			}
+			if value == '\n' {
+				value = '\r'
+			}
+			terminal.mutex.Lock()
+			terminal.incoming = append(terminal.incoming, value)
+			terminal.mutex.Unlock()
+		}
+	}()
+}
+
+// ready reports whether a character is waiting to be read.
+func (terminal *terminal) ready() bool {
+	terminal.mutex.Lock()
+	defer terminal.mutex.Unlock()
+
+	return len(terminal.incoming) > 0
+}
+
+// read returns the next character from the host, or 0 if there isn't one.
+func (terminal *terminal) read() byte {
+	terminal.mutex.Lock()
+	defer terminal.mutex.Unlock()
+
+	if len(terminal.incoming) == 0 {
+		return 0
+	}
+	value := terminal.incoming[0]
+	terminal.incoming = terminal.incoming[1:]
+	return value
+}
+
+// write sends a character to the host.  Bit 7 is cleared, as Altair software
+// often sets it, and terminals of the time ignored it.
+func (terminal *terminal) write(value byte) {
+	terminal.mutex.Lock()
+	writer := terminal.writer
+	terminal.mutex.Unlock()
+
+	if writer != nil {
+		writer.Write([]byte{value & 0x7F})
+	}
+}
+
+// 88-SIO status bits, which are active low.
+const (
+	SIOInputReady  = 1 << 0 // Clear when a character is waiting to be read
+	SIOOutputReady = 1 << 7 // Clear when a character can be written
+)
+
+// SIO emulates the MITS 88-SIO serial board, with its status port and data
+// port.  Characters are sent to the host as soon as they're written.
+type SIO struct {
+	terminal *terminal
+	console  bool
+}
+
+func newSIO(terminal *terminal, console bool) *SIO {
+	return &SIO{terminal: terminal, console: console}
+}
+
+// ReadPort returns the status from the status port, or the next character from
+// the data port.
+func (sio *SIO) ReadPort(port byte) byte {
+	if port&1 == 1 {
+		if !sio.console {
+			return 0
+		}
+		return sio.terminal.read()
+	}
+
+	var status byte = SIOInputReady // Output is always ready
+	if sio.console && sio.terminal.ready() {
+		status &^= SIOInputReady
+	}
+	return status
+}
+
+// WritePort sends a character written to the data port.  Writes to the status
+// port, which enable the board's interrupts, are ignored.
+func (sio *SIO) WritePort(port byte, value byte) {
+	if port&1 == 1 {
+		sio.terminal.write(value)
+	}
+}
+
+// 88-2SIO (Motorola 6850 ACIA) status bits.
+const (
+	ACIAReceiveFull   = 1 << 0 // RDRF - a character is waiting to be read
+	ACIATransmitEmpty = 1 << 1 // TDRE - a character can be written
+	ACIAInterrupt     = 1 << 7 // IRQ - the receive interrupt is enabled, and a character is waiting
+)
+
+const (
+	aciaMasterReset      = 0b11   // Counter divide select bits of the control register
+	aciaReceiveInterrupt = 1 << 7 // Receive interrupt enable bit of the control register
+)
+
+// ACIA emulates one port of the MITS 88-2SIO serial board, which is built
+// around a Motorola 6850 ACIA, with its control and status port and its data
+// port.  Characters are sent to the host as soon as they're written.
+type ACIA struct {
+	terminal *terminal
+	console  bool
+	control  byte
+	reset    bool
+}
+
+func newACIA(terminal *terminal, console bool) *ACIA {
+	return &ACIA{terminal: terminal, console: console}
+}
+
+// ReadPort returns the status from the control port, or the next character
+// from the data port.
+func (acia *ACIA) ReadPort(port byte) byte {
+	if port&1 == 1 {
+		if !acia.console {
+			return 0
+		}
+		return acia.terminal.read()
+	}
+
+	if acia.reset {
+		return 0 // Held in reset until the control register is written again
+	}
+	var status byte = ACIATransmitEmpty
+	if acia.console && acia.terminal.ready() {
+		status |= ACIAReceiveFull
+		if acia.control&aciaReceiveInterrupt != 0 {
+			status |= ACIAInterrupt
+		}
+	}
+	return status
+}
+
+// WritePort writes the control register from the control port, or sends a
+// character written to the data port.  The word format and baud rate divider
+// bits of the control register are accepted and ignored.
+func (acia *ACIA) WritePort(port byte, value byte) {
+	if port&1 == 1 {
+		acia.terminal.write(value)
+		return
+	}
+
+	acia.control = value
+	acia.reset = value&aciaMasterReset == aciaMasterReset
+}
@@ -0,0 +1,564 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpm"
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// I8080ClockHz is the clock speed of a standard 2 MHz Intel 8080, which
+// effective clock speeds are compared against.
+const I8080ClockHz = 2_000_000
+
+// Workload is a program used to measure the speed of the emulator.  Each
+// program loops forever, or is a CP/M program restarted each time it ends, so
+// it can be run for as long as a measurement needs.
+type Workload struct {
+	Name        string
+	Description string
+	Program     []byte
+
+	// CPM runs Program at 0x0100 as a CP/M program with a BDOS, rather than
+	// at 0x0000 on its own.
+	CPM bool
+	// Passed is the console output of a CP/M program that passed.  Any other
+	// output fails the workload.
+	Passed string
+}
+
+// Workloads holds the instruction mixes measured by the benchmarks and by
+// "cpu bench".  The programs are hand-assembled, and listed with the address
+// and disassembly of each instruction.
+var Workloads = []Workload{
+	{
+		Name:        "tight-loop",
+		Description: "register arithmetic in a short counted loop",
+		Program: []byte{
+			0x06, 0x00, // 0000 MVI B,0x00
+			0x3C,             // 0002 INR A
+			0x80,             // 0003 ADD B
+			0x4F,             // 0004 MOV C,A
+			0xAA,             // 0005 XRA D
+			0x57,             // 0006 MOV D,A
+			0x05,             // 0007 DCR B
+			0xC2, 0x02, 0x00, // 0008 JNZ 0x0002
+			0xC3, 0x00, 0x00, // 000B JMP 0x0000
+		},
+	},
+	{
+		Name:        "memory",
+		Description: "copies and sums a 256 byte block through memory",
+		Program: []byte{
+			0x21, 0x00, 0x10, // 0000 LXI H,0x1000
+			0x11, 0x00, 0x18, // 0003 LXI D,0x1800
+			0x06, 0x00, // 0006 MVI B,0x00
+			0x7E,             // 0008 MOV A,M
+			0x80,             // 0009 ADD B
+			0x12,             // 000A STAX D
+			0x23,             // 000B INX H
+			0x13,             // 000C INX D
+			0x05,             // 000D DCR B
+			0xC2, 0x08, 0x00, // 000E JNZ 0x0008
+			0x21, 0x00, 0x18, // 0011 LXI H,0x1800
+			0x0E, 0x00, // 0014 MVI C,0x00
+			0xAF,             // 0016 XRA A
+			0x86,             // 0017 ADD M
+			0x23,             // 0018 INX H
+			0x0D,             // 0019 DCR C
+			0xC2, 0x17, 0x00, // 001A JNZ 0x0017
+			0x32, 0x00, 0x1F, // 001D STA 0x1F00
+			0x2A, 0x00, 0x1F, // 0020 LHLD 0x1F00
+			0x22, 0x02, 0x1F, // 0023 SHLD 0x1F02
+			0xC3, 0x00, 0x00, // 0026 JMP 0x0000
+		},
+	},
+	{
+		Name:        "call-ret",
+		Description: "nested subroutine calls, with conditional calls and register saves",
+		Program: []byte{
+			0x31, 0x00, 0x20, // 0000 LXI SP,0x2000
+			0x06, 0x10, // 0003 MVI B,0x10
+			0xCD, 0x0F, 0x00, // 0005 CALL 0x000F
+			0x05,             // 0008 DCR B
+			0xC2, 0x05, 0x00, // 0009 JNZ 0x0005
+			0xC3, 0x00, 0x00, // 000C JMP 0x0000
+			0xC5,             // 000F PUSH B
+			0xCD, 0x15, 0x00, // 0010 CALL 0x0015
+			0xC1,       // 0013 POP B
+			0xC9,       // 0014 RET
+			0xD5,       // 0015 PUSH D
+			0xE5,       // 0016 PUSH H
+			0x78,       // 0017 MOV A,B
+			0xE6, 0x01, // 0018 ANI 0x01
+			0xCC, 0x23, 0x00, // 001A CZ 0x0023
+			0xCD, 0x23, 0x00, // 001D CALL 0x0023
+			0xE1, // 0020 POP H
+			0xD1, // 0021 POP D
+			0xC9, // 0022 RET
+			0x1C, // 0023 INR E
+			0xC9, // 0024 RET
+		},
+	},
+	{
+		Name:        "alu-sweep",
+		Description: "a hand-assembled sweep of every ALU operation over every pair of operands, checksumming results and flags",
+		Program: []byte{
+			0x31, 0x00, 0x20, // 0000 LXI SP,0x2000
+			0x21, 0x00, 0x00, // 0003 LXI H,0x0000
+			0x06, 0x00, // 0006 MVI B,0x00
+			0x0E, 0x00, // 0008 MVI C,0x00
+			0x79,             // 000A MOV A,C
+			0x80,             // 000B ADD B
+			0x27,             // 000C DAA
+			0xCD, 0x49, 0x00, // 000D CALL 0x0049
+			0x79,             // 0010 MOV A,C
+			0x88,             // 0011 ADC B
+			0xCD, 0x49, 0x00, // 0012 CALL 0x0049
+			0x79,             // 0015 MOV A,C
+			0x90,             // 0016 SUB B
+			0xCD, 0x49, 0x00, // 0017 CALL 0x0049
+			0x79,             // 001A MOV A,C
+			0x98,             // 001B SBB B
+			0xCD, 0x49, 0x00, // 001C CALL 0x0049
+			0x79,             // 001F MOV A,C
+			0xA0,             // 0020 ANA B
+			0xCD, 0x49, 0x00, // 0021 CALL 0x0049
+			0x79,             // 0024 MOV A,C
+			0xA8,             // 0025 XRA B
+			0xCD, 0x49, 0x00, // 0026 CALL 0x0049
+			0x79,             // 0029 MOV A,C
+			0xB0,             // 002A ORA B
+			0xCD, 0x49, 0x00, // 002B CALL 0x0049
+			0x79,             // 002E MOV A,C
+			0xB8,             // 002F CMP B
+			0xCD, 0x49, 0x00, // 0030 CALL 0x0049
+			0x79,             // 0033 MOV A,C
+			0x07,             // 0034 RLC
+			0x17,             // 0035 RAL
+			0x0F,             // 0036 RRC
+			0x1F,             // 0037 RAR
+			0xCD, 0x49, 0x00, // 0038 CALL 0x0049
+			0x0C,             // 003B INR C
+			0xC2, 0x0A, 0x00, // 003C JNZ 0x000A
+			0x04,             // 003F INR B
+			0xC2, 0x08, 0x00, // 0040 JNZ 0x0008
+			0x22, 0x00, 0x10, // 0043 SHLD 0x1000
+			0xC3, 0x00, 0x00, // 0046 JMP 0x0000
+			0xF5, // 0049 PUSH PSW (fold the result and flags into the checksum in H&L)
+			0xD1, // 004A POP D
+			0x19, // 004B DAD D
+			0xC9, // 004C RET
+		},
+	},
+	{
+		Name:        "exerciser",
+		Description: "a CPU exerciser in the style of CPUDIAG, checking flags, jumps, calls and returns, moves, DAA, register pairs, rotates and memory operands, run under the CP/M BDOS",
+		Program: []byte{
+			0x31, 0x00, 0x10, // 0100 START: LXI SP,0x1000
+			0xE6, 0x00, // 0103 ANI 0x00 (test the flags and conditional jumps)
+			0xDA, 0xB7, 0x02, // 0105 JC 0x02B7
+			0xE2, 0xB7, 0x02, // 0108 JPO 0x02B7
+			0xFA, 0xB7, 0x02, // 010B JM 0x02B7
+			0xC2, 0xB7, 0x02, // 010E JNZ 0x02B7
+			0xF6, 0x06, // 0111 ORI 0x06
+			0xDA, 0xB7, 0x02, // 0113 JC 0x02B7
+			0xE2, 0xB7, 0x02, // 0116 JPO 0x02B7
+			0xFA, 0xB7, 0x02, // 0119 JM 0x02B7
+			0xCA, 0xB7, 0x02, // 011C JZ 0x02B7
+			0xEE, 0x87, // 011F XRI 0x87
+			0xDA, 0xB7, 0x02, // 0121 JC 0x02B7
+			0xE2, 0xB7, 0x02, // 0124 JPO 0x02B7
+			0xF2, 0xB7, 0x02, // 0127 JP 0x02B7
+			0xCA, 0xB7, 0x02, // 012A JZ 0x02B7
+			0xF6, 0x10, // 012D ORI 0x10
+			0xEA, 0xB7, 0x02, // 012F JPE 0x02B7
+			0xF2, 0xB7, 0x02, // 0132 JP 0x02B7
+			0xC6, 0x70, // 0135 ADI 0x70
+			0xD2, 0xB7, 0x02, // 0137 JNC 0x02B7
+			0xCA, 0xB7, 0x02, // 013A JZ 0x02B7
+			0xFA, 0xB7, 0x02, // 013D JM 0x02B7
+			0xFE, 0x01, // 0140 CPI 0x01
+			0xC2, 0xB7, 0x02, // 0142 JNZ 0x02B7
+			0xDA, 0xB7, 0x02, // 0145 JC 0x02B7
+			0xFE, 0x02, // 0148 CPI 0x02
+			0xD2, 0xB7, 0x02, // 014A JNC 0x02B7
+			0xCA, 0xB7, 0x02, // 014D JZ 0x02B7
+			0xF2, 0xB7, 0x02, // 0150 JP 0x02B7
+			0xAF,             // 0153 XRA A (test the conditional calls and returns)
+			0xC4, 0xB7, 0x02, // 0154 CNZ 0x02B7
+			0xDC, 0xB7, 0x02, // 0157 CC 0x02B7
+			0xE4, 0xB7, 0x02, // 015A CPO 0x02B7
+			0xFC, 0xB7, 0x02, // 015D CM 0x02B7
+			0xCC, 0xC2, 0x02, // 0160 CZ 0x02C2
+			0xEC, 0xCB, 0x02, // 0163 CPE 0x02CB
+			0xF4, 0xD0, 0x02, // 0166 CP 0x02D0
+			0xD4, 0xD5, 0x02, // 0169 CNC 0x02D5
+			0xFE, 0x01, // 016C CPI 0x01
+			0xC2, 0xB7, 0x02, // 016E JNZ 0x02B7
+			0xF6, 0x80, // 0171 ORI 0x80
+			0xFC, 0xDA, 0x02, // 0173 CM 0x02DA
+			0xE4, 0xDF, 0x02, // 0176 CPO 0x02DF
+			0x37,             // 0179 STC
+			0xDC, 0xE4, 0x02, // 017A CC 0x02E4
+			0xD2, 0xB7, 0x02, // 017D JNC 0x02B7
+			0x3E, 0x77, // 0180 MVI A,0x77 (test MOV, INR and DCR, as CPUDIAG does)
+			0x3C,       // 0182 INR A
+			0x47,       // 0183 MOV B,A
+			0x04,       // 0184 INR B
+			0x48,       // 0185 MOV C,B
+			0x0D,       // 0186 DCR C
+			0x51,       // 0187 MOV D,C
+			0x5A,       // 0188 MOV E,D
+			0x63,       // 0189 MOV H,E
+			0x6C,       // 018A MOV L,H
+			0x7D,       // 018B MOV A,L
+			0x3D,       // 018C DCR A
+			0x4F,       // 018D MOV C,A
+			0x59,       // 018E MOV E,C
+			0x6B,       // 018F MOV L,E
+			0x45,       // 0190 MOV B,L
+			0x50,       // 0191 MOV D,B
+			0x62,       // 0192 MOV H,D
+			0x7C,       // 0193 MOV A,H
+			0x57,       // 0194 MOV D,A
+			0x14,       // 0195 INR D
+			0x6A,       // 0196 MOV L,D
+			0x4D,       // 0197 MOV C,L
+			0x0C,       // 0198 INR C
+			0x61,       // 0199 MOV H,C
+			0x44,       // 019A MOV B,H
+			0x05,       // 019B DCR B
+			0x58,       // 019C MOV E,B
+			0x7B,       // 019D MOV A,E
+			0x5F,       // 019E MOV E,A
+			0x1C,       // 019F INR E
+			0x43,       // 01A0 MOV B,E
+			0x60,       // 01A1 MOV H,B
+			0x24,       // 01A2 INR H
+			0x4C,       // 01A3 MOV C,H
+			0x69,       // 01A4 MOV L,C
+			0x55,       // 01A5 MOV D,L
+			0x15,       // 01A6 DCR D
+			0x7A,       // 01A7 MOV A,D
+			0x67,       // 01A8 MOV H,A
+			0x25,       // 01A9 DCR H
+			0x54,       // 01AA MOV D,H
+			0x42,       // 01AB MOV B,D
+			0x68,       // 01AC MOV L,B
+			0x2C,       // 01AD INR L
+			0x5D,       // 01AE MOV E,L
+			0x1D,       // 01AF DCR E
+			0x4B,       // 01B0 MOV C,E
+			0x79,       // 01B1 MOV A,C
+			0x6F,       // 01B2 MOV L,A
+			0x2D,       // 01B3 DCR L
+			0x65,       // 01B4 MOV H,L
+			0x5C,       // 01B5 MOV E,H
+			0x53,       // 01B6 MOV D,E
+			0x4A,       // 01B7 MOV C,D
+			0x41,       // 01B8 MOV B,C
+			0x78,       // 01B9 MOV A,B
+			0xFE, 0x77, // 01BA CPI 0x77
+			0xC2, 0xB7, 0x02, // 01BC JNZ 0x02B7
+			0xB9,             // 01BF CMP C
+			0xC2, 0xB7, 0x02, // 01C0 JNZ 0x02B7
+			0xBA,             // 01C3 CMP D
+			0xC2, 0xB7, 0x02, // 01C4 JNZ 0x02B7
+			0xBB,             // 01C7 CMP E
+			0xC2, 0xB7, 0x02, // 01C8 JNZ 0x02B7
+			0xBC,             // 01CB CMP H
+			0xC2, 0xB7, 0x02, // 01CC JNZ 0x02B7
+			0xBD,             // 01CF CMP L
+			0xC2, 0xB7, 0x02, // 01D0 JNZ 0x02B7
+			0x3E, 0x29, // 01D3 MVI A,0x29 (test DAA, as CPUDIAG does)
+			0x06, 0x49, // 01D5 MVI B,0x49
+			0x80,       // 01D7 ADD B
+			0x27,       // 01D8 DAA
+			0xFE, 0x78, // 01D9 CPI 0x78
+			0xC2, 0xB7, 0x02, // 01DB JNZ 0x02B7
+			0x3E, 0x85, // 01DE MVI A,0x85
+			0x0E, 0x36, // 01E0 MVI C,0x36
+			0x81,             // 01E2 ADD C
+			0x27,             // 01E3 DAA
+			0xD2, 0xB7, 0x02, // 01E4 JNC 0x02B7
+			0xFE, 0x21, // 01E7 CPI 0x21
+			0xC2, 0xB7, 0x02, // 01E9 JNZ 0x02B7
+			0x21, 0x34, 0x12, // 01EC LXI H,0x1234 (test the register pairs)
+			0x01, 0x34, 0x12, // 01EF LXI B,0x1234
+			0x29,       // 01F2 DAD H
+			0x09,       // 01F3 DAD B
+			0x23,       // 01F4 INX H
+			0x2B,       // 01F5 DCX H
+			0x7C,       // 01F6 MOV A,H
+			0xFE, 0x36, // 01F7 CPI 0x36
+			0xC2, 0xB7, 0x02, // 01F9 JNZ 0x02B7
+			0x7D,       // 01FC MOV A,L
+			0xFE, 0x9C, // 01FD CPI 0x9C
+			0xC2, 0xB7, 0x02, // 01FF JNZ 0x02B7
+			0x11, 0x78, 0x56, // 0202 LXI D,0x5678
+			0xEB,       // 0205 XCHG
+			0xD5,       // 0206 PUSH D
+			0xC1,       // 0207 POP B
+			0x78,       // 0208 MOV A,B
+			0xFE, 0x36, // 0209 CPI 0x36
+			0xC2, 0xB7, 0x02, // 020B JNZ 0x02B7
+			0x79,       // 020E MOV A,C
+			0xFE, 0x9C, // 020F CPI 0x9C
+			0xC2, 0xB7, 0x02, // 0211 JNZ 0x02B7
+			0xE5,       // 0214 PUSH H
+			0xE3,       // 0215 XTHL
+			0xD1,       // 0216 POP D
+			0x7A,       // 0217 MOV A,D
+			0xFE, 0x56, // 0218 CPI 0x56
+			0xC2, 0xB7, 0x02, // 021A JNZ 0x02B7
+			0x22, 0x10, 0x03, // 021D SHLD 0x0310
+			0x21, 0x00, 0x00, // 0220 LXI H,0x0000
+			0x2A, 0x10, 0x03, // 0223 LHLD 0x0310
+			0x7D,       // 0226 MOV A,L
+			0xFE, 0x78, // 0227 CPI 0x78
+			0xC2, 0xB7, 0x02, // 0229 JNZ 0x02B7
+			0x01, 0x10, 0x03, // 022C LXI B,0x0310
+			0x3E, 0x55, // 022F MVI A,0x55
+			0x02,       // 0231 STAX B
+			0x3E, 0x00, // 0232 MVI A,0x00
+			0x3A, 0x10, 0x03, // 0234 LDA 0x0310
+			0xFE, 0x55, // 0237 CPI 0x55
+			0xC2, 0xB7, 0x02, // 0239 JNZ 0x02B7
+			0x21, 0xFF, 0xFF, // 023C LXI H,0xFFFF
+			0x11, 0x01, 0x00, // 023F LXI D,0x0001
+			0x19,             // 0242 DAD D
+			0xD2, 0xB7, 0x02, // 0243 JNC 0x02B7
+			0x7C,             // 0246 MOV A,H
+			0xB5,             // 0247 ORA L
+			0xC2, 0xB7, 0x02, // 0248 JNZ 0x02B7
+			0x21, 0x52, 0x02, // 024B LXI H,0x0252
+			0xE9,             // 024E PCHL
+			0xC3, 0xB7, 0x02, // 024F JMP 0x02B7
+			0x3E, 0x81, // 0252 JUMPED: MVI A,0x81 (test the rotates)
+			0x07,             // 0254 RLC
+			0xD2, 0xB7, 0x02, // 0255 JNC 0x02B7
+			0xFE, 0x03, // 0258 CPI 0x03
+			0xC2, 0xB7, 0x02, // 025A JNZ 0x02B7
+			0x0F,             // 025D RRC
+			0xD2, 0xB7, 0x02, // 025E JNC 0x02B7
+			0xFE, 0x81, // 0261 CPI 0x81
+			0xC2, 0xB7, 0x02, // 0263 JNZ 0x02B7
+			0x17,             // 0266 RAL
+			0xD2, 0xB7, 0x02, // 0267 JNC 0x02B7
+			0xFE, 0x02, // 026A CPI 0x02
+			0xC2, 0xB7, 0x02, // 026C JNZ 0x02B7
+			0x1F,             // 026F RAR
+			0xDA, 0xB7, 0x02, // 0270 JC 0x02B7
+			0xFE, 0x01, // 0273 CPI 0x01
+			0xC2, 0xB7, 0x02, // 0275 JNZ 0x02B7
+			0x2F,       // 0278 CMA
+			0xFE, 0xFE, // 0279 CPI 0xFE
+			0xC2, 0xB7, 0x02, // 027B JNZ 0x02B7
+			0x37,             // 027E STC
+			0x3F,             // 027F CMC
+			0xDA, 0xB7, 0x02, // 0280 JC 0x02B7
+			0x21, 0x10, 0x03, // 0283 LXI H,0x0310 (test memory operands)
+			0x36, 0x40, // 0286 MVI M,0x40
+			0x3E, 0x02, // 0288 MVI A,0x02
+			0x86,             // 028A ADD M
+			0x96,             // 028B SUB M
+			0x34,             // 028C INR M
+			0xBE,             // 028D CMP M
+			0xD2, 0xB7, 0x02, // 028E JNC 0x02B7
+			0x35,       // 0291 DCR M
+			0x35,       // 0292 DCR M
+			0x7E,       // 0293 MOV A,M
+			0xFE, 0x3F, // 0294 CPI 0x3F
+			0xC2, 0xB7, 0x02, // 0296 JNZ 0x02B7
+			0xAE,             // 0299 XRA M
+			0xC2, 0xB7, 0x02, // 029A JNZ 0x02B7
+			0x21, 0x00, 0x00, // 029D LXI H,0x0000 (check the stack is back where it started)
+			0x39,       // 02A0 DAD SP
+			0x7C,       // 02A1 MOV A,H
+			0xFE, 0x10, // 02A2 CPI 0x10
+			0xC2, 0xB7, 0x02, // 02A4 JNZ 0x02B7
+			0x7D,             // 02A7 MOV A,L
+			0xB7,             // 02A8 ORA A
+			0xC2, 0xB7, 0x02, // 02A9 JNZ 0x02B7
+			0x0E, 0x09, // 02AC MVI C,0x09
+			0x11, 0xE9, 0x02, // 02AE LXI D,0x02E9
+			0xCD, 0x05, 0x00, // 02B1 CALL 0x0005
+			0xC3, 0x00, 0x00, // 02B4 JMP 0x0000
+			0x0E, 0x09, // 02B7 ERROR: MVI C,0x09
+			0x11, 0xFE, 0x02, // 02B9 LXI D,0x02FE
+			0xCD, 0x05, 0x00, // 02BC CALL 0x0005
+			0xC3, 0x00, 0x00, // 02BF JMP 0x0000
+			0xC0,             // 02C2 ZERO: RNZ
+			0xD8,             // 02C3 RC
+			0xE0,             // 02C4 RPO
+			0xF8,             // 02C5 RM
+			0x3C,             // 02C6 INR A
+			0xC0,             // 02C7 RNZ
+			0xC3, 0xB7, 0x02, // 02C8 JMP 0x02B7
+			0xE0,             // 02CB EVEN: RPO
+			0xE8,             // 02CC RPE
+			0xC3, 0xB7, 0x02, // 02CD JMP 0x02B7
+			0xF8,             // 02D0 PLUS: RM
+			0xF0,             // 02D1 RP
+			0xC3, 0xB7, 0x02, // 02D2 JMP 0x02B7
+			0xD8,             // 02D5 NOCARRY: RC
+			0xD0,             // 02D6 RNC
+			0xC3, 0xB7, 0x02, // 02D7 JMP 0x02B7
+			0xF0,             // 02DA MINUS: RP
+			0xF8,             // 02DB RM
+			0xC3, 0xB7, 0x02, // 02DC JMP 0x02B7
+			0xE8,             // 02DF ODD: RPE
+			0xE0,             // 02E0 RPO
+			0xC3, 0xB7, 0x02, // 02E1 JMP 0x02B7
+			0xD0,             // 02E4 CARRY: RNC
+			0xD8,             // 02E5 RC
+			0xC3, 0xB7, 0x02, // 02E6 JMP 0x02B7
+			'C', 'P', 'U', ' ', 'I', 'S', ' ', 'O', 'P', 'E', 'R', 'A', 'T', 'I', 'O', 'N', 'A', 'L', '\r', '\n', '$', // 02E9 PASSED: DB "CPU IS OPERATIONAL",0x0D,0x0A,'$'
+			'C', 'P', 'U', ' ', 'H', 'A', 'S', ' ', 'F', 'A', 'I', 'L', 'E', 'D', '!', '\r', '\n', '$', // 02FE FAILED: DB "CPU HAS FAILED!",0x0D,0x0A,'$'
+			0x00, 0x00, // 0310 TEMP: DW 0x0000
+		},
+		CPM:    true,
+		Passed: "CPU IS OPERATIONAL\r\n",
+	},
+}
+
+// Find returns the workload with the given name.
+func Find(name string) (Workload, error) {
+	for _, workload := range Workloads {
+		if workload.Name == name {
+			return workload, nil
+		}
+	}
+
+	return Workload{}, fmt.Errorf("unknown workload %q", name)
+}
+
+// Result holds the outcome of running a workload.
+type Result struct {
+	Workload     string
+	Variant      cpu.Variant
+	Instructions uint64
+	Cycles       uint64
+	Elapsed      time.Duration
+}
+
+// InstructionsPerSecond returns the number of emulated instructions executed
+// per second of host time.
+func (result Result) InstructionsPerSecond() float64 {
+	return float64(result.Instructions) / result.Elapsed.Seconds()
+}
+
+// EffectiveHz returns the clock speed a real processor would need to execute
+// the workload's clock states in the same time.
+func (result Result) EffectiveHz() float64 {
+	return float64(result.Cycles) / result.Elapsed.Seconds()
+}
+
+// SpeedUp returns how many times faster than a 2 MHz 8080 the workload ran.
+func (result Result) SpeedUp() float64 {
+	return result.EffectiveHz() / I8080ClockHz
+}
+
+// checkEvery is how many instructions are executed between checks of the
+// elapsed time, so that reading the clock doesn't dominate the measurement.
+const checkEvery = 10_000
+
+// cpmTop is where the BDOS is installed for CP/M workloads.
+const cpmTop = 0xFF00
+
+// machine runs a workload on a CPU, restarting CP/M programs each time they
+// end.
+type machine struct {
+	cpu      *cpu.CPU
+	workload Workload
+	bdos     *cpm.BDOS // nil unless the workload is a CP/M program
+	output   bytes.Buffer
+}
+
+// newMachine loads workload on a new CPU of the given variant.
+func newMachine(variant cpu.Variant, workload Workload) (*machine, error) {
+	machine := &machine{cpu: cpu.NewWithVariant(variant), workload: workload}
+	if !workload.CPM {
+		return machine, machine.cpu.Load(workload.Program)
+	}
+
+	bdos, err := cpm.Install(machine.cpu, cpmTop, nil, &machine.output)
+	if err != nil {
+		return nil, err
+	}
+	machine.bdos = bdos
+	for i, value := range workload.Program {
+		address := 0x0100 + types.Word(i)
+		err := machine.cpu.Bus.WriteByteAt(address, value)
+		if err != nil {
+			return nil, fmt.Errorf("could not write byte 0x%02X at address 0x%04X: %v", value, address, err)
+		}
+	}
+	machine.cpu.SetProgramCounter(0x0100)
+
+	return machine, nil
+}
+
+// step executes the next instruction, first checking the output of a CP/M
+// program that has ended and restarting it.
+func (machine *machine) step() error {
+	if machine.bdos != nil && machine.cpu.Halted() {
+		err := machine.restart()
+		if err != nil {
+			return err
+		}
+	}
+
+	return machine.cpu.Step()
+}
+
+// restart runs a CP/M program again, if it passed.
+func (machine *machine) restart() error {
+	err := machine.bdos.Err()
+	if err != nil {
+		return err
+	}
+	if output := machine.output.String(); output != machine.workload.Passed {
+		return fmt.Errorf("program failed with output %q", output)
+	}
+
+	machine.output.Reset()
+	machine.cpu.Reset()
+	machine.cpu.SetProgramCounter(0x0100)
+
+	return nil
+}
+
+// Run executes workload on a new CPU of the given variant for at least
+// duration, and returns the number of instructions and clock states executed.
+// A CP/M program that fails stops the run with an error.
+func Run(variant cpu.Variant, workload Workload, duration time.Duration) (Result, error) {
+	machine, err := newMachine(variant, workload)
+	if err != nil {
+		return Result{}, fmt.Errorf("could not load workload %q: %v", workload.Name, err)
+	}
+
+	var instructions uint64
+	start := time.Now()
+	for time.Since(start) < duration {
+		for i := 0; i < checkEvery; i++ {
+			err := machine.step()
+			if err != nil {
+				return Result{}, fmt.Errorf("could not run workload %q: %w", workload.Name, err)
+			}
+		}
+		instructions += checkEvery
+	}
+
+	return Result{
+		Workload:     workload.Name,
+		Variant:      variant,
+		Instructions: instructions,
+		Cycles:       machine.cpu.Cycles(),
+		Elapsed:      time.Since(start),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package bench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+)
+
+func BenchmarkWorkloads(b *testing.B) {
+	for _, workload := range Workloads {
+		b.Run(workload.Name, func(b *testing.B) {
+			machine, err := newMachine(cpu.Intel8080, workload)
+			if err != nil {
+				b.Fatalf("error loading workload: %v", err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := machine.step()
+				if err != nil {
+					b.Fatalf("error stepping cpu: %v", err)
+				}
+			}
+
+			seconds := b.Elapsed().Seconds()
+			b.ReportMetric(float64(b.N)/seconds/1e6, "MIPS")
+			b.ReportMetric(float64(machine.cpu.Cycles())/seconds/1e6, "MHz")
+		})
+	}
+}
+
+func TestWorkloads(t *testing.T) {
+	for _, workload := range Workloads {
+		t.Run(workload.Name, func(t *testing.T) {
+			result, err := Run(cpu.Intel8080, workload, time.Millisecond)
+			if err != nil {
+				t.Fatalf("error running workload: %v", err)
+			}
+
+			if result.Instructions == 0 || result.Cycles < 4*result.Instructions {
+				t.Errorf("result = %+v, want instructions taking at least 4 cycles each", result)
+			}
+		})
+	}
+}
+
+func TestExerciser(t *testing.T) {
+	workload, err := Find("exerciser")
+	if err != nil {
+		t.Fatalf("error finding workload: %v", err)
+	}
+
+	for _, variant := range []cpu.Variant{cpu.Intel8080, cpu.Intel8085, cpu.ZilogZ80} {
+		t.Run(variant.String(), func(t *testing.T) {
+			machine, err := newMachine(variant, workload)
+			if err != nil {
+				t.Fatalf("error loading workload: %v", err)
+			}
+
+			for i := 0; i < 10000 && !machine.cpu.Halted(); i++ {
+				err := machine.step()
+				if err != nil {
+					t.Fatalf("error stepping cpu: %v", err)
+				}
+			}
+			if !machine.cpu.Halted() {
+				t.Fatalf("exerciser didn't end")
+			}
+			if output := machine.output.String(); output != workload.Passed {
+				t.Errorf("output = %q, want %q", output, workload.Passed)
+			}
+
+			err = machine.restart()
+			if err != nil {
+				t.Fatalf("error restarting exerciser: %v", err)
+			}
+			if machine.cpu.Halted() || machine.cpu.ProgramCounter() != 0x0100 {
+				t.Errorf("restarted at 0x%04X, halted %v, want 0x0100 running", machine.cpu.ProgramCounter(), machine.cpu.Halted())
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	workload, err := Find("call-ret")
+	if err != nil {
+		t.Fatalf("error finding workload: %v", err)
+	}
+	if workload.Name != "call-ret" {
+		t.Errorf("Name = %q, want %q", workload.Name, "call-ret")
+	}
+
+	_, err = Find("missing")
+	if err == nil {
+		t.Errorf("expected an error for an unknown workload, but got none")
+	}
+}
+
+func TestResult(t *testing.T) {
+	result := Result{Instructions: 1_000_000, Cycles: 4_000_000, Elapsed: time.Second}
+
+	if got := result.InstructionsPerSecond(); got != 1_000_000 {
+		t.Errorf("InstructionsPerSecond() = %v, want 1000000", got)
+	}
+	if got := result.EffectiveHz(); got != 4_000_000 {
+		t.Errorf("EffectiveHz() = %v, want 4000000", got)
+	}
+	if got := result.SpeedUp(); got != 2 {
+		t.Errorf("SpeedUp() = %v, want 2", got)
+	}
+}
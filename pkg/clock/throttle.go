@@ -0,0 +1,202 @@
+package clock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+)
+
+// Common processor clock speeds, in hertz.
+const (
+	Intel8080Hz   = 2_000_000 // Intel 8080A
+	Intel8080A1Hz = 3_125_000 // Intel 8080A-1
+	Intel8085Hz   = 3_072_000 // Intel 8085A, with a 6.144 MHz crystal
+	ZilogZ80Hz    = 4_000_000 // Zilog Z80A
+)
+
+const (
+	// slice is how much emulated time runs between checks of the wall clock.
+	slice = time.Millisecond
+
+	// maxLag is how far the emulated clock can fall behind the wall clock
+	// before the throttle gives up on catching up, rather than running flat
+	// out until it does (after the host was suspended, for example).
+	maxLag = 100 * time.Millisecond
+)
+
+// Throttle paces a CPU to a target clock speed, using the clock states counted
+// for each instruction.  Rather than sleeping for a fixed time per instruction,
+// it compares the emulated time since it started with the wall clock time, so
+// that oversleeping or a slow instruction is made up for over the next slice.
+type Throttle struct {
+	hz    float64
+	turbo atomic.Bool
+
+	started     bool
+	first       time.Time // Wall clock time the throttle started
+	start       time.Time // Wall clock time at which startCycles had executed
+	startCycles uint64
+	cycles      uint64 // Clock states executed since the throttle started
+	elapsed     time.Duration
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// Stats holds the speed a throttle has actually achieved.
+type Stats struct {
+	TargetHz float64
+	ActualHz float64
+	Cycles   uint64
+	Elapsed  time.Duration
+}
+
+// String returns the actual and target speed, for example
+// "1.998 MHz (99.9% of 2.000 MHz)".
+func (stats Stats) String() string {
+	var percent float64
+	if stats.TargetHz > 0 {
+		percent = stats.ActualHz / stats.TargetHz * 100
+	}
+
+	return fmt.Sprintf("%.3f MHz (%.1f%% of %.3f MHz)", stats.ActualHz/1e6, percent, stats.TargetHz/1e6)
+}
+
+// New returns a throttle pacing execution to hz clock states per second.
+func New(hz float64) *Throttle {
+	return &Throttle{
+		hz:    hz,
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+}
+
+// SetTurbo turns turbo mode on or off.  In turbo mode the CPU runs as fast as
+// the host allows; turning it off resumes pacing from the current time rather
+// than sleeping off the time gained.  It's safe to call from another goroutine.
+func (throttle *Throttle) SetTurbo(turbo bool) {
+	throttle.turbo.Store(turbo)
+}
+
+// Turbo reports whether turbo mode is on.
+func (throttle *Throttle) Turbo() bool {
+	return throttle.turbo.Load()
+}
+
+// Sync records that cycles more clock states have executed, and sleeps until
+// the wall clock catches up with the emulated clock.  The first call starts the
+// throttle's clock.
+func (throttle *Throttle) Sync(cycles uint64) {
+	now := throttle.now()
+	if !throttle.started {
+		throttle.started = true
+		throttle.first = now
+		throttle.rebase(now)
+	}
+	throttle.cycles += cycles
+
+	if throttle.Turbo() || throttle.hz <= 0 {
+		throttle.rebase(now)
+	} else {
+		emulated := time.Duration(float64(throttle.cycles-throttle.startCycles) / throttle.hz * float64(time.Second))
+		wall := now.Sub(throttle.start)
+
+		switch {
+		case emulated > wall:
+			throttle.sleep(emulated - wall)
+			now = now.Add(emulated - wall)
+		case wall-emulated > maxLag:
+			throttle.rebase(now)
+		}
+	}
+
+	throttle.elapsed = now.Sub(throttle.first)
+}
+
+// rebase restarts the comparison between the emulated and wall clocks at now.
+func (throttle *Throttle) rebase(now time.Time) {
+	throttle.start = now
+	throttle.startCycles = throttle.cycles
+}
+
+// Run steps the CPU until it halts, pacing it to the throttle's clock speed.
+func (throttle *Throttle) Run(goCPU *cpu.CPU) error {
+	throttle.Sync(0)
+	for {
+		cycles, halted, err := throttle.runSlice(goCPU)
+		throttle.Sync(cycles)
+		if err != nil || halted {
+			return err
+		}
+	}
+}
+
+// runSlice steps the CPU for one slice of emulated time, returning the number
+// of clock states executed and whether the CPU halted.
+func (throttle *Throttle) runSlice(goCPU *cpu.CPU) (uint64, bool, error) {
+	budget := uint64(throttle.hz * slice.Seconds())
+	if budget == 0 {
+		budget = 1
+	}
+
+	start := goCPU.Cycles()
+	for goCPU.Cycles()-start < budget {
+		if goCPU.Halted() {
+			return goCPU.Cycles() - start, true, nil
+		}
+
+		err := goCPU.Step()
+		if err != nil {
+			return goCPU.Cycles() - start, true, err
+		}
+	}
+
+	return goCPU.Cycles() - start, false, nil
+}
+
+// Stats returns the target speed, and the speed actually achieved since the
+// throttle started.  It must be called from the goroutine running the CPU.
+func (throttle *Throttle) Stats() Stats {
+	stats := Stats{
+		TargetHz: throttle.hz,
+		Cycles:   throttle.cycles,
+		Elapsed:  throttle.elapsed,
+	}
+	if stats.Elapsed > 0 {
+		stats.ActualHz = float64(stats.Cycles) / stats.Elapsed.Seconds()
+	}
+
+	return stats
+}
+
+// ParseHz parses a clock speed such as "2MHz", "3.125 MHz", "500kHz" or
+// "2000000", returning it in hertz.
+func ParseHz(s string) (float64, error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"mhz", 1e6},
+		{"khz", 1e3},
+		{"hz", 1},
+	} {
+		if number, found := strings.CutSuffix(text, unit.suffix); found {
+			text, multiplier = strings.TrimSpace(number), unit.multiplier
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid clock speed %q", s)
+	}
+
+	return value * multiplier, nil
+}
@@ -0,0 +1,156 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+)
+
+// fakeClock is a wall clock that only moves when slept on or advanced.
+type fakeClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func (clock *fakeClock) sleep(duration time.Duration) {
+	clock.slept = append(clock.slept, duration)
+	clock.now = clock.now.Add(duration)
+}
+
+func newFakeThrottle(hz float64) (*Throttle, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	throttle := New(hz)
+	throttle.now = func() time.Time { return clock.now }
+	throttle.sleep = clock.sleep
+	return throttle, clock
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name      string
+		turbo     bool
+		steps     []time.Duration // Wall clock time taken to execute each 1000 cycles
+		wantSlept []time.Duration
+	}{
+		{
+			name:      "sleeps off the time gained",
+			steps:     []time.Duration{0, 0},
+			wantSlept: []time.Duration{time.Millisecond, time.Millisecond},
+		},
+		{
+			name:      "slow host doesn't sleep",
+			steps:     []time.Duration{2 * time.Millisecond, 2 * time.Millisecond},
+			wantSlept: nil,
+		},
+		{
+			name: "compensates for drift",
+			// The first slice overruns by 500us, so the second sleeps that much less
+			steps:     []time.Duration{1500 * time.Microsecond, 0},
+			wantSlept: []time.Duration{500 * time.Microsecond},
+		},
+		{
+			name: "gives up catching up after a long stall",
+			// The stall resets the comparison, so the next slice is paced normally
+			steps:     []time.Duration{time.Second, 0},
+			wantSlept: []time.Duration{time.Millisecond},
+		},
+		{
+			name:      "turbo doesn't sleep",
+			turbo:     true,
+			steps:     []time.Duration{0, 0},
+			wantSlept: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle, clock := newFakeThrottle(1_000_000) // 1000 cycles per millisecond
+			throttle.SetTurbo(tt.turbo)
+			throttle.Sync(0)
+
+			for _, step := range tt.steps {
+				clock.now = clock.now.Add(step)
+				throttle.Sync(1000)
+			}
+
+			if len(clock.slept) != len(tt.wantSlept) {
+				t.Fatalf("slept %v, want %v", clock.slept, tt.wantSlept)
+			}
+			for i := range tt.wantSlept {
+				if clock.slept[i] != tt.wantSlept[i] {
+					t.Errorf("slept %v, want %v", clock.slept, tt.wantSlept)
+				}
+			}
+		})
+	}
+}
+
+func TestTurboOff(t *testing.T) {
+	throttle, clock := newFakeThrottle(1_000_000)
+	throttle.SetTurbo(true)
+	throttle.Sync(0)
+	throttle.Sync(100_000) // 100ms of emulated time in no time at all
+
+	throttle.SetTurbo(false)
+	throttle.Sync(1000)
+
+	if len(clock.slept) != 1 || clock.slept[0] != time.Millisecond {
+		t.Errorf("slept %v, want [1ms]", clock.slept)
+	}
+}
+
+func TestRun(t *testing.T) {
+	goCPU := cpu.New()
+	// MVI B 0x00, loop: DCR B, JNZ loop, HLT
+	goCPU.Load([]byte{0x06, 0x00, 0x05, 0xC2, 0x02, 0x00, 0x76})
+
+	throttle, clock := newFakeThrottle(2_000_000)
+	err := throttle.Run(goCPU)
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	wantCycles := uint64(7 + 256*(5+10) + 7)
+	stats := throttle.Stats()
+	if stats.Cycles != wantCycles {
+		t.Errorf("Cycles = %d, want %d", stats.Cycles, wantCycles)
+	}
+	// The fake clock only moves when slept on, so the run takes exactly as
+	// long as it would on a real 2 MHz 8080.
+	if want := time.Duration(wantCycles) * 500 * time.Nanosecond; clock.now.Sub(time.Unix(0, 0)) != want {
+		t.Errorf("run took %v, want %v", clock.now.Sub(time.Unix(0, 0)), want)
+	}
+	if stats.ActualHz != 2_000_000 {
+		t.Errorf("ActualHz = %v, want 2000000", stats.ActualHz)
+	}
+	if got, want := stats.String(), "2.000 MHz (100.0% of 2.000 MHz)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseHz(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{input: "2MHz", want: 2_000_000},
+		{input: "3.125 MHz", want: 3_125_000},
+		{input: "500kHz", want: 500_000},
+		{input: "100hz", want: 100},
+		{input: "2000000", want: 2_000_000},
+		{input: "fast", wantErr: true},
+		{input: "0MHz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseHz(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHz() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHz() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
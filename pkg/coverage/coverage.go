@@ -0,0 +1,136 @@
+package coverage
+
+import (
+	"strings"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Branch counts the times a conditional jump, call or return was taken and
+// not taken.
+type Branch struct {
+	Taken    uint64
+	NotTaken uint64
+}
+
+// Coverage records which instructions a program executed, and which way its
+// conditional jumps, calls and returns went, so tests of 8080 routines can
+// show what they exercised.  It implements cpu.Profiler.
+//
+// Instructions are described by the CPU's instruction set, so on a Z80 only
+// the first byte of each instruction executed is covered and branches aren't
+// recorded.  A conditional jump to the next instruction counts as not taken.
+//
+// Example:
+//
+//	cover := coverage.New(processor)
+//	processor.Profiler = cover
+//	err := processor.Run()
+//	cover.WriteLcov(file, table)
+type Coverage struct {
+	processor *cpu.CPU
+
+	counts   []uint64 // Executions of the instruction at each address
+	lengths  []uint8  // Length of the instruction at each address, once executed
+	covered  []bool   // Bytes executed as part of an instruction
+	branches map[types.Word]*Branch
+}
+
+// New returns an empty coverage record for processor.
+func New(processor *cpu.CPU) *Coverage {
+	return &Coverage{
+		processor: processor,
+		counts:    make([]uint64, 0x10000),
+		lengths:   make([]uint8, 0x10000),
+		covered:   make([]bool, 0x10000),
+		branches:  map[types.Word]*Branch{},
+	}
+}
+
+// Executed records the instruction at address being executed, and whether it
+// branched if it's conditional.  Steps that serviced an interrupt aren't
+// recorded, as the instruction at address wasn't executed.
+func (coverage *Coverage) Executed(address types.Word, cycles uint64) {
+	if coverage.processor.Interrupted() {
+		return
+	}
+
+	if coverage.counts[address] == 0 {
+		instruction, ok := coverage.lookup(address)
+		length := 1
+		if ok {
+			length = instruction.Length
+			if conditional(instruction) {
+				coverage.branches[address] = &Branch{}
+			}
+		}
+		coverage.lengths[address] = uint8(length)
+		for i := 0; i < length; i++ {
+			coverage.covered[address+types.Word(i)] = true
+		}
+	}
+	coverage.counts[address]++
+
+	if branch := coverage.branches[address]; branch != nil {
+		if coverage.processor.ProgramCounter() != address+types.Word(coverage.lengths[address]) {
+			branch.Taken++
+		} else {
+			branch.NotTaken++
+		}
+	}
+}
+
+// Count returns the number of times the instruction at address was executed.
+func (coverage *Coverage) Count(address types.Word) uint64 {
+	return coverage.counts[address]
+}
+
+// Covered returns whether the byte at address was executed as part of an
+// instruction.
+func (coverage *Coverage) Covered(address types.Word) bool {
+	return coverage.covered[address]
+}
+
+// Branch returns the counts for the conditional instruction at address, if
+// it was executed.
+func (coverage *Coverage) Branch(address types.Word) (Branch, bool) {
+	branch, ok := coverage.branches[address]
+	if !ok {
+		return Branch{}, false
+	}
+
+	return *branch, true
+}
+
+// Bytes returns the number of bytes executed as part of an instruction.
+func (coverage *Coverage) Bytes() int {
+	count := 0
+	for _, covered := range coverage.covered {
+		if covered {
+			count++
+		}
+	}
+
+	return count
+}
+
+// lookup describes the instruction in memory at address.
+func (coverage *Coverage) lookup(address types.Word) (cpu.Instruction, bool) {
+	opCode, err := coverage.processor.Peek(address)
+	if err != nil {
+		return cpu.Instruction{}, false
+	}
+
+	return coverage.processor.Lookup(opCode)
+}
+
+// conditional returns whether instruction is a conditional jump, call or
+// return.
+func conditional(instruction cpu.Instruction) bool {
+	if instruction.CyclesTaken != 0 {
+		return true
+	}
+
+	return strings.HasPrefix(instruction.Mnemonic, "J") && instruction.Mnemonic != "JMP a16"
+}
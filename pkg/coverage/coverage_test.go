@@ -0,0 +1,272 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/symbols"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// run takes the coverage of a program that calls DELAY, which counts C down
+// from 5, for each count of B down from 10, and leaves a conditional return
+// after it unexecuted.
+func run(t *testing.T) (*Coverage, *symbols.Table) {
+	t.Helper()
+
+	program := make([]byte, 0x18)
+	copy(program[0x00:], []byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0x06, 0x0A, // 0003 MVI B,10
+		0xCD, 0x10, 0x00, // 0005 AGAIN: CALL DELAY
+		0x05,             // 0008 DCR B
+		0xC2, 0x05, 0x00, // 0009 JNZ AGAIN
+		0x76, // 000C HLT
+		0xC0, // 000D RNZ
+	})
+	copy(program[0x10:], []byte{
+		0x0E, 0x05, // 0010 DELAY: MVI C,5
+		0x0D,             // 0012 LOOP: DCR C
+		0xC2, 0x12, 0x00, // 0013 JNZ LOOP
+		0xC9, // 0016 RET
+	})
+
+	processor := cpu.New()
+	processor.Load(program)
+	table := symbols.New()
+	table.AddLabel("DELAY", 0x0010)
+	for line, address := range []types.Word{0x0000, 0x0003, 0x0005, 0x0008, 0x0009, 0x000C, 0x000D, 0x0010, 0x0012, 0x0013, 0x0016} {
+		table.AddLine(address, symbols.Location{File: "delay.asm", Line: line + 1})
+	}
+	// Both instructions of a macro share its line
+	table.AddLine(0x0013, symbols.Location{File: "delay.asm", Line: 9})
+	processor.Symbols = table
+
+	cover := New(processor)
+	processor.Profiler = cover
+	err := processor.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	return cover, table
+}
+
+func TestCoverage(t *testing.T) {
+	cover, _ := run(t)
+
+	tests := []struct {
+		address    types.Word
+		wantCount  uint64
+		wantBranch *Branch
+	}{
+		{address: 0x0005, wantCount: 10},
+		{address: 0x0009, wantCount: 10, wantBranch: &Branch{Taken: 9, NotTaken: 1}},
+		{address: 0x000D, wantCount: 0},
+		{address: 0x0012, wantCount: 50},
+		{address: 0x0013, wantCount: 50, wantBranch: &Branch{Taken: 40, NotTaken: 10}},
+	}
+	for _, test := range tests {
+		if got := cover.Count(test.address); got != test.wantCount {
+			t.Errorf("Count(0x%04X) = %d, want %d", test.address, got, test.wantCount)
+		}
+		branch, ok := cover.Branch(test.address)
+		if ok != (test.wantBranch != nil) || ok && branch != *test.wantBranch {
+			t.Errorf("Branch(0x%04X) = %+v, %v, want %+v", test.address, branch, ok, test.wantBranch)
+		}
+	}
+
+	// Operands are covered along with their opcodes
+	for address, want := range map[types.Word]bool{0x0006: true, 0x0015: true, 0x000D: false, 0x0017: false} {
+		if got := cover.Covered(address); got != want {
+			t.Errorf("Covered(0x%04X) = %v, want %v", address, got, want)
+		}
+	}
+	if got := cover.Bytes(); got != 20 {
+		t.Errorf("Bytes() = %d, want 20", got)
+	}
+}
+
+func TestCoverageConditionalReturn(t *testing.T) {
+	program := make([]byte, 0x18)
+	copy(program[0x00:], []byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0x06, 0x02, // 0003 MVI B,2
+		0xCD, 0x10, 0x00, // 0005 AGAIN: CALL CHECK
+		0xCD, 0x15, 0x00, // 0008 CALL DONE
+		0x05,             // 000B DCR B
+		0xC2, 0x05, 0x00, // 000C JNZ AGAIN
+		0x76, // 000F HLT
+	})
+	copy(program[0x10:], []byte{
+		0x78,       // 0010 CHECK: MOV A,B
+		0xFE, 0x01, // 0011 CPI 1
+		0xC0, // 0013 RNZ, taken the first time only
+		0xC9, // 0014 RET
+		0xC9, // 0015 DONE: RET
+	})
+
+	processor := cpu.New()
+	processor.Load(program)
+	cover := New(processor)
+	processor.Profiler = cover
+	err := processor.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	tests := []struct {
+		address    types.Word
+		wantCount  uint64
+		wantBranch *Branch
+	}{
+		{address: 0x0008, wantCount: 2},
+		{address: 0x000B, wantCount: 2},
+		{address: 0x000C, wantCount: 2, wantBranch: &Branch{Taken: 1, NotTaken: 1}},
+		{address: 0x000F, wantCount: 1},
+		{address: 0x0013, wantCount: 2, wantBranch: &Branch{Taken: 1, NotTaken: 1}},
+		{address: 0x0014, wantCount: 1},
+		{address: 0x0015, wantCount: 2},
+	}
+	for _, test := range tests {
+		if got := cover.Count(test.address); got != test.wantCount {
+			t.Errorf("Count(0x%04X) = %d, want %d", test.address, got, test.wantCount)
+		}
+		branch, ok := cover.Branch(test.address)
+		if ok != (test.wantBranch != nil) || ok && branch != *test.wantBranch {
+			t.Errorf("Branch(0x%04X) = %+v, %v, want %+v", test.address, branch, ok, test.wantBranch)
+		}
+	}
+	if processor.StackPointer() != 0x1000 {
+		t.Errorf("stackPointer = 0x%04X, want 0x1000", processor.StackPointer())
+	}
+}
+
+func TestCoverageInterrupt(t *testing.T) {
+	processor := cpu.New()
+	processor.Load([]byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0xFB, // 0003 EI
+		0x00, // 0004 NOP
+		0x76, // 0005 HLT
+		0x00, 0x00,
+		0xC9, // 0008 RET
+	})
+	cover := New(processor)
+	processor.Profiler = cover
+
+	for i := 0; i < 2; i++ {
+		err := processor.Step()
+		if err != nil {
+			t.Fatalf("did not expect an error, but got: %v", err)
+		}
+	}
+	processor.Interrupt(0xCF) // RST 1, serviced before the NOP
+	err := processor.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	if got := cover.Count(0x0004); got != 1 {
+		t.Errorf("Count(0x0004) = %d, want 1 as servicing the interrupt doesn't execute it", got)
+	}
+	if got := cover.Count(0x0008); got != 1 {
+		t.Errorf("Count(0x0008) = %d, want 1", got)
+	}
+}
+
+func TestWriteListing(t *testing.T) {
+	cover, _ := run(t)
+
+	var listing bytes.Buffer
+	err := cover.WriteListing(&listing, 0x0009, 0x0018)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	want := `Coverage: 11 of 15 bytes executed, 2 of 3 branches both ways
+
+     10  0009  C2 05 00  JNZ 0x0005       taken 9, not taken 1
+      1  000C  76        HLT
+  #####  000D  C0        RNZ
+  #####  000E  00        NOP
+  #####  000F  00        NOP
+                        DELAY:
+     10  0010  0E 05     MVI C,0x05
+     50  0012  0D        DCR C
+     50  0013  C2 12 00  JNZ 0x0012       taken 40, not taken 10
+     10  0016  C9        RET
+  #####  0017  00        NOP
+`
+	if listing.String() != want {
+		t.Errorf("WriteListing() wrote:\n%s\nwant:\n%s", listing.String(), want)
+	}
+
+	// Operands of an instruction before the start are data, as is an
+	// unexecuted instruction running into an executed one
+	cover.processor.Bus.WriteByteAt(0x000F, 0xC3) // JMP
+	listing.Reset()
+	err = cover.WriteListing(&listing, 0x0004, 0x0012)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	want = `Coverage: 11 of 14 bytes executed, 1 of 2 branches both ways
+
+         0004  0A        DB 0x0A
+     10  0005  CD 10 00  CALL DELAY
+     10  0008  05        DCR B
+     10  0009  C2 05 00  JNZ 0x0005       taken 9, not taken 1
+      1  000C  76        HLT
+  #####  000D  C0        RNZ
+  #####  000E  00        NOP
+  #####  000F  C3        DB 0xC3
+                        DELAY:
+     10  0010  0E 05     MVI C,0x05
+`
+	if listing.String() != want {
+		t.Errorf("WriteListing() wrote:\n%s\nwant:\n%s", listing.String(), want)
+	}
+}
+
+func TestWriteLcov(t *testing.T) {
+	cover, table := run(t)
+
+	var lcov bytes.Buffer
+	err := cover.WriteLcov(&lcov, table)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	want := `TN:
+SF:delay.asm
+DA:1,1
+DA:2,1
+DA:3,10
+DA:4,10
+DA:5,10
+BRDA:5,0,0,9
+BRDA:5,0,1,1
+DA:6,1
+DA:7,0
+BRDA:7,0,0,-
+BRDA:7,0,1,-
+DA:8,10
+DA:9,50
+BRDA:9,0,0,40
+BRDA:9,0,1,10
+DA:11,10
+BRF:6
+BRH:4
+LF:10
+LH:9
+end_of_record
+`
+	if lcov.String() != want {
+		t.Errorf("WriteLcov() wrote:\n%s\nwant:\n%s", lcov.String(), want)
+	}
+
+	if err := cover.WriteLcov(&lcov, symbols.New()); err == nil {
+		t.Errorf("expected an error writing coverage without source lines, but got none")
+	}
+}
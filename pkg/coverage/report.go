@@ -0,0 +1,234 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/lukepeterson/go8080cpu/pkg/symbols"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// WriteListing writes an assembler listing of memory from start up to, but not
+// including, end, with the times each instruction was executed, ##### for
+// those that never were, and which way conditional instructions went.
+// Labels are taken from the CPU's Symbols.
+//
+// Example:
+//
+//	Coverage: 21 of 23 bytes executed, 1 of 2 branches both ways
+//
+//	                        DELAY:
+//	     10  0010  0E 05     MVI C,0x05
+//	     50  0012  0D        DCR C
+//	     50  0013  C2 12 00  JNZ 0x0012  taken 40, not taken 10
+//	  #####  0016  C0        RNZ
+func (coverage *Coverage) WriteListing(w io.Writer, start, end types.Word) error {
+	bytes, executed := 0, 0
+	for address := int(start); address < int(end); address++ {
+		bytes++
+		if coverage.covered[address] {
+			executed++
+		}
+	}
+	branches, both := 0, 0
+	for address := int(start); address < int(end); address++ {
+		if _, ok := coverage.conditionalAt(types.Word(address)); ok {
+			branches++
+			if branch := coverage.branches[types.Word(address)]; branch != nil && branch.Taken > 0 && branch.NotTaken > 0 {
+				both++
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "Coverage: %d of %d bytes executed, %d of %d branches both ways\n\n", executed, bytes, both, branches)
+	if err != nil {
+		return err
+	}
+
+	for address := int(start); address < int(end); {
+		if coverage.processor.Symbols != nil {
+			if label, ok := coverage.processor.Symbols.Label(types.Word(address)); ok {
+				fmt.Fprintf(w, "%24s%s:\n", "", label)
+			}
+		}
+
+		text, length := coverage.instruction(types.Word(address), int(end)-address)
+		raw := make([]string, length)
+		for i := range raw {
+			value, _ := coverage.processor.Peek(types.Word(address + i))
+			raw[i] = fmt.Sprintf("%02X", value)
+		}
+
+		count := "#####"
+		if coverage.counts[address] > 0 {
+			count = fmt.Sprint(coverage.counts[address])
+		} else if coverage.covered[address] {
+			count = "" // Operands of an instruction before start
+		}
+		line := fmt.Sprintf("%7s  %04X  %-9s %s", count, address, strings.Join(raw, " "), text)
+		if branch := coverage.branches[types.Word(address)]; branch != nil {
+			line = fmt.Sprintf("%-40s  taken %d, not taken %d", line, branch.Taken, branch.NotTaken)
+		}
+		_, err = fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+
+		address += length
+	}
+
+	return nil
+}
+
+// instruction disassembles the instruction at address, of at most limit
+// bytes.  Instructions that were executed keep their length.  Others stop
+// short of any executed instruction they'd overlap, and are shown as data if
+// they do, as are the operands of an executed instruction before address.
+func (coverage *Coverage) instruction(address types.Word, limit int) (string, int) {
+	if coverage.counts[address] == 0 && coverage.covered[address] {
+		length := 1
+		for length < limit && coverage.counts[address+types.Word(length)] == 0 && coverage.covered[address+types.Word(length)] {
+			length++
+		}
+		return coverage.data(address, length), length
+	}
+
+	text, length, err := coverage.processor.Disassemble(address)
+	if err != nil {
+		text, length = "?", 1
+	}
+	if coverage.counts[address] > 0 {
+		return text, min(int(coverage.lengths[address]), limit)
+	}
+
+	for i := 1; i < length; i++ {
+		if i == limit || coverage.counts[address+types.Word(i)] > 0 {
+			return coverage.data(address, i), i
+		}
+	}
+
+	return text, length
+}
+
+// data shows length bytes from address as data.
+func (coverage *Coverage) data(address types.Word, length int) string {
+	values := make([]string, length)
+	for i := range values {
+		value, _ := coverage.processor.Peek(address + types.Word(i))
+		values[i] = fmt.Sprintf("0x%02X", value)
+	}
+
+	return "DB " + strings.Join(values, ",")
+}
+
+// conditionalAt returns the counts for the conditional instruction at
+// address, or whether the instruction in memory there is conditional if it
+// wasn't executed.
+func (coverage *Coverage) conditionalAt(address types.Word) (*Branch, bool) {
+	if coverage.counts[address] > 0 {
+		branch, ok := coverage.branches[address]
+		return branch, ok
+	}
+
+	instruction, ok := coverage.lookup(address)
+	if !ok || !conditional(instruction) {
+		return nil, false
+	}
+
+	return nil, true
+}
+
+// sourceLine is the coverage of a line of source.
+type sourceLine struct {
+	line     int
+	count    uint64
+	branches []*Branch // nil for those never executed
+}
+
+// WriteLcov writes the coverage of the instructions with source lines in
+// table in the lcov tracefile format, for genhtml and editors.  Each line
+// records the most times any of its instructions was executed, and each
+// conditional instruction on it is a block with taken and not taken branches.
+//
+// Example:
+//
+//	TN:
+//	SF:delay.asm
+//	DA:3,50
+//	BRDA:4,0,0,40
+//	BRDA:4,0,1,10
+//	BRF:2
+//	BRH:2
+//	LF:2
+//	LH:2
+//	end_of_record
+func (coverage *Coverage) WriteLcov(w io.Writer, table *symbols.Table) error {
+	addresses := table.Instructions()
+	if len(addresses) == 0 {
+		return fmt.Errorf("no source lines to write coverage for")
+	}
+
+	files := map[string]map[int]*sourceLine{}
+	for _, address := range addresses {
+		location, _ := table.Line(address)
+		if files[location.File] == nil {
+			files[location.File] = map[int]*sourceLine{}
+		}
+		line := files[location.File][location.Line]
+		if line == nil {
+			line = &sourceLine{line: location.Line}
+			files[location.File][location.Line] = line
+		}
+		line.count = max(line.count, coverage.counts[address])
+		if branch, ok := coverage.conditionalAt(address); ok {
+			line.branches = append(line.branches, branch)
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		lines := make([]*sourceLine, 0, len(files[name]))
+		for _, line := range files[name] {
+			lines = append(lines, line)
+		}
+		sort.Slice(lines, func(i, j int) bool { return lines[i].line < lines[j].line })
+
+		var records strings.Builder
+		fmt.Fprintf(&records, "TN:\nSF:%s\n", name)
+		hit, branches, branchesHit := 0, 0, 0
+		for _, line := range lines {
+			fmt.Fprintf(&records, "DA:%d,%d\n", line.line, line.count)
+			if line.count > 0 {
+				hit++
+			}
+			for block, branch := range line.branches {
+				branches += 2
+				if branch == nil {
+					fmt.Fprintf(&records, "BRDA:%d,%d,0,-\nBRDA:%d,%d,1,-\n", line.line, block, line.line, block)
+					continue
+				}
+				fmt.Fprintf(&records, "BRDA:%d,%d,0,%d\nBRDA:%d,%d,1,%d\n", line.line, block, branch.Taken, line.line, block, branch.NotTaken)
+				if branch.Taken > 0 {
+					branchesHit++
+				}
+				if branch.NotTaken > 0 {
+					branchesHit++
+				}
+			}
+		}
+		fmt.Fprintf(&records, "BRF:%d\nBRH:%d\nLF:%d\nLH:%d\nend_of_record\n", branches, branchesHit, len(lines), hit)
+
+		_, err := io.WriteString(w, records.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
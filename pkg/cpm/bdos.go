@@ -0,0 +1,274 @@
+package cpm
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// BDOSEntry is the address CP/M programs call for BDOS functions.
+const BDOSEntry = 0x0005
+
+// Port is the I/O port the BDOS handler uses to pass calls to the BDOS.
+const Port = 0xFF
+
+// BDOS functions supported.
+const (
+	SystemReset       = 0
+	ConsoleInput      = 1
+	ConsoleOutput     = 2
+	DirectConsoleIO   = 6
+	PrintString       = 9
+	ReadConsoleBuffer = 10
+	GetConsoleStatus  = 11
+	ReturnVersion     = 12
+)
+
+const (
+	version         = 0x22 // CP/M 2.2
+	endOfFile       = 0x1A // Ctrl-Z, returned for input past the end
+	directInput     = 0xFF // DirectConsoleIO's E for input
+	directStatus    = 0xFE // DirectConsoleIO's E for the console status
+	maxStringLength = 0x10000
+)
+
+// BDOS is enough of CP/M's BDOS to run programs that use the console, such as
+// CPU exercisers: console input and output, printing strings, reading lines
+// and the console status.  Calling any other function, system reset or
+// jumping to 0x0000 ends the program by halting the CPU.
+//
+// Console input isn't echoed, as the host terminal does that.  It's read from
+// a separate goroutine into a queue, so the console status shows whether input
+// is waiting without blocking the CPU.
+//
+// Example:
+//
+//	bdos, err := cpm.Install(cpu, 0xFF00, os.Stdin, os.Stdout)
+//	err = cpu.Load(program) // Loaded at 0x0100
+//	cpu.SetProgramCounter(0x0100)
+//	err = cpu.Run()
+//	err = bdos.Err()
+type BDOS struct {
+	cpu    *cpu.CPU
+	writer io.Writer
+	err    error
+
+	mutex    sync.Mutex
+	received *sync.Cond // Signalled when input is queued or ends
+	queue    []byte
+	ended    bool
+	readErr  error // Why the input ended, if not at the end of the reader
+}
+
+// Install sets up the page zero of CP/M on processor, with the BDOS handler at
+// top, which programs take as the top of their memory.  Console input is read
+// from reader and output written to writer.  The stack pointer is set just
+// below top, with a return address of 0x0000 so that a program returning from
+// its entry point ends.
+func Install(processor *cpu.CPU, top types.Word, reader io.Reader, writer io.Writer) (*BDOS, error) {
+	if top < 0x0100 || top > 0xFFFD {
+		return nil, fmt.Errorf("invalid BDOS address 0x%04X (must be 0x0100 to 0xFFFD)", top)
+	}
+
+	high, low := byte(top>>8), byte(top)
+	code := []struct {
+		address types.Word
+		data    []byte
+	}{
+		{0x0000, []byte{0x76}},               // HLT, for a warm boot
+		{BDOSEntry, []byte{0xC3, low, high}}, // JMP top
+		{top, []byte{0xD3, Port, 0xC9}},      // OUT Port, RET
+		{top - 2, []byte{0x00, 0x00}},        // Return address of 0x0000
+	}
+	for _, block := range code {
+		for i, value := range block.data {
+			err := processor.Bus.WriteByteAt(block.address+types.Word(i), value)
+			if err != nil {
+				return nil, fmt.Errorf("could not install BDOS: %v", err)
+			}
+		}
+	}
+	processor.SetStackPointer(top - 2)
+
+	bdos := &BDOS{cpu: processor, writer: writer}
+	bdos.received = sync.NewCond(&bdos.mutex)
+	bdos.receive(reader)
+	processor.Attach(bdos, Port)
+
+	return bdos, nil
+}
+
+// receive queues console input from reader until it ends, from a separate
+// goroutine so that reader can block, as os.Stdin does.
+func (bdos *BDOS) receive(reader io.Reader) {
+	if reader == nil {
+		bdos.ended = true
+		return
+	}
+
+	go func() {
+		buffer := make([]byte, 256)
+		for {
+			n, err := reader.Read(buffer)
+			bdos.mutex.Lock()
+			bdos.queue = append(bdos.queue, buffer[:n]...)
+			if err != nil {
+				bdos.ended = true
+				if err != io.EOF {
+					bdos.readErr = err
+				}
+			}
+			bdos.received.Broadcast()
+			bdos.mutex.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// Err returns the error that ended the program, if any: an unsupported
+// function, or an error reading or writing the console.
+func (bdos *BDOS) Err() error {
+	return bdos.err
+}
+
+// ReadPort reads 0xFF, as the BDOS is only called with OUT.
+func (bdos *BDOS) ReadPort(port byte) byte {
+	return 0xFF
+}
+
+// WritePort calls the BDOS function in C, with its parameter in E or DE, and
+// returns its result in A and HL.
+func (bdos *BDOS) WritePort(port byte, value byte) {
+	processor := bdos.cpu
+	parameter := types.Word(processor.D)<<8 | types.Word(processor.E)
+
+	var result byte
+	switch processor.C {
+	case SystemReset:
+		bdos.exit(nil)
+	case ConsoleInput:
+		result = bdos.read()
+	case ConsoleOutput:
+		bdos.write(processor.E)
+	case DirectConsoleIO:
+		switch processor.E {
+		case directInput:
+			if bdos.status() != 0 {
+				result = bdos.read()
+			}
+		case directStatus:
+			result = bdos.status()
+		default:
+			bdos.write(processor.E)
+		}
+	case PrintString:
+		bdos.printString(parameter)
+	case ReadConsoleBuffer:
+		bdos.readLine(parameter)
+	case GetConsoleStatus:
+		result = bdos.status()
+	case ReturnVersion:
+		result = version
+	default:
+		bdos.exit(fmt.Errorf("unsupported BDOS function %d", processor.C))
+	}
+
+	processor.A, processor.L, processor.H = result, result, 0
+}
+
+// exit ends the program by jumping to the HLT at 0x0000, recording err.
+func (bdos *BDOS) exit(err error) {
+	if err != nil && bdos.err == nil {
+		bdos.err = err
+	}
+	bdos.cpu.SetProgramCounter(0x0000)
+}
+
+// read waits for a byte of console input, returning Ctrl-Z at the end of the
+// input.
+func (bdos *BDOS) read() byte {
+	bdos.mutex.Lock()
+	for len(bdos.queue) == 0 && !bdos.ended {
+		bdos.received.Wait()
+	}
+	if len(bdos.queue) == 0 {
+		err := bdos.readErr
+		bdos.mutex.Unlock()
+		if err != nil {
+			bdos.exit(fmt.Errorf("could not read console: %v", err))
+		}
+		return endOfFile
+	}
+	value := bdos.queue[0]
+	bdos.queue = bdos.queue[1:]
+	bdos.mutex.Unlock()
+
+	if value == '\n' {
+		return '\r' // CP/M programs expect Return
+	}
+
+	return value
+}
+
+// status returns 0xFF if console input is waiting, or 0x00 if not.
+func (bdos *BDOS) status() byte {
+	bdos.mutex.Lock()
+	defer bdos.mutex.Unlock()
+	if len(bdos.queue) > 0 {
+		return 0xFF
+	}
+
+	return 0x00
+}
+
+func (bdos *BDOS) write(value byte) {
+	if bdos.writer == nil {
+		return
+	}
+
+	_, err := bdos.writer.Write([]byte{value})
+	if err != nil {
+		bdos.exit(fmt.Errorf("could not write console: %v", err))
+	}
+}
+
+// printString writes the string at address, up to a $.
+func (bdos *BDOS) printString(address types.Word) {
+	for i := 0; i < maxStringLength; i++ {
+		value, err := bdos.cpu.Bus.ReadByteAt(address + types.Word(i))
+		if err != nil {
+			bdos.exit(fmt.Errorf("could not read string at 0x%04X: %v", address, err))
+			return
+		}
+		if value == '$' {
+			return
+		}
+		bdos.write(value)
+	}
+}
+
+// readLine reads a line of input into the buffer at address, which holds its
+// size, then the length read, then the line.
+func (bdos *BDOS) readLine(address types.Word) {
+	size, err := bdos.cpu.Bus.ReadByteAt(address)
+	if err != nil {
+		bdos.exit(fmt.Errorf("could not read console buffer at 0x%04X: %v", address, err))
+		return
+	}
+
+	var length byte
+	for length < size {
+		value := bdos.read()
+		if value == '\r' || value == endOfFile {
+			break
+		}
+		bdos.cpu.Bus.WriteByteAt(address+2+types.Word(length), value)
+		length++
+	}
+	bdos.cpu.Bus.WriteByteAt(address+1, length)
+}
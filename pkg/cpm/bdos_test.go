@@ -0,0 +1,247 @@
+package cpm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// runProgram runs program at 0x0100 with a BDOS, returning the console output.
+func runProgram(t *testing.T, program []byte, input string) (*cpu.CPU, *BDOS, string) {
+	processor := cpu.New()
+	processor.Bus = &memory.Memory{Data: make([]byte, 0x10000)}
+
+	var output bytes.Buffer
+	bdos, err := Install(processor, 0xFF00, strings.NewReader(input), &output)
+	if err != nil {
+		t.Fatalf("could not install BDOS: %v", err)
+	}
+	for i, value := range program {
+		processor.Bus.WriteByteAt(0x0100+types.Word(i), value)
+	}
+	processor.SetProgramCounter(0x0100)
+
+	for i := 0; i < 10000 && !processor.Halted(); i++ {
+		err := processor.Step()
+		if err != nil {
+			t.Fatalf("could not step cpu: %v", err)
+		}
+	}
+	if !processor.Halted() {
+		t.Fatalf("program didn't end")
+	}
+
+	return processor, bdos, output.String()
+}
+
+func TestPrintString(t *testing.T) {
+	program := []byte{
+		0x0E, 0x09, // 0100 MVI C,9
+		0x11, 0x0B, 0x01, // 0102 LXI D,0x010B
+		0xCD, 0x05, 0x00, // 0105 CALL 0x0005
+		0xC3, 0x00, 0x00, // 0108 JMP 0x0000
+		'H', 'i', '!', '$', // 010B
+	}
+
+	processor, bdos, output := runProgram(t, program, "")
+	if output != "Hi!" {
+		t.Errorf("output = %q, want %q", output, "Hi!")
+	}
+	if bdos.Err() != nil {
+		t.Errorf("did not expect an error, but got: %v", bdos.Err())
+	}
+	if processor.ProgramCounter() != 0x0001 {
+		t.Errorf("halted at 0x%04X, want 0x0000", processor.ProgramCounter()-1)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	// Echoes input with functions 1 and 2 until a Ctrl-Z, then returns to CP/M
+	program := []byte{
+		0x0E, 0x01, // 0100 MVI C,1
+		0xCD, 0x05, 0x00, // 0102 CALL 0x0005
+		0xFE, 0x1A, // 0105 CPI 0x1A
+		0xCA, 0x00, 0x00, // 0107 JZ 0x0000
+		0x5F,       // 010A MOV E,A
+		0x0E, 0x02, // 010B MVI C,2
+		0xCD, 0x05, 0x00, // 010D CALL 0x0005
+		0xC3, 0x00, 0x01, // 0110 JMP 0x0100
+	}
+
+	_, bdos, output := runProgram(t, program, "ab\nc")
+	if output != "ab\rc" {
+		t.Errorf("output = %q, want %q", output, "ab\rc")
+	}
+	if bdos.Err() != nil {
+		t.Errorf("did not expect an error, but got: %v", bdos.Err())
+	}
+}
+
+func TestReadConsoleBuffer(t *testing.T) {
+	program := []byte{
+		0x0E, 0x0A, // 0100 MVI C,10
+		0x11, 0x00, 0x02, // 0102 LXI D,0x0200
+		0xCD, 0x05, 0x00, // 0105 CALL 0x0005
+		0x76, // 0108 HLT
+	}
+
+	tests := []struct {
+		input string
+		size  byte
+		want  string
+	}{
+		{input: "hello\nworld\n", size: 80, want: "hello"},
+		{input: "hello", size: 80, want: "hello"},
+		{input: "hello", size: 3, want: "hel"},
+		{input: "\n", size: 80, want: ""},
+	}
+
+	for _, test := range tests {
+		processor := cpu.New()
+		processor.Bus = &memory.Memory{Data: make([]byte, 0x10000)}
+		Install(processor, 0xFF00, strings.NewReader(test.input), nil)
+		for i, value := range program {
+			processor.Bus.WriteByteAt(0x0100+types.Word(i), value)
+		}
+		processor.SetProgramCounter(0x0100)
+		processor.Bus.WriteByteAt(0x0200, test.size)
+		processor.Run()
+
+		length, _ := processor.Bus.ReadByteAt(0x0201)
+		got := make([]byte, length)
+		for i := range got {
+			got[i], _ = processor.Bus.ReadByteAt(0x0202 + types.Word(i))
+		}
+		if string(got) != test.want {
+			t.Errorf("read %q from %q into %d bytes, want %q", got, test.input, test.size, test.want)
+		}
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []struct {
+		name    string
+		c, e    byte
+		input   string
+		wantA   byte
+		wantOut string
+	}{
+		{name: "version", c: ReturnVersion, wantA: 0x22},
+		{name: "status with no input", c: GetConsoleStatus, wantA: 0x00},
+		{name: "direct output", c: DirectConsoleIO, e: 'x', wantOut: "x"},
+		{name: "direct status", c: DirectConsoleIO, e: 0xFE, wantA: 0x00},
+		{name: "direct input with none", c: DirectConsoleIO, e: 0xFF, wantA: 0x00},
+		{name: "input at end", c: ConsoleInput, wantA: 0x1A},
+		{name: "input", c: ConsoleInput, input: "q", wantA: 'q'},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			program := []byte{
+				0x0E, test.c, // 0100 MVI C,c
+				0x1E, test.e, // 0102 MVI E,e
+				0xCD, 0x05, 0x00, // 0104 CALL 0x0005
+				0x76, // 0107 HLT
+			}
+
+			processor, bdos, output := runProgram(t, program, test.input)
+			if processor.A != test.wantA || processor.L != test.wantA || processor.H != 0 {
+				t.Errorf("A = 0x%02X, HL = 0x%02X%02X, want 0x%02X and 0x00%02X", processor.A, processor.H, processor.L, test.wantA, test.wantA)
+			}
+			if output != test.wantOut {
+				t.Errorf("output = %q, want %q", output, test.wantOut)
+			}
+			if bdos.Err() != nil {
+				t.Errorf("did not expect an error, but got: %v", bdos.Err())
+			}
+		})
+	}
+}
+
+func TestFunctionsWithInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		c, e  byte
+		wantA byte
+	}{
+		{name: "status with input", c: GetConsoleStatus, wantA: 0xFF},
+		{name: "direct status with input", c: DirectConsoleIO, e: 0xFE, wantA: 0xFF},
+		{name: "direct input with input", c: DirectConsoleIO, e: 0xFF, wantA: 'a'},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			processor := cpu.New()
+			bdos, err := Install(processor, 0xFF00, strings.NewReader("abc"), nil)
+			if err != nil {
+				t.Fatalf("could not install BDOS: %v", err)
+			}
+			for i, value := range []byte{
+				0x0E, test.c, // 0100 MVI C,c
+				0x1E, test.e, // 0102 MVI E,e
+				0xCD, 0x05, 0x00, // 0104 CALL 0x0005
+				0x76, // 0107 HLT
+			} {
+				processor.Bus.WriteByteAt(0x0100+types.Word(i), value)
+			}
+			processor.SetProgramCounter(0x0100)
+
+			// Wait for the input to be queued, as a program polling the status would
+			for deadline := time.Now().Add(time.Second); bdos.status() == 0; {
+				if time.Now().After(deadline) {
+					t.Fatalf("input was never queued")
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			err = processor.Run()
+			if err != nil {
+				t.Fatalf("could not run cpu: %v", err)
+			}
+			if processor.A != test.wantA {
+				t.Errorf("A = 0x%02X, want 0x%02X", processor.A, test.wantA)
+			}
+		})
+	}
+}
+
+func TestExit(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+		wantErr bool
+	}{
+		{name: "return", program: []byte{0xC9}},                                                   // RET
+		{name: "system reset", program: []byte{0x0E, 0x00, 0xCD, 0x05, 0x00, 0x3C}},               // MVI C,0; CALL 5; INR A
+		{name: "unsupported", program: []byte{0x0E, 0x0F, 0xCD, 0x05, 0x00, 0x3C}, wantErr: true}, // Open file
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			processor, bdos, _ := runProgram(t, test.program, "")
+			if processor.ProgramCounter() != 0x0001 || processor.A != 0x00 {
+				t.Errorf("halted at 0x%04X with A = 0x%02X, want 0x0000 and 0x00", processor.ProgramCounter()-1, processor.A)
+			}
+			if test.wantErr && bdos.Err() == nil {
+				t.Errorf("expected an error, but got none")
+			}
+			if !test.wantErr && bdos.Err() != nil {
+				t.Errorf("did not expect an error, but got: %v", bdos.Err())
+			}
+		})
+	}
+}
+
+func TestInstall(t *testing.T) {
+	for _, top := range []types.Word{0x00FF, 0xFFFE} {
+		_, err := Install(cpu.New(), top, nil, nil)
+		if err == nil {
+			t.Errorf("expected an error installing at 0x%04X, but got none", top)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package cpu
+
+import "github.com/lukepeterson/go8080cpu/pkg/types"
+
+// maxMismatches is how many of the latest return mismatches a CallStack keeps.
+const maxMismatches = 64
+
+// Call is a call on a CallStack.
+type Call struct {
+	From         types.Word // The calling instruction, or the one interrupted
+	To           types.Word // The address called
+	Return       types.Word // The return address pushed
+	StackPointer types.Word // The stack pointer after the return address was pushed
+	Interrupt    bool       // Whether the call was an interrupt
+}
+
+// ReturnMismatch is a return to an address that wasn't pushed by a matching
+// call, as when the stack has been overwritten or a return is used as a
+// computed jump.
+type ReturnMismatch struct {
+	PC   types.Word // The return instruction
+	To   types.Word // The address returned to
+	Call *Call      // The call whose return address was expected, or nil if none was
+}
+
+// CallStack is a shadow call stack, kept alongside the real one by watching
+// calls, restarts and interrupts go in and returns come out.  It shows how a
+// program got where it is even when the real stack has been overwritten, and
+// flags returns that don't match their calls.
+//
+// Example:
+//
+//	processor.CallStack = cpu.NewCallStack()
+//	err := processor.Run()
+//	for _, call := range processor.CallStack.Calls() {
+//		fmt.Printf("0x%04X called from 0x%04X\n", call.To, call.From)
+//	}
+type CallStack struct {
+	// OnMismatch, when set, is called with each return that doesn't match its
+	// call.
+	OnMismatch func(mismatch ReturnMismatch)
+
+	calls      []Call
+	mismatches []ReturnMismatch
+	count      int
+}
+
+// NewCallStack returns an empty call stack.
+func NewCallStack() *CallStack {
+	return &CallStack{}
+}
+
+// Calls returns the calls that haven't returned, outermost first.
+func (stack *CallStack) Calls() []Call {
+	return append([]Call(nil), stack.calls...)
+}
+
+// Top returns the innermost call that hasn't returned, if there is one.
+func (stack *CallStack) Top() (Call, bool) {
+	if len(stack.calls) == 0 {
+		return Call{}, false
+	}
+
+	return stack.calls[len(stack.calls)-1], true
+}
+
+// Depth returns the number of calls that haven't returned.
+func (stack *CallStack) Depth() int {
+	return len(stack.calls)
+}
+
+// Mismatches returns the number of returns that didn't match their calls, and
+// up to the latest 64 of them, oldest first.
+func (stack *CallStack) Mismatches() (int, []ReturnMismatch) {
+	return stack.count, append([]ReturnMismatch(nil), stack.mismatches...)
+}
+
+// called records a call, dropping calls that were abandoned with their stack,
+// such as by loading the stack pointer.
+func (stack *CallStack) called(call Call) {
+	for len(stack.calls) > 0 && stack.calls[len(stack.calls)-1].StackPointer <= call.StackPointer {
+		stack.calls = stack.calls[:len(stack.calls)-1]
+	}
+	stack.calls = append(stack.calls, call)
+}
+
+// returned records the return instruction at pc popping the return address to
+// and leaving the stack pointer at stackPointer.  The call whose return address
+// was popped, and any above it that were abandoned, come off the stack.  If
+// it's a different address, or no call pushed it, the return is a mismatch.
+func (stack *CallStack) returned(pc, to, stackPointer types.Word) {
+	mismatch := ReturnMismatch{PC: pc, To: to}
+	matched := false
+	for i := len(stack.calls) - 1; i >= 0; i-- {
+		call := stack.calls[i]
+		if call.StackPointer+2 != stackPointer {
+			continue
+		}
+
+		matched = call.Return == to
+		if !matched {
+			mismatch.Call = &call
+		}
+		stack.calls = stack.calls[:i]
+		break
+	}
+	if matched {
+		return
+	}
+
+	// Calls below the stack pointer have returned some other way
+	for len(stack.calls) > 0 && stack.calls[len(stack.calls)-1].StackPointer < stackPointer {
+		stack.calls = stack.calls[:len(stack.calls)-1]
+	}
+
+	if len(stack.mismatches) == maxMismatches {
+		stack.mismatches = append(stack.mismatches[:0], stack.mismatches[1:]...)
+	}
+	stack.mismatches = append(stack.mismatches, mismatch)
+	stack.count++
+	if stack.OnMismatch != nil {
+		stack.OnMismatch(mismatch)
+	}
+}
+
+// returned records a return to address with the CallStack, after it was
+// popped.
+func (cpu *CPU) returned(address types.Word) {
+	if cpu.CallStack != nil {
+		cpu.CallStack.returned(cpu.instructionStart, address, cpu.stackPointer)
+	}
+}
@@ -0,0 +1,175 @@
+package cpu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCallStack(t *testing.T) {
+	program := make([]byte, 0x40)
+	copy(program[0x00:], []byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0xCD, 0x10, 0x00, // 0003 CALL 0x0010
+		0x76, // 0006 HLT
+	})
+	copy(program[0x10:], []byte{
+		0xCF, // 0010 RST 1
+		0xC9, // 0011 RET
+	})
+	program[0x08] = 0xC9 // 0008 RET
+
+	cpu := New()
+	cpu.Load(program)
+	cpu.CallStack = NewCallStack()
+
+	var deepest []Call
+	for !cpu.Halted() {
+		err := cpu.Step()
+		if err != nil {
+			t.Fatalf("did not expect an error, but got: %v", err)
+		}
+		if cpu.CallStack.Depth() > len(deepest) {
+			deepest = cpu.CallStack.Calls()
+		}
+	}
+
+	want := []Call{
+		{From: 0x0003, To: 0x0010, Return: 0x0006, StackPointer: 0x0FFE},
+		{From: 0x0010, To: 0x0008, Return: 0x0011, StackPointer: 0x0FFC},
+	}
+	if !reflect.DeepEqual(deepest, want) {
+		t.Errorf("deepest calls = %+v, want %+v", deepest, want)
+	}
+	if cpu.CallStack.Depth() != 0 {
+		t.Errorf("Depth() = %d after returning, want 0", cpu.CallStack.Depth())
+	}
+	if count, _ := cpu.CallStack.Mismatches(); count != 0 {
+		t.Errorf("%d returns mismatched, want none", count)
+	}
+}
+
+func TestCallStackMismatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		routine   []byte
+		want      ReturnMismatch
+		wantDepth int
+	}{
+		{
+			name: "computed return",
+			routine: []byte{
+				0x21, 0x30, 0x00, // 0010 LXI H,0x0030
+				0xE5, // 0013 PUSH H
+				0xC9, // 0014 RET
+			},
+			want:      ReturnMismatch{PC: 0x0014, To: 0x0030},
+			wantDepth: 1,
+		},
+		{
+			name: "overwritten return address",
+			routine: []byte{
+				0x21, 0x30, 0x00, // 0010 LXI H,0x0030
+				0x22, 0xFE, 0x0F, // 0013 SHLD 0x0FFE
+				0xC9, // 0016 RET
+			},
+			want: ReturnMismatch{PC: 0x0016, To: 0x0030, Call: &Call{From: 0x0003, To: 0x0010, Return: 0x0006, StackPointer: 0x0FFE}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			program := make([]byte, 0x40)
+			copy(program, []byte{
+				0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+				0xCD, 0x10, 0x00, // 0003 CALL 0x0010
+				0x76, // 0006 HLT
+			})
+			copy(program[0x10:], test.routine)
+			program[0x30] = 0x76 // 0030 HLT
+
+			cpu := New()
+			cpu.Load(program)
+			cpu.CallStack = NewCallStack()
+			var reported []ReturnMismatch
+			cpu.CallStack.OnMismatch = func(mismatch ReturnMismatch) {
+				reported = append(reported, mismatch)
+			}
+
+			err := cpu.Run()
+			if err != nil {
+				t.Fatalf("did not expect an error, but got: %v", err)
+			}
+
+			count, mismatches := cpu.CallStack.Mismatches()
+			if count != 1 || len(mismatches) != 1 || !reflect.DeepEqual(mismatches[0], test.want) {
+				t.Fatalf("Mismatches() = %d, %+v, want 1, %+v", count, mismatches, test.want)
+			}
+			if !reflect.DeepEqual(reported, mismatches) {
+				t.Errorf("OnMismatch reported %+v, want %+v", reported, mismatches)
+			}
+			if cpu.CallStack.Depth() != test.wantDepth {
+				t.Errorf("Depth() = %d, want %d", cpu.CallStack.Depth(), test.wantDepth)
+			}
+		})
+	}
+}
+
+func TestCallStackConditionalReturnNotTaken(t *testing.T) {
+	program := make([]byte, 0x20)
+	copy(program[0x00:], []byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0xCD, 0x10, 0x00, // 0003 CALL 0x0010
+		0x76, // 0006 HLT
+	})
+	copy(program[0x10:], []byte{
+		0xF6, 0x01, // 0010 ORI 1
+		0xC8, // 0012 RZ, not taken
+		0xC9, // 0013 RET
+	})
+
+	cpu := New()
+	cpu.Load(program)
+	cpu.CallStack = NewCallStack()
+
+	err := cpu.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	if count, mismatches := cpu.CallStack.Mismatches(); count != 0 {
+		t.Errorf("Mismatches() = %d, %+v, want none", count, mismatches)
+	}
+	if cpu.CallStack.Depth() != 0 {
+		t.Errorf("Depth() = %d after returning, want 0", cpu.CallStack.Depth())
+	}
+	if cpu.StackPointer() != 0x1000 {
+		t.Errorf("stackPointer = 0x%04X, want 0x1000", cpu.StackPointer())
+	}
+}
+
+func TestCallStackInterrupt(t *testing.T) {
+	cpu := New()
+	cpu.Load([]byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0xFB, // 0003 EI
+		0x00, // 0004 NOP
+		0x76, // 0005 HLT
+		0x00, // 0006
+		0x00, // 0007
+		0x76, // 0008 HLT
+	})
+	cpu.CallStack = NewCallStack()
+	cpu.Step()
+	cpu.Step()
+	cpu.Interrupt(0xCF) // RST 1
+
+	err := cpu.Step()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	want := []Call{{From: 0x0004, To: 0x0008, Return: 0x0004, StackPointer: 0x0FFE, Interrupt: true}}
+	if calls := cpu.CallStack.Calls(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("Calls() = %+v, want %+v", calls, want)
+	}
+}
@@ -2,6 +2,7 @@ package cpu
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/lukepeterson/go8080cpu/pkg/memory"
 	"github.com/lukepeterson/go8080cpu/pkg/types"
@@ -13,8 +14,44 @@ type Flags struct {
 	AuxCarry bool
 	Parity   bool
 	Carry    bool
+
+	// Overflow (V) and UnderflowIndicator (K) are the undocumented 8085 flags,
+	// stored in bits 1 and 5 of the flags byte.  They're never set on an 8080.
+	Overflow           bool
+	UnderflowIndicator bool
+
+	// Subtract (N) and the undocumented X and Y flags (copies of bits 3 and 5 of
+	// a result) only exist on the Z80, where Parity doubles as the P/V flag.
+	Subtract bool
+	X, Y     bool
 }
 
+// Variant selects the processor emulated by a CPU.
+type Variant int
+
+const (
+	Intel8080 Variant = iota
+	Intel8085
+	ZilogZ80
+)
+
+func (variant Variant) String() string {
+	switch variant {
+	case Intel8080:
+		return "8080"
+	case Intel8085:
+		return "8085"
+	case ZilogZ80:
+		return "Z80"
+	}
+
+	return fmt.Sprintf("Variant(%d)", int(variant))
+}
+
+// haltCycles is how many clock states each Step of a halted CPU idles for,
+// the length of the NOPs a halted Z80 executes.
+const haltCycles = 4
+
 type CPU struct {
 	A     byte
 	B, C  byte
@@ -25,15 +62,90 @@ type CPU struct {
 	stackPointer   types.Word
 	programCounter types.Word
 
-	ports map[byte]byte
+	ports   map[byte]byte
+	devices map[byte]Device
+	clocked []Clocked
 
 	interruptEnabled     bool
 	interruptPending     bool
 	interruptInstruction byte
+	interruptController  InterruptController
+	acknowledging        bool // Instruction bytes are fetched from interruptController
+
+	variant     Variant
+	i8085       i8085State
+	z80         z80State
+	cycles      uint64
+	branchTaken bool
+
+	fetched       [4]byte // Bytes of the instruction being executed, for faults
+	fetchedLength int
+
+	instructionStart types.Word // Address of the instruction being executed
+	interrupting     bool       // Whether an interrupt is being serviced
 
 	Bus       Bus
 	halted    bool
 	DebugMode bool
+
+	// StrictOpcodes traps the undocumented opcodes as illegal instructions,
+	// returning an IllegalOpcodeError, instead of executing them as aliases of
+	// NOP, JMP, RET and CALL.
+	StrictOpcodes bool
+
+	// FaultPolicy chooses what happens when an instruction faults, such as
+	// halting, carrying on or trapping to a handler.
+	FaultPolicy FaultPolicy
+	lastFault   error
+
+	// StackGuard, when set, faults pushes and pops outside the stack's region
+	// and measures its use.
+	StackGuard *StackGuard
+
+	// CallStack, when set, keeps a shadow of the calls on the stack.
+	CallStack *CallStack
+
+	// Trace, when set, receives a line for each instruction executed, giving
+	// its address, bytes, disassembly and the registers before it executes.
+	Trace io.Writer
+
+	// Symbols, when set, names addresses in traces, disassembly and Describe,
+	// such as with the labels and source lines of the program.
+	Symbols Symbols
+
+	// Profiler, when set, is told of each instruction executed.
+	Profiler Profiler
+}
+
+// Symbols names addresses, such as with the labels and source lines of the
+// program being run.  It's implemented by symbols.Table.
+type Symbols interface {
+	// Label returns the label at address, if there is one.
+	Label(address types.Word) (string, bool)
+
+	// Describe describes address, such as LOOP+3 (prog.asm:17).
+	Describe(address types.Word) string
+}
+
+// Profiler is told of each instruction the CPU executes, or interrupt it
+// services, with its address and the clock states it took.  It's implemented
+// by profile.Profiler.
+type Profiler interface {
+	Executed(address types.Word, cycles uint64)
+}
+
+// Profilers tells each of several Profilers of each instruction, to profile
+// and take coverage of the same run.
+//
+// Example:
+//
+//	processor.Profiler = cpu.Profilers{profiler, cover}
+type Profilers []Profiler
+
+func (profilers Profilers) Executed(address types.Word, cycles uint64) {
+	for _, profiler := range profilers {
+		profiler.Executed(address, cycles)
+	}
 }
 
 type Bus interface {
@@ -41,13 +153,117 @@ type Bus interface {
 	WriteByteAt(address types.Word, data byte) error
 }
 
+// Fetcher is implemented by a Bus that tells instruction fetches from data
+// reads, such as heatmap.Heatmap.  The CPU fetches opcodes and operands with
+// FetchByteAt if its Bus has it.
+type Fetcher interface {
+	FetchByteAt(address types.Word) (byte, error)
+}
+
+// Peeker is implemented by a Bus that counts reads, such as heatmap.Heatmap, so
+// that traces, disassembly and coverage can read memory without the reads
+// being counted as the program's.  The CPU reads for them with PeekByteAt if
+// its Bus has it.
+type Peeker interface {
+	PeekByteAt(address types.Word) (byte, error)
+}
+
 func New() *CPU {
+	return NewWithVariant(Intel8080)
+}
+
+// NewWithVariant returns a CPU emulating the given processor variant.
+func NewWithVariant(variant Variant) *CPU {
 	return &CPU{
-		Bus:   memory.New(),
-		ports: make(map[byte]byte, 256),
+		Bus:     memory.New(),
+		ports:   make(map[byte]byte, 256),
+		variant: variant,
 	}
 }
 
+// Variant returns the processor variant emulated by the CPU.
+func (cpu CPU) Variant() Variant {
+	return cpu.variant
+}
+
+// Cycles returns the number of clock states executed since the CPU was created.
+func (cpu CPU) Cycles() uint64 {
+	return cpu.cycles
+}
+
+// Halted reports whether the CPU has executed a HLT instruction.
+func (cpu CPU) Halted() bool {
+	return cpu.halted
+}
+
+// ProgramCounter returns the address of the next instruction to execute.
+func (cpu CPU) ProgramCounter() types.Word {
+	return cpu.programCounter
+}
+
+// InterruptWaiting reports whether the next Step will accept an interrupt,
+// waking the CPU if it's halted.
+func (cpu CPU) InterruptWaiting() bool {
+	if cpu.variant == Intel8085 && cpu.interrupt8085Waiting() || cpu.variant == ZilogZ80 && cpu.z80.nmiPending {
+		return true
+	}
+
+	return cpu.interruptEnabled && (cpu.interruptPending || cpu.controllerPending())
+}
+
+// Interrupted returns whether the last Step serviced an interrupt, rather
+// than executing the instruction at the program counter.
+func (cpu CPU) Interrupted() bool {
+	return cpu.interrupting
+}
+
+// Describe describes address using Symbols, such as LOOP+3 (prog.asm:17), or
+// as a number if there are none.
+func (cpu CPU) Describe(address types.Word) string {
+	if cpu.Symbols == nil {
+		return fmt.Sprintf("0x%04X", address)
+	}
+
+	return cpu.Symbols.Describe(address)
+}
+
+// SetProgramCounter sets the address of the next instruction to execute, as a
+// front panel's examine switch does.
+func (cpu *CPU) SetProgramCounter(address types.Word) {
+	cpu.programCounter = address
+}
+
+// StackPointer returns the stack pointer.
+func (cpu CPU) StackPointer() types.Word {
+	return cpu.stackPointer
+}
+
+// SetStackPointer sets the stack pointer, as a loader does before starting a
+// program that expects a stack.
+func (cpu *CPU) SetStackPointer(address types.Word) {
+	cpu.stackPointer = address
+}
+
+// Flags returns the flags.
+func (cpu CPU) Flags() Flags {
+	return cpu.flags
+}
+
+// InterruptsEnabled reports whether interrupts are enabled (the INTE output).
+func (cpu CPU) InterruptsEnabled() bool {
+	return cpu.interruptEnabled
+}
+
+// Reset resets the CPU as its RESET input does, clearing the program counter,
+// disabling interrupts and leaving the halt state.  The other registers and
+// memory are unchanged.
+func (cpu *CPU) Reset() {
+	cpu.programCounter = 0
+	cpu.interruptEnabled = false
+	cpu.interruptPending = false
+	cpu.halted = false
+}
+
 func (cpu *CPU) Load(data []byte) error {
 	for addr, value := range data {
 		err := cpu.Bus.WriteByteAt(types.Word(addr), value)
@@ -59,70 +275,183 @@ func (cpu *CPU) Load(data []byte) error {
 	return nil
 }
 
+// Run steps the CPU until it halts with no interrupt waiting to wake it.
 func (cpu *CPU) Run() error {
-	for !cpu.halted {
-		var nextInstruction byte
-		var err error
-		if cpu.interruptEnabled && cpu.interruptPending {
-			cpu.interruptEnabled = false
-			cpu.interruptPending = false
-			nextInstruction = cpu.interruptInstruction
-		} else {
-			nextInstruction, err = cpu.fetchByte()
-			if err != nil {
-				return fmt.Errorf("could not fetch byte: %v", err)
-			}
+	for !cpu.halted || cpu.InterruptWaiting() {
+		err := cpu.Step()
+		if err != nil {
+			return err
 		}
+	}
 
-		err = cpu.Execute(nextInstruction)
-		if err != nil {
-			return fmt.Errorf("could not execute nextInstruction 0x%02X: %v", nextInstruction, err)
+	return nil
+}
+
+// Step services a pending interrupt, or fetches and executes the next
+// instruction, writing it to Trace first if a trace is being taken.  Attached
+// Clocked devices are then ticked with the clock states it took.  A halted CPU
+// with no interrupt waiting idles for haltCycles instead, still ticking the
+// devices so that one of them can interrupt and wake it.
+//
+// Errors from the instruction, such as an IllegalOpcodeError or BusError, are
+// returned wrapped, with their Fault giving the instruction's address and
+// bytes, unless the FaultPolicy handles them.
+func (cpu *CPU) Step() error {
+	if cpu.halted && !cpu.InterruptWaiting() {
+		cpu.cycles += haltCycles
+		cpu.interrupting = false
+		if len(cpu.clocked) > 0 {
+			cpu.tick(haltCycles)
 		}
+		return nil
+	}
+
+	startCycles := cpu.cycles
+	pc := cpu.programCounter
+	cpu.fetchedLength = 0
+	cpu.instructionStart = pc
+	cpu.interrupting = false
+	err := cpu.step()
+	if err != nil {
+		cpu.locate(err, pc)
+		err = cpu.handleFault(err, pc)
+	}
+	if cpu.Profiler != nil {
+		cpu.Profiler.Executed(pc, cpu.cycles-startCycles)
+	}
+	if len(cpu.clocked) > 0 {
+		cpu.tick(cpu.cycles - startCycles)
+	}
 
-		if cpu.DebugMode {
-			cpu.DumpRegisters()
-			cpu.DumpMemory(0x0000, 0x0020) // Start of program code
-			cpu.DumpMemory(0xFFDF, 0xFFFF) // End of stack
+	return err
+}
+
+func (cpu *CPU) step() error {
+	serviced, err := cpu.serviceInterrupts()
+	if err != nil {
+		return fmt.Errorf("could not service interrupt: %w", err)
+	}
+	if serviced {
+		return nil
+	}
+
+	var nextInstruction byte
+	switch {
+	case cpu.interruptEnabled && cpu.interruptPending:
+		cpu.interruptEnabled = false
+		cpu.interruptPending = false
+		cpu.interrupting = true
+		cpu.halted = false
+		nextInstruction = cpu.interruptInstruction
+		cpu.record(nextInstruction)
+		if cpu.Trace != nil {
+			cpu.traceInterrupt(nextInstruction)
+		}
+	case cpu.interruptEnabled && cpu.controllerPending():
+		// The controller supplies the opcode and any operands in place of memory,
+		// leaving the program counter at the interrupted instruction.
+		cpu.interruptEnabled = false
+		cpu.acknowledging = true
+		cpu.interrupting = true
+		cpu.halted = false
+		nextInstruction = cpu.interruptController.Acknowledge()
+		cpu.record(nextInstruction)
+		if cpu.Trace != nil {
+			cpu.traceInterrupt(nextInstruction)
+		}
+	default:
+		if cpu.Trace != nil {
+			cpu.trace()
 		}
+		nextInstruction, err = cpu.fetchByte()
+		if err != nil {
+			return fmt.Errorf("could not fetch byte: %w", err)
+		}
+	}
+
+	err = cpu.Execute(nextInstruction)
+	cpu.acknowledging = false
+	if err != nil {
+		return fmt.Errorf("could not execute nextInstruction 0x%02X: %w", nextInstruction, err)
+	}
+
+	if cpu.DebugMode {
+		cpu.DumpRegisters()
+		cpu.DumpMemory(0x0000, 0x0020) // Start of program code
+		cpu.DumpMemory(0xFFDF, 0xFFFF) // End of stack
 	}
 
 	return nil
 }
 
+// Peek reads the byte at address on behalf of a tool rather than the program,
+// with PeekByteAt if the Bus has it.
+func (cpu *CPU) Peek(address types.Word) (byte, error) {
+	if peeker, ok := cpu.Bus.(Peeker); ok {
+		return peeker.PeekByteAt(address)
+	}
+
+	return cpu.Bus.ReadByteAt(address)
+}
+
 // fetchByte fetches the byte in memory pointed to by the program counter and then
-// increments the program counter by one.
+// increments the program counter by one.  While an interrupt is being
+// acknowledged, it fetches the byte from the interrupt controller instead.
 func (cpu *CPU) fetchByte() (byte, error) {
-	readByte, err := cpu.Bus.ReadByteAt(cpu.programCounter)
+	if cpu.acknowledging {
+		readByte := cpu.interruptController.Acknowledge()
+		cpu.record(readByte)
+		return readByte, nil
+	}
+
+	var readByte byte
+	var err error
+	if fetcher, ok := cpu.Bus.(Fetcher); ok {
+		readByte, err = fetcher.FetchByteAt(cpu.programCounter)
+	} else {
+		readByte, err = cpu.Bus.ReadByteAt(cpu.programCounter)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("could not fetch byte at 0x%04X: %v", cpu.programCounter, err)
+		err = cpu.busFault(cpu.programCounter, BusFetch, err)
+		if err != nil {
+			return 0, err
+		}
+		readByte = 0x00 // A NOP
 	}
 
+	cpu.record(readByte)
 	cpu.programCounter++
 	return readByte, nil
 }
 
+// record records a byte fetched as part of the instruction being executed.
+func (cpu *CPU) record(readByte byte) {
+	if cpu.fetchedLength < len(cpu.fetched) {
+		cpu.fetched[cpu.fetchedLength] = readByte
+		cpu.fetchedLength++
+	}
+}
+
 // fetchWord fetches the next two bytes in memory using fetchByte, and returns
 // them as a single two-byte word.
 func (cpu *CPU) fetchWord() (types.Word, error) {
 	low, err := cpu.fetchByte() // 8080 is little endian, so low byte comes first when reading from memory
 	if err != nil {
-		return 0, fmt.Errorf("could not fetch low byte of fetchWord: %v", err)
+		return 0, fmt.Errorf("could not fetch low byte of fetchWord: %w", err)
 	}
 
 	high, err := cpu.fetchByte()
 	if err != nil {
-		return 0, fmt.Errorf("could not fetch high byte of fetchWord: %v", err)
+		return 0, fmt.Errorf("could not fetch high byte of fetchWord: %w", err)
 	}
 
 	return joinBytes(high, low), nil
 }
 
 // getFlags returns the current state of the CPU flags packed into a single byte.
-// The flags are ordered from MSB (bit 7) to LSB (bit 0).
-//
-// This method performs the following steps:
-// 1. Generates a slice of eight bools for the flag storage
-// 2. Iterates through each bit in the slice, shifting the bits to the left if set
+// The flags are ordered from MSB (bit 7) to LSB (bit 0).  On an 8085 with the
+// undocumented flags enabled, bits 5 and 1 hold the K and V flags instead, and
+// on a Z80 bits 5, 3 and 1 hold the Y, X and N flags.
 //
 // Example:
 //
@@ -130,23 +459,19 @@ func (cpu *CPU) fetchWord() (types.Word, error) {
 //	result := cpu.getFlags()
 //	// result is 0b10000110 (0x86 or 134)
 func (cpu CPU) getFlags() byte {
-	flags := []bool{
-		cpu.flags.Sign,
-		cpu.flags.Zero,
-		false, // Bit 5 is always false
-		cpu.flags.AuxCarry,
-		false, // Bit 3 is always false
-		cpu.flags.Parity,
-		true, // Bit 1 is always true
-		cpu.flags.Carry,
-	}
-
-	// Convert the five boolean values stored in cpu.flags into an 8-bit byte
-	var result byte
-	for i, flag := range flags {
-		if flag {
-			result |= 1 << (7 - i)
-		}
+	result := boolToByte(cpu.flags.Sign)<<7 |
+		boolToByte(cpu.flags.Zero)<<6 |
+		boolToByte(cpu.flags.AuxCarry)<<4 |
+		boolToByte(cpu.flags.Parity)<<2 |
+		boolToByte(cpu.flags.Carry)
+
+	switch {
+	case cpu.undocumented8085():
+		result |= boolToByte(cpu.flags.UnderflowIndicator)<<5 | boolToByte(cpu.flags.Overflow)<<1
+	case cpu.variant == ZilogZ80:
+		result |= boolToByte(cpu.flags.Y)<<5 | boolToByte(cpu.flags.X)<<3 | boolToByte(cpu.flags.Subtract)<<1
+	default:
+		result |= 1 << 1 // Bit 1 is always true, bits 5 and 3 are always false
 	}
 
 	return result
@@ -167,4 +492,27 @@ func (cpu *CPU) setFlags(flags byte) {
 	cpu.flags.Parity = (flags & (1 << 2)) != 0
 	// Bit 1 is always true
 	cpu.flags.Carry = (flags & (1 << 0)) != 0
+
+	if cpu.undocumented8085() {
+		cpu.flags.UnderflowIndicator = (flags & (1 << 5)) != 0
+		cpu.flags.Overflow = (flags & (1 << 1)) != 0
+	}
+	if cpu.variant == ZilogZ80 {
+		cpu.flags.Y = (flags & (1 << 5)) != 0
+		cpu.flags.X = (flags & (1 << 3)) != 0
+		cpu.flags.Subtract = (flags & (1 << 1)) != 0
+	}
+}
+
+// serviceInterrupts services any pending interrupt that bypasses the 8080's
+// single interrupt instruction, returning true if one was serviced.
+func (cpu *CPU) serviceInterrupts() (bool, error) {
+	switch cpu.variant {
+	case Intel8085:
+		return cpu.service8085Interrupts()
+	case ZilogZ80:
+		return cpu.serviceZ80Interrupts()
+	}
+
+	return false, nil
 }
@@ -100,3 +100,27 @@ func TestCPUSetFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestReset(t *testing.T) {
+	cpu := New()
+	cpu.Load([]byte{0xFB, 0x3C, 0x76}) // EI, INR A, HLT
+	cpu.SetProgramCounter(0x0000)
+
+	err := cpu.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+	if !cpu.Halted() || !cpu.InterruptsEnabled() || cpu.ProgramCounter() != 0x0003 {
+		t.Fatalf("Halted() = %v, InterruptsEnabled() = %v, ProgramCounter() = 0x%04X, want true, true, 0x0003",
+			cpu.Halted(), cpu.InterruptsEnabled(), cpu.ProgramCounter())
+	}
+
+	cpu.Reset()
+	if cpu.Halted() || cpu.InterruptsEnabled() || cpu.ProgramCounter() != 0x0000 {
+		t.Errorf("Halted() = %v, InterruptsEnabled() = %v, ProgramCounter() = 0x%04X after reset, want false, false, 0x0000",
+			cpu.Halted(), cpu.InterruptsEnabled(), cpu.ProgramCounter())
+	}
+	if cpu.A != 0x01 {
+		t.Errorf("A = 0x%02X after reset, want 0x01 (unchanged)", cpu.A)
+	}
+}
@@ -0,0 +1,105 @@
+package cpu
+
+// cycles8085 holds the number of clock states taken by each 8085 instruction,
+// which replace the 8080 timings in instructions8085.  Conditional JMP, CALL
+// and RET instructions list the cost when the condition isn't met; the cost
+// when it is met is held in cyclesTaken8085.
+var cycles8085 = [256]byte{
+	4, 10, 7, 6, 4, 4, 7, 4, 10, 10, 7, 6, 4, 4, 7, 4, // 00
+	7, 10, 7, 6, 4, 4, 7, 4, 10, 10, 7, 6, 4, 4, 7, 4, // 10
+	4, 10, 16, 6, 4, 4, 7, 4, 10, 10, 16, 6, 4, 4, 7, 4, // 20
+	4, 10, 13, 6, 10, 10, 10, 4, 10, 10, 13, 6, 4, 4, 7, 4, // 30
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 40
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 50
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 60
+	7, 7, 7, 7, 7, 7, 5, 7, 4, 4, 4, 4, 4, 4, 7, 4, // 70
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 80
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 90
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // A0
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // B0
+	6, 10, 7, 10, 9, 12, 7, 12, 6, 10, 7, 6, 9, 18, 7, 12, // C0
+	6, 10, 7, 10, 9, 12, 7, 12, 6, 10, 7, 10, 9, 7, 7, 12, // D0
+	6, 10, 7, 16, 9, 12, 7, 12, 6, 6, 7, 4, 9, 10, 7, 12, // E0
+	6, 10, 7, 4, 9, 12, 7, 12, 6, 6, 7, 4, 9, 7, 7, 12, // F0
+}
+
+// cyclesTaken8085 holds the clock states taken by conditional 8085 instructions
+// when their condition is met.
+var cyclesTaken8085 = [256]byte{
+	0xC0: 12,
+	0xC2: 10,
+	0xC4: 18,
+	0xC8: 12,
+	0xCA: 10,
+	0xCB: 12,
+	0xCC: 18,
+	0xD0: 12,
+	0xD2: 10,
+	0xD4: 18,
+	0xD8: 12,
+	0xDA: 10,
+	0xDC: 18,
+	0xDD: 10,
+	0xE0: 12,
+	0xE2: 10,
+	0xE4: 18,
+	0xE8: 12,
+	0xEA: 10,
+	0xEC: 18,
+	0xF0: 12,
+	0xF2: 10,
+	0xF4: 18,
+	0xF8: 12,
+	0xFA: 10,
+	0xFC: 18,
+	0xFD: 10,
+}
+
+// cyclesZ80 holds the number of T-states taken by each unprefixed Z80 instruction.
+// Conditional relative jumps, calls and returns list the cost when the condition
+// isn't met; the cost when it is met is held in cyclesTakenZ80.  The CB, DD, ED
+// and FD prefixes are costed as part of the instruction they introduce.
+var cyclesZ80 = [256]byte{
+	4, 10, 7, 6, 4, 4, 7, 4, 4, 11, 7, 6, 4, 4, 7, 4, // 00
+	8, 10, 7, 6, 4, 4, 7, 4, 12, 11, 7, 6, 4, 4, 7, 4, // 10
+	7, 10, 16, 6, 4, 4, 7, 4, 7, 11, 16, 6, 4, 4, 7, 4, // 20
+	7, 10, 13, 6, 11, 11, 10, 4, 7, 11, 13, 6, 4, 4, 7, 4, // 30
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 40
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 50
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 60
+	7, 7, 7, 7, 7, 7, 4, 7, 4, 4, 4, 4, 4, 4, 7, 4, // 70
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 80
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // 90
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // A0
+	4, 4, 4, 4, 4, 4, 7, 4, 4, 4, 4, 4, 4, 4, 7, 4, // B0
+	5, 10, 10, 10, 10, 11, 7, 11, 5, 10, 10, 0, 10, 17, 7, 11, // C0
+	5, 10, 10, 11, 10, 11, 7, 11, 5, 4, 10, 11, 10, 0, 7, 11, // D0
+	5, 10, 10, 19, 10, 11, 7, 11, 5, 4, 10, 4, 10, 0, 7, 11, // E0
+	5, 10, 10, 4, 10, 11, 7, 11, 5, 6, 10, 4, 10, 0, 7, 11, // F0
+}
+
+// cyclesTakenZ80 holds the T-states taken by conditional Z80 instructions when
+// their condition is met.
+var cyclesTakenZ80 = [256]byte{
+	0x10: 13,
+	0x20: 12,
+	0x28: 12,
+	0x30: 12,
+	0x38: 12,
+	0xC0: 11,
+	0xC4: 17,
+	0xC8: 11,
+	0xCC: 17,
+	0xD0: 11,
+	0xD4: 17,
+	0xD8: 11,
+	0xDC: 17,
+	0xE0: 11,
+	0xE4: 17,
+	0xE8: 11,
+	0xEC: 17,
+	0xF0: 11,
+	0xF4: 17,
+	0xF8: 11,
+	0xFC: 17,
+}
@@ -51,3 +51,46 @@ func boolToInt(in bool) int {
 
 	return 0
 }
+
+// trace writes the instruction at the program counter to Trace, along with the
+// registers as they are before it executes, and with Symbols, where it is.
+//
+// Example:
+//
+//	0003  3E 12     MVI A,0x12     A=00 BC=0000 DE=0000 HL=0000 SP=0000 F=02 CYC=10
+//	0003  3E 12     MVI A,0x12     A=00 BC=0000 DE=0000 HL=0000 SP=0000 F=02 CYC=10  START+3 (prog.asm:2)
+func (cpu *CPU) trace() {
+	text, length, err := cpu.Disassemble(cpu.programCounter)
+	if err != nil {
+		text, length = "", 1
+	}
+
+	var raw strings.Builder
+	for i := 0; i < length; i++ {
+		readByte, _ := cpu.Peek(cpu.programCounter + types.Word(i))
+		fmt.Fprintf(&raw, "%02X ", readByte)
+	}
+
+	var where string
+	if cpu.Symbols != nil {
+		where = "  " + cpu.Symbols.Describe(cpu.programCounter)
+	}
+
+	cpu.traceLine(fmt.Sprintf("%04X", cpu.programCounter), raw.String(), text, where)
+}
+
+// traceInterrupt writes the instruction supplied by an interrupting device to
+// Trace, in place of an address.
+func (cpu *CPU) traceInterrupt(opCode byte) {
+	var text string
+	if instruction, ok := cpu.Lookup(opCode); ok {
+		text = instruction.Mnemonic
+	}
+
+	cpu.traceLine("INT ", fmt.Sprintf("%02X ", opCode), text, "")
+}
+
+func (cpu *CPU) traceLine(address, raw, text, where string) {
+	fmt.Fprintf(cpu.Trace, "%s  %-9s %-14s A=%02X BC=%04X DE=%04X HL=%04X SP=%04X F=%02X CYC=%d%s\n",
+		address, raw, text, cpu.A, cpu.getBC(), cpu.getDE(), cpu.getHL(), cpu.stackPointer, cpu.getFlags(), cpu.cycles, where)
+}
@@ -0,0 +1,99 @@
+package cpu
+
+// Device is a peripheral attached to one or more of the CPU's I/O ports.  IN
+// and OUT instructions addressing those ports are passed to the device rather
+// than the CPU's own port latches.
+type Device interface {
+	ReadPort(port byte) byte
+	WritePort(port byte, value byte)
+}
+
+// Clocked is implemented by devices that keep time with the CPU, such as a
+// USART's baud rate generator.  Tick is called after each instruction with the
+// number of clock states it took.
+type Clocked interface {
+	Tick(cycles uint64)
+}
+
+// Attach connects device to the given I/O ports, replacing any device already
+// attached to them.  A Clocked device is ticked once per instruction, however
+// many ports it's attached to.
+//
+// Example:
+//
+//	cpu.Attach(serial, 0x10, 0x11) // Data on port 0x10, control and status on 0x11
+func (cpu *CPU) Attach(device Device, ports ...byte) {
+	if cpu.devices == nil {
+		cpu.devices = make(map[byte]Device, len(ports))
+	}
+	for _, port := range ports {
+		cpu.devices[port] = device
+	}
+
+	clocked, ok := device.(Clocked)
+	if !ok {
+		return
+	}
+	for _, existing := range cpu.clocked {
+		if existing == clocked {
+			return
+		}
+	}
+	cpu.clocked = append(cpu.clocked, clocked)
+}
+
+// Interrupt requests an interrupt.  When interrupts are enabled it's
+// acknowledged before the next instruction is fetched, and instruction (normally
+// one of the RST instructions) is executed in its place.  On a Z80 in interrupt
+// mode 2, instruction is instead the low byte of the vector table address.
+// Accepting it wakes a CPU waiting in HLT.
+func (cpu *CPU) Interrupt(instruction byte) {
+	cpu.interruptPending = true
+	cpu.interruptInstruction = instruction
+}
+
+// InterruptController is implemented by an interrupt controller, such as the
+// 8259A, that drives the CPU's interrupt request line and supplies the
+// instruction executed when an interrupt is acknowledged, one byte per
+// acknowledge cycle.  This lets a controller supply an instruction of more than
+// one byte, such as the 8259A's three byte CALL.
+type InterruptController interface {
+	// Pending reports whether the controller is requesting an interrupt.
+	Pending() bool
+
+	// Acknowledge returns the next byte of the interrupt instruction.  It's
+	// called once for the opcode, then once for each of its operand bytes.
+	Acknowledge() byte
+}
+
+// SetInterruptController connects controller to the CPU's interrupt request
+// line.  Its interrupts are serviced when interrupts are enabled, after any
+// requested with Interrupt, and on a Z80 only in interrupt mode 0.
+//
+// Example:
+//
+//	cpu.Attach(controller, 0x20, 0x21)
+//	cpu.SetInterruptController(controller)
+func (cpu *CPU) SetInterruptController(controller InterruptController) {
+	cpu.interruptController = controller
+}
+
+// controllerPending reports whether an interrupt controller is requesting an
+// interrupt that the CPU can take in its current mode.
+func (cpu *CPU) controllerPending() bool {
+	if cpu.interruptController == nil {
+		return false
+	}
+	if cpu.variant == ZilogZ80 && cpu.z80.interruptMode != 0 {
+		return false
+	}
+
+	return cpu.interruptController.Pending()
+}
+
+// tick passes the clock states taken by the last instruction to each Clocked device.
+func (cpu *CPU) tick(cycles uint64) {
+	for _, device := range cpu.clocked {
+		device.Tick(cycles)
+	}
+}
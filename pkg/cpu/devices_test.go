@@ -0,0 +1,192 @@
+package cpu
+
+import "testing"
+
+// testDevice records the ports it's accessed through and the clock states it's ticked with.
+type testDevice struct {
+	value  byte
+	writes map[byte]byte
+	cycles uint64
+	ticks  int
+}
+
+func (device *testDevice) ReadPort(port byte) byte {
+	return device.value + port
+}
+
+func (device *testDevice) WritePort(port byte, value byte) {
+	device.writes[port] = value
+}
+
+func (device *testDevice) Tick(cycles uint64) {
+	device.cycles += cycles
+	device.ticks++
+}
+
+func TestAttach(t *testing.T) {
+	device := &testDevice{value: 0x40, writes: map[byte]byte{}}
+	cpu := New()
+	cpu.Attach(device, 0x10, 0x11)
+	// IN 0x11, OUT 0x10, MVI A 0x55, OUT 0x20, HLT
+	cpu.Load([]byte{0xDB, 0x11, 0xD3, 0x10, 0x3E, 0x55, 0xD3, 0x20, 0x76})
+
+	err := cpu.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	if device.writes[0x10] != 0x51 {
+		t.Errorf("device port 0x10 = 0x%02X, want 0x51", device.writes[0x10])
+	}
+	if _, ok := device.writes[0x20]; ok {
+		t.Errorf("device written through port 0x20, which it isn't attached to")
+	}
+	if cpu.ports[0x20] != 0x55 {
+		t.Errorf("port 0x20 = 0x%02X, want 0x55", cpu.ports[0x20])
+	}
+	if device.ticks != 5 || device.cycles != cpu.Cycles() {
+		t.Errorf("device ticked %d times with %d cycles, want 5 times with %d cycles", device.ticks, device.cycles, cpu.Cycles())
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	// LXI SP 0x1000, EI, NOP, HLT, ... 0x0038: MVI B 0x01, HLT
+	program := append([]byte{0x31, 0x00, 0x10, 0xFB, 0x00, 0x76}, make([]byte, 0x32)...)
+	program = append(program, 0x06, 0x01, 0x76)
+
+	cpu := New()
+	cpu.Load(program)
+	cpu.Interrupt(0xFF) // RST 7
+
+	err := cpu.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	if cpu.B != 0x01 {
+		t.Errorf("B = 0x%02X, want 0x01 (interrupt taken)", cpu.B)
+	}
+	if returnAddress, _ := cpu.pop(); returnAddress != 0x0004 {
+		t.Errorf("return address = 0x%04X, want 0x0004", returnAddress)
+	}
+}
+
+// interruptingDevice interrupts the CPU with RST 7 once it's been ticked for
+// the given number of clock states.
+type interruptingDevice struct {
+	cpu    *CPU
+	cycles uint64
+}
+
+func (device *interruptingDevice) ReadPort(port byte) byte { return 0 }
+
+func (device *interruptingDevice) WritePort(port byte, value byte) {}
+
+func (device *interruptingDevice) Tick(cycles uint64) {
+	if device.cycles > 0 && cycles >= device.cycles {
+		device.cpu.Interrupt(0xFF)
+	}
+	device.cycles -= min(cycles, device.cycles)
+}
+
+func TestInterruptWakesHalt(t *testing.T) {
+	// LXI SP 0x1000, EI, HLT, HLT, ... 0x0038: MVI B 0x01, HLT
+	program := append([]byte{0x31, 0x00, 0x10, 0xFB, 0x76, 0x76}, make([]byte, 0x32)...)
+	program = append(program, 0x06, 0x01, 0x76)
+
+	t.Run("interrupt instruction", func(t *testing.T) {
+		cpu := New()
+		cpu.Load(program)
+		cpu.Run()
+
+		cpu.Interrupt(0xFF) // RST 7
+		err := cpu.Run()
+		if err != nil {
+			t.Fatalf("error running cpu: %v", err)
+		}
+		if cpu.B != 0x01 || cpu.programCounter != 0x003B {
+			t.Errorf("B = 0x%02X at 0x%04X, want 0x01 at 0x003B (interrupt taken)", cpu.B, cpu.programCounter)
+		}
+		if returnAddress, _ := cpu.pop(); returnAddress != 0x0005 {
+			t.Errorf("return address = 0x%04X, want 0x0005", returnAddress)
+		}
+	})
+
+	t.Run("interrupt controller", func(t *testing.T) {
+		cpu := New()
+		cpu.Load(program)
+		cpu.Run()
+
+		cpu.SetInterruptController(&testController{instruction: []byte{0xCD, 0x38, 0x00}}) // CALL 0x0038
+		err := cpu.Run()
+		if err != nil {
+			t.Fatalf("error running cpu: %v", err)
+		}
+		if cpu.B != 0x01 || cpu.programCounter != 0x003B {
+			t.Errorf("B = 0x%02X at 0x%04X, want 0x01 at 0x003B (interrupt taken)", cpu.B, cpu.programCounter)
+		}
+	})
+
+	t.Run("clocked device", func(t *testing.T) {
+		cpu := New()
+		cpu.Load(program)
+		cpu.Attach(&interruptingDevice{cpu: cpu, cycles: 100}, 0x10)
+		cpu.Run()
+
+		steps := 0
+		for cpu.Halted() && steps < 100 {
+			err := cpu.Step()
+			if err != nil {
+				t.Fatalf("error stepping cpu: %v", err)
+			}
+			steps++
+		}
+		if cpu.Halted() || cpu.programCounter != 0x0038 {
+			t.Fatalf("Halted() = %v at 0x%04X after %d steps, want false at 0x0038", cpu.Halted(), cpu.programCounter, steps)
+		}
+		if returnAddress, _ := cpu.pop(); returnAddress != 0x0005 {
+			t.Errorf("return address = 0x%04X, want 0x0005 as idling doesn't execute past the HLT", returnAddress)
+		}
+	})
+}
+
+// testController supplies a queue of instruction bytes as an interrupt controller.
+type testController struct {
+	instruction []byte
+}
+
+func (controller *testController) Pending() bool {
+	return len(controller.instruction) > 0
+}
+
+func (controller *testController) Acknowledge() byte {
+	value := controller.instruction[0]
+	controller.instruction = controller.instruction[1:]
+	return value
+}
+
+func TestInterruptController(t *testing.T) {
+	// LXI SP 0x1000, EI, NOP, HLT, ... 0x0040: MVI B 0x01, HLT
+	program := append([]byte{0x31, 0x00, 0x10, 0xFB, 0x00, 0x76}, make([]byte, 0x3A)...)
+	program = append(program, 0x06, 0x01, 0x76)
+
+	cpu := New()
+	cpu.Load(program)
+	controller := &testController{instruction: []byte{0xCD, 0x40, 0x00}} // CALL 0x0040
+	cpu.SetInterruptController(controller)
+
+	err := cpu.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	if cpu.B != 0x01 {
+		t.Errorf("B = 0x%02X, want 0x01 (interrupt taken)", cpu.B)
+	}
+	if len(controller.instruction) != 0 {
+		t.Errorf("%d bytes of the CALL left unread, want 0", len(controller.instruction))
+	}
+	if returnAddress, _ := cpu.pop(); returnAddress != 0x0004 {
+		t.Errorf("return address = 0x%04X, want 0x0004", returnAddress)
+	}
+}
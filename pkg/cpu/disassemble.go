@@ -0,0 +1,63 @@
+package cpu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Disassemble returns the assembly language form of the instruction at
+// address, with any immediate operand shown in hex, and its length in bytes.
+// An address operand with a label in Symbols is shown as the label.
+//
+// Example:
+//
+//	// Assuming memory holds 0x3E 0x12 from address 0x0000:
+//	text, length, _ := cpu.Disassemble(0x0000)
+//	// text is "MVI A,0x12", length is 2
+func (cpu *CPU) Disassemble(address types.Word) (string, int, error) {
+	if cpu.variant == ZilogZ80 {
+		return "", 0, fmt.Errorf("disassembly isn't supported on the %v", cpu.variant)
+	}
+
+	opCode, err := cpu.Peek(address)
+	if err != nil {
+		return "", 0, fmt.Errorf("could not read opcode at 0x%04X: %v", address, err)
+	}
+	instruction := &cpu.instructionSet()[opCode]
+
+	// Operands are little endian, so read them from the last byte backwards
+	var operand int
+	for i := instruction.Length - 1; i > 0; i-- {
+		readByte, err := cpu.Peek(address + types.Word(i))
+		if err != nil {
+			return "", 0, fmt.Errorf("could not read operand at 0x%04X: %v", address+types.Word(i), err)
+		}
+		operand = operand<<8 | int(readByte)
+	}
+
+	if prefix, found := strings.CutSuffix(instruction.Mnemonic, "a16"); found && cpu.Symbols != nil {
+		if label, ok := cpu.Symbols.Label(types.Word(operand)); ok {
+			return prefix + label, instruction.Length, nil
+		}
+	}
+
+	return formatMnemonic(instruction.Mnemonic, operand), instruction.Length, nil
+}
+
+// formatMnemonic replaces the d8, d16 or a16 placeholder at the end of
+// mnemonic with operand.
+func formatMnemonic(mnemonic string, operand int) string {
+	if prefix, found := strings.CutSuffix(mnemonic, "d8"); found {
+		return fmt.Sprintf("%s0x%02X", prefix, operand)
+	}
+	if prefix, found := strings.CutSuffix(mnemonic, "d16"); found {
+		return fmt.Sprintf("%s0x%04X", prefix, operand)
+	}
+	if prefix, found := strings.CutSuffix(mnemonic, "a16"); found {
+		return fmt.Sprintf("%s0x%04X", prefix, operand)
+	}
+
+	return mnemonic
+}
@@ -0,0 +1,227 @@
+package cpu
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestDisassemble(t *testing.T) {
+	tests := []struct {
+		name       string
+		variant    Variant
+		program    []byte
+		want       string
+		wantLength int
+	}{
+		{name: "one byte", program: []byte{0x41}, want: "MOV B,C", wantLength: 1},
+		{name: "immediate byte", program: []byte{0x3E, 0x12}, want: "MVI A,0x12", wantLength: 2},
+		{name: "immediate word", program: []byte{0x21, 0x34, 0x12}, want: "LXI H,0x1234", wantLength: 3},
+		{name: "address", program: []byte{0xC3, 0xEF, 0xBE}, want: "JMP 0xBEEF", wantLength: 3},
+		{name: "port", program: []byte{0xD3, 0x10}, want: "OUT 0x10", wantLength: 2},
+		{name: "undocumented alias", program: []byte{0xDD, 0x00, 0x01}, want: "*CALL 0x0100", wantLength: 3},
+		{name: "8085 instruction", variant: Intel8085, program: []byte{0x28, 0x10}, want: "LDHI 0x10", wantLength: 2},
+		{name: "8085 shares the 8080 instructions", variant: Intel8085, program: []byte{0xFE, 0x01}, want: "CPI 0x01", wantLength: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewWithVariant(tt.variant)
+			cpu.Load(tt.program)
+
+			got, length, err := cpu.Disassemble(0x0000)
+			if err != nil {
+				t.Fatalf("error disassembling: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Disassemble() = %q, want %q", got, tt.want)
+			}
+			if length != tt.wantLength {
+				t.Errorf("length = %d, want %d", length, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestInstructionTables(t *testing.T) {
+	for _, variant := range []Variant{Intel8080, Intel8085} {
+		cpu := NewWithVariant(variant)
+		for opCode := 0; opCode < 256; opCode++ {
+			instruction, ok := cpu.Lookup(byte(opCode))
+			if !ok {
+				t.Fatalf("%v: Lookup(0x%02X) not ok", variant, opCode)
+			}
+			if instruction.execute == nil || instruction.Mnemonic == "" || instruction.Cycles == 0 {
+				t.Errorf("%v: opcode 0x%02X is incomplete: %+v", variant, opCode, instruction)
+			}
+
+			wantLength := 1
+			switch {
+			case strings.HasSuffix(instruction.Mnemonic, "d8"):
+				wantLength = 2
+			case strings.HasSuffix(instruction.Mnemonic, "d16"), strings.HasSuffix(instruction.Mnemonic, "a16"):
+				wantLength = 3
+			}
+			if instruction.Length != wantLength {
+				t.Errorf("%v: opcode 0x%02X (%s) has length %d, want %d", variant, opCode, instruction.Mnemonic, instruction.Length, wantLength)
+			}
+		}
+	}
+
+	if _, ok := NewWithVariant(ZilogZ80).Lookup(0x00); ok {
+		t.Errorf("Z80 Lookup() ok = true, want false")
+	}
+}
+
+func TestConditionalReturns(t *testing.T) {
+	tests := []struct {
+		opCode   byte
+		notTaken Flags
+		taken    Flags
+	}{
+		{opCode: 0xC0, notTaken: Flags{Zero: true}, taken: Flags{}},   // RNZ
+		{opCode: 0xC8, notTaken: Flags{}, taken: Flags{Zero: true}},   // RZ
+		{opCode: 0xD0, notTaken: Flags{Carry: true}, taken: Flags{}},  // RNC
+		{opCode: 0xD8, notTaken: Flags{}, taken: Flags{Carry: true}},  // RC
+		{opCode: 0xE0, notTaken: Flags{Parity: true}, taken: Flags{}}, // RPO
+		{opCode: 0xE8, notTaken: Flags{}, taken: Flags{Parity: true}}, // RPE
+		{opCode: 0xF0, notTaken: Flags{Sign: true}, taken: Flags{}},   // RP
+		{opCode: 0xF8, notTaken: Flags{}, taken: Flags{Sign: true}},   // RM
+	}
+	for _, variant := range []Variant{Intel8080, Intel8085} {
+		for _, test := range tests {
+			instruction, _ := NewWithVariant(variant).Lookup(test.opCode)
+			t.Run(fmt.Sprintf("%v %s", variant, instruction.Mnemonic), func(t *testing.T) {
+				cpu := NewWithVariant(variant)
+				cpu.Load([]byte{test.opCode, 0x00, 0x00, 0x00, 0x34, 0x12}) // Return address 0x1234 at 0x0004
+				cpu.SetStackPointer(0x0004)
+
+				cpu.flags = test.notTaken
+				err := cpu.Step()
+				if err != nil {
+					t.Fatalf("did not expect an error, but got: %v", err)
+				}
+				if cpu.StackPointer() != 0x0004 || cpu.programCounter != 0x0001 {
+					t.Errorf("not taken: SP = 0x%04X, PC = 0x%04X, want 0x0004 and 0x0001", cpu.StackPointer(), cpu.programCounter)
+				}
+				if cpu.cycles != uint64(instruction.Cycles) {
+					t.Errorf("not taken: %d cycles, want %d", cpu.cycles, instruction.Cycles)
+				}
+
+				cpu.programCounter = 0x0000
+				cpu.flags = test.taken
+				err = cpu.Step()
+				if err != nil {
+					t.Fatalf("did not expect an error, but got: %v", err)
+				}
+				if cpu.StackPointer() != 0x0006 || cpu.programCounter != 0x1234 {
+					t.Errorf("taken: SP = 0x%04X, PC = 0x%04X, want 0x0006 and 0x1234", cpu.StackPointer(), cpu.programCounter)
+				}
+			})
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var trace bytes.Buffer
+	cpu := New()
+	cpu.Trace = &trace
+	cpu.Load([]byte{0x3E, 0x12, 0x47, 0x76}) // MVI A 0x12, MOV B,A, HLT
+
+	err := cpu.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	want := []string{
+		"0000  3E 12     MVI A,0x12     A=00 BC=0000 DE=0000 HL=0000 SP=0000 F=02 CYC=0",
+		"0002  47        MOV B,A        A=12 BC=0000 DE=0000 HL=0000 SP=0000 F=02 CYC=7",
+		"0003  76        HLT            A=12 BC=1200 DE=0000 HL=0000 SP=0000 F=02 CYC=12",
+	}
+	got := strings.Split(strings.TrimSuffix(trace.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("trace has %d lines, want %d:\n%s", len(got), len(want), trace.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("trace line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// testSymbols labels addresses from a map, describing them by label alone.
+type testSymbols map[types.Word]string
+
+func (symbols testSymbols) Label(address types.Word) (string, bool) {
+	label, ok := symbols[address]
+	return label, ok
+}
+
+func (symbols testSymbols) Describe(address types.Word) string {
+	if label, ok := symbols[address]; ok {
+		return label
+	}
+
+	return fmt.Sprintf("0x%04X", address)
+}
+
+func TestSymbols(t *testing.T) {
+	var trace bytes.Buffer
+	cpu := New()
+	cpu.Trace = &trace
+	cpu.Load([]byte{0xC3, 0x04, 0x00, 0x00, 0xC3, 0x10, 0x00}) // JMP 0x0004, NOP, JMP 0x0010
+
+	if got := cpu.Describe(0x0004); got != "0x0004" {
+		t.Errorf("Describe(0x0004) = %q with no symbols, want %q", got, "0x0004")
+	}
+
+	cpu.Symbols = testSymbols{0x0000: "START", 0x0004: "LOOP"}
+	tests := []struct {
+		address types.Word
+		want    string
+	}{
+		{address: 0x0000, want: "JMP LOOP"},
+		{address: 0x0004, want: "JMP 0x0010"}, // No label
+	}
+	for _, test := range tests {
+		got, _, err := cpu.Disassemble(test.address)
+		if err != nil {
+			t.Fatalf("error disassembling: %v", err)
+		}
+		if got != test.want {
+			t.Errorf("Disassemble(0x%04X) = %q, want %q", test.address, got, test.want)
+		}
+	}
+
+	if got := cpu.Describe(0x0004); got != "LOOP" {
+		t.Errorf("Describe(0x0004) = %q, want %q", got, "LOOP")
+	}
+
+	cpu.Step()
+	cpu.Step()
+	want := "0000  C3 04 00  JMP LOOP       A=00 BC=0000 DE=0000 HL=0000 SP=0000 F=02 CYC=0  START\n" +
+		"0004  C3 10 00  JMP 0x0010     A=00 BC=0000 DE=0000 HL=0000 SP=0000 F=02 CYC=10  LOOP\n"
+	if trace.String() != want {
+		t.Errorf("trace = %q, want %q", trace.String(), want)
+	}
+}
+
+func TestStep(t *testing.T) {
+	cpu := New()
+	cpu.Load([]byte{0x3C, 0x3C, 0x76}) // INR A, INR A, HLT
+
+	err := cpu.Step()
+	if err != nil {
+		t.Fatalf("error stepping cpu: %v", err)
+	}
+
+	if cpu.A != 0x01 {
+		t.Errorf("A = 0x%02X, want 0x01", cpu.A)
+	}
+	if cpu.programCounter != 0x0001 {
+		t.Errorf("programCounter = 0x%04X, want 0x0001", cpu.programCounter)
+	}
+}
@@ -0,0 +1,160 @@
+package cpu
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Fault locates an error in the program: the address of the instruction that
+// caused it and the instruction bytes fetched before it did.  Step fills it in
+// for the errors below, which embed it.
+type Fault struct {
+	PC          types.Word
+	Instruction []byte
+}
+
+func (fault *Fault) locate(pc types.Word, instruction []byte) {
+	fault.PC = pc
+	fault.Instruction = instruction
+}
+
+// locatable is implemented by errors that embed a Fault.
+type locatable interface {
+	error
+	locate(pc types.Word, instruction []byte)
+}
+
+// IllegalOpcodeError is returned executing an opcode the CPU won't run, such
+// as an undocumented opcode with StrictOpcodes set.
+//
+// Example:
+//
+//	var illegal *cpu.IllegalOpcodeError
+//	if errors.As(err, &illegal) {
+//		fmt.Printf("0x%02X at 0x%04X\n", illegal.Opcode, illegal.PC)
+//	}
+type IllegalOpcodeError struct {
+	Fault
+	Opcode byte
+}
+
+func (err *IllegalOpcodeError) Error() string {
+	return fmt.Sprintf("illegal instruction 0x%02X at 0x%04X", err.Opcode, err.PC)
+}
+
+// BusOp is the kind of bus access that failed.
+type BusOp int
+
+const (
+	BusRead  BusOp = iota // Reading data
+	BusWrite              // Writing data
+	BusFetch              // Fetching an instruction byte
+)
+
+func (op BusOp) String() string {
+	switch op {
+	case BusRead:
+		return "read"
+	case BusWrite:
+		return "write"
+	case BusFetch:
+		return "fetch"
+	}
+
+	return fmt.Sprintf("BusOp(%d)", int(op))
+}
+
+// BusError is returned when the Bus fails to read or write an address, such
+// as one past the end of memory.  Err is the Bus's error.
+type BusError struct {
+	Fault
+	Address types.Word
+	Op      BusOp
+	Err     error
+}
+
+func (err *BusError) Error() string {
+	return fmt.Sprintf("could not %v address 0x%04X: %v", err.Op, err.Address, err.Err)
+}
+
+func (err *BusError) Unwrap() error {
+	return err.Err
+}
+
+// StackOverflowError is returned when a push would leave the StackGuard's
+// region, or fails as the stack has grown outside of memory.  Err is the
+// BusError of a failed push, or nil.
+type StackOverflowError struct {
+	Fault
+	StackPointer types.Word
+	Err          error
+}
+
+func (err *StackOverflowError) Error() string {
+	if err.Err == nil {
+		return fmt.Sprintf("stack overflow pushing with SP at 0x%04X", err.StackPointer)
+	}
+
+	return fmt.Sprintf("stack overflow pushing with SP at 0x%04X: %v", err.StackPointer, err.Err)
+}
+
+func (err *StackOverflowError) Unwrap() error {
+	return err.Err
+}
+
+// StackUnderflowError is returned when a pop would read from outside the
+// StackGuard's region, or fails as the stack has shrunk outside of memory.
+// Err is the BusError of a failed pop, or nil.
+type StackUnderflowError struct {
+	Fault
+	StackPointer types.Word
+	Err          error
+}
+
+func (err *StackUnderflowError) Error() string {
+	if err.Err == nil {
+		return fmt.Sprintf("stack underflow popping with SP at 0x%04X", err.StackPointer)
+	}
+
+	return fmt.Sprintf("stack underflow popping with SP at 0x%04X: %v", err.StackPointer, err.Err)
+}
+
+func (err *StackUnderflowError) Unwrap() error {
+	return err.Err
+}
+
+// locate fills in the Fault of each error in err's chain with the address of
+// the instruction being executed and the bytes fetched for it.
+func (cpu *CPU) locate(err error, pc types.Word) {
+	instruction := append([]byte(nil), cpu.fetched[:cpu.fetchedLength]...)
+	for err != nil {
+		if fault, ok := err.(locatable); ok {
+			fault.locate(pc, instruction)
+		}
+		err = errors.Unwrap(err)
+	}
+}
+
+// readByte reads a byte from the Bus, returning a BusError if it fails, or 0
+// if the FaultPolicy ignores it.
+func (cpu *CPU) readByte(address types.Word) (byte, error) {
+	value, err := cpu.Bus.ReadByteAt(address)
+	if err != nil {
+		return 0, cpu.busFault(address, BusRead, err)
+	}
+
+	return value, nil
+}
+
+// writeByte writes a byte to the Bus, returning a BusError if it fails unless
+// the FaultPolicy ignores it.
+func (cpu *CPU) writeByte(address types.Word, value byte) error {
+	err := cpu.Bus.WriteByteAt(address, value)
+	if err != nil {
+		return cpu.busFault(address, BusWrite, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package cpu
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestIllegalOpcodeError(t *testing.T) {
+	cpu := New()
+	cpu.StrictOpcodes = true
+	cpu.Load([]byte{0x00, 0x08}) // NOP, undocumented NOP
+	cpu.Step()
+
+	err := cpu.Step()
+	var illegal *IllegalOpcodeError
+	if !errors.As(err, &illegal) {
+		t.Fatalf("expected an IllegalOpcodeError, but got: %v", err)
+	}
+	if illegal.Opcode != 0x08 || illegal.PC != 0x0001 || !bytes.Equal(illegal.Instruction, []byte{0x08}) {
+		t.Errorf("error = %+v, want opcode 0x08 at 0x0001", illegal)
+	}
+}
+
+func TestBusError(t *testing.T) {
+	tests := []struct {
+		name            string
+		program         []byte
+		size            int
+		stackPointer    types.Word
+		wantAddress     types.Word
+		wantOp          BusOp
+		wantInstruction []byte
+		wantStack       error
+	}{
+		{
+			name:            "read",
+			program:         []byte{0x3A, 0x00, 0x20}, // LDA 0x2000
+			wantAddress:     0x2000,
+			wantOp:          BusRead,
+			wantInstruction: []byte{0x3A, 0x00, 0x20},
+		},
+		{
+			name:            "write",
+			program:         []byte{0x21, 0x00, 0x20, 0x77}, // LXI H,0x2000; MOV M,A
+			wantAddress:     0x2000,
+			wantOp:          BusWrite,
+			wantInstruction: []byte{0x77},
+		},
+		{
+			name:            "fetch",
+			program:         []byte{0xC3, 0x00, 0x20}, // JMP 0x2000
+			wantAddress:     0x2000,
+			wantOp:          BusFetch,
+			wantInstruction: []byte{},
+		},
+		{
+			name:            "fetch operand",
+			program:         []byte{0x00, 0x01}, // NOP, then LXI B at the end of memory
+			size:            2,
+			wantAddress:     0x0002,
+			wantOp:          BusFetch,
+			wantInstruction: []byte{0x01},
+		},
+		{
+			name:            "push",
+			program:         []byte{0xC5}, // PUSH B
+			stackPointer:    0x2000,
+			wantAddress:     0x1FFF,
+			wantOp:          BusWrite,
+			wantInstruction: []byte{0xC5},
+			wantStack:       &StackOverflowError{},
+		},
+		{
+			name:            "pop",
+			program:         []byte{0xC9}, // RET
+			stackPointer:    0x2000,
+			wantAddress:     0x2000,
+			wantOp:          BusRead,
+			wantInstruction: []byte{0xC9},
+			wantStack:       &StackUnderflowError{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := New()
+			size := test.size
+			if size == 0 {
+				size = 0x1000
+			}
+			cpu.Bus = &memory.Memory{Data: make([]byte, size)}
+			cpu.Load(test.program)
+			cpu.SetStackPointer(test.stackPointer)
+
+			var err error
+			var pc types.Word
+			for i := 0; i < 3 && err == nil; i++ {
+				pc = cpu.ProgramCounter()
+				err = cpu.Step()
+			}
+
+			var busErr *BusError
+			if !errors.As(err, &busErr) {
+				t.Fatalf("expected a BusError, but got: %v", err)
+			}
+			if busErr.Address != test.wantAddress || busErr.Op != test.wantOp {
+				t.Errorf("bus error = %v of 0x%04X, want %v of 0x%04X", busErr.Op, busErr.Address, test.wantOp, test.wantAddress)
+			}
+			if busErr.PC != pc || !bytes.Equal(busErr.Instruction, test.wantInstruction) {
+				t.Errorf("fault = % X at 0x%04X, want % X at 0x%04X", busErr.Instruction, busErr.PC, test.wantInstruction, pc)
+			}
+
+			var addressErr *memory.AddressError
+			if !errors.As(err, &addressErr) || addressErr.Address != test.wantAddress {
+				t.Errorf("expected the memory's AddressError for 0x%04X, but got: %v", test.wantAddress, err)
+			}
+
+			switch test.wantStack.(type) {
+			case *StackOverflowError:
+				var overflow *StackOverflowError
+				if !errors.As(err, &overflow) || overflow.StackPointer != test.stackPointer || overflow.PC != pc {
+					t.Errorf("expected a StackOverflowError at SP 0x%04X, but got: %v", test.stackPointer, err)
+				}
+			case *StackUnderflowError:
+				var underflow *StackUnderflowError
+				if !errors.As(err, &underflow) || underflow.StackPointer != test.stackPointer || underflow.PC != pc {
+					t.Errorf("expected a StackUnderflowError at SP 0x%04X, but got: %v", test.stackPointer, err)
+				}
+			}
+		})
+	}
+}
@@ -1408,7 +1408,7 @@ func TestExecute(t *testing.T) {
 						HLT
 					`,
 			initCPU: &CPU{},
-			wantCPU: &CPU{stackPointer: 0xFFFF, programCounter: 0x0009},
+			wantCPU: &CPU{stackPointer: 0xFFFD, programCounter: 0x0009},
 		},
 		{
 			name: "RC (carry set - return)",
@@ -1449,7 +1449,7 @@ func TestExecute(t *testing.T) {
 						HLT
 				`,
 			initCPU: &CPU{},
-			wantCPU: &CPU{flags: Flags{Carry: true}, stackPointer: 0xFFFF, programCounter: 0x000A},
+			wantCPU: &CPU{flags: Flags{Carry: true}, stackPointer: 0xFFFD, programCounter: 0x000A},
 		},
 		{
 			name: "RZ (zero set - return)",
@@ -1476,7 +1476,7 @@ func TestExecute(t *testing.T) {
 						HLT
 				`,
 			initCPU: &CPU{},
-			wantCPU: &CPU{stackPointer: 0xFFFF, programCounter: 0x0009},
+			wantCPU: &CPU{stackPointer: 0xFFFD, programCounter: 0x0009},
 		},
 		{
 			name: "RNZ (zero set - don't return)",
@@ -1489,7 +1489,7 @@ func TestExecute(t *testing.T) {
 				HLT
 				`,
 			initCPU: &CPU{},
-			wantCPU: &CPU{flags: Flags{Zero: true, Parity: true}, stackPointer: 0xFFFF, programCounter: 0x000A},
+			wantCPU: &CPU{flags: Flags{Zero: true, Parity: true}, stackPointer: 0xFFFD, programCounter: 0x000A},
 		},
 		{
 			name: "RNZ (zero not set - return)",
@@ -1515,7 +1515,7 @@ func TestExecute(t *testing.T) {
 				HLT
 				`,
 			initCPU: &CPU{},
-			wantCPU: &CPU{A: 0x80, flags: Flags{Sign: true, AuxCarry: true}, stackPointer: 0xFFFF, programCounter: 0x000C},
+			wantCPU: &CPU{A: 0x80, flags: Flags{Sign: true, AuxCarry: true}, stackPointer: 0xFFFD, programCounter: 0x000C},
 		},
 		{
 			name: "RP (sign flag not set - return)",
@@ -1557,7 +1557,7 @@ func TestExecute(t *testing.T) {
 				HLT
 				`,
 			initCPU: &CPU{},
-			wantCPU: &CPU{A: 0x7F, stackPointer: 0xFFFF, programCounter: 0x000C},
+			wantCPU: &CPU{A: 0x7F, stackPointer: 0xFFFD, programCounter: 0x000C},
 		},
 		{
 			name: "RPE (parity even - return)",
@@ -1585,7 +1585,7 @@ func TestExecute(t *testing.T) {
 				HLT
 				`,
 			initCPU: &CPU{},
-			wantCPU: &CPU{A: 0x02, stackPointer: 0xFFFF, programCounter: 0x000C},
+			wantCPU: &CPU{A: 0x02, stackPointer: 0xFFFD, programCounter: 0x000C},
 		},
 		{
 			name: "RPO (parity even - don't return)",
@@ -1599,7 +1599,7 @@ func TestExecute(t *testing.T) {
 				HLT
 				`,
 			initCPU: &CPU{},
-			wantCPU: &CPU{A: 0x03, flags: Flags{Parity: true}, stackPointer: 0xFFFF, programCounter: 0x000C},
+			wantCPU: &CPU{A: 0x03, flags: Flags{Parity: true}, stackPointer: 0xFFFD, programCounter: 0x000C},
 		},
 		{
 			name: "RPO (parity odd - return)",
@@ -3599,3 +3599,51 @@ func (cpu *CPU) registersEqual(otherCPU *CPU) bool {
 
 	return false
 }
+
+// benchmarkProgram is a hand-assembled loop of register, ALU, stack and jump
+// instructions, including PUSH PSW, which runs until the benchmark stops it.
+var benchmarkProgram = []byte{
+	0x31, 0x00, 0x10, // LXI SP 0x1000
+	0x06, 0x00, // MVI B 0x00
+	0x3C,             // INR A
+	0x80,             // ADD B
+	0x4F,             // MOV C,A
+	0xF5,             // PUSH PSW
+	0xD1,             // POP D
+	0x05,             // DCR B
+	0xC2, 0x05, 0x00, // JNZ 0x0005
+	0xC3, 0x03, 0x00, // JMP 0x0003
+}
+
+func BenchmarkExecute(b *testing.B) {
+	for _, variant := range []Variant{Intel8080, Intel8085, ZilogZ80} {
+		b.Run(variant.String(), func(b *testing.B) {
+			cpu := NewWithVariant(variant)
+			cpu.Load(benchmarkProgram)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := cpu.Step()
+				if err != nil {
+					b.Fatalf("error stepping cpu: %v", err)
+				}
+			}
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds()/1e6, "MIPS")
+		})
+	}
+}
+
+func TestExecuteDoesNotAllocate(t *testing.T) {
+	for _, variant := range []Variant{Intel8080, Intel8085, ZilogZ80} {
+		cpu := NewWithVariant(variant)
+		cpu.Load(benchmarkProgram)
+
+		allocs := testing.AllocsPerRun(1000, func() {
+			cpu.Step()
+		})
+		if allocs != 0 {
+			t.Errorf("%v: %v allocations per instruction, want 0", variant, allocs)
+		}
+	}
+}
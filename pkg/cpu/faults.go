@@ -0,0 +1,144 @@
+package cpu
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// FaultAction is what the CPU does when an instruction faults.
+type FaultAction int
+
+const (
+	FaultDefault FaultAction = iota // Halt on an illegal opcode and return the error, or just return a bus error
+	FaultError                      // Return the error from Step, leaving the CPU running
+	FaultHalt                       // Halt the CPU, as HLT does, without returning the error
+	FaultIgnore                     // Carry on: illegal opcodes run as NOPs, failed reads return 0 and failed writes are dropped
+	FaultTrap                       // Call the policy's Trap, or restart at its TrapVector
+)
+
+var faultActionNames = map[FaultAction]string{
+	FaultDefault: "default",
+	FaultError:   "error",
+	FaultHalt:    "halt",
+	FaultIgnore:  "ignore",
+	FaultTrap:    "trap",
+}
+
+func (action FaultAction) String() string {
+	if name, ok := faultActionNames[action]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("FaultAction(%d)", int(action))
+}
+
+// FaultPolicy chooses what the CPU does when an instruction faults, with an
+// IllegalOpcodeError, a BusError or, outside the StackGuard's region, a stack
+// error, so it can emulate forgiving hardware as well as strict test
+// environments.  The zero value halts on illegal opcodes and returns all
+// faults as errors.
+//
+// Example:
+//
+//	// Run undocumented opcodes as NOPs, and send bus errors to RST 7
+//	processor.StrictOpcodes = true
+//	processor.FaultPolicy = cpu.FaultPolicy{IllegalOpcode: cpu.FaultIgnore, Bus: cpu.FaultTrap, TrapVector: 7}
+type FaultPolicy struct {
+	IllegalOpcode FaultAction
+	Bus           FaultAction
+	Stack         FaultAction // Ignoring lets the stack leave the StackGuard's region
+
+	// Trap, when set, handles faults with the FaultTrap action.  It's called
+	// with the program counter back at the faulting instruction, and what it
+	// returns is returned from Step, so returning nil carries on.
+	Trap func(cpu *CPU, err error) error
+
+	// TrapVector is the restart, 0 to 7, that faults with the FaultTrap action
+	// go to when there's no Trap.  The address of the faulting instruction is
+	// pushed, as an RST instruction would push a return address.
+	TrapVector byte
+}
+
+// LastFault returns the last fault handled by the FaultPolicy without
+// returning it from Step, or nil if there hasn't been one.
+func (cpu CPU) LastFault() error {
+	return cpu.lastFault
+}
+
+// action returns what the FaultPolicy does with err, if it's a fault.
+func (cpu *CPU) action(err error) (FaultAction, bool) {
+	var illegal *IllegalOpcodeError
+	if errors.As(err, &illegal) {
+		return cpu.FaultPolicy.IllegalOpcode, true
+	}
+	var busErr *BusError
+	if errors.As(err, &busErr) {
+		return cpu.FaultPolicy.Bus, true
+	}
+	var overflow *StackOverflowError
+	var underflow *StackUnderflowError
+	if errors.As(err, &overflow) || errors.As(err, &underflow) {
+		return cpu.FaultPolicy.Stack, true
+	}
+
+	return FaultDefault, false
+}
+
+// illegalOpcode faults on an opcode the CPU won't run.
+func (cpu *CPU) illegalOpcode(opCode byte) error {
+	err := &IllegalOpcodeError{Opcode: opCode}
+	switch cpu.FaultPolicy.IllegalOpcode {
+	case FaultDefault:
+		cpu.halted = true
+	case FaultIgnore:
+		cpu.lastFault = err
+		cpu.cycles += uint64(instructions8080[0x00].Cycles)
+		return nil
+	}
+
+	return err
+}
+
+// busFault returns a BusError for a failed access, or records it and returns
+// nil if the FaultPolicy ignores bus errors.
+func (cpu *CPU) busFault(address types.Word, op BusOp, err error) error {
+	busErr := &BusError{Address: address, Op: op, Err: err}
+	if cpu.FaultPolicy.Bus == FaultIgnore {
+		cpu.lastFault = busErr
+		return nil
+	}
+
+	return busErr
+}
+
+// handleFault applies the FaultPolicy to an error from executing the
+// instruction at pc, returning the error Step returns.
+func (cpu *CPU) handleFault(err error, pc types.Word) error {
+	action, ok := cpu.action(err)
+	if !ok {
+		return err
+	}
+
+	switch action {
+	case FaultHalt:
+		cpu.halted = true
+		cpu.lastFault = err
+		return nil
+	case FaultTrap:
+		cpu.lastFault = err
+		cpu.programCounter = pc
+		if cpu.FaultPolicy.Trap != nil {
+			return cpu.FaultPolicy.Trap(cpu, err)
+		}
+
+		trapErr := cpu.rst(types.Word(cpu.FaultPolicy.TrapVector&0x07) * 8)
+		if trapErr != nil {
+			return fmt.Errorf("could not trap %v: %w", err, trapErr)
+		}
+		return nil
+	}
+
+	return err
+}
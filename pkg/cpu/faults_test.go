@@ -0,0 +1,231 @@
+package cpu
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestIllegalOpcodePolicy(t *testing.T) {
+	program := []byte{
+		0x31, 0x00, 0x01, // 0000 LXI SP,0x0100
+		0x08,             // 0003 Undocumented NOP
+		0x76,             // 0004 HLT
+		0x00, 0x00, 0x00, // 0005
+		0x3C, // 0008 INR A
+		0x76, // 0009 HLT
+	}
+
+	errTrap := errors.New("trapped")
+	tests := []struct {
+		name        string
+		policy      FaultPolicy
+		wantErr     error
+		wantHalted  bool
+		wantPC      types.Word
+		wantA       byte
+		wantFault   bool
+		wantPointer types.Word
+	}{
+		{name: "default", wantErr: &IllegalOpcodeError{}, wantHalted: true, wantPC: 0x0004, wantPointer: 0x0100},
+		{name: "error", policy: FaultPolicy{IllegalOpcode: FaultError}, wantErr: &IllegalOpcodeError{}, wantPC: 0x0004, wantPointer: 0x0100},
+		{name: "halt", policy: FaultPolicy{IllegalOpcode: FaultHalt}, wantHalted: true, wantPC: 0x0004, wantFault: true, wantPointer: 0x0100},
+		{name: "ignore", policy: FaultPolicy{IllegalOpcode: FaultIgnore}, wantHalted: true, wantPC: 0x0005, wantFault: true, wantPointer: 0x0100},
+		{name: "trap vector", policy: FaultPolicy{IllegalOpcode: FaultTrap, TrapVector: 1}, wantHalted: true, wantPC: 0x000A, wantA: 0x01, wantFault: true, wantPointer: 0x00FE},
+		{
+			name: "trap handler",
+			policy: FaultPolicy{IllegalOpcode: FaultTrap, Trap: func(cpu *CPU, err error) error {
+				cpu.A = 0x42
+				cpu.SetProgramCounter(cpu.ProgramCounter() + 1)
+				return nil
+			}},
+			wantHalted:  true,
+			wantPC:      0x0005,
+			wantA:       0x42,
+			wantFault:   true,
+			wantPointer: 0x0100,
+		},
+		{
+			name: "trap handler error",
+			policy: FaultPolicy{IllegalOpcode: FaultTrap, Trap: func(cpu *CPU, err error) error {
+				return errTrap
+			}},
+			wantErr:     errTrap,
+			wantPC:      0x0003,
+			wantFault:   true,
+			wantPointer: 0x0100,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := New()
+			cpu.StrictOpcodes = true
+			cpu.FaultPolicy = test.policy
+			cpu.Load(program)
+
+			err := cpu.Run()
+			switch want := test.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("did not expect an error, but got: %v", err)
+				}
+			case *IllegalOpcodeError:
+				if !errors.As(err, &want) {
+					t.Fatalf("expected an IllegalOpcodeError, but got: %v", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("expected %v, but got: %v", want, err)
+				}
+			}
+
+			if cpu.Halted() != test.wantHalted {
+				t.Errorf("Halted() = %v, want %v", cpu.Halted(), test.wantHalted)
+			}
+			if cpu.ProgramCounter() != test.wantPC {
+				t.Errorf("programCounter = 0x%04X, want 0x%04X", cpu.ProgramCounter(), test.wantPC)
+			}
+			if cpu.A != test.wantA {
+				t.Errorf("A = 0x%02X, want 0x%02X", cpu.A, test.wantA)
+			}
+			if cpu.StackPointer() != test.wantPointer {
+				t.Errorf("stackPointer = 0x%04X, want 0x%04X", cpu.StackPointer(), test.wantPointer)
+			}
+
+			var illegal *IllegalOpcodeError
+			if test.wantFault && (!errors.As(cpu.LastFault(), &illegal) || illegal.Opcode != 0x08) {
+				t.Errorf("LastFault() = %v, want the illegal opcode 0x08", cpu.LastFault())
+			}
+			if !test.wantFault && cpu.LastFault() != nil {
+				t.Errorf("LastFault() = %v, want nil", cpu.LastFault())
+			}
+		})
+	}
+}
+
+func TestIllegalOpcodeTrapReturnAddress(t *testing.T) {
+	cpu := New()
+	cpu.StrictOpcodes = true
+	cpu.FaultPolicy = FaultPolicy{IllegalOpcode: FaultTrap, TrapVector: 1}
+	cpu.Load([]byte{0x31, 0x00, 0x01, 0x08, 0x76, 0x00, 0x00, 0x00, 0x3C, 0x76})
+	cpu.Run()
+
+	low, _ := cpu.Bus.ReadByteAt(0x00FE)
+	high, _ := cpu.Bus.ReadByteAt(0x00FF)
+	if address := joinBytes(high, low); address != 0x0003 {
+		t.Errorf("pushed 0x%04X, want the faulting instruction's address 0x0003", address)
+	}
+}
+
+func TestBusPolicy(t *testing.T) {
+	program := []byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0x3E, 0x05, // 0003 MVI A,0x05
+		0x32, 0x00, 0x20, // 0005 STA 0x2000
+		0x3A, 0x00, 0x20, // 0008 LDA 0x2000
+		0x76,                   // 000B HLT
+		0x00, 0x00, 0x00, 0x00, // 000C
+		0x3E, 0x77, // 0010 MVI A,0x77
+		0x76, // 0012 HLT
+	}
+
+	tests := []struct {
+		name       string
+		policy     FaultPolicy
+		wantErr    bool
+		wantHalted bool
+		wantPC     types.Word
+		wantA      byte
+		wantOp     BusOp
+	}{
+		{name: "default", wantErr: true, wantPC: 0x0008, wantA: 0x05},
+		{name: "error", policy: FaultPolicy{Bus: FaultError}, wantErr: true, wantPC: 0x0008, wantA: 0x05},
+		{name: "halt", policy: FaultPolicy{Bus: FaultHalt}, wantHalted: true, wantPC: 0x0008, wantA: 0x05, wantOp: BusWrite},
+		{name: "ignore", policy: FaultPolicy{Bus: FaultIgnore}, wantHalted: true, wantPC: 0x000C, wantA: 0x00, wantOp: BusRead},
+		{name: "trap vector", policy: FaultPolicy{Bus: FaultTrap, TrapVector: 2}, wantHalted: true, wantPC: 0x0013, wantA: 0x77, wantOp: BusWrite},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := New()
+			cpu.Bus = &memory.Memory{Data: make([]byte, 0x1000)}
+			cpu.FaultPolicy = test.policy
+			cpu.Load(program)
+
+			err := cpu.Run()
+			var busErr *BusError
+			if test.wantErr && (!errors.As(err, &busErr) || busErr.Op != BusWrite) {
+				t.Errorf("expected a BusError writing, but got: %v", err)
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("did not expect an error, but got: %v", err)
+			}
+
+			if cpu.Halted() != test.wantHalted {
+				t.Errorf("Halted() = %v, want %v", cpu.Halted(), test.wantHalted)
+			}
+			if cpu.ProgramCounter() != test.wantPC {
+				t.Errorf("programCounter = 0x%04X, want 0x%04X", cpu.ProgramCounter(), test.wantPC)
+			}
+			if cpu.A != test.wantA {
+				t.Errorf("A = 0x%02X, want 0x%02X", cpu.A, test.wantA)
+			}
+			if !test.wantErr && (!errors.As(cpu.LastFault(), &busErr) || busErr.Op != test.wantOp || busErr.Address != 0x2000) {
+				t.Errorf("LastFault() = %v, want a %v of 0x2000", cpu.LastFault(), test.wantOp)
+			}
+		})
+	}
+}
+
+func TestBusPolicyIgnoreOperands(t *testing.T) {
+	tests := []struct {
+		name        string
+		variant     Variant
+		program     []byte
+		wantAddress types.Word
+	}{
+		{name: "M", program: []byte{0x21, 0x00, 0x20, 0x7E, 0x76}, wantAddress: 0x2000},                                           // LXI H,0x2000; MOV A,M; HLT
+		{name: "(IX+d)", variant: ZilogZ80, program: []byte{0xDD, 0x21, 0x00, 0x20, 0xDD, 0x7E, 0x00, 0x76}, wantAddress: 0x2000}, // LD IX,0x2000; LD A,(IX+0); HALT
+		{name: "word", variant: ZilogZ80, program: []byte{0x2A, 0x00, 0x20, 0x76}, wantAddress: 0x2001},                           // LD HL,(0x2000); HALT, faulting last on the high byte
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := NewWithVariant(test.variant)
+			cpu.Bus = &memory.Memory{Data: make([]byte, 0x1000)}
+			cpu.FaultPolicy = FaultPolicy{Bus: FaultIgnore}
+			cpu.Load(test.program)
+
+			err := cpu.Run()
+			if err != nil {
+				t.Fatalf("did not expect an error, but got: %v", err)
+			}
+			var busErr *BusError
+			if !errors.As(cpu.LastFault(), &busErr) || busErr.Op != BusRead || busErr.Address != test.wantAddress {
+				t.Errorf("LastFault() = %v, want a %v of 0x%04X", cpu.LastFault(), BusRead, test.wantAddress)
+			}
+		})
+	}
+}
+
+func TestBusPolicyIgnoreFetch(t *testing.T) {
+	cpu := New()
+	cpu.Bus = &memory.Memory{Data: make([]byte, 0x10)}
+	cpu.FaultPolicy = FaultPolicy{Bus: FaultIgnore}
+	cpu.Load([]byte{0xC3, 0x00, 0x20}) // JMP 0x2000
+
+	for i := 0; i < 3; i++ {
+		err := cpu.Step()
+		if err != nil {
+			t.Fatalf("did not expect an error, but got: %v", err)
+		}
+	}
+
+	// Fetches past the end of memory run as NOPs
+	if cpu.ProgramCounter() != 0x2002 || cpu.Cycles() != 18 {
+		t.Errorf("programCounter = 0x%04X after %d cycles, want 0x2002 after 18", cpu.ProgramCounter(), cpu.Cycles())
+	}
+}
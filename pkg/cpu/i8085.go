@@ -0,0 +1,290 @@
+package cpu
+
+import "github.com/lukepeterson/go8080cpu/pkg/types"
+
+// InterruptLine identifies one of the 8085's hardware interrupt inputs.
+type InterruptLine int
+
+const (
+	RST55 InterruptLine = iota // RST 5.5 - level triggered, maskable, vectors to 0x002C
+	RST65                      // RST 6.5 - level triggered, maskable, vectors to 0x0034
+	RST75                      // RST 7.5 - rising edge triggered, maskable, vectors to 0x003C
+	TRAP                       // TRAP - non-maskable, vectors to 0x0024
+)
+
+// Interrupt mask bits, as used by SIM and returned by RIM.
+const (
+	mask55 = 1 << 0
+	mask65 = 1 << 1
+	mask75 = 1 << 2
+)
+
+// i8085State holds the interrupt and serial I/O state that only exists on the 8085.
+type i8085State struct {
+	masks byte // M5.5, M6.5 and M7.5 in bits 0 to 2, set means masked
+
+	rst55Level  bool
+	rst65Level  bool
+	rst75Level  bool
+	rst75Latch  bool // RST 7.5 is edge triggered, so a pending request is latched until serviced or reset by SIM
+	trapLevel   bool
+	trapPending bool
+
+	// interruptEnabledBeforeTrap holds the interrupt enable flag from before the
+	// last TRAP, which RIM reports in place of the current flag until it's read.
+	interruptEnabledBeforeTrap bool
+	trapped                    bool
+
+	sid bool
+	sod bool
+}
+
+// undocumented8085 reports whether the undocumented 8085 instructions and
+// flags are available, which is always on an 8085 unless StrictOpcodes is set.
+func (cpu CPU) undocumented8085() bool {
+	return cpu.variant == Intel8085 && !cpu.StrictOpcodes
+}
+
+// SetInterruptLine drives one of the 8085 interrupt inputs high (active) or low.
+//
+// RST 5.5 and RST 6.5 are level triggered and must be held active until the
+// interrupt is serviced.  RST 7.5 and TRAP are edge triggered, so a low to high
+// transition is latched as a pending request.  It has no effect on an 8080.
+func (cpu *CPU) SetInterruptLine(line InterruptLine, active bool) {
+	state := &cpu.i8085
+	switch line {
+	case RST55:
+		state.rst55Level = active
+	case RST65:
+		state.rst65Level = active
+	case RST75:
+		if active && !state.rst75Level {
+			state.rst75Latch = true
+		}
+		state.rst75Level = active
+	case TRAP:
+		if active && !state.trapLevel {
+			state.trapPending = true
+		}
+		state.trapLevel = active
+	}
+}
+
+// SetSID sets the level of the 8085 serial input data pin, read by RIM.
+func (cpu *CPU) SetSID(level bool) {
+	cpu.i8085.sid = level
+}
+
+// SOD returns the level of the 8085 serial output data pin, set by SIM.
+func (cpu CPU) SOD() bool {
+	return cpu.i8085.sod
+}
+
+// interrupt8085Waiting reports whether TRAP, or an unmasked RST 7.5, 6.5 or
+// 5.5 while interrupts are enabled, is waiting to be serviced.
+func (cpu CPU) interrupt8085Waiting() bool {
+	state := &cpu.i8085
+	if state.trapPending {
+		return true
+	}
+
+	return cpu.interruptEnabled && (state.rst75Latch && state.masks&mask75 == 0 ||
+		state.rst65Level && state.masks&mask65 == 0 ||
+		state.rst55Level && state.masks&mask55 == 0)
+}
+
+// service8085Interrupts checks the 8085 interrupt inputs in priority order
+// (TRAP, RST 7.5, RST 6.5 then RST 5.5) and vectors to the highest priority
+// pending interrupt, returning true if one was serviced.  Servicing one wakes
+// the CPU from HLT.
+func (cpu *CPU) service8085Interrupts() (bool, error) {
+	state := &cpu.i8085
+
+	var vector types.Word
+	switch {
+	case state.trapPending:
+		state.trapPending = false
+		state.interruptEnabledBeforeTrap = cpu.interruptEnabled
+		state.trapped = true
+		vector = 0x0024
+	case !cpu.interruptEnabled:
+		return false, nil
+	case state.rst75Latch && state.masks&mask75 == 0:
+		state.rst75Latch = false
+		vector = 0x003C
+	case state.rst65Level && state.masks&mask65 == 0:
+		vector = 0x0034
+	case state.rst55Level && state.masks&mask55 == 0:
+		vector = 0x002C
+	default:
+		return false, nil
+	}
+
+	cpu.interruptEnabled = false
+	cpu.interrupting = true
+	cpu.halted = false
+	err := cpu.rst(vector)
+	if err != nil {
+		return false, err
+	}
+
+	cpu.cycles += 12
+	return true, nil
+}
+
+// rim (read interrupt masks) loads the accumulator with the serial input data,
+// pending interrupts, interrupt enable flag and interrupt masks:
+//
+//	Bit 7: SID, Bit 6: I7.5, Bit 5: I6.5, Bit 4: I5.5, Bit 3: IE, Bits 2-0: M7.5, M6.5, M5.5
+func (cpu *CPU) rim() {
+	state := &cpu.i8085
+
+	interruptEnabled := cpu.interruptEnabled
+	if state.trapped {
+		interruptEnabled = state.interruptEnabledBeforeTrap
+		state.trapped = false
+	}
+
+	var result byte
+	if state.sid {
+		result |= 1 << 7
+	}
+	if state.rst75Latch {
+		result |= 1 << 6
+	}
+	if state.rst65Level {
+		result |= 1 << 5
+	}
+	if state.rst55Level {
+		result |= 1 << 4
+	}
+	if interruptEnabled {
+		result |= 1 << 3
+	}
+	cpu.A = result | state.masks
+}
+
+// sim (set interrupt masks) uses the accumulator to program the interrupt masks
+// and the serial output data pin:
+//
+//	Bit 7: SOD, Bit 6: SOD enable, Bit 4: reset RST 7.5, Bit 3: mask set enable, Bits 2-0: M7.5, M6.5, M5.5
+func (cpu *CPU) sim() {
+	state := &cpu.i8085
+
+	if cpu.A&(1<<3) != 0 {
+		state.masks = cpu.A & (mask55 | mask65 | mask75)
+	}
+	if cpu.A&(1<<4) != 0 {
+		state.rst75Latch = false
+	}
+	if cpu.A&(1<<6) != 0 {
+		state.sod = cpu.A&(1<<7) != 0
+	}
+}
+
+// instructions8085 holds the 8085 instruction set, indexed by opcode.  It's
+// the 8080 instruction set with 8085 timings, plus the instructions in
+// instructions8085Only.
+var instructions8085 = build8085Instructions()
+
+// instructions8085Only holds RIM and SIM, and the undocumented 8085
+// instructions that occupy the opcodes the 8080 leaves unassigned, as described
+// in "Unspecified 8085 op codes enhance programming" (Dehnhardt and Sorensen,
+// 1979).  When StrictOpcodes is set the undocumented ones are trapped as illegal.
+var instructions8085Only = map[byte]Instruction{
+	0x20: {Mnemonic: "RIM", Length: 1, execute: func(cpu *CPU) error { cpu.rim(); return nil }},
+	0x30: {Mnemonic: "SIM", Length: 1, execute: func(cpu *CPU) error { cpu.sim(); return nil }},
+	0x08: {Mnemonic: "DSUB", Length: 1, Flags: "SZAPC", Undocumented: true, execute: func(cpu *CPU) error { cpu.dsub(); return nil }},
+	0x10: {Mnemonic: "ARHL", Length: 1, Flags: "C", Undocumented: true, execute: func(cpu *CPU) error {
+		cpu.flags.Carry = cpu.L&1 == 1
+		hl := cpu.getHL()
+		cpu.H, cpu.L = splitWord(hl>>1 | hl&0x8000)
+		return nil
+	}},
+	0x18: {Mnemonic: "RDEL", Length: 1, Flags: "C", Undocumented: true, execute: func(cpu *CPU) error {
+		de := cpu.getDE()
+		result := de << 1
+		if cpu.flags.Carry {
+			result |= 1
+		}
+		cpu.flags.Carry = de&0x8000 != 0
+		cpu.flags.Overflow = (de^result)&0x8000 != 0
+		cpu.D, cpu.E = splitWord(result)
+		return nil
+	}},
+	0x28: {Mnemonic: "LDHI d8", Length: 2, Undocumented: true, execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.D, cpu.E = splitWord(cpu.getHL() + types.Word(fetchedByte))
+		return nil
+	}},
+	0x38: {Mnemonic: "LDSI d8", Length: 2, Undocumented: true, execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.D, cpu.E = splitWord(cpu.stackPointer + types.Word(fetchedByte))
+		return nil
+	}},
+	0xCB: {Mnemonic: "RSTV", Length: 1, Undocumented: true, execute: func(cpu *CPU) error {
+		if !cpu.flags.Overflow {
+			return nil
+		}
+		cpu.branchTaken = true
+		return cpu.rst(0x0040)
+	}},
+	0xD9: {Mnemonic: "SHLX", Length: 1, Undocumented: true, execute: func(cpu *CPU) error {
+		err := cpu.writeByte(cpu.getDE(), cpu.L)
+		if err != nil {
+			return err
+		}
+		return cpu.writeByte(cpu.getDE()+1, cpu.H)
+	}},
+	0xDD: {Mnemonic: "JNK a16", Length: 3, Undocumented: true, execute: func(cpu *CPU) error { return cpu.jmp(!cpu.flags.UnderflowIndicator) }},
+	0xED: {Mnemonic: "LHLX", Length: 1, Undocumented: true, execute: func(cpu *CPU) error {
+		var err error
+		cpu.L, err = cpu.readByte(cpu.getDE())
+		if err != nil {
+			return err
+		}
+		cpu.H, err = cpu.readByte(cpu.getDE() + 1)
+		return err
+	}},
+	0xFD: {Mnemonic: "JK a16", Length: 3, Undocumented: true, execute: func(cpu *CPU) error { return cpu.jmp(cpu.flags.UnderflowIndicator) }},
+}
+
+// build8085Instructions returns the 8085 instruction set, built from the 8080
+// instruction set.
+func build8085Instructions() [256]Instruction {
+	instructions := instructions8080
+	for opCode, instruction := range instructions8085Only {
+		instructions[opCode] = instruction
+	}
+	for opCode := range instructions {
+		instructions[opCode].Cycles = int(cycles8085[opCode])
+		instructions[opCode].CyclesTaken = int(cyclesTaken8085[opCode])
+	}
+
+	return instructions
+}
+
+// dsub subtracts the B&C register pair from the H&L register pair, setting all
+// flags.  The subtraction is performed a byte at a time, so the sign, parity,
+// auxiliary carry and overflow flags reflect the high byte of the result,
+// while the zero flag reflects the full 16-bit result.
+func (cpu *CPU) dsub() {
+	tempA := cpu.A
+
+	cpu.A = cpu.L
+	cpu.sub(cpu.C, NoCarry)
+	cpu.L = cpu.A
+
+	cpu.A = cpu.H
+	cpu.sub(cpu.B, boolToByte(cpu.flags.Carry))
+	cpu.H = cpu.A
+
+	cpu.A = tempA
+	cpu.flags.Zero = cpu.getHL() == 0
+}
@@ -0,0 +1,323 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestI8085Instructions(t *testing.T) {
+	// The assembler only supports the 8080 instruction set, so these programs
+	// are hand-assembled.
+	tests := []struct {
+		name    string
+		program []byte
+		initCPU func(cpu *CPU)
+		check   func(t *testing.T, cpu *CPU)
+	}{
+		{
+			name:    "SIM sets masks, RIM reads them back",
+			program: []byte{0x3E, 0x0D, 0x30, 0xAF, 0x20, 0x76}, // MVI A 0x0D, SIM, XRA A, RIM, HLT
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0x05 {
+					t.Errorf("A = 0x%02X, want 0x05", cpu.A)
+				}
+			},
+		},
+		{
+			name:    "RIM reads SID, pending interrupts and IE",
+			program: []byte{0x20, 0x76}, // RIM, HLT
+			initCPU: func(cpu *CPU) {
+				cpu.SetSID(true)
+				cpu.SetInterruptLine(RST65, true)
+				cpu.SetInterruptLine(RST75, true)
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0b1110_0000 {
+					t.Errorf("A = 0b%08b, want 0b11100000", cpu.A)
+				}
+			},
+		},
+		{
+			name:    "SIM sets SOD",
+			program: []byte{0x3E, 0xC0, 0x30, 0x76}, // MVI A 0xC0, SIM, HLT
+			check: func(t *testing.T, cpu *CPU) {
+				if !cpu.SOD() {
+					t.Errorf("SOD = false, want true")
+				}
+			},
+		},
+		{
+			name:    "DSUB",
+			program: []byte{0x08, 0x76}, // DSUB, HLT
+			initCPU: func(cpu *CPU) {
+				cpu.H, cpu.L = 0x12, 0x34
+				cpu.B, cpu.C = 0x02, 0x35
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				if got := cpu.getHL(); got != 0x0FFF {
+					t.Errorf("HL = 0x%04X, want 0x0FFF", got)
+				}
+				if cpu.flags.Carry || cpu.flags.Zero {
+					t.Errorf("flags = %+v, want carry and zero unset", cpu.flags)
+				}
+			},
+		},
+		{
+			name:    "ARHL",
+			program: []byte{0x10, 0x76}, // ARHL, HLT
+			initCPU: func(cpu *CPU) { cpu.H, cpu.L = 0x80, 0x03 },
+			check: func(t *testing.T, cpu *CPU) {
+				if got := cpu.getHL(); got != 0xC001 {
+					t.Errorf("HL = 0x%04X, want 0xC001", got)
+				}
+				if !cpu.flags.Carry {
+					t.Errorf("carry = false, want true")
+				}
+			},
+		},
+		{
+			name:    "RDEL",
+			program: []byte{0x37, 0x18, 0x76}, // STC, RDEL, HLT
+			initCPU: func(cpu *CPU) { cpu.D, cpu.E = 0x40, 0x01 },
+			check: func(t *testing.T, cpu *CPU) {
+				if got := cpu.getDE(); got != 0x8003 {
+					t.Errorf("DE = 0x%04X, want 0x8003", got)
+				}
+				if cpu.flags.Carry || !cpu.flags.Overflow {
+					t.Errorf("flags = %+v, want carry unset and overflow set", cpu.flags)
+				}
+			},
+		},
+		{
+			name:    "LDHI and LDSI",
+			program: []byte{0x28, 0x10, 0x7A, 0x38, 0x02, 0x76}, // LDHI 0x10, MOV A,D, LDSI 0x02, HLT
+			initCPU: func(cpu *CPU) {
+				cpu.H, cpu.L = 0x12, 0xF8
+				cpu.stackPointer = 0x2000
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0x13 {
+					t.Errorf("A = 0x%02X, want 0x13", cpu.A)
+				}
+				if got := cpu.getDE(); got != 0x2002 {
+					t.Errorf("DE = 0x%04X, want 0x2002", got)
+				}
+			},
+		},
+		{
+			name:    "SHLX and LHLX",
+			program: []byte{0xD9, 0x21, 0x00, 0x00, 0xED, 0x76}, // SHLX, LXI H 0x0000, LHLX, HLT
+			initCPU: func(cpu *CPU) {
+				cpu.D, cpu.E = 0x01, 0x00
+				cpu.H, cpu.L = 0xBE, 0xEF
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				if got := cpu.getHL(); got != 0xBEEF {
+					t.Errorf("HL = 0x%04X, want 0xBEEF", got)
+				}
+			},
+		},
+		{
+			name: "JK after signed comparison",
+			// MVI A 0x01, CPI 0x02, JK 0x0009, HLT, INR B, HLT
+			program: []byte{0x3E, 0x01, 0xFE, 0x02, 0xFD, 0x09, 0x00, 0x76, 0x00, 0x04, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.B != 0x01 {
+					t.Errorf("B = 0x%02X, want 0x01 (jump taken)", cpu.B)
+				}
+			},
+		},
+		{
+			name: "JNK after signed comparison",
+			// MVI A 0x03, CPI 0x02, JNK 0x0009, HLT, INR B, HLT
+			program: []byte{0x3E, 0x03, 0xFE, 0x02, 0xDD, 0x09, 0x00, 0x76, 0x00, 0x04, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.B != 0x01 {
+					t.Errorf("B = 0x%02X, want 0x01 (jump taken)", cpu.B)
+				}
+			},
+		},
+		{
+			name:    "V and K flags pushed with PSW",
+			program: []byte{0x3E, 0x7F, 0x3C, 0xF5, 0xC1, 0x76}, // MVI A 0x7F, INR A, PUSH PSW, POP B, HLT
+			initCPU: func(cpu *CPU) { cpu.stackPointer = 0x1000 },
+			check: func(t *testing.T, cpu *CPU) {
+				// S, AC and V are set, and K is S XOR V (so unset)
+				if cpu.C != 0b1001_0010 {
+					t.Errorf("flags = 0b%08b, want 0b10010010", cpu.C)
+				}
+			},
+		},
+		{
+			name: "RSTV",
+			// MVI A 0x7F, INR A, RSTV, HLT, ... 0x0040: INR B, HLT
+			program: append([]byte{0x3E, 0x7F, 0x3C, 0xCB, 0x76}, append(make([]byte, 0x3B), 0x04, 0x76)...),
+			initCPU: func(cpu *CPU) { cpu.stackPointer = 0x1000 },
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.B != 0x01 {
+					t.Errorf("B = 0x%02X, want 0x01 (restart taken)", cpu.B)
+				}
+				if cpu.programCounter != 0x0042 {
+					t.Errorf("programCounter = 0x%04X, want 0x0042", cpu.programCounter)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewWithVariant(Intel8085)
+			cpu.Load(tt.program)
+			if tt.initCPU != nil {
+				tt.initCPU(cpu)
+			}
+
+			err := cpu.Run()
+			if err != nil {
+				t.Fatalf("error running cpu: %v", err)
+			}
+
+			tt.check(t, cpu)
+		})
+	}
+}
+
+func TestI8085Interrupts(t *testing.T) {
+	tests := []struct {
+		name        string
+		masks       byte
+		enabled     bool
+		lines       []InterruptLine
+		wantVector  types.Word
+		wantEnabled bool
+	}{
+		{name: "no interrupts pending", enabled: true, wantEnabled: true},
+		{name: "RST 5.5", enabled: true, lines: []InterruptLine{RST55}, wantVector: 0x002C},
+		{name: "RST 6.5 beats RST 5.5", enabled: true, lines: []InterruptLine{RST55, RST65}, wantVector: 0x0034},
+		{name: "RST 7.5 beats RST 6.5", enabled: true, lines: []InterruptLine{RST65, RST75}, wantVector: 0x003C},
+		{name: "TRAP beats RST 7.5", enabled: true, lines: []InterruptLine{RST75, TRAP}, wantVector: 0x0024},
+		{name: "masked RST 7.5", enabled: true, masks: mask75, lines: []InterruptLine{RST75, RST55}, wantVector: 0x002C},
+		{name: "all masked", enabled: true, masks: mask55 | mask65 | mask75, lines: []InterruptLine{RST55, RST65, RST75}, wantEnabled: true},
+		{name: "interrupts disabled", lines: []InterruptLine{RST55, RST65, RST75}},
+		{name: "TRAP ignores interrupt enable", lines: []InterruptLine{TRAP}, wantVector: 0x0024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewWithVariant(Intel8085)
+			cpu.stackPointer = 0x1000
+			cpu.programCounter = 0x0100
+			cpu.interruptEnabled = tt.enabled
+			cpu.i8085.masks = tt.masks
+			for _, line := range tt.lines {
+				cpu.SetInterruptLine(line, true)
+			}
+
+			serviced, err := cpu.service8085Interrupts()
+			if err != nil {
+				t.Fatalf("error servicing interrupts: %v", err)
+			}
+
+			if serviced != (tt.wantVector != 0) {
+				t.Fatalf("serviced = %v, want %v", serviced, tt.wantVector != 0)
+			}
+			if serviced {
+				if cpu.programCounter != tt.wantVector {
+					t.Errorf("programCounter = 0x%04X, want 0x%04X", cpu.programCounter, tt.wantVector)
+				}
+				returnAddress, _ := cpu.pop()
+				if returnAddress != 0x0100 {
+					t.Errorf("return address = 0x%04X, want 0x0100", returnAddress)
+				}
+			}
+			if cpu.interruptEnabled != tt.wantEnabled {
+				t.Errorf("interruptEnabled = %v, want %v", cpu.interruptEnabled, tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestI8085InterruptWakesHalt(t *testing.T) {
+	cpu := NewWithVariant(Intel8085)
+	cpu.Load([]byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0xFB, // 0003 EI
+		0x76, // 0004 HLT
+		0x76, // 0005 HLT
+	})
+	cpu.Bus.WriteByteAt(0x0024, 0x76) // 0024 HLT, in the TRAP routine
+	cpu.Bus.WriteByteAt(0x002C, 0x76) // 002C HLT, in the RST 5.5 routine
+
+	err := cpu.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if !cpu.Halted() || cpu.programCounter != 0x0005 {
+		t.Fatalf("Halted() = %v at 0x%04X, want true at 0x0005", cpu.Halted(), cpu.programCounter)
+	}
+
+	cpu.SetInterruptLine(RST55, true)
+	if !cpu.InterruptWaiting() {
+		t.Errorf("InterruptWaiting() = false with RST 5.5 active, want true")
+	}
+	err = cpu.Step()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if cpu.Halted() || cpu.programCounter != 0x002C {
+		t.Errorf("Halted() = %v at 0x%04X after RST 5.5, want false at 0x002C", cpu.Halted(), cpu.programCounter)
+	}
+	if returnAddress, _ := cpu.pop(); returnAddress != 0x0005 {
+		t.Errorf("return address = 0x%04X, want 0x0005", returnAddress)
+	}
+
+	// Run wakes the CPU too, rather than returning while an interrupt waits
+	cpu.SetInterruptLine(RST55, false)
+	cpu.Reset()
+	cpu.SetInterruptLine(TRAP, true)
+	cpu.halted = true
+	err = cpu.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if cpu.programCounter != 0x0025 {
+		t.Errorf("programCounter = 0x%04X after Run() with TRAP pending, want 0x0025 past the HLT at 0x0024", cpu.programCounter)
+	}
+}
+
+func TestI8085StrictOpcodes(t *testing.T) {
+	cpu := NewWithVariant(Intel8085)
+	cpu.StrictOpcodes = true
+	cpu.Load([]byte{0x08, 0x76}) // DSUB, HLT
+
+	err := cpu.Run()
+	if err == nil {
+		t.Errorf("expected an error in strict mode, but got none")
+	}
+}
+
+func TestCycles(t *testing.T) {
+	// LXI SP 0x1000, MVI A 0x01, CALL 0x000A, HLT, CPI 0x01, CNZ 0x0000, RZ
+	program := []byte{0x31, 0x00, 0x10, 0x3E, 0x01, 0xCD, 0x0A, 0x00, 0x76, 0x00, 0xFE, 0x01, 0xC4, 0x00, 0x00, 0xC8}
+	tests := []struct {
+		variant Variant
+		want    uint64
+	}{
+		{variant: Intel8080, want: 10 + 7 + 17 + 7 + 11 + 11 + 7},
+		{variant: Intel8085, want: 10 + 7 + 18 + 7 + 9 + 12 + 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.variant.String(), func(t *testing.T) {
+			cpu := NewWithVariant(tt.variant)
+			cpu.Load(program)
+
+			err := cpu.Run()
+			if err != nil {
+				t.Fatalf("error running cpu: %v", err)
+			}
+
+			if cpu.Cycles() != tt.want {
+				t.Errorf("Cycles() = %d, want %d", cpu.Cycles(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,605 @@
+package cpu
+
+// Instruction describes a single opcode: its mnemonic, length and timing, the
+// flags it affects and the handler that executes it.  The same table drives
+// execution, disassembly and tracing, so they can't disagree with each other.
+type Instruction struct {
+	// Mnemonic is the assembly language form of the instruction, with d8, d16
+	// and a16 standing in for an immediate byte, word and address.
+	Mnemonic string
+
+	// Length is the instruction length in bytes, including the opcode.
+	Length int
+
+	// Cycles is the number of clock states the instruction takes.  For
+	// conditional CALL and RET instructions it's the cost when the condition
+	// isn't met, with CyclesTaken holding the cost when it is.
+	Cycles      int
+	CyclesTaken int
+
+	// Flags lists the documented flags the instruction affects, from "SZAPC".
+	Flags string
+
+	// Undocumented is set for opcodes that aren't listed in the datasheet.
+	Undocumented bool
+
+	execute func(cpu *CPU) error
+}
+
+// noOperation is the handler for NOP, and for the MOV instructions that move a
+// register to itself.
+func noOperation(cpu *CPU) error {
+	return nil
+}
+
+// instructions8080 holds the 8080 instruction set, indexed by opcode.
+// Grouped by instruction set group as per "Table 2. Instruction Set Summary",
+// in the Intel 8080A 8-BIT N-CHANNEL MICROPROCESSOR datasheet.
+var instructions8080 = [256]Instruction{
+	// MOVE, LOAD AND STORE
+	0x40: {Mnemonic: "MOV B,B", Length: 1, Cycles: 5, execute: noOperation},
+	0x41: {Mnemonic: "MOV B,C", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.B = cpu.C; return nil }},
+	0x42: {Mnemonic: "MOV B,D", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.B = cpu.D; return nil }},
+	0x43: {Mnemonic: "MOV B,E", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.B = cpu.E; return nil }},
+	0x44: {Mnemonic: "MOV B,H", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.B = cpu.H; return nil }},
+	0x45: {Mnemonic: "MOV B,L", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.B = cpu.L; return nil }},
+	0x46: {Mnemonic: "MOV B,M", Length: 1, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.B, err = cpu.getM(); return err }},
+	0x47: {Mnemonic: "MOV B,A", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.B = cpu.A; return nil }},
+	0x48: {Mnemonic: "MOV C,B", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.C = cpu.B; return nil }},
+	0x49: {Mnemonic: "MOV C,C", Length: 1, Cycles: 5, execute: noOperation},
+	0x4A: {Mnemonic: "MOV C,D", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.C = cpu.D; return nil }},
+	0x4B: {Mnemonic: "MOV C,E", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.C = cpu.E; return nil }},
+	0x4C: {Mnemonic: "MOV C,H", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.C = cpu.H; return nil }},
+	0x4D: {Mnemonic: "MOV C,L", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.C = cpu.L; return nil }},
+	0x4E: {Mnemonic: "MOV C,M", Length: 1, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.C, err = cpu.getM(); return err }},
+	0x4F: {Mnemonic: "MOV C,A", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.C = cpu.A; return nil }},
+	0x50: {Mnemonic: "MOV D,B", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.D = cpu.B; return nil }},
+	0x51: {Mnemonic: "MOV D,C", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.D = cpu.C; return nil }},
+	0x52: {Mnemonic: "MOV D,D", Length: 1, Cycles: 5, execute: noOperation},
+	0x53: {Mnemonic: "MOV D,E", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.D = cpu.E; return nil }},
+	0x54: {Mnemonic: "MOV D,H", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.D = cpu.H; return nil }},
+	0x55: {Mnemonic: "MOV D,L", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.D = cpu.L; return nil }},
+	0x56: {Mnemonic: "MOV D,M", Length: 1, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.D, err = cpu.getM(); return err }},
+	0x57: {Mnemonic: "MOV D,A", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.D = cpu.A; return nil }},
+	0x58: {Mnemonic: "MOV E,B", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.E = cpu.B; return nil }},
+	0x59: {Mnemonic: "MOV E,C", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.E = cpu.C; return nil }},
+	0x5A: {Mnemonic: "MOV E,D", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.E = cpu.D; return nil }},
+	0x5B: {Mnemonic: "MOV E,E", Length: 1, Cycles: 5, execute: noOperation},
+	0x5C: {Mnemonic: "MOV E,H", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.E = cpu.H; return nil }},
+	0x5D: {Mnemonic: "MOV E,L", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.E = cpu.L; return nil }},
+	0x5E: {Mnemonic: "MOV E,M", Length: 1, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.E, err = cpu.getM(); return err }},
+	0x5F: {Mnemonic: "MOV E,A", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.E = cpu.A; return nil }},
+	0x60: {Mnemonic: "MOV H,B", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.H = cpu.B; return nil }},
+	0x61: {Mnemonic: "MOV H,C", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.H = cpu.C; return nil }},
+	0x62: {Mnemonic: "MOV H,D", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.H = cpu.D; return nil }},
+	0x63: {Mnemonic: "MOV H,E", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.H = cpu.E; return nil }},
+	0x64: {Mnemonic: "MOV H,H", Length: 1, Cycles: 5, execute: noOperation},
+	0x65: {Mnemonic: "MOV H,L", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.H = cpu.L; return nil }},
+	0x66: {Mnemonic: "MOV H,M", Length: 1, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.H, err = cpu.getM(); return err }},
+	0x67: {Mnemonic: "MOV H,A", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.H = cpu.A; return nil }},
+	0x68: {Mnemonic: "MOV L,B", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.L = cpu.B; return nil }},
+	0x69: {Mnemonic: "MOV L,C", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.L = cpu.C; return nil }},
+	0x6A: {Mnemonic: "MOV L,D", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.L = cpu.D; return nil }},
+	0x6B: {Mnemonic: "MOV L,E", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.L = cpu.E; return nil }},
+	0x6C: {Mnemonic: "MOV L,H", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.L = cpu.H; return nil }},
+	0x6D: {Mnemonic: "MOV L,L", Length: 1, Cycles: 5, execute: noOperation},
+	0x6E: {Mnemonic: "MOV L,M", Length: 1, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.L, err = cpu.getM(); return err }},
+	0x6F: {Mnemonic: "MOV L,A", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.L = cpu.A; return nil }},
+	0x70: {Mnemonic: "MOV M,B", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { return cpu.setM(cpu.B) }},
+	0x71: {Mnemonic: "MOV M,C", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { return cpu.setM(cpu.C) }},
+	0x72: {Mnemonic: "MOV M,D", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { return cpu.setM(cpu.D) }},
+	0x73: {Mnemonic: "MOV M,E", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { return cpu.setM(cpu.E) }},
+	0x74: {Mnemonic: "MOV M,H", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { return cpu.setM(cpu.H) }},
+	0x75: {Mnemonic: "MOV M,L", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { return cpu.setM(cpu.L) }},
+	0x77: {Mnemonic: "MOV M,A", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { return cpu.setM(cpu.A) }},
+	0x78: {Mnemonic: "MOV A,B", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.A = cpu.B; return nil }},
+	0x79: {Mnemonic: "MOV A,C", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.A = cpu.C; return nil }},
+	0x7A: {Mnemonic: "MOV A,D", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.A = cpu.D; return nil }},
+	0x7B: {Mnemonic: "MOV A,E", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.A = cpu.E; return nil }},
+	0x7C: {Mnemonic: "MOV A,H", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.A = cpu.H; return nil }},
+	0x7D: {Mnemonic: "MOV A,L", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.A = cpu.L; return nil }},
+	0x7E: {Mnemonic: "MOV A,M", Length: 1, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.A, err = cpu.getM(); return err }},
+	0x7F: {Mnemonic: "MOV A,A", Length: 1, Cycles: 5, execute: noOperation},
+	0x06: {Mnemonic: "MVI B,d8", Length: 2, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.B, err = cpu.fetchByte(); return err }},
+	0x0E: {Mnemonic: "MVI C,d8", Length: 2, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.C, err = cpu.fetchByte(); return err }},
+	0x16: {Mnemonic: "MVI D,d8", Length: 2, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.D, err = cpu.fetchByte(); return err }},
+	0x1E: {Mnemonic: "MVI E,d8", Length: 2, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.E, err = cpu.fetchByte(); return err }},
+	0x26: {Mnemonic: "MVI H,d8", Length: 2, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.H, err = cpu.fetchByte(); return err }},
+	0x2E: {Mnemonic: "MVI L,d8", Length: 2, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.L, err = cpu.fetchByte(); return err }},
+	0x36: {Mnemonic: "MVI M,d8", Length: 2, Cycles: 10, execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		return cpu.setM(fetchedByte)
+	}},
+	0x3E: {Mnemonic: "MVI A,d8", Length: 2, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.A, err = cpu.fetchByte(); return err }},
+	0x01: {Mnemonic: "LXI B,d16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error {
+		fetchedWord, err := cpu.fetchWord()
+		if err != nil {
+			return err
+		}
+		cpu.B, cpu.C = splitWord(fetchedWord)
+		return nil
+	}},
+	0x11: {Mnemonic: "LXI D,d16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error {
+		fetchedWord, err := cpu.fetchWord()
+		if err != nil {
+			return err
+		}
+		cpu.D, cpu.E = splitWord(fetchedWord)
+		return nil
+	}},
+	0x21: {Mnemonic: "LXI H,d16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error {
+		fetchedWord, err := cpu.fetchWord()
+		if err != nil {
+			return err
+		}
+		cpu.H, cpu.L = splitWord(fetchedWord)
+		return nil
+	}},
+	0x02: {Mnemonic: "STAX B", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { return cpu.writeByte(cpu.getBC(), cpu.A) }},
+	0x12: {Mnemonic: "STAX D", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { return cpu.writeByte(cpu.getDE(), cpu.A) }},
+	0x0A: {Mnemonic: "LDAX B", Length: 1, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.A, err = cpu.readByte(cpu.getBC()); return err }},
+	0x1A: {Mnemonic: "LDAX D", Length: 1, Cycles: 7, execute: func(cpu *CPU) (err error) { cpu.A, err = cpu.readByte(cpu.getDE()); return err }},
+	0x32: {Mnemonic: "STA a16", Length: 3, Cycles: 13, execute: func(cpu *CPU) error {
+		address, err := cpu.fetchWord()
+		if err != nil {
+			return err
+		}
+		return cpu.writeByte(address, cpu.A)
+	}},
+	0x3A: {Mnemonic: "LDA a16", Length: 3, Cycles: 13, execute: func(cpu *CPU) error {
+		address, err := cpu.fetchWord()
+		if err != nil {
+			return err
+		}
+		cpu.A, err = cpu.readByte(address)
+		return err
+	}},
+	0x22: {Mnemonic: "SHLD a16", Length: 3, Cycles: 16, execute: func(cpu *CPU) error {
+		address, err := cpu.fetchWord()
+		if err != nil {
+			return err
+		}
+		err = cpu.writeByte(address, cpu.L)
+		if err != nil {
+			return err
+		}
+		return cpu.writeByte(address+1, cpu.H)
+	}},
+	0x2A: {Mnemonic: "LHLD a16", Length: 3, Cycles: 16, execute: func(cpu *CPU) error {
+		address, err := cpu.fetchWord()
+		if err != nil {
+			return err
+		}
+		cpu.L, err = cpu.readByte(address)
+		if err != nil {
+			return err
+		}
+		cpu.H, err = cpu.readByte(address + 1)
+		return err
+	}},
+	0xEB: {Mnemonic: "XCHG", Length: 1, Cycles: 4, execute: func(cpu *CPU) error { cpu.D, cpu.E, cpu.H, cpu.L = cpu.H, cpu.L, cpu.D, cpu.E; return nil }},
+
+	// STACK OPERATIONS
+	0xC5: {Mnemonic: "PUSH B", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.push(cpu.getBC()) }},
+	0xD5: {Mnemonic: "PUSH D", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.push(cpu.getDE()) }},
+	0xE5: {Mnemonic: "PUSH H", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.push(cpu.getHL()) }},
+	0xF5: {Mnemonic: "PUSH PSW", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.push(cpu.getAWithFlags()) }},
+	0xC1: {Mnemonic: "POP B", Length: 1, Cycles: 10, execute: func(cpu *CPU) error {
+		readWord, err := cpu.pop()
+		if err != nil {
+			return err
+		}
+		cpu.B, cpu.C = splitWord(readWord)
+		return nil
+	}},
+	0xD1: {Mnemonic: "POP D", Length: 1, Cycles: 10, execute: func(cpu *CPU) error {
+		readWord, err := cpu.pop()
+		if err != nil {
+			return err
+		}
+		cpu.D, cpu.E = splitWord(readWord)
+		return nil
+	}},
+	0xE1: {Mnemonic: "POP H", Length: 1, Cycles: 10, execute: func(cpu *CPU) error {
+		readWord, err := cpu.pop()
+		if err != nil {
+			return err
+		}
+		cpu.H, cpu.L = splitWord(readWord)
+		return nil
+	}},
+	0xF1: {Mnemonic: "POP PSW", Length: 1, Cycles: 10, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		readWord, err := cpu.pop()
+		if err != nil {
+			return err
+		}
+		var flags byte
+		cpu.A, flags = splitWord(readWord)
+		cpu.setFlags(flags)
+		return nil
+	}},
+	0xE3: {Mnemonic: "XTHL", Length: 1, Cycles: 18, execute: func(cpu *CPU) error {
+		err := cpu.checkPop()
+		if err != nil {
+			return err
+		}
+		cpu.H, err = cpu.readByte(cpu.stackPointer + 1)
+		if err != nil {
+			return err
+		}
+		cpu.L, err = cpu.readByte(cpu.stackPointer)
+		if err != nil {
+			return err
+		}
+		err = cpu.writeByte(cpu.stackPointer+1, cpu.H)
+		if err != nil {
+			return err
+		}
+		return cpu.writeByte(cpu.stackPointer, cpu.L)
+	}},
+	0xF9: {Mnemonic: "SPHL", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.stackPointer = cpu.getHL(); return nil }},
+	0x31: {Mnemonic: "LXI SP,d16", Length: 3, Cycles: 10, execute: func(cpu *CPU) (err error) { cpu.stackPointer, err = cpu.fetchWord(); return err }},
+	0x33: {Mnemonic: "INX SP", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.stackPointer = cpu.inx(cpu.stackPointer); return nil }},
+	0x3B: {Mnemonic: "DCX SP", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.stackPointer = cpu.dcx(cpu.stackPointer); return nil }},
+
+	// JUMP
+	0xC3: {Mnemonic: "JMP a16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error { return cpu.jmp(true) }},
+	0xC2: {Mnemonic: "JNZ a16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error { return cpu.jmp(!cpu.flags.Zero) }},
+	0xCA: {Mnemonic: "JZ a16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error { return cpu.jmp(cpu.flags.Zero) }},
+	0xD2: {Mnemonic: "JNC a16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error { return cpu.jmp(!cpu.flags.Carry) }},
+	0xDA: {Mnemonic: "JC a16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error { return cpu.jmp(cpu.flags.Carry) }},
+	0xE2: {Mnemonic: "JPO a16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error { return cpu.jmp(!cpu.flags.Parity) }},
+	0xEA: {Mnemonic: "JPE a16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error { return cpu.jmp(cpu.flags.Parity) }},
+	0xF2: {Mnemonic: "JP a16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error { return cpu.jmp(!cpu.flags.Sign) }},
+	0xFA: {Mnemonic: "JM a16", Length: 3, Cycles: 10, execute: func(cpu *CPU) error { return cpu.jmp(cpu.flags.Sign) }},
+	0xE9: {Mnemonic: "PCHL", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.programCounter = cpu.getHL(); return nil }},
+
+	// CALL
+	0xCD: {Mnemonic: "CALL a16", Length: 3, Cycles: 17, execute: func(cpu *CPU) error { return cpu.call(true) }},
+	0xC4: {Mnemonic: "CNZ a16", Length: 3, Cycles: 11, CyclesTaken: 17, execute: func(cpu *CPU) error { return cpu.call(!cpu.flags.Zero) }},
+	0xCC: {Mnemonic: "CZ a16", Length: 3, Cycles: 11, CyclesTaken: 17, execute: func(cpu *CPU) error { return cpu.call(cpu.flags.Zero) }},
+	0xD4: {Mnemonic: "CNC a16", Length: 3, Cycles: 11, CyclesTaken: 17, execute: func(cpu *CPU) error { return cpu.call(!cpu.flags.Carry) }},
+	0xDC: {Mnemonic: "CC a16", Length: 3, Cycles: 11, CyclesTaken: 17, execute: func(cpu *CPU) error { return cpu.call(cpu.flags.Carry) }},
+	0xE4: {Mnemonic: "CPO a16", Length: 3, Cycles: 11, CyclesTaken: 17, execute: func(cpu *CPU) error { return cpu.call(!cpu.flags.Parity) }},
+	0xEC: {Mnemonic: "CPE a16", Length: 3, Cycles: 11, CyclesTaken: 17, execute: func(cpu *CPU) error { return cpu.call(cpu.flags.Parity) }},
+	0xF4: {Mnemonic: "CP a16", Length: 3, Cycles: 11, CyclesTaken: 17, execute: func(cpu *CPU) error { return cpu.call(!cpu.flags.Sign) }},
+	0xFC: {Mnemonic: "CM a16", Length: 3, Cycles: 11, CyclesTaken: 17, execute: func(cpu *CPU) error { return cpu.call(cpu.flags.Sign) }},
+
+	// RETURN
+	0xC9: {Mnemonic: "RET", Length: 1, Cycles: 10, execute: func(cpu *CPU) error { return cpu.ret(true) }},
+	0xC0: {Mnemonic: "RNZ", Length: 1, Cycles: 5, CyclesTaken: 11, execute: func(cpu *CPU) error { return cpu.ret(!cpu.flags.Zero) }},
+	0xC8: {Mnemonic: "RZ", Length: 1, Cycles: 5, CyclesTaken: 11, execute: func(cpu *CPU) error { return cpu.ret(cpu.flags.Zero) }},
+	0xD0: {Mnemonic: "RNC", Length: 1, Cycles: 5, CyclesTaken: 11, execute: func(cpu *CPU) error { return cpu.ret(!cpu.flags.Carry) }},
+	0xD8: {Mnemonic: "RC", Length: 1, Cycles: 5, CyclesTaken: 11, execute: func(cpu *CPU) error { return cpu.ret(cpu.flags.Carry) }},
+	0xE0: {Mnemonic: "RPO", Length: 1, Cycles: 5, CyclesTaken: 11, execute: func(cpu *CPU) error { return cpu.ret(!cpu.flags.Parity) }},
+	0xE8: {Mnemonic: "RPE", Length: 1, Cycles: 5, CyclesTaken: 11, execute: func(cpu *CPU) error { return cpu.ret(cpu.flags.Parity) }},
+	0xF0: {Mnemonic: "RP", Length: 1, Cycles: 5, CyclesTaken: 11, execute: func(cpu *CPU) error { return cpu.ret(!cpu.flags.Sign) }},
+	0xF8: {Mnemonic: "RM", Length: 1, Cycles: 5, CyclesTaken: 11, execute: func(cpu *CPU) error { return cpu.ret(cpu.flags.Sign) }},
+
+	// RESTART
+	0xC7: {Mnemonic: "RST 0", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.rst(0x0000) }},
+	0xCF: {Mnemonic: "RST 1", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.rst(0x0008) }},
+	0xD7: {Mnemonic: "RST 2", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.rst(0x0010) }},
+	0xDF: {Mnemonic: "RST 3", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.rst(0x0018) }},
+	0xE7: {Mnemonic: "RST 4", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.rst(0x0020) }},
+	0xEF: {Mnemonic: "RST 5", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.rst(0x0028) }},
+	0xF7: {Mnemonic: "RST 6", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.rst(0x0030) }},
+	0xFF: {Mnemonic: "RST 7", Length: 1, Cycles: 11, execute: func(cpu *CPU) error { return cpu.rst(0x0038) }},
+
+	// INCREMENT AND DECREMENT
+	0x04: {Mnemonic: "INR B", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.inr(&cpu.B); return nil }},
+	0x0C: {Mnemonic: "INR C", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.inr(&cpu.C); return nil }},
+	0x14: {Mnemonic: "INR D", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.inr(&cpu.D); return nil }},
+	0x1C: {Mnemonic: "INR E", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.inr(&cpu.E); return nil }},
+	0x24: {Mnemonic: "INR H", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.inr(&cpu.H); return nil }},
+	0x2C: {Mnemonic: "INR L", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.inr(&cpu.L); return nil }},
+	0x34: {Mnemonic: "INR M", Length: 1, Cycles: 10, Flags: "SZAP", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.inr(&readByte)
+		return cpu.setM(readByte)
+	}},
+	0x3C: {Mnemonic: "INR A", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.inr(&cpu.A); return nil }},
+	0x05: {Mnemonic: "DCR B", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.dcr(&cpu.B); return nil }},
+	0x0D: {Mnemonic: "DCR C", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.dcr(&cpu.C); return nil }},
+	0x15: {Mnemonic: "DCR D", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.dcr(&cpu.D); return nil }},
+	0x1D: {Mnemonic: "DCR E", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.dcr(&cpu.E); return nil }},
+	0x25: {Mnemonic: "DCR H", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.dcr(&cpu.H); return nil }},
+	0x2D: {Mnemonic: "DCR L", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.dcr(&cpu.L); return nil }},
+	0x35: {Mnemonic: "DCR M", Length: 1, Cycles: 10, Flags: "SZAP", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.dcr(&readByte)
+		return cpu.setM(readByte)
+	}},
+	0x3D: {Mnemonic: "DCR A", Length: 1, Cycles: 5, Flags: "SZAP", execute: func(cpu *CPU) error { cpu.dcr(&cpu.A); return nil }},
+	0x03: {Mnemonic: "INX B", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.B, cpu.C = splitWord(cpu.inx(cpu.getBC())); return nil }},
+	0x13: {Mnemonic: "INX D", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.D, cpu.E = splitWord(cpu.inx(cpu.getDE())); return nil }},
+	0x23: {Mnemonic: "INX H", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.H, cpu.L = splitWord(cpu.inx(cpu.getHL())); return nil }},
+	0x0B: {Mnemonic: "DCX B", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.B, cpu.C = splitWord(cpu.dcx(cpu.getBC())); return nil }},
+	0x1B: {Mnemonic: "DCX D", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.D, cpu.E = splitWord(cpu.dcx(cpu.getDE())); return nil }},
+	0x2B: {Mnemonic: "DCX H", Length: 1, Cycles: 5, execute: func(cpu *CPU) error { cpu.H, cpu.L = splitWord(cpu.dcx(cpu.getHL())); return nil }},
+
+	// ADD
+	0x80: {Mnemonic: "ADD B", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.B, NoCarry); return nil }},
+	0x81: {Mnemonic: "ADD C", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.C, NoCarry); return nil }},
+	0x82: {Mnemonic: "ADD D", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.D, NoCarry); return nil }},
+	0x83: {Mnemonic: "ADD E", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.E, NoCarry); return nil }},
+	0x84: {Mnemonic: "ADD H", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.H, NoCarry); return nil }},
+	0x85: {Mnemonic: "ADD L", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.L, NoCarry); return nil }},
+	0x86: {Mnemonic: "ADD M", Length: 1, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.add(readByte, NoCarry)
+		return nil
+	}},
+	0x87: {Mnemonic: "ADD A", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.A, NoCarry); return nil }},
+	0x88: {Mnemonic: "ADC B", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.B, WithCarry); return nil }},
+	0x89: {Mnemonic: "ADC C", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.C, WithCarry); return nil }},
+	0x8A: {Mnemonic: "ADC D", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.D, WithCarry); return nil }},
+	0x8B: {Mnemonic: "ADC E", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.E, WithCarry); return nil }},
+	0x8C: {Mnemonic: "ADC H", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.H, WithCarry); return nil }},
+	0x8D: {Mnemonic: "ADC L", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.L, WithCarry); return nil }},
+	0x8E: {Mnemonic: "ADC M", Length: 1, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.add(readByte, WithCarry)
+		return nil
+	}},
+	0x8F: {Mnemonic: "ADC A", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.add(cpu.A, WithCarry); return nil }},
+	0xC6: {Mnemonic: "ADI d8", Length: 2, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.add(fetchedByte, NoCarry)
+		return nil
+	}},
+	0xCE: {Mnemonic: "ACI d8", Length: 2, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.add(fetchedByte, WithCarry)
+		return nil
+	}},
+	0x09: {Mnemonic: "DAD B", Length: 1, Cycles: 10, Flags: "C", execute: func(cpu *CPU) error { cpu.dad(cpu.getBC()); return nil }},
+	0x19: {Mnemonic: "DAD D", Length: 1, Cycles: 10, Flags: "C", execute: func(cpu *CPU) error { cpu.dad(cpu.getDE()); return nil }},
+	0x29: {Mnemonic: "DAD H", Length: 1, Cycles: 10, Flags: "C", execute: func(cpu *CPU) error { cpu.dad(cpu.getHL()); return nil }},
+	0x39: {Mnemonic: "DAD SP", Length: 1, Cycles: 10, Flags: "C", execute: func(cpu *CPU) error { cpu.dad(cpu.stackPointer); return nil }},
+
+	// SUBTRACT
+	0x90: {Mnemonic: "SUB B", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.B, NoCarry); return nil }},
+	0x91: {Mnemonic: "SUB C", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.C, NoCarry); return nil }},
+	0x92: {Mnemonic: "SUB D", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.D, NoCarry); return nil }},
+	0x93: {Mnemonic: "SUB E", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.E, NoCarry); return nil }},
+	0x94: {Mnemonic: "SUB H", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.H, NoCarry); return nil }},
+	0x95: {Mnemonic: "SUB L", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.L, NoCarry); return nil }},
+	0x96: {Mnemonic: "SUB M", Length: 1, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.sub(readByte, NoCarry)
+		return nil
+	}},
+	0x97: {Mnemonic: "SUB A", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.A, NoCarry); return nil }},
+	0x98: {Mnemonic: "SBB B", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.B, WithCarry); return nil }},
+	0x99: {Mnemonic: "SBB C", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.C, WithCarry); return nil }},
+	0x9A: {Mnemonic: "SBB D", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.D, WithCarry); return nil }},
+	0x9B: {Mnemonic: "SBB E", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.E, WithCarry); return nil }},
+	0x9C: {Mnemonic: "SBB H", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.H, WithCarry); return nil }},
+	0x9D: {Mnemonic: "SBB L", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.L, WithCarry); return nil }},
+	0x9E: {Mnemonic: "SBB M", Length: 1, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.sub(readByte, WithCarry)
+		return nil
+	}},
+	0x9F: {Mnemonic: "SBB A", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.sub(cpu.A, WithCarry); return nil }},
+	0xD6: {Mnemonic: "SUI d8", Length: 2, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.sub(fetchedByte, NoCarry)
+		return nil
+	}},
+	0xDE: {Mnemonic: "SBI d8", Length: 2, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.sub(fetchedByte, WithCarry)
+		return nil
+	}},
+
+	// LOGICAL
+	0xA0: {Mnemonic: "ANA B", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ana(cpu.B); return nil }},
+	0xA1: {Mnemonic: "ANA C", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ana(cpu.C); return nil }},
+	0xA2: {Mnemonic: "ANA D", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ana(cpu.D); return nil }},
+	0xA3: {Mnemonic: "ANA E", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ana(cpu.E); return nil }},
+	0xA4: {Mnemonic: "ANA H", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ana(cpu.H); return nil }},
+	0xA5: {Mnemonic: "ANA L", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ana(cpu.L); return nil }},
+	0xA6: {Mnemonic: "ANA M", Length: 1, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.ana(readByte)
+		return nil
+	}},
+	0xA7: {Mnemonic: "ANA A", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ana(cpu.A); return nil }},
+	0xA8: {Mnemonic: "XRA B", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.xra(cpu.B); return nil }},
+	0xA9: {Mnemonic: "XRA C", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.xra(cpu.C); return nil }},
+	0xAA: {Mnemonic: "XRA D", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.xra(cpu.D); return nil }},
+	0xAB: {Mnemonic: "XRA E", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.xra(cpu.E); return nil }},
+	0xAC: {Mnemonic: "XRA H", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.xra(cpu.H); return nil }},
+	0xAD: {Mnemonic: "XRA L", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.xra(cpu.L); return nil }},
+	0xAE: {Mnemonic: "XRA M", Length: 1, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.xra(readByte)
+		return nil
+	}},
+	0xAF: {Mnemonic: "XRA A", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.xra(cpu.A); return nil }},
+	0xB0: {Mnemonic: "ORA B", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ora(cpu.B); return nil }},
+	0xB1: {Mnemonic: "ORA C", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ora(cpu.C); return nil }},
+	0xB2: {Mnemonic: "ORA D", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ora(cpu.D); return nil }},
+	0xB3: {Mnemonic: "ORA E", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ora(cpu.E); return nil }},
+	0xB4: {Mnemonic: "ORA H", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ora(cpu.H); return nil }},
+	0xB5: {Mnemonic: "ORA L", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ora(cpu.L); return nil }},
+	0xB6: {Mnemonic: "ORA M", Length: 1, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.ora(readByte)
+		return nil
+	}},
+	0xB7: {Mnemonic: "ORA A", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.ora(cpu.A); return nil }},
+	0xB8: {Mnemonic: "CMP B", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.cmp(cpu.B); return nil }},
+	0xB9: {Mnemonic: "CMP C", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.cmp(cpu.C); return nil }},
+	0xBA: {Mnemonic: "CMP D", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.cmp(cpu.D); return nil }},
+	0xBB: {Mnemonic: "CMP E", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.cmp(cpu.E); return nil }},
+	0xBC: {Mnemonic: "CMP H", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.cmp(cpu.H); return nil }},
+	0xBD: {Mnemonic: "CMP L", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.cmp(cpu.L); return nil }},
+	0xBE: {Mnemonic: "CMP M", Length: 1, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		readByte, err := cpu.getM()
+		if err != nil {
+			return err
+		}
+		cpu.cmp(readByte)
+		return nil
+	}},
+	0xBF: {Mnemonic: "CMP A", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.cmp(cpu.A); return nil }},
+	0xE6: {Mnemonic: "ANI d8", Length: 2, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.ana(fetchedByte)
+		return nil
+	}},
+	0xEE: {Mnemonic: "XRI d8", Length: 2, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.xra(fetchedByte)
+		return nil
+	}},
+	0xF6: {Mnemonic: "ORI d8", Length: 2, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.ora(fetchedByte)
+		return nil
+	}},
+	0xFE: {Mnemonic: "CPI d8", Length: 2, Cycles: 7, Flags: "SZAPC", execute: func(cpu *CPU) error {
+		fetchedByte, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.cmp(fetchedByte)
+		return nil
+	}},
+
+	// ROTATE
+	0x07: {Mnemonic: "RLC", Length: 1, Cycles: 4, Flags: "C", execute: func(cpu *CPU) error {
+		msb := cpu.A >> 7 // Isolate the MSB (bit 7)
+		cpu.A <<= 1       // Shift everything one bit to the left
+		cpu.A |= msb      // Replace the LSB (bit 0) with the MSB (bit 7)
+		cpu.flags.Carry = (msb == 1)
+		return nil
+	}},
+	0x0F: {Mnemonic: "RRC", Length: 1, Cycles: 4, Flags: "C", execute: func(cpu *CPU) error {
+		lsb := cpu.A & 1  // Isolate the LSB (bit 0)
+		cpu.A >>= 1       // Shift everything one bit to the right
+		cpu.A |= lsb << 7 // Replace the MSB (bit 7) with the LSB (bit 0)
+		cpu.flags.Carry = (lsb == 1)
+		return nil
+	}},
+	0x17: {Mnemonic: "RAL", Length: 1, Cycles: 4, Flags: "C", execute: func(cpu *CPU) error {
+		msb := cpu.A >> 7 // Isolate the MSB (bit 7)
+		cpu.A <<= 1       // Shift everything one bit to the left
+		if cpu.flags.Carry {
+			cpu.A |= 0b0000_0001 // Replace the LSB (bit 0) with the carry flag
+		}
+		cpu.flags.Carry = (msb == 1)
+		return nil
+	}},
+	0x1F: {Mnemonic: "RAR", Length: 1, Cycles: 4, Flags: "C", execute: func(cpu *CPU) error {
+		lsb := cpu.A & 1 // Isolate the LSB (bit 0)
+		cpu.A >>= 1      // Shift everything one bit to the right
+		if cpu.flags.Carry {
+			cpu.A |= 0b1000_0000 // Replace the MSB (bit 7) with the carry flag
+		}
+		cpu.flags.Carry = (lsb == 1)
+		return nil
+	}},
+
+	// SPECIALS
+	0x2F: {Mnemonic: "CMA", Length: 1, Cycles: 4, execute: func(cpu *CPU) error { cpu.A = ^cpu.A; return nil }},
+	0x37: {Mnemonic: "STC", Length: 1, Cycles: 4, Flags: "C", execute: func(cpu *CPU) error { cpu.flags.Carry = true; return nil }},
+	0x3F: {Mnemonic: "CMC", Length: 1, Cycles: 4, Flags: "C", execute: func(cpu *CPU) error { cpu.flags.Carry = !cpu.flags.Carry; return nil }},
+	0x27: {Mnemonic: "DAA", Length: 1, Cycles: 4, Flags: "SZAPC", execute: func(cpu *CPU) error { cpu.daa(); return nil }},
+
+	// INPUT/OUTPUT
+	0xDB: {Mnemonic: "IN d8", Length: 2, Cycles: 10, execute: func(cpu *CPU) error {
+		port, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.in(port)
+		return nil
+	}},
+	0xD3: {Mnemonic: "OUT d8", Length: 2, Cycles: 10, execute: func(cpu *CPU) error {
+		port, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.out(port)
+		return nil
+	}},
+
+	// CONTROL
+	0xFB: {Mnemonic: "EI", Length: 1, Cycles: 4, execute: func(cpu *CPU) error { cpu.interruptEnabled = true; return nil }},
+	0xF3: {Mnemonic: "DI", Length: 1, Cycles: 4, execute: func(cpu *CPU) error { cpu.interruptEnabled = false; return nil }},
+	0x00: {Mnemonic: "NOP", Length: 1, Cycles: 4, execute: noOperation},
+	0x76: {Mnemonic: "HLT", Length: 1, Cycles: 7, execute: func(cpu *CPU) error { cpu.halted = true; return nil }},
+
+	// UNDOCUMENTED
+	//
+	// The twelve opcodes left unassigned by the Intel 8080 datasheet.  Real 8080
+	// silicon doesn't fully decode these, so rather than faulting they behave as
+	// aliases of a documented instruction.  StrictOpcodes traps them instead.
+	0x08: {Mnemonic: "*NOP", Length: 1, Cycles: 4, Undocumented: true, execute: noOperation},
+	0x10: {Mnemonic: "*NOP", Length: 1, Cycles: 4, Undocumented: true, execute: noOperation},
+	0x18: {Mnemonic: "*NOP", Length: 1, Cycles: 4, Undocumented: true, execute: noOperation},
+	0x20: {Mnemonic: "*NOP", Length: 1, Cycles: 4, Undocumented: true, execute: noOperation},
+	0x28: {Mnemonic: "*NOP", Length: 1, Cycles: 4, Undocumented: true, execute: noOperation},
+	0x30: {Mnemonic: "*NOP", Length: 1, Cycles: 4, Undocumented: true, execute: noOperation},
+	0x38: {Mnemonic: "*NOP", Length: 1, Cycles: 4, Undocumented: true, execute: noOperation},
+	0xCB: {Mnemonic: "*JMP a16", Length: 3, Cycles: 10, Undocumented: true, execute: func(cpu *CPU) error { return cpu.jmp(true) }},
+	0xD9: {Mnemonic: "*RET", Length: 1, Cycles: 10, Undocumented: true, execute: func(cpu *CPU) error { return cpu.ret(true) }},
+	0xDD: {Mnemonic: "*CALL a16", Length: 3, Cycles: 17, Undocumented: true, execute: func(cpu *CPU) error { return cpu.call(true) }},
+	0xED: {Mnemonic: "*CALL a16", Length: 3, Cycles: 17, Undocumented: true, execute: func(cpu *CPU) error { return cpu.call(true) }},
+	0xFD: {Mnemonic: "*CALL a16", Length: 3, Cycles: 17, Undocumented: true, execute: func(cpu *CPU) error { return cpu.call(true) }},
+}
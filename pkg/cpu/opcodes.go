@@ -22,19 +22,26 @@ const (
 //	// Memory location 0xFFFE (stackPointer - 1) = 0x12
 //	// Memory location 0xFFFD (stackPointer - 2) = 0x34
 func (cpu *CPU) push(value types.Word) error {
-	high, low := splitWord(value)
+	err := cpu.checkPush()
+	if err != nil {
+		return err
+	}
 
-	err := cpu.Bus.WriteByteAt(cpu.stackPointer-1, high)
+	high, low := splitWord(value)
+	err = cpu.writeByte(cpu.stackPointer-1, high)
 	if err != nil {
-		return fmt.Errorf("could not write 0x%02X to cpu.stackPointer - 1 (0x%04X): %v", high, cpu.stackPointer-1, err)
+		return &StackOverflowError{StackPointer: cpu.stackPointer, Err: err}
 	}
 
-	err = cpu.Bus.WriteByteAt(cpu.stackPointer-2, low)
+	err = cpu.writeByte(cpu.stackPointer-2, low)
 	if err != nil {
-		return fmt.Errorf("could not write 0x%02X to cpu.stackPointer - 2 (0x%04X): %v", low, cpu.stackPointer-2, err)
+		return &StackOverflowError{StackPointer: cpu.stackPointer, Err: err}
 	}
 
 	cpu.stackPointer -= 2
+	if cpu.StackGuard != nil {
+		cpu.StackGuard.pushed(cpu.StackGuard.position(cpu.stackPointer))
+	}
 	return nil
 }
 
@@ -49,17 +56,25 @@ func (cpu *CPU) push(value types.Word) error {
 // poppedValue, _ := cpu.pop()
 // // poppedValue = 0x1234
 func (cpu *CPU) pop() (types.Word, error) {
-	low, err := cpu.Bus.ReadByteAt(cpu.stackPointer)
+	err := cpu.checkPop()
 	if err != nil {
-		return 0, fmt.Errorf("could not read byte from cpu.stackPointer (0x%04X): %v", cpu.stackPointer, err)
+		return 0, err
 	}
 
-	high, err := cpu.Bus.ReadByteAt(cpu.stackPointer + 1)
+	low, err := cpu.readByte(cpu.stackPointer)
 	if err != nil {
-		return 0, fmt.Errorf("could not read byte from cpu.stackPointer + 1 (0x%04X): %v", cpu.stackPointer+1, err)
+		return 0, &StackUnderflowError{StackPointer: cpu.stackPointer, Err: err}
+	}
+
+	high, err := cpu.readByte(cpu.stackPointer + 1)
+	if err != nil {
+		return 0, &StackUnderflowError{StackPointer: cpu.stackPointer, Err: err}
 	}
 
 	cpu.stackPointer += 2
+	if cpu.StackGuard != nil {
+		cpu.StackGuard.popped(cpu.StackGuard.position(cpu.stackPointer))
+	}
 	return joinBytes(high, low), nil
 }
 
@@ -84,6 +99,7 @@ func (cpu *CPU) inr(register *byte) {
 	cpu.flags.AuxCarry = (*register & 0x0F) == 0x0F
 	*register++
 	cpu.setSignZeroParityFlags(*register)
+	cpu.setOverflowFlags(*register == 0x80)
 }
 
 // dcr decrements the value of a given register by 1, updating the CPU flags accordingly.
@@ -107,6 +123,7 @@ func (cpu *CPU) dcr(register *byte) {
 	cpu.flags.AuxCarry = (*register & 0x0F) == 0x0F
 	*register--
 	cpu.setSignZeroParityFlags(*register)
+	cpu.setOverflowFlags(*register == 0x7F)
 }
 
 // add adds the value of a register and an optional carry-in to the accumulator,
@@ -146,6 +163,9 @@ func (cpu *CPU) add(register byte, carry byte) {
 	// taken note of whether there was a carry-in to bit eight above.
 	cpu.setSignZeroParityFlags(byte(result))
 
+	// Signed overflow occurs when both operands share a sign that the result doesn't.
+	cpu.setOverflowFlags((cpu.A^byte(result))&(register^byte(result))&0b1000_0000 != 0)
+
 	// Return the eight least significant bits (LSB) only
 	cpu.A = byte(result)
 }
@@ -198,6 +218,10 @@ func (cpu *CPU) sub(register byte, borrow byte) {
 	// taken note of whether there was a carry-in to bit eight above.
 	cpu.setSignZeroParityFlags(byte(result))
 
+	// Signed overflow occurs when the operands differ in sign and the result takes
+	// the sign of the subtrahend.
+	cpu.setOverflowFlags((cpu.A^register)&(cpu.A^byte(result))&0b1000_0000 != 0)
+
 	// Return the eight least significant bits (LSB) only
 	cpu.A = byte(result)
 }
@@ -209,6 +233,7 @@ func (cpu *CPU) sub(register byte, borrow byte) {
 func (cpu *CPU) ana(register byte) {
 	cpu.A = cpu.A & register
 	cpu.setSignZeroParityFlags(cpu.A)
+	cpu.setOverflowFlags(false)
 	cpu.flags.AuxCarry = false
 	cpu.flags.Carry = false
 }
@@ -220,6 +245,7 @@ func (cpu *CPU) ana(register byte) {
 func (cpu *CPU) xra(register byte) {
 	cpu.A = cpu.A ^ register
 	cpu.setSignZeroParityFlags(cpu.A)
+	cpu.setOverflowFlags(false)
 	cpu.flags.AuxCarry = false
 	cpu.flags.Carry = false
 }
@@ -231,6 +257,7 @@ func (cpu *CPU) xra(register byte) {
 func (cpu *CPU) ora(register byte) {
 	cpu.A = cpu.A | register
 	cpu.setSignZeroParityFlags(cpu.A)
+	cpu.setOverflowFlags(false)
 	cpu.flags.AuxCarry = false
 	cpu.flags.Carry = false
 }
@@ -288,11 +315,12 @@ func (cpu *CPU) cmp(register byte) {
 func (cpu *CPU) jmp(condition bool) error {
 	address, err := cpu.fetchWord()
 	if err != nil {
-		return fmt.Errorf("could not jmp() to address 0x%04X: %v", address, err)
+		return fmt.Errorf("could not jmp() to address 0x%04X: %w", address, err)
 	}
 
 	if condition {
 		cpu.programCounter = address
+		cpu.branchTaken = true
 	}
 
 	return nil
@@ -313,9 +341,11 @@ func (cpu *CPU) call(condition bool) error {
 	if condition {
 		err = cpu.push(cpu.programCounter)
 		if err != nil {
-			return fmt.Errorf("could not call() to address 0x%04X: %v", address, err)
+			return fmt.Errorf("could not call() to address 0x%04X: %w", address, err)
 		}
+		cpu.called(address)
 		cpu.programCounter = address
+		cpu.branchTaken = true
 	}
 
 	return nil
@@ -327,17 +357,20 @@ func (cpu *CPU) call(condition bool) error {
 //
 // Parameters:
 //   - condition (bool): determines whether to return to the address specified in the last two bytes
-//     popped off the stack.
+//     on the stack.  The stack is left alone when it's false.
 func (cpu *CPU) ret(condition bool) error {
-	address, err := cpu.pop()
-	if err != nil {
-		return fmt.Errorf("could not ret() from address 0x%04X: %v", address, err)
+	if !condition {
+		return nil
 	}
 
-	if condition {
-		cpu.programCounter = address
+	address, err := cpu.pop()
+	if err != nil {
+		return fmt.Errorf("could not ret() from address 0x%04X: %w", address, err)
 	}
 
+	cpu.returned(address)
+	cpu.programCounter = address
+	cpu.branchTaken = true
 	return nil
 }
 
@@ -348,21 +381,45 @@ func (cpu *CPU) ret(condition bool) error {
 func (cpu *CPU) rst(address types.Word) error {
 	err := cpu.push(cpu.programCounter)
 	if err != nil {
-		return fmt.Errorf("could not push() cpu.programCounter: 0x%04X: %v", cpu.programCounter, err)
+		return fmt.Errorf("could not push() cpu.programCounter: 0x%04X: %w", cpu.programCounter, err)
 	}
 
+	cpu.called(address)
 	cpu.programCounter = address
 	return nil
 }
 
 // in reads an 8-bit value from the port specified in the port parameter
 func (cpu *CPU) in(port byte) {
-	cpu.A = cpu.ports[port]
+	cpu.A = cpu.readPort(port)
 }
 
 // out writes the value of the accumulator to the port specified in the port parameter
 func (cpu *CPU) out(port byte) {
-	cpu.ports[port] = cpu.A
+	cpu.writePort(port, cpu.A)
+}
+
+// readPort reads from the device attached to the port specified in the port
+// parameter, or otherwise returns the value last written to it
+func (cpu CPU) readPort(port byte) byte {
+	if device, ok := cpu.devices[port]; ok {
+		return device.ReadPort(port)
+	}
+
+	return cpu.ports[port]
+}
+
+// writePort writes value to the device attached to the port specified in the
+// port parameter, or otherwise latches it
+func (cpu *CPU) writePort(port byte, value byte) {
+	if device, ok := cpu.devices[port]; ok {
+		device.WritePort(port, value)
+		return
+	}
+	if cpu.ports == nil {
+		cpu.ports = make(map[byte]byte, 256)
+	}
+	cpu.ports[port] = value
 }
 
 // joinBytes combines two bytes into a 16-bit word.
@@ -431,6 +488,41 @@ func (cpu *CPU) setSignZeroParityFlags(input byte) {
 	cpu.flags.Parity = bits.OnesCount8(input)%2 == 0 // Check if parity is even
 }
 
+// setOverflowFlags sets the undocumented 8085 V and K flags after an arithmetic or
+// logical instruction.  V records a two's complement overflow, and K is set when the
+// signed result is negative (the sign flag XOR the overflow flag), which is what
+// the JK and JNK instructions test after a comparison.  It's a no-op on the 8080.
+func (cpu *CPU) setOverflowFlags(overflow bool) {
+	if !cpu.undocumented8085() {
+		return
+	}
+
+	cpu.flags.Overflow = overflow
+	cpu.flags.UnderflowIndicator = cpu.flags.Sign != overflow
+}
+
+// inx returns value incremented by one.  On the 8085 the K flag is also set
+// when the increment overflows from 0xFFFF to 0x0000.
+func (cpu *CPU) inx(value types.Word) types.Word {
+	value++
+	if cpu.undocumented8085() {
+		cpu.flags.UnderflowIndicator = value == 0x0000
+	}
+
+	return value
+}
+
+// dcx returns value decremented by one.  On the 8085 the K flag is also set
+// when the decrement underflows from 0x0000 to 0xFFFF.
+func (cpu *CPU) dcx(value types.Word) types.Word {
+	value--
+	if cpu.undocumented8085() {
+		cpu.flags.UnderflowIndicator = value == 0xFFFF
+	}
+
+	return value
+}
+
 // getBC returns a two byte word by joining the B and C registers
 func (cpu CPU) getBC() types.Word {
 	return joinBytes(cpu.B, cpu.C)
@@ -452,8 +544,8 @@ func (cpu CPU) getAWithFlags() types.Word {
 }
 
 // getM returns a byte stored in memory, pointed to by the H and L registers
-func (cpu CPU) getM() (byte, error) {
-	readByte, err := cpu.Bus.ReadByteAt(cpu.getHL())
+func (cpu *CPU) getM() (byte, error) {
+	readByte, err := cpu.readByte(cpu.getHL())
 	if err != nil {
 		return 0, err
 	}
@@ -463,10 +555,19 @@ func (cpu CPU) getM() (byte, error) {
 
 // setM stores a byte stored in memory, pointed to by the H and L registers
 func (cpu *CPU) setM(value byte) error {
-	err := cpu.Bus.WriteByteAt(cpu.getHL(), value)
+	err := cpu.writeByte(cpu.getHL(), value)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// boolToByte returns 1 if in is true, otherwise 0
+func boolToByte(in bool) byte {
+	if in {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,152 @@
+package cpu
+
+import "github.com/lukepeterson/go8080cpu/pkg/types"
+
+// StackGuard declares the region of memory the stack may use, so pushes and
+// pops outside it fault with a StackOverflowError or StackUnderflowError
+// instead of silently overwriting the program.  It also measures how much of
+// the region a run uses, and the deepest chain of calls it makes.
+//
+// Example:
+//
+//	guard := cpu.NewStackGuard(0xFF00, 0x0000) // The top 256 bytes of memory
+//	processor.StackGuard = guard
+//	err := processor.Run()
+//	fmt.Println(guard.HighWater(), guard.DeepestChain())
+type StackGuard struct {
+	bottom int // The lowest address the stack may use
+	top    int // The address above the stack, where the stack pointer starts
+
+	highWater    int
+	frames       []frame
+	deepestChain []types.Word
+}
+
+// frame is a call on the stack: the address called, and the stack pointer
+// after its return address was pushed.
+type frame struct {
+	address      types.Word
+	stackPointer int
+}
+
+// NewStackGuard returns a guard for a stack using the memory from bottom up to,
+// but not including, top.  A top of 0 is the top of memory, where a stack
+// pointer of 0 starts the stack.
+func NewStackGuard(bottom, top types.Word) *StackGuard {
+	guard := &StackGuard{bottom: int(bottom), top: int(top)}
+	if top == 0 {
+		guard.top = 0x10000
+	}
+
+	return guard
+}
+
+// HighWater returns the most bytes of the stack used at once.
+func (guard *StackGuard) HighWater() int {
+	return guard.highWater
+}
+
+// Depth returns the number of calls on the stack.
+func (guard *StackGuard) Depth() int {
+	return len(guard.frames)
+}
+
+// DeepestChain returns the addresses called in the deepest chain of calls,
+// outermost first.  Restarts, including interrupts, count as calls.
+func (guard *StackGuard) DeepestChain() []types.Word {
+	return append([]types.Word(nil), guard.deepestChain...)
+}
+
+// position returns the stack pointer as an address in the guarded region,
+// where a stack pointer of 0 is the top of memory if the region reaches it.
+func (guard *StackGuard) position(stackPointer types.Word) int {
+	if stackPointer == 0 && guard.top == 0x10000 {
+		return 0x10000
+	}
+
+	return int(stackPointer)
+}
+
+// contains returns whether the word at position is in the guarded region.
+func (guard *StackGuard) contains(position int) bool {
+	return position >= guard.bottom && position+2 <= guard.top
+}
+
+// pushed records a push leaving the stack pointer at position, dropping calls
+// above it that were abandoned, such as by loading the stack pointer.
+func (guard *StackGuard) pushed(position int) {
+	for len(guard.frames) > 0 && guard.frames[len(guard.frames)-1].stackPointer <= position {
+		guard.frames = guard.frames[:len(guard.frames)-1]
+	}
+	guard.highWater = max(guard.highWater, guard.top-position)
+}
+
+// popped records a pop leaving the stack pointer at position, dropping the
+// calls whose return addresses it popped.
+func (guard *StackGuard) popped(position int) {
+	for len(guard.frames) > 0 && guard.frames[len(guard.frames)-1].stackPointer < position {
+		guard.frames = guard.frames[:len(guard.frames)-1]
+	}
+}
+
+// called records a call to address, whose return address was pushed leaving
+// the stack pointer at position.
+func (guard *StackGuard) called(address types.Word, position int) {
+	guard.frames = append(guard.frames, frame{address: address, stackPointer: position})
+	if len(guard.frames) > len(guard.deepestChain) {
+		guard.deepestChain = guard.deepestChain[:0]
+		for _, frame := range guard.frames {
+			guard.deepestChain = append(guard.deepestChain, frame.address)
+		}
+	}
+}
+
+// checkPush returns a StackOverflowError if pushing would leave the guarded
+// region, unless the FaultPolicy ignores it.
+func (cpu *CPU) checkPush() error {
+	guard := cpu.StackGuard
+	if guard == nil || guard.contains(guard.position(cpu.stackPointer)-2) {
+		return nil
+	}
+
+	return cpu.stackFault(&StackOverflowError{StackPointer: cpu.stackPointer})
+}
+
+// checkPop returns a StackUnderflowError if the word at the top of the stack
+// is outside the guarded region, unless the FaultPolicy ignores it.
+func (cpu *CPU) checkPop() error {
+	guard := cpu.StackGuard
+	if guard == nil || guard.contains(guard.position(cpu.stackPointer)) {
+		return nil
+	}
+
+	return cpu.stackFault(&StackUnderflowError{StackPointer: cpu.stackPointer})
+}
+
+// called records a call to address with the StackGuard and CallStack, after
+// its return address was pushed and before the program counter is set.
+func (cpu *CPU) called(address types.Word) {
+	if cpu.StackGuard != nil {
+		cpu.StackGuard.called(address, cpu.StackGuard.position(cpu.stackPointer))
+	}
+	if cpu.CallStack != nil {
+		cpu.CallStack.called(Call{
+			From:         cpu.instructionStart,
+			To:           address,
+			Return:       cpu.programCounter,
+			StackPointer: cpu.stackPointer,
+			Interrupt:    cpu.interrupting,
+		})
+	}
+}
+
+// stackFault returns a stack error, or records it and returns nil if the
+// FaultPolicy ignores stack errors.
+func (cpu *CPU) stackFault(err error) error {
+	if cpu.FaultPolicy.Stack == FaultIgnore {
+		cpu.lastFault = err
+		return nil
+	}
+
+	return err
+}
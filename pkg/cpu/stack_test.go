@@ -0,0 +1,221 @@
+package cpu
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestStackGuard(t *testing.T) {
+	tests := []struct {
+		name          string
+		program       []byte
+		policy        FaultPolicy
+		wantErr       error
+		wantSP        types.Word
+		wantHighWater int
+	}{
+		{
+			name:          "overflow",
+			program:       []byte{0x31, 0x00, 0x01, 0xC5, 0xC3, 0x03, 0x00}, // LXI SP,0x0100; PUSH B; JMP 0x0003
+			wantErr:       &StackOverflowError{},
+			wantSP:        0x00F8,
+			wantHighWater: 8,
+		},
+		{
+			name:    "underflow",
+			program: []byte{0x31, 0x00, 0x01, 0xC1, 0x76}, // LXI SP,0x0100; POP B; HLT
+			wantErr: &StackUnderflowError{},
+			wantSP:  0x0100,
+		},
+		{
+			name:    "exchange at the top",
+			program: []byte{0x31, 0x00, 0x01, 0xE3, 0x76}, // LXI SP,0x0100; XTHL; HLT
+			wantErr: &StackUnderflowError{},
+			wantSP:  0x0100,
+		},
+		{
+			name:    "stack pointer below the region",
+			program: []byte{0x31, 0x00, 0x00, 0xC5, 0x76}, // LXI SP,0x0000; PUSH B; HLT
+			wantErr: &StackOverflowError{},
+			wantSP:  0x0000,
+		},
+		{
+			name:          "ignored",
+			program:       []byte{0x31, 0x00, 0x01, 0xC5, 0xC5, 0xC5, 0xC5, 0xC5, 0x76}, // LXI SP,0x0100; PUSH B x 5; HLT
+			policy:        FaultPolicy{Stack: FaultIgnore},
+			wantSP:        0x00F6,
+			wantHighWater: 10,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := New()
+			cpu.Load(test.program)
+			cpu.StackGuard = NewStackGuard(0x00F8, 0x0100)
+			cpu.FaultPolicy = test.policy
+			cpu.B = 0x12
+
+			err := cpu.Run()
+			switch want := test.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("did not expect an error, but got: %v", err)
+				}
+				if cpu.LastFault() == nil {
+					t.Errorf("LastFault() = nil, want the ignored overflow")
+				}
+			case *StackOverflowError:
+				if !errors.As(err, &want) || want.Err != nil {
+					t.Fatalf("expected a StackOverflowError from the guard, but got: %v", err)
+				}
+			case *StackUnderflowError:
+				if !errors.As(err, &want) || want.Err != nil {
+					t.Fatalf("expected a StackUnderflowError from the guard, but got: %v", err)
+				}
+			}
+
+			if cpu.StackPointer() != test.wantSP {
+				t.Errorf("stackPointer = 0x%04X, want 0x%04X", cpu.StackPointer(), test.wantSP)
+			}
+			if cpu.StackGuard.HighWater() != test.wantHighWater {
+				t.Errorf("HighWater() = %d, want %d", cpu.StackGuard.HighWater(), test.wantHighWater)
+			}
+		})
+	}
+}
+
+func TestStackGuardProtectsMemory(t *testing.T) {
+	cpu := New()
+	cpu.Load([]byte{0x31, 0x02, 0x01, 0x01, 0xFF, 0xFF, 0xC5}) // LXI SP,0x0102; LXI B,0xFFFF; PUSH B
+	cpu.StackGuard = NewStackGuard(0x0100, 0x0102)
+	cpu.Run()
+	cpu.SetStackPointer(0x0100)
+	cpu.Bus.WriteByteAt(0x00FF, 0xAA)
+
+	err := cpu.Step()
+	if err == nil {
+		t.Fatalf("expected an error, but got none")
+	}
+	if value, _ := cpu.Bus.ReadByteAt(0x00FF); value != 0xAA {
+		t.Errorf("memory below the stack = 0x%02X, want it unchanged at 0xAA", value)
+	}
+}
+
+func TestStackGuardTopOfMemory(t *testing.T) {
+	cpu := New()
+	cpu.Bus = &memory.Memory{Data: make([]byte, 0x10000)}
+	cpu.Load([]byte{0xC5, 0xC1, 0xC1}) // PUSH B; POP B; POP B
+	cpu.StackGuard = NewStackGuard(0xFF00, 0x0000)
+
+	for i := 0; i < 2; i++ {
+		err := cpu.Step()
+		if err != nil {
+			t.Fatalf("did not expect an error, but got: %v", err)
+		}
+	}
+
+	var underflow *StackUnderflowError
+	err := cpu.Step()
+	if !errors.As(err, &underflow) || underflow.StackPointer != 0x0000 {
+		t.Errorf("expected a StackUnderflowError at 0x0000, but got: %v", err)
+	}
+	if cpu.StackGuard.HighWater() != 2 {
+		t.Errorf("HighWater() = %d, want 2", cpu.StackGuard.HighWater())
+	}
+}
+
+func TestStackGuardCallChain(t *testing.T) {
+	program := make([]byte, 0x40)
+	copy(program[0x00:], []byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0xCD, 0x10, 0x00, // 0003 CALL 0x0010
+		0xCD, 0x20, 0x00, // 0006 CALL 0x0020
+		0x76, // 0009 HLT
+	})
+	copy(program[0x10:], []byte{
+		0xCD, 0x20, 0x00, // 0010 CALL 0x0020
+		0xC9, // 0013 RET
+	})
+	copy(program[0x20:], []byte{
+		0xC5,             // 0020 PUSH B
+		0xCD, 0x30, 0x00, // 0021 CALL 0x0030
+		0xC1, // 0024 POP B
+		0xC9, // 0025 RET
+	})
+	program[0x30] = 0xC9 // 0030 RET
+
+	cpu := New()
+	cpu.Load(program)
+	cpu.StackGuard = NewStackGuard(0x0F00, 0x1000)
+
+	var deepest int
+	for !cpu.Halted() {
+		err := cpu.Step()
+		if err != nil {
+			t.Fatalf("did not expect an error, but got: %v", err)
+		}
+		deepest = max(deepest, cpu.StackGuard.Depth())
+	}
+
+	if want := []types.Word{0x0010, 0x0020, 0x0030}; !slices.Equal(cpu.StackGuard.DeepestChain(), want) {
+		t.Errorf("DeepestChain() = %04X, want %04X", cpu.StackGuard.DeepestChain(), want)
+	}
+	if deepest != 3 || cpu.StackGuard.Depth() != 0 {
+		t.Errorf("depth reached %d and ended at %d, want 3 and 0", deepest, cpu.StackGuard.Depth())
+	}
+	if cpu.StackGuard.HighWater() != 8 {
+		t.Errorf("HighWater() = %d, want 8", cpu.StackGuard.HighWater())
+	}
+}
+
+func TestStackGuardReloadedStackPointer(t *testing.T) {
+	cpu := New()
+	cpu.Load([]byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0xCD, 0x07, 0x00, // 0003 CALL 0x0007
+		0x76,             // 0006 HLT
+		0x31, 0x00, 0x10, // 0007 LXI SP,0x1000, abandoning the call
+		0xC5, // 000A PUSH B
+		0x76, // 000B HLT
+	})
+	cpu.StackGuard = NewStackGuard(0x0F00, 0x1000)
+	cpu.Run()
+
+	if cpu.StackGuard.Depth() != 0 {
+		t.Errorf("Depth() = %d after reloading the stack pointer, want 0", cpu.StackGuard.Depth())
+	}
+}
+
+func TestStackGuardConditionalReturnNotTaken(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+	}{
+		{name: "RZ", program: []byte{0x31, 0x00, 0x10, 0xF6, 0x01, 0xC8, 0x76}}, // LXI SP,0x1000; ORI 1; RZ; HLT
+		{name: "RNZ", program: []byte{0x31, 0x00, 0x10, 0xAF, 0xC0, 0x76}},      // LXI SP,0x1000; XRA A; RNZ; HLT
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := New()
+			cpu.Load(test.program)
+			cpu.StackGuard = NewStackGuard(0x0F00, 0x1000)
+
+			err := cpu.Run()
+			if err != nil {
+				t.Fatalf("did not expect an error, but got: %v", err)
+			}
+			if cpu.StackPointer() != 0x1000 {
+				t.Errorf("stackPointer = 0x%04X, want it unchanged at 0x1000", cpu.StackPointer())
+			}
+			if cpu.LastFault() != nil {
+				t.Errorf("LastFault() = %v, want none", cpu.LastFault())
+			}
+		})
+	}
+}
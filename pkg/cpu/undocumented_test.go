@@ -0,0 +1,89 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestUndocumentedOpcodes(t *testing.T) {
+	// The assembler won't emit undocumented opcodes, so these programs are
+	// hand-assembled.
+	tests := []struct {
+		name               string
+		program            []byte
+		wantA              byte
+		wantProgramCounter types.Word
+		wantStackPointer   types.Word
+	}{
+		{
+			name:               "*NOP aliases",
+			program:            []byte{0x08, 0x10, 0x18, 0x20, 0x28, 0x30, 0x38, 0x3C, 0x76}, // *NOP x7, INR A, HLT
+			wantA:              0x01,
+			wantProgramCounter: 0x0009,
+		},
+		{
+			name:               "*JMP",
+			program:            []byte{0xCB, 0x04, 0x00, 0x76, 0x3C, 0x76}, // *JMP 0x0004, HLT, INR A, HLT
+			wantA:              0x01,
+			wantProgramCounter: 0x0006,
+		},
+		{
+			name: "*CALL and *RET",
+			// LXI SP 0x0100, *CALL 0x0008, HLT, INR A, *RET
+			program:            []byte{0x31, 0x00, 0x01, 0xDD, 0x08, 0x00, 0x76, 0x00, 0x3C, 0xD9},
+			wantA:              0x01,
+			wantProgramCounter: 0x0007,
+			wantStackPointer:   0x0100,
+		},
+		{
+			name: "*CALL ED and FD",
+			// LXI SP 0x0100, *CALL 0x000C, *CALL 0x000C, HLT, INR A, RET
+			program:            []byte{0x31, 0x00, 0x01, 0xED, 0x0C, 0x00, 0xFD, 0x0C, 0x00, 0x76, 0x00, 0x00, 0x3C, 0xC9},
+			wantA:              0x02,
+			wantProgramCounter: 0x000A,
+			wantStackPointer:   0x0100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := &CPU{Bus: memory.New()}
+			cpu.Load(tt.program)
+
+			err := cpu.Run()
+			if err != nil {
+				t.Fatalf("error running cpu: %v", err)
+			}
+
+			if cpu.A != tt.wantA {
+				t.Errorf("A = 0x%02X, want 0x%02X", cpu.A, tt.wantA)
+			}
+			if cpu.programCounter != tt.wantProgramCounter {
+				t.Errorf("programCounter = 0x%04X, want 0x%04X", cpu.programCounter, tt.wantProgramCounter)
+			}
+			if cpu.stackPointer != tt.wantStackPointer {
+				t.Errorf("stackPointer = 0x%04X, want 0x%04X", cpu.stackPointer, tt.wantStackPointer)
+			}
+		})
+	}
+}
+
+func TestUndocumentedOpcodesStrictMode(t *testing.T) {
+	for opCode, instruction := range instructions8080 {
+		if !instruction.Undocumented {
+			continue
+		}
+
+		cpu := &CPU{Bus: memory.New(), StrictOpcodes: true}
+		cpu.Load([]byte{byte(opCode), 0x76})
+
+		err := cpu.Run()
+		if err == nil {
+			t.Errorf("opcode 0x%02X: expected an error in strict mode, but got none", opCode)
+		}
+		if !cpu.halted {
+			t.Errorf("opcode 0x%02X: expected cpu to be halted in strict mode", opCode)
+		}
+	}
+}
@@ -0,0 +1,756 @@
+package cpu
+
+import (
+	"fmt"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Z80 flag bits, as laid out in the F register.
+const (
+	z80FlagC  = 1 << 0
+	z80FlagN  = 1 << 1
+	z80FlagPV = 1 << 2
+	z80FlagX  = 1 << 3
+	z80FlagH  = 1 << 4
+	z80FlagY  = 1 << 5
+	z80FlagZ  = 1 << 6
+	z80FlagS  = 1 << 7
+)
+
+// Index register selected by a DD or FD prefix.
+const (
+	z80IndexHL = iota
+	z80IndexIX
+	z80IndexIY
+)
+
+// z80State holds the registers and interrupt state that only exist on the Z80.
+// The 8080 registers are shared with the CPU, and IFF1 is cpu.interruptEnabled.
+type z80State struct {
+	alternateA, alternateF   byte
+	alternateB, alternateC   byte
+	alternateD, alternateE   byte
+	alternateH, alternateL   byte
+	ix, iy                   types.Word
+	interruptVector, refresh byte // I and R
+	iff2                     bool
+	interruptMode            byte
+	nmiPending               bool
+
+	// Per-instruction decode state.
+	index      int  // HL, IX or IY, as selected by a DD or FD prefix
+	memOperand bool // set once an instruction has addressed (HL) or (IX+d), after which H and L are no longer IXH and IXL
+}
+
+// IX returns the Z80 IX index register.
+func (cpu CPU) IX() types.Word {
+	return cpu.z80.ix
+}
+
+// IY returns the Z80 IY index register.
+func (cpu CPU) IY() types.Word {
+	return cpu.z80.iy
+}
+
+// InterruptMode returns the Z80 interrupt mode (0, 1 or 2) set by the IM instruction.
+func (cpu CPU) InterruptMode() byte {
+	return cpu.z80.interruptMode
+}
+
+// NMI raises a Z80 non-maskable interrupt, which is serviced before the next
+// instruction by calling address 0x0066.  It has no effect on other variants.
+func (cpu *CPU) NMI() {
+	if cpu.variant == ZilogZ80 {
+		cpu.z80.nmiPending = true
+	}
+}
+
+// serviceZ80Interrupts services a pending non-maskable interrupt, or a maskable
+// interrupt in mode 1 or 2, returning true if one was serviced.  Mode 0 places
+// an instruction on the data bus exactly as the 8080 does, so it's left for Run
+// to execute the pending interrupt instruction.  Servicing one wakes the CPU
+// from HALT.
+func (cpu *CPU) serviceZ80Interrupts() (bool, error) {
+	if cpu.z80.nmiPending {
+		cpu.z80.nmiPending = false
+		cpu.z80.iff2 = cpu.interruptEnabled
+		cpu.interruptEnabled = false
+		cpu.z80IncrementRefresh()
+
+		cpu.interrupting = true
+		cpu.halted = false
+		err := cpu.push(cpu.programCounter)
+		if err != nil {
+			return false, err
+		}
+		cpu.called(0x0066)
+		cpu.programCounter = 0x0066
+		cpu.cycles += 11
+		return true, nil
+	}
+
+	if !cpu.interruptEnabled || !cpu.interruptPending {
+		return false, nil
+	}
+
+	cpu.z80.iff2 = false
+	switch cpu.z80.interruptMode {
+	case 1:
+		cpu.interruptEnabled = false
+		cpu.interruptPending = false
+		cpu.z80IncrementRefresh()
+
+		cpu.interrupting = true
+		cpu.halted = false
+		err := cpu.push(cpu.programCounter)
+		if err != nil {
+			return false, err
+		}
+		cpu.called(0x0038)
+		cpu.programCounter = 0x0038
+		cpu.cycles += 13
+		return true, nil
+	case 2:
+		cpu.interruptEnabled = false
+		cpu.interruptPending = false
+		cpu.z80IncrementRefresh()
+
+		// The interrupting device supplies the low byte of an address in the table
+		// pointed to by I, which holds the address of the interrupt routine.
+		tableAddress := joinBytes(cpu.z80.interruptVector, cpu.interruptInstruction)
+		low, err := cpu.readByte(tableAddress)
+		if err != nil {
+			return false, err
+		}
+		high, err := cpu.readByte(tableAddress + 1)
+		if err != nil {
+			return false, err
+		}
+
+		cpu.interrupting = true
+		cpu.halted = false
+		err = cpu.push(cpu.programCounter)
+		if err != nil {
+			return false, err
+		}
+		cpu.called(joinBytes(high, low))
+		cpu.programCounter = joinBytes(high, low)
+		cpu.cycles += 19
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// z80IncrementRefresh increments the lower seven bits of the R register, as
+// happens on every opcode fetch.
+func (cpu *CPU) z80IncrementRefresh() {
+	r := cpu.z80.refresh
+	cpu.z80.refresh = r&0x80 | (r+1)&0x7F
+}
+
+// executeZ80 executes the Z80 instruction starting with opCode, fetching any
+// prefixed opcode bytes that follow, and returns the T-states it took.
+//
+// Opcodes are decoded by splitting them into the x, y, z, p and q fields
+// described in "Decoding Z80 Opcodes" (C. Young), where x is bits 7-6, y is
+// bits 5-3, z is bits 2-0, p is bits 5-4 and q is bit 3.
+func (cpu *CPU) executeZ80(opCode byte) (int, error) {
+	if cpu.DebugMode {
+		fmt.Printf("Executing instruction: 0x%02X\n", opCode)
+	}
+
+	cpu.z80.index = z80IndexHL
+	cpu.z80.memOperand = false
+	cpu.z80IncrementRefresh()
+
+	cycles := 0
+	for opCode == 0xDD || opCode == 0xFD {
+		cpu.z80.index = z80IndexIX
+		if opCode == 0xFD {
+			cpu.z80.index = z80IndexIY
+		}
+
+		var err error
+		opCode, err = cpu.fetchByte()
+		if err != nil {
+			return 0, err
+		}
+		cpu.z80IncrementRefresh()
+		cycles += 4
+	}
+
+	var err error
+	switch opCode {
+	case 0xCB:
+		cycles, err = cpu.z80ExecuteCB(cycles)
+	case 0xED:
+		cpu.z80.index = z80IndexHL // ED instructions ignore any DD or FD prefix
+		cycles, err = cpu.z80ExecuteED(cycles)
+	default:
+		cycles, err = cpu.z80ExecuteUnprefixed(opCode, cycles)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return cycles, nil
+}
+
+// z80ExecuteUnprefixed executes an unprefixed (or DD/FD prefixed) Z80 instruction.
+func (cpu *CPU) z80ExecuteUnprefixed(opCode byte, cycles int) (int, error) {
+	x, y, z := opCode>>6, (opCode>>3)&7, opCode&7
+	p, q := y>>1, y&1
+
+	cycles += int(cyclesZ80[opCode])
+	if cpu.z80.index != z80IndexHL && cpu.z80UsesMemoryOperand(opCode) {
+		cycles += 8 // Fetching the displacement and calculating IX+d
+	}
+
+	switch x {
+	case 0:
+		switch z {
+		case 0:
+			return cpu.z80RelativeJumps(opCode, y, cycles)
+		case 1:
+			if q == 0 { // LD rp[p], nn
+				value, err := cpu.fetchWord()
+				if err != nil {
+					return 0, err
+				}
+				cpu.z80SetRegisterPair(p, value)
+			} else { // ADD HL, rp[p]
+				cpu.z80SetRegisterPair(2, cpu.z80Add16(cpu.z80RegisterPair(2), cpu.z80RegisterPair(p)))
+			}
+		case 2:
+			return cycles, cpu.z80IndirectLoads(p, q)
+		case 3:
+			if q == 0 { // INC rp[p]
+				cpu.z80SetRegisterPair(p, cpu.z80RegisterPair(p)+1)
+			} else { // DEC rp[p]
+				cpu.z80SetRegisterPair(p, cpu.z80RegisterPair(p)-1)
+			}
+		case 4, 5: // INC r[y], DEC r[y]
+			address, err := cpu.z80OperandAddress(y)
+			if err != nil {
+				return 0, err
+			}
+			value, err := cpu.z80Register(y, address)
+			if err != nil {
+				return 0, err
+			}
+			if z == 4 {
+				value = cpu.z80Inc(value)
+			} else {
+				value = cpu.z80Dec(value)
+			}
+			return cycles, cpu.z80SetRegister(y, address, value)
+		case 6: // LD r[y], n
+			address, err := cpu.z80OperandAddress(y)
+			if err != nil {
+				return 0, err
+			}
+			value, err := cpu.fetchByte()
+			if err != nil {
+				return 0, err
+			}
+			if y == 6 && cpu.z80.index != z80IndexHL {
+				cycles -= 3 // LD (IX+d), n overlaps the displacement calculation with the operand fetch
+			}
+			return cycles, cpu.z80SetRegister(y, address, value)
+		case 7:
+			cpu.z80AccumulatorOp(y)
+		}
+
+	case 1:
+		if opCode == 0x76 { // HALT
+			cpu.halted = true
+			return cycles, nil
+		}
+
+		// LD r[y], r[z]
+		address, err := cpu.z80OperandAddress(y, z)
+		if err != nil {
+			return 0, err
+		}
+		value, err := cpu.z80Register(z, address)
+		if err != nil {
+			return 0, err
+		}
+		return cycles, cpu.z80SetRegister(y, address, value)
+
+	case 2: // alu[y] r[z]
+		address, err := cpu.z80OperandAddress(z)
+		if err != nil {
+			return 0, err
+		}
+		value, err := cpu.z80Register(z, address)
+		if err != nil {
+			return 0, err
+		}
+		cpu.z80ALU(y, value)
+
+	case 3:
+		return cpu.z80ExecuteX3(opCode, y, z, p, q, cycles)
+	}
+
+	return cycles, nil
+}
+
+// z80RelativeJumps executes the x=0, z=0 group: NOP, EX AF,AF', DJNZ, JR and JR cc.
+func (cpu *CPU) z80RelativeJumps(opCode byte, y byte, cycles int) (int, error) {
+	switch y {
+	case 0: // NOP
+		return cycles, nil
+	case 1: // EX AF, AF'
+		a, f := cpu.A, cpu.getFlags()
+		cpu.A = cpu.z80.alternateA
+		cpu.setFlags(cpu.z80.alternateF)
+		cpu.z80.alternateA, cpu.z80.alternateF = a, f
+		return cycles, nil
+	}
+
+	displacement, err := cpu.fetchByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var condition bool
+	switch y {
+	case 2: // DJNZ d
+		cpu.B--
+		condition = cpu.B != 0
+	case 3: // JR d
+		condition = true
+	default: // JR cc[y-4], d
+		condition = cpu.z80Condition(y - 4)
+	}
+
+	if condition {
+		cpu.programCounter += types.Word(int8(displacement))
+		cpu.branchTaken = true
+		if cyclesTakenZ80[opCode] != 0 {
+			cycles += int(cyclesTakenZ80[opCode]) - int(cyclesZ80[opCode])
+		}
+	}
+
+	return cycles, nil
+}
+
+// z80IndirectLoads executes the x=0, z=2 group of loads through BC, DE and direct addresses.
+func (cpu *CPU) z80IndirectLoads(p, q byte) error {
+	switch p {
+	case 0, 1: // LD (BC), A / LD (DE), A / LD A, (BC) / LD A, (DE)
+		address := cpu.getBC()
+		if p == 1 {
+			address = cpu.getDE()
+		}
+		if q == 0 {
+			return cpu.writeByte(address, cpu.A)
+		}
+
+		var err error
+		cpu.A, err = cpu.readByte(address)
+		return err
+	}
+
+	address, err := cpu.fetchWord()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case p == 2 && q == 0: // LD (nn), HL
+		return cpu.z80WriteWord(address, cpu.z80RegisterPair(2))
+	case p == 2 && q == 1: // LD HL, (nn)
+		value, err := cpu.z80ReadWord(address)
+		if err != nil {
+			return err
+		}
+		cpu.z80SetRegisterPair(2, value)
+		return nil
+	case q == 0: // LD (nn), A
+		return cpu.writeByte(address, cpu.A)
+	default: // LD A, (nn)
+		cpu.A, err = cpu.readByte(address)
+		return err
+	}
+}
+
+// z80AccumulatorOp executes the x=0, z=7 group: RLCA, RRCA, RLA, RRA, DAA, CPL, SCF and CCF.
+func (cpu *CPU) z80AccumulatorOp(y byte) {
+	flags := cpu.getFlags()
+	preserved := flags & (z80FlagS | z80FlagZ | z80FlagPV)
+	carry := flags & z80FlagC
+
+	switch y {
+	case 0: // RLCA
+		carry = cpu.A >> 7
+		cpu.A = cpu.A<<1 | carry
+	case 1: // RRCA
+		carry = cpu.A & 1
+		cpu.A = cpu.A>>1 | carry<<7
+	case 2: // RLA
+		newCarry := cpu.A >> 7
+		cpu.A = cpu.A<<1 | carry
+		carry = newCarry
+	case 3: // RRA
+		newCarry := cpu.A & 1
+		cpu.A = cpu.A>>1 | carry<<7
+		carry = newCarry
+	case 4: // DAA
+		cpu.z80DAA()
+		return
+	case 5: // CPL
+		cpu.A = ^cpu.A
+		cpu.setFlags(flags&^(z80FlagX|z80FlagY) | z80FlagH | z80FlagN | cpu.A&(z80FlagX|z80FlagY))
+		return
+	case 6: // SCF
+		carry = z80FlagC
+	case 7: // CCF
+		if carry != 0 {
+			preserved |= z80FlagH
+		}
+		carry ^= z80FlagC
+	}
+
+	cpu.setFlags(preserved | carry | cpu.A&(z80FlagX|z80FlagY))
+}
+
+// z80ExecuteX3 executes the x=3 group: conditional and unconditional control
+// transfers, stack operations, exchanges, I/O and immediate arithmetic.
+func (cpu *CPU) z80ExecuteX3(opCode, y, z, p, q byte, cycles int) (int, error) {
+	switch z {
+	case 0: // RET cc[y]
+		if cpu.z80Condition(y) {
+			err := cpu.ret(true)
+			if err != nil {
+				return 0, err
+			}
+			cycles = cycles - int(cyclesZ80[opCode]) + int(cyclesTakenZ80[opCode])
+		}
+	case 1:
+		if q == 0 { // POP rp2[p]
+			value, err := cpu.pop()
+			if err != nil {
+				return 0, err
+			}
+			cpu.z80SetRegisterPair2(p, value)
+			return cycles, nil
+		}
+
+		switch p {
+		case 0: // RET
+			return cycles, cpu.ret(true)
+		case 1: // EXX
+			cpu.B, cpu.z80.alternateB = cpu.z80.alternateB, cpu.B
+			cpu.C, cpu.z80.alternateC = cpu.z80.alternateC, cpu.C
+			cpu.D, cpu.z80.alternateD = cpu.z80.alternateD, cpu.D
+			cpu.E, cpu.z80.alternateE = cpu.z80.alternateE, cpu.E
+			cpu.H, cpu.z80.alternateH = cpu.z80.alternateH, cpu.H
+			cpu.L, cpu.z80.alternateL = cpu.z80.alternateL, cpu.L
+		case 2: // JP (HL)
+			cpu.programCounter = cpu.z80RegisterPair(2)
+		case 3: // LD SP, HL
+			cpu.stackPointer = cpu.z80RegisterPair(2)
+		}
+	case 2: // JP cc[y], nn
+		return cycles, cpu.jmp(cpu.z80Condition(y))
+	case 3:
+		return cycles, cpu.z80ExecuteX3Z3(y)
+	case 4: // CALL cc[y], nn
+		condition := cpu.z80Condition(y)
+		err := cpu.call(condition)
+		if err != nil {
+			return 0, err
+		}
+		if condition {
+			cycles = cycles - int(cyclesZ80[opCode]) + int(cyclesTakenZ80[opCode])
+		}
+	case 5:
+		if q == 0 { // PUSH rp2[p]
+			return cycles, cpu.push(cpu.z80RegisterPair2(p))
+		}
+		// CALL nn (p=0), the DD, ED and FD prefixes are handled by executeZ80
+		return cycles, cpu.call(true)
+	case 6: // alu[y] n
+		value, err := cpu.fetchByte()
+		if err != nil {
+			return 0, err
+		}
+		cpu.z80ALU(y, value)
+	case 7: // RST y*8
+		return cycles, cpu.rst(types.Word(y) * 8)
+	}
+
+	return cycles, nil
+}
+
+// z80ExecuteX3Z3 executes the x=3, z=3 group: JP nn, OUT (n),A, IN A,(n), EX (SP),HL, EX DE,HL, DI and EI.
+func (cpu *CPU) z80ExecuteX3Z3(y byte) error {
+	switch y {
+	case 0: // JP nn
+		return cpu.jmp(true)
+	case 2: // OUT (n), A
+		port, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.out(port)
+	case 3: // IN A, (n)
+		port, err := cpu.fetchByte()
+		if err != nil {
+			return err
+		}
+		cpu.in(port)
+	case 4: // EX (SP), HL
+		err := cpu.checkPop()
+		if err != nil {
+			return err
+		}
+		value, err := cpu.z80ReadWord(cpu.stackPointer)
+		if err != nil {
+			return err
+		}
+		err = cpu.z80WriteWord(cpu.stackPointer, cpu.z80RegisterPair(2))
+		if err != nil {
+			return err
+		}
+		cpu.z80SetRegisterPair(2, value)
+	case 5: // EX DE, HL (never affected by a DD or FD prefix)
+		cpu.D, cpu.E, cpu.H, cpu.L = cpu.H, cpu.L, cpu.D, cpu.E
+	case 6: // DI
+		cpu.interruptEnabled = false
+		cpu.z80.iff2 = false
+	case 7: // EI
+		cpu.interruptEnabled = true
+		cpu.z80.iff2 = true
+	}
+
+	return nil
+}
+
+// z80Condition returns whether condition cc[y] (NZ, Z, NC, C, PO, PE, P, M) is met.
+func (cpu CPU) z80Condition(y byte) bool {
+	switch y {
+	case 0:
+		return !cpu.flags.Zero
+	case 1:
+		return cpu.flags.Zero
+	case 2:
+		return !cpu.flags.Carry
+	case 3:
+		return cpu.flags.Carry
+	case 4:
+		return !cpu.flags.Parity
+	case 5:
+		return cpu.flags.Parity
+	case 6:
+		return !cpu.flags.Sign
+	}
+
+	return cpu.flags.Sign
+}
+
+// z80UsesMemoryOperand reports whether an unprefixed opcode addresses (HL),
+// which becomes (IX+d) or (IY+d) when prefixed.
+func (cpu CPU) z80UsesMemoryOperand(opCode byte) bool {
+	x, y, z := opCode>>6, (opCode>>3)&7, opCode&7
+	switch x {
+	case 0:
+		return y == 6 && (z == 4 || z == 5 || z == 6)
+	case 1:
+		return (y == 6 || z == 6) && opCode != 0x76
+	case 2:
+		return z == 6
+	}
+
+	return false
+}
+
+// z80OperandAddress returns the address used by register index 6 ((HL), or
+// (IX+d) when prefixed) if any of the given register indexes is 6, fetching the
+// displacement byte for an indexed instruction.
+func (cpu *CPU) z80OperandAddress(registers ...byte) (types.Word, error) {
+	for _, register := range registers {
+		if register != 6 {
+			continue
+		}
+
+		cpu.z80.memOperand = true
+		switch cpu.z80.index {
+		case z80IndexIX, z80IndexIY:
+			displacement, err := cpu.fetchByte()
+			if err != nil {
+				return 0, err
+			}
+			return cpu.z80IndexRegister() + types.Word(int8(displacement)), nil
+		}
+		return cpu.getHL(), nil
+	}
+
+	return 0, nil
+}
+
+// z80IndexRegister returns HL, IX or IY depending on the current prefix.
+func (cpu CPU) z80IndexRegister() types.Word {
+	switch cpu.z80.index {
+	case z80IndexIX:
+		return cpu.z80.ix
+	case z80IndexIY:
+		return cpu.z80.iy
+	}
+
+	return cpu.getHL()
+}
+
+// z80Register returns register r[index] (B, C, D, E, H, L, (HL), A), where H and L
+// become the high and low halves of IX or IY when prefixed, unless the
+// instruction also addresses memory.
+func (cpu *CPU) z80Register(index byte, address types.Word) (byte, error) {
+	switch index {
+	case 0:
+		return cpu.B, nil
+	case 1:
+		return cpu.C, nil
+	case 2:
+		return cpu.D, nil
+	case 3:
+		return cpu.E, nil
+	case 4:
+		if cpu.z80.index != z80IndexHL && !cpu.z80.memOperand {
+			high, _ := splitWord(cpu.z80IndexRegister())
+			return high, nil
+		}
+		return cpu.H, nil
+	case 5:
+		if cpu.z80.index != z80IndexHL && !cpu.z80.memOperand {
+			_, low := splitWord(cpu.z80IndexRegister())
+			return low, nil
+		}
+		return cpu.L, nil
+	case 6:
+		return cpu.readByte(address)
+	}
+
+	return cpu.A, nil
+}
+
+// z80SetRegister sets register r[index], following the same rules as z80Register.
+func (cpu *CPU) z80SetRegister(index byte, address types.Word, value byte) error {
+	switch index {
+	case 0:
+		cpu.B = value
+	case 1:
+		cpu.C = value
+	case 2:
+		cpu.D = value
+	case 3:
+		cpu.E = value
+	case 4:
+		if cpu.z80.index != z80IndexHL && !cpu.z80.memOperand {
+			_, low := splitWord(cpu.z80IndexRegister())
+			cpu.z80SetRegisterPair(2, joinBytes(value, low))
+			return nil
+		}
+		cpu.H = value
+	case 5:
+		if cpu.z80.index != z80IndexHL && !cpu.z80.memOperand {
+			high, _ := splitWord(cpu.z80IndexRegister())
+			cpu.z80SetRegisterPair(2, joinBytes(high, value))
+			return nil
+		}
+		cpu.L = value
+	case 6:
+		return cpu.writeByte(address, value)
+	case 7:
+		cpu.A = value
+	}
+
+	return nil
+}
+
+// z80RegisterPair returns register pair rp[p] (BC, DE, HL, SP), where HL becomes
+// IX or IY when prefixed.
+func (cpu CPU) z80RegisterPair(p byte) types.Word {
+	switch p {
+	case 0:
+		return cpu.getBC()
+	case 1:
+		return cpu.getDE()
+	case 2:
+		return cpu.z80IndexRegister()
+	}
+
+	return cpu.stackPointer
+}
+
+// z80SetRegisterPair sets register pair rp[p], following the same rules as z80RegisterPair.
+func (cpu *CPU) z80SetRegisterPair(p byte, value types.Word) {
+	switch p {
+	case 0:
+		cpu.B, cpu.C = splitWord(value)
+	case 1:
+		cpu.D, cpu.E = splitWord(value)
+	case 2:
+		switch cpu.z80.index {
+		case z80IndexIX:
+			cpu.z80.ix = value
+		case z80IndexIY:
+			cpu.z80.iy = value
+		default:
+			cpu.H, cpu.L = splitWord(value)
+		}
+	case 3:
+		cpu.stackPointer = value
+	}
+}
+
+// z80RegisterPair2 returns register pair rp2[p] (BC, DE, HL, AF), as used by PUSH and POP.
+func (cpu CPU) z80RegisterPair2(p byte) types.Word {
+	if p == 3 {
+		return cpu.getAWithFlags()
+	}
+
+	return cpu.z80RegisterPair(p)
+}
+
+// z80SetRegisterPair2 sets register pair rp2[p], as used by PUSH and POP.
+func (cpu *CPU) z80SetRegisterPair2(p byte, value types.Word) {
+	if p == 3 {
+		var flags byte
+		cpu.A, flags = splitWord(value)
+		cpu.setFlags(flags)
+		return
+	}
+
+	cpu.z80SetRegisterPair(p, value)
+}
+
+// z80ReadWord reads a little endian word from memory.
+func (cpu *CPU) z80ReadWord(address types.Word) (types.Word, error) {
+	low, err := cpu.readByte(address)
+	if err != nil {
+		return 0, err
+	}
+
+	high, err := cpu.readByte(address + 1)
+	if err != nil {
+		return 0, err
+	}
+
+	return joinBytes(high, low), nil
+}
+
+// z80WriteWord writes a little endian word to memory.
+func (cpu *CPU) z80WriteWord(address types.Word, value types.Word) error {
+	high, low := splitWord(value)
+
+	err := cpu.writeByte(address, low)
+	if err != nil {
+		return err
+	}
+
+	return cpu.writeByte(address+1, high)
+}
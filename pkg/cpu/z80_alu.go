@@ -0,0 +1,268 @@
+package cpu
+
+import (
+	"math/bits"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// z80SignZero53 returns the S, Z, Y and X flags for an 8-bit result.  Y and X
+// are undocumented copies of bits 5 and 3 of the result.
+func z80SignZero53(value byte) byte {
+	flags := value & (z80FlagS | z80FlagY | z80FlagX)
+	if value == 0 {
+		flags |= z80FlagZ
+	}
+
+	return flags
+}
+
+// z80SignZero53Parity returns the S, Z, Y, X and P/V flags for an 8-bit result,
+// with P/V set when the result has even parity.
+func z80SignZero53Parity(value byte) byte {
+	flags := z80SignZero53(value)
+	if bits.OnesCount8(value)%2 == 0 {
+		flags |= z80FlagPV
+	}
+
+	return flags
+}
+
+// z80ALU performs alu[y] (ADD, ADC, SUB, SBC, AND, XOR, OR, CP) on the
+// accumulator and value.
+func (cpu *CPU) z80ALU(y byte, value byte) {
+	carry := boolToByte(cpu.flags.Carry)
+	switch y {
+	case 0: // ADD
+		cpu.z80Add(value, 0)
+	case 1: // ADC
+		cpu.z80Add(value, carry)
+	case 2: // SUB
+		cpu.A = cpu.z80Sub(value, 0)
+	case 3: // SBC
+		cpu.A = cpu.z80Sub(value, carry)
+	case 4: // AND
+		cpu.A &= value
+		cpu.setFlags(z80SignZero53Parity(cpu.A) | z80FlagH)
+	case 5: // XOR
+		cpu.A ^= value
+		cpu.setFlags(z80SignZero53Parity(cpu.A))
+	case 6: // OR
+		cpu.A |= value
+		cpu.setFlags(z80SignZero53Parity(cpu.A))
+	case 7: // CP
+		cpu.z80Sub(value, 0)
+		// CP takes the undocumented X and Y flags from the operand, not the result
+		flags := cpu.getFlags()
+		cpu.setFlags(flags&^(z80FlagX|z80FlagY) | value&(z80FlagX|z80FlagY))
+	}
+}
+
+// z80Add adds value and carry to the accumulator, setting all flags, with
+// P/V indicating a two's complement overflow.
+func (cpu *CPU) z80Add(value byte, carry byte) {
+	result := types.Word(cpu.A) + types.Word(value) + types.Word(carry)
+
+	flags := z80SignZero53(byte(result))
+	if result > 0xFF {
+		flags |= z80FlagC
+	}
+	if cpu.A&0x0F+value&0x0F+carry > 0x0F {
+		flags |= z80FlagH
+	}
+	if (cpu.A^byte(result))&(value^byte(result))&0x80 != 0 {
+		flags |= z80FlagPV
+	}
+
+	cpu.A = byte(result)
+	cpu.setFlags(flags)
+}
+
+// z80Sub subtracts value and carry from the accumulator, setting all flags,
+// and returns the result without storing it so that CP can share it.
+func (cpu *CPU) z80Sub(value byte, carry byte) byte {
+	result := types.Word(cpu.A) - types.Word(value) - types.Word(carry)
+
+	flags := z80SignZero53(byte(result)) | z80FlagN
+	if result > 0xFF {
+		flags |= z80FlagC
+	}
+	if types.Word(cpu.A&0x0F) < types.Word(value&0x0F)+types.Word(carry) {
+		flags |= z80FlagH
+	}
+	if (cpu.A^value)&(cpu.A^byte(result))&0x80 != 0 {
+		flags |= z80FlagPV
+	}
+
+	cpu.setFlags(flags)
+	return byte(result)
+}
+
+// z80Inc returns value incremented by one, setting all flags except carry.
+func (cpu *CPU) z80Inc(value byte) byte {
+	result := value + 1
+
+	flags := cpu.getFlags()&z80FlagC | z80SignZero53(result)
+	if value&0x0F == 0x0F {
+		flags |= z80FlagH
+	}
+	if value == 0x7F {
+		flags |= z80FlagPV
+	}
+
+	cpu.setFlags(flags)
+	return result
+}
+
+// z80Dec returns value decremented by one, setting all flags except carry.
+func (cpu *CPU) z80Dec(value byte) byte {
+	result := value - 1
+
+	flags := cpu.getFlags()&z80FlagC | z80SignZero53(result) | z80FlagN
+	if value&0x0F == 0x00 {
+		flags |= z80FlagH
+	}
+	if value == 0x80 {
+		flags |= z80FlagPV
+	}
+
+	cpu.setFlags(flags)
+	return result
+}
+
+// z80Add16 returns a + b, as used by ADD HL, ADD IX and ADD IY.  S, Z and P/V
+// are unaffected, H is the carry out of bit 11 and X and Y come from the high
+// byte of the result.
+func (cpu *CPU) z80Add16(a, b types.Word) types.Word {
+	result := uint32(a) + uint32(b)
+
+	flags := cpu.getFlags()&(z80FlagS|z80FlagZ|z80FlagPV) | byte(result>>8)&(z80FlagX|z80FlagY)
+	if result > 0xFFFF {
+		flags |= z80FlagC
+	}
+	if a&0x0FFF+b&0x0FFF > 0x0FFF {
+		flags |= z80FlagH
+	}
+
+	cpu.setFlags(flags)
+	return types.Word(result)
+}
+
+// z80Adc16 returns a + b + carry, setting all flags, as used by ADC HL.
+func (cpu *CPU) z80Adc16(a, b types.Word) types.Word {
+	carry := uint32(boolToByte(cpu.flags.Carry))
+	result := uint32(a) + uint32(b) + carry
+
+	flags := cpu.z80Flags16(types.Word(result))
+	if result > 0xFFFF {
+		flags |= z80FlagC
+	}
+	if uint32(a&0x0FFF)+uint32(b&0x0FFF)+carry > 0x0FFF {
+		flags |= z80FlagH
+	}
+	if (a^types.Word(result))&(b^types.Word(result))&0x8000 != 0 {
+		flags |= z80FlagPV
+	}
+
+	cpu.setFlags(flags)
+	return types.Word(result)
+}
+
+// z80Sbc16 returns a - b - carry, setting all flags, as used by SBC HL.
+func (cpu *CPU) z80Sbc16(a, b types.Word) types.Word {
+	carry := uint32(boolToByte(cpu.flags.Carry))
+	result := uint32(a) - uint32(b) - carry
+
+	flags := cpu.z80Flags16(types.Word(result)) | z80FlagN
+	if result > 0xFFFF {
+		flags |= z80FlagC
+	}
+	if uint32(a&0x0FFF) < uint32(b&0x0FFF)+carry {
+		flags |= z80FlagH
+	}
+	if (a^b)&(a^types.Word(result))&0x8000 != 0 {
+		flags |= z80FlagPV
+	}
+
+	cpu.setFlags(flags)
+	return types.Word(result)
+}
+
+// z80Flags16 returns the S, Z, Y and X flags for a 16-bit result.
+func (cpu CPU) z80Flags16(result types.Word) byte {
+	flags := byte(result>>8) & (z80FlagS | z80FlagY | z80FlagX)
+	if result == 0 {
+		flags |= z80FlagZ
+	}
+
+	return flags
+}
+
+// z80DAA adjusts the accumulator to binary coded decimal after an addition or,
+// unlike the 8080, a subtraction (as recorded by the N flag).
+func (cpu *CPU) z80DAA() {
+	flags := cpu.getFlags()
+	value := cpu.A
+
+	var correction byte
+	carry := flags & z80FlagC
+	if flags&z80FlagH != 0 || value&0x0F > 9 {
+		correction |= 0x06
+	}
+	if carry != 0 || value > 0x99 {
+		correction |= 0x60
+		carry = z80FlagC
+	}
+
+	var halfCarry byte
+	if flags&z80FlagN != 0 {
+		if flags&z80FlagH != 0 && value&0x0F < 6 {
+			halfCarry = z80FlagH
+		}
+		cpu.A -= correction
+	} else {
+		if value&0x0F > 9 {
+			halfCarry = z80FlagH
+		}
+		cpu.A += correction
+	}
+
+	cpu.setFlags(z80SignZero53Parity(cpu.A) | flags&z80FlagN | carry | halfCarry)
+}
+
+// z80Rotate performs rot[y] (RLC, RRC, RL, RR, SLA, SRA, SLL, SRL) on value,
+// setting S, Z, P/V and C from the result.
+func (cpu *CPU) z80Rotate(y byte, value byte) byte {
+	carryIn := boolToByte(cpu.flags.Carry)
+
+	var result, carry byte
+	switch y {
+	case 0: // RLC
+		carry = value >> 7
+		result = value<<1 | carry
+	case 1: // RRC
+		carry = value & 1
+		result = value>>1 | carry<<7
+	case 2: // RL
+		carry = value >> 7
+		result = value<<1 | carryIn
+	case 3: // RR
+		carry = value & 1
+		result = value>>1 | carryIn<<7
+	case 4: // SLA
+		carry = value >> 7
+		result = value << 1
+	case 5: // SRA
+		carry = value & 1
+		result = value>>1 | value&0x80
+	case 6: // SLL (undocumented, shifts a one into bit 0)
+		carry = value >> 7
+		result = value<<1 | 1
+	case 7: // SRL
+		carry = value & 1
+		result = value >> 1
+	}
+
+	cpu.setFlags(z80SignZero53Parity(result) | carry)
+	return result
+}
@@ -0,0 +1,287 @@
+package cpu
+
+import (
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// z80ExecuteCB executes a CB prefixed instruction: rotates and shifts, BIT, RES
+// and SET.  When DD or FD prefixed, the displacement comes before the final
+// opcode byte and the instruction operates on (IX+d) or (IY+d); any register
+// named by the opcode also receives a copy of the result (undocumented).
+func (cpu *CPU) z80ExecuteCB(cycles int) (int, error) {
+	var address types.Word
+	indexed := cpu.z80.index != z80IndexHL
+	if indexed {
+		displacement, err := cpu.fetchByte()
+		if err != nil {
+			return 0, err
+		}
+		address = cpu.z80IndexRegister() + types.Word(int8(displacement))
+	} else {
+		address = cpu.getHL()
+	}
+
+	opCode, err := cpu.fetchByte()
+	if err != nil {
+		return 0, err
+	}
+	if !indexed {
+		cpu.z80IncrementRefresh()
+	}
+	cpu.z80.memOperand = true // IXH and IXL can't be addressed by CB instructions
+
+	x, y, z := opCode>>6, (opCode>>3)&7, opCode&7
+	operand := z
+	if indexed {
+		operand = 6
+	}
+
+	switch {
+	case indexed && x == 1:
+		cycles += 16
+	case indexed:
+		cycles += 19
+	case z == 6 && x == 1:
+		cycles += 12
+	case z == 6:
+		cycles += 15
+	default:
+		cycles += 8
+	}
+
+	value, err := cpu.z80Register(operand, address)
+	if err != nil {
+		return 0, err
+	}
+
+	switch x {
+	case 0: // rot[y] r[z]
+		value = cpu.z80Rotate(y, value)
+	case 1: // BIT y, r[z]
+		flags := cpu.getFlags()&z80FlagC | z80FlagH
+		undocumented := value
+		if operand == 6 {
+			undocumented, _ = splitWord(address) // X and Y leak from the internal address register
+		}
+		flags |= undocumented & (z80FlagX | z80FlagY)
+
+		bit := value & (1 << y)
+		if bit == 0 {
+			flags |= z80FlagZ | z80FlagPV
+		}
+		if y == 7 && bit != 0 {
+			flags |= z80FlagS
+		}
+		cpu.setFlags(flags)
+		return cycles, nil
+	case 2: // RES y, r[z]
+		value &^= 1 << y
+	case 3: // SET y, r[z]
+		value |= 1 << y
+	}
+
+	err = cpu.z80SetRegister(operand, address, value)
+	if err != nil {
+		return 0, err
+	}
+	if indexed && z != 6 {
+		return cycles, cpu.z80SetRegister(z, address, value)
+	}
+
+	return cycles, nil
+}
+
+// z80ExecuteED executes an ED prefixed instruction.  Opcodes that aren't
+// assigned execute as an 8 T-state NOP.
+func (cpu *CPU) z80ExecuteED(cycles int) (int, error) {
+	opCode, err := cpu.fetchByte()
+	if err != nil {
+		return 0, err
+	}
+	cpu.z80IncrementRefresh()
+
+	x, y, z := opCode>>6, (opCode>>3)&7, opCode&7
+	p, q := y>>1, y&1
+
+	switch {
+	case x == 1:
+		return cpu.z80ExecuteEDX1(y, z, p, q, cycles)
+	case x == 2 && z <= 3 && y >= 4:
+		return cpu.z80BlockInstruction(y, z, cycles)
+	}
+
+	return cycles + 8, nil // NONI - No operation, no interrupts
+}
+
+// z80ExecuteEDX1 executes the x=1 group of ED prefixed instructions.
+func (cpu *CPU) z80ExecuteEDX1(y, z, p, q byte, cycles int) (int, error) {
+	switch z {
+	case 0: // IN r[y], (C), or IN (C) when y=6 to only set the flags
+		value := cpu.readPort(cpu.C)
+		cpu.setFlags(cpu.getFlags()&z80FlagC | z80SignZero53Parity(value))
+		if y != 6 {
+			cpu.z80SetRegister(y, 0, value)
+		}
+		return cycles + 12, nil
+	case 1: // OUT (C), r[y], or OUT (C), 0 when y=6
+		var value byte
+		if y != 6 {
+			value, _ = cpu.z80Register(y, 0)
+		}
+		cpu.writePort(cpu.C, value)
+		return cycles + 12, nil
+	case 2:
+		if q == 0 { // SBC HL, rp[p]
+			cpu.z80SetRegisterPair(2, cpu.z80Sbc16(cpu.getHL(), cpu.z80RegisterPair(p)))
+		} else { // ADC HL, rp[p]
+			cpu.z80SetRegisterPair(2, cpu.z80Adc16(cpu.getHL(), cpu.z80RegisterPair(p)))
+		}
+		return cycles + 15, nil
+	case 3:
+		address, err := cpu.fetchWord()
+		if err != nil {
+			return 0, err
+		}
+		if q == 0 { // LD (nn), rp[p]
+			return cycles + 20, cpu.z80WriteWord(address, cpu.z80RegisterPair(p))
+		}
+		// LD rp[p], (nn)
+		value, err := cpu.z80ReadWord(address)
+		if err != nil {
+			return 0, err
+		}
+		cpu.z80SetRegisterPair(p, value)
+		return cycles + 20, nil
+	case 4: // NEG
+		value := cpu.A
+		cpu.A = 0
+		cpu.A = cpu.z80Sub(value, 0)
+		return cycles + 8, nil
+	case 5: // RETN, or RETI when y=1
+		cpu.interruptEnabled = cpu.z80.iff2
+		return cycles + 14, cpu.ret(true)
+	case 6: // IM 0, 0/1, 1, 2
+		cpu.z80.interruptMode = [8]byte{0, 0, 1, 2, 0, 0, 1, 2}[y]
+		return cycles + 8, nil
+	}
+
+	// z == 7
+	switch y {
+	case 0: // LD I, A
+		cpu.z80.interruptVector = cpu.A
+	case 1: // LD R, A
+		cpu.z80.refresh = cpu.A
+	case 2, 3: // LD A, I / LD A, R
+		cpu.A = cpu.z80.interruptVector
+		if y == 3 {
+			cpu.A = cpu.z80.refresh
+		}
+		flags := cpu.getFlags()&z80FlagC | z80SignZero53(cpu.A)
+		if cpu.z80.iff2 {
+			flags |= z80FlagPV
+		}
+		cpu.setFlags(flags)
+	case 4, 5: // RRD / RLD
+		value, err := cpu.readByte(cpu.getHL())
+		if err != nil {
+			return 0, err
+		}
+		if y == 4 {
+			value, cpu.A = cpu.A<<4|value>>4, cpu.A&0xF0|value&0x0F
+		} else {
+			value, cpu.A = value<<4|cpu.A&0x0F, cpu.A&0xF0|value>>4
+		}
+		err = cpu.writeByte(cpu.getHL(), value)
+		if err != nil {
+			return 0, err
+		}
+		cpu.setFlags(cpu.getFlags()&z80FlagC | z80SignZero53Parity(cpu.A))
+		return cycles + 18, nil
+	}
+
+	return cycles + 9, nil
+}
+
+// z80BlockInstruction executes the block transfer, search and I/O instructions
+// bli[y,z]: LDI, CPI, INI, OUTI, LDD, CPD, IND, OUTD and their repeating forms.
+// A repeating instruction that hasn't finished winds the program counter back
+// so it executes again, allowing interrupts between iterations.
+func (cpu *CPU) z80BlockInstruction(y, z byte, cycles int) (int, error) {
+	step := types.Word(1)
+	if y&1 == 1 {
+		step = 0xFFFF // Decrement
+	}
+	repeat := y >= 6
+
+	hl := cpu.getHL()
+	flags := cpu.getFlags()
+	var again bool
+
+	switch z {
+	case 0: // LDI, LDD, LDIR, LDDR
+		value, err := cpu.readByte(hl)
+		if err != nil {
+			return 0, err
+		}
+		err = cpu.writeByte(cpu.getDE(), value)
+		if err != nil {
+			return 0, err
+		}
+		cpu.D, cpu.E = splitWord(cpu.getDE() + step)
+		cpu.B, cpu.C = splitWord(cpu.getBC() - 1)
+
+		n := value + cpu.A
+		flags = flags&(z80FlagS|z80FlagZ|z80FlagC) | n&z80FlagX | (n<<4)&z80FlagY
+		if cpu.getBC() != 0 {
+			flags |= z80FlagPV
+		}
+		again = cpu.getBC() != 0
+	case 1: // CPI, CPD, CPIR, CPDR
+		value, err := cpu.readByte(hl)
+		if err != nil {
+			return 0, err
+		}
+		result := cpu.A - value
+		cpu.B, cpu.C = splitWord(cpu.getBC() - 1)
+
+		flags = flags&z80FlagC | z80FlagN | z80SignZero53(result)&(z80FlagS|z80FlagZ)
+		n := result
+		if cpu.A&0x0F < value&0x0F {
+			flags |= z80FlagH
+			n--
+		}
+		flags |= n&z80FlagX | (n<<4)&z80FlagY
+		if cpu.getBC() != 0 {
+			flags |= z80FlagPV
+		}
+		again = cpu.getBC() != 0 && result != 0
+	case 2: // INI, IND, INIR, INDR
+		value := cpu.readPort(cpu.C)
+		err := cpu.writeByte(hl, value)
+		if err != nil {
+			return 0, err
+		}
+		cpu.B--
+		flags = flags&z80FlagC | z80SignZero53(cpu.B) | z80FlagN
+		again = cpu.B != 0
+	case 3: // OUTI, OUTD, OTIR, OTDR
+		value, err := cpu.readByte(hl)
+		if err != nil {
+			return 0, err
+		}
+		cpu.B--
+		cpu.writePort(cpu.C, value)
+		flags = flags&z80FlagC | z80SignZero53(cpu.B) | z80FlagN
+		again = cpu.B != 0
+	}
+
+	cpu.H, cpu.L = splitWord(hl + step)
+	cpu.setFlags(flags)
+
+	if repeat && again {
+		cpu.programCounter -= 2
+		return cycles + 21, nil
+	}
+
+	return cycles + 16, nil
+}
@@ -0,0 +1,330 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestZ80Instructions(t *testing.T) {
+	// The assembler only supports the 8080 instruction set, so these programs
+	// are hand-assembled.
+	tests := []struct {
+		name    string
+		program []byte
+		initCPU func(cpu *CPU)
+		check   func(t *testing.T, cpu *CPU)
+	}{
+		{
+			name: "EXX and EX AF,AF'",
+			// LD BC,0x1234; EXX; LD BC,0x5678; EXX; LD A,0x11; EX AF,AF'; LD A,0x22; EX AF,AF'; HALT
+			program: []byte{0x01, 0x34, 0x12, 0xD9, 0x01, 0x78, 0x56, 0xD9, 0x3E, 0x11, 0x08, 0x3E, 0x22, 0x08, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if got := cpu.getBC(); got != 0x1234 {
+					t.Errorf("BC = 0x%04X, want 0x1234", got)
+				}
+				if got := joinBytes(cpu.z80.alternateB, cpu.z80.alternateC); got != 0x5678 {
+					t.Errorf("BC' = 0x%04X, want 0x5678", got)
+				}
+				if cpu.A != 0x11 || cpu.z80.alternateA != 0x22 {
+					t.Errorf("A = 0x%02X, A' = 0x%02X, want 0x11 and 0x22", cpu.A, cpu.z80.alternateA)
+				}
+			},
+		},
+		{
+			name: "DJNZ loop",
+			// LD B,5; XOR A; loop: INC A; DJNZ loop; HALT
+			program: []byte{0x06, 0x05, 0xAF, 0x3C, 0x10, 0xFD, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0x05 || cpu.B != 0x00 {
+					t.Errorf("A = 0x%02X, B = 0x%02X, want 0x05 and 0x00", cpu.A, cpu.B)
+				}
+				if want := uint64(7 + 4 + 5*4 + 4*13 + 8 + 4); cpu.Cycles() != want {
+					t.Errorf("Cycles() = %d, want %d", cpu.Cycles(), want)
+				}
+			},
+		},
+		{
+			name: "JR and JR cc",
+			// XOR A; JR Z,+1; HALT; JR NZ,+2; JR +1; HALT; LD A,1; HALT
+			program: []byte{0xAF, 0x28, 0x01, 0x76, 0x20, 0x02, 0x18, 0x01, 0x76, 0x3E, 0x01, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0x01 {
+					t.Errorf("A = 0x%02X, want 0x01", cpu.A)
+				}
+			},
+		},
+		{
+			name: "IX indexed addressing",
+			// LD IX,0x0100; LD (IX+5),0x42; LD A,(IX+5); INC (IX+5); LD B,(IX+5); LD H,(IX-1); HALT
+			program: []byte{0xDD, 0x21, 0x00, 0x01, 0xDD, 0x36, 0x05, 0x42, 0xDD, 0x7E, 0x05, 0xDD, 0x34, 0x05, 0xDD, 0x46, 0x05, 0xDD, 0x66, 0xFF, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0x42 || cpu.B != 0x43 {
+					t.Errorf("A = 0x%02X, B = 0x%02X, want 0x42 and 0x43", cpu.A, cpu.B)
+				}
+				if cpu.H != 0x00 || cpu.IX() != 0x0100 {
+					t.Errorf("H = 0x%02X, IX = 0x%04X, want H loaded from memory and IX unchanged", cpu.H, cpu.IX())
+				}
+			},
+		},
+		{
+			name: "IY halves and ADD IY",
+			// LD IY,0x1234; LD A,IYH; LD IYL,0x00; LD BC,0x0100; ADD IY,BC; HALT
+			program: []byte{0xFD, 0x21, 0x34, 0x12, 0xFD, 0x7C, 0xFD, 0x2E, 0x00, 0x01, 0x00, 0x01, 0xFD, 0x09, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0x12 {
+					t.Errorf("A = 0x%02X, want 0x12", cpu.A)
+				}
+				if cpu.IY() != 0x1300 {
+					t.Errorf("IY = 0x%04X, want 0x1300", cpu.IY())
+				}
+				if cpu.getHL() != 0x0000 {
+					t.Errorf("HL = 0x%04X, want 0x0000", cpu.getHL())
+				}
+			},
+		},
+		{
+			name: "LDIR",
+			// LD HL,0x000E; LD DE,0x0200; LD BC,3; LDIR; HALT; DB 0xAA, 0xBB, 0xCC
+			program: []byte{0x21, 0x0E, 0x00, 0x11, 0x00, 0x02, 0x01, 0x03, 0x00, 0xED, 0xB0, 0x76, 0x00, 0x00, 0xAA, 0xBB, 0xCC},
+			check: func(t *testing.T, cpu *CPU) {
+				for i, want := range []byte{0xAA, 0xBB, 0xCC} {
+					got, _ := cpu.Bus.ReadByteAt(0x0200 + types.Word(i))
+					if got != want {
+						t.Errorf("memory 0x%04X = 0x%02X, want 0x%02X", 0x0200+i, got, want)
+					}
+				}
+				if cpu.getBC() != 0 || cpu.getDE() != 0x0203 || cpu.getHL() != 0x0011 {
+					t.Errorf("BC = 0x%04X, DE = 0x%04X, HL = 0x%04X", cpu.getBC(), cpu.getDE(), cpu.getHL())
+				}
+				if cpu.flags.Parity {
+					t.Errorf("P/V = true, want false once BC reaches zero")
+				}
+			},
+		},
+		{
+			name: "CPIR finds a byte",
+			// LD HL,0x000E; LD BC,3; LD A,0xBB; CPIR; HALT; DB 0xAA, 0xBB, 0xCC
+			program: []byte{0x21, 0x0E, 0x00, 0x01, 0x03, 0x00, 0x3E, 0xBB, 0xED, 0xB1, 0x76, 0x00, 0x00, 0x00, 0xAA, 0xBB, 0xCC},
+			check: func(t *testing.T, cpu *CPU) {
+				if !cpu.flags.Zero || cpu.getHL() != 0x0010 || cpu.getBC() != 0x0001 {
+					t.Errorf("Z = %v, HL = 0x%04X, BC = 0x%04X, want true, 0x0010, 0x0001", cpu.flags.Zero, cpu.getHL(), cpu.getBC())
+				}
+			},
+		},
+		{
+			name: "ADC HL overflow",
+			// LD HL,0x7FFF; LD BC,0x0001; OR A; ADC HL,BC; HALT
+			program: []byte{0x21, 0xFF, 0x7F, 0x01, 0x01, 0x00, 0xB7, 0xED, 0x4A, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.getHL() != 0x8000 {
+					t.Errorf("HL = 0x%04X, want 0x8000", cpu.getHL())
+				}
+				want := Flags{Sign: true, AuxCarry: true, Parity: true}
+				if cpu.flags != want {
+					t.Errorf("flags = %+v, want %+v", cpu.flags, want)
+				}
+			},
+		},
+		{
+			name: "SBC HL to zero",
+			// LD HL,0x1000; LD DE,0x0FFF; SCF; SBC HL,DE; HALT
+			program: []byte{0x21, 0x00, 0x10, 0x11, 0xFF, 0x0F, 0x37, 0xED, 0x52, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.getHL() != 0x0000 {
+					t.Errorf("HL = 0x%04X, want 0x0000", cpu.getHL())
+				}
+				want := Flags{Zero: true, AuxCarry: true, Subtract: true}
+				if cpu.flags != want {
+					t.Errorf("flags = %+v, want %+v", cpu.flags, want)
+				}
+			},
+		},
+		{
+			name: "SUB sets overflow, not parity",
+			// LD A,0x80; SUB 1; HALT
+			program: []byte{0x3E, 0x80, 0xD6, 0x01, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				want := Flags{AuxCarry: true, Parity: true, Subtract: true, X: true, Y: true}
+				if cpu.A != 0x7F || cpu.flags != want {
+					t.Errorf("A = 0x%02X, flags = %+v, want 0x7F, %+v", cpu.A, cpu.flags, want)
+				}
+			},
+		},
+		{
+			name: "XOR sets parity",
+			// LD A,0x03; XOR 0; HALT
+			program: []byte{0x3E, 0x03, 0xEE, 0x00, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				want := Flags{Parity: true}
+				if cpu.flags != want {
+					t.Errorf("flags = %+v, want %+v", cpu.flags, want)
+				}
+			},
+		},
+		{
+			name: "DAA after subtraction",
+			// LD A,0x15; SUB 0x06; DAA; HALT
+			program: []byte{0x3E, 0x15, 0xD6, 0x06, 0x27, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0x09 {
+					t.Errorf("A = 0x%02X, want 0x09", cpu.A)
+				}
+			},
+		},
+		{
+			name: "NEG",
+			// LD A,1; NEG; HALT
+			program: []byte{0x3E, 0x01, 0xED, 0x44, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0xFF || !cpu.flags.Carry || !cpu.flags.Subtract {
+					t.Errorf("A = 0x%02X, flags = %+v, want 0xFF with carry and subtract set", cpu.A, cpu.flags)
+				}
+			},
+		},
+		{
+			name: "CB rotates and bit operations",
+			// LD A,0x81; RLC A; SET 4,B; RES 0,A; BIT 7,A; HALT
+			program: []byte{0x3E, 0x81, 0xCB, 0x07, 0xCB, 0xE0, 0xCB, 0x87, 0xCB, 0x7F, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0x02 || cpu.B != 0x10 {
+					t.Errorf("A = 0x%02X, B = 0x%02X, want 0x02 and 0x10", cpu.A, cpu.B)
+				}
+				if !cpu.flags.Zero || !cpu.flags.AuxCarry || !cpu.flags.Carry {
+					t.Errorf("flags = %+v, want zero, half carry and carry set", cpu.flags)
+				}
+			},
+		},
+		{
+			name: "DDCB on (IX+d) with register copy",
+			// LD IX,0x0100; LD (IX+1),0x01; SLA (IX+1),C; HALT
+			program: []byte{0xDD, 0x21, 0x00, 0x01, 0xDD, 0x36, 0x01, 0x01, 0xDD, 0xCB, 0x01, 0x21, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				got, _ := cpu.Bus.ReadByteAt(0x0101)
+				if got != 0x02 || cpu.C != 0x02 {
+					t.Errorf("(IX+1) = 0x%02X, C = 0x%02X, want 0x02 for both", got, cpu.C)
+				}
+			},
+		},
+		{
+			name: "PUSH AF keeps N, X and Y",
+			// LD SP,0x1000; LD A,0x28; SUB 0; PUSH AF; POP BC; HALT
+			program: []byte{0x31, 0x00, 0x10, 0x3E, 0x28, 0xD6, 0x00, 0xF5, 0xC1, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.C != z80FlagN|z80FlagX|z80FlagY {
+					t.Errorf("F = 0b%08b, want 0b00101010", cpu.C)
+				}
+			},
+		},
+		{
+			name: "LD A,I and interrupt mode",
+			// LD A,0x20; LD I,A; XOR A; IM 2; EI; LD A,I; HALT
+			program: []byte{0x3E, 0x20, 0xED, 0x47, 0xAF, 0xED, 0x5E, 0xFB, 0xED, 0x57, 0x76},
+			check: func(t *testing.T, cpu *CPU) {
+				if cpu.A != 0x20 || !cpu.flags.Parity {
+					t.Errorf("A = 0x%02X, P/V = %v, want 0x20 and IFF2 copied to P/V", cpu.A, cpu.flags.Parity)
+				}
+				if cpu.InterruptMode() != 2 {
+					t.Errorf("InterruptMode() = %d, want 2", cpu.InterruptMode())
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewWithVariant(ZilogZ80)
+			cpu.Load(tt.program)
+			if tt.initCPU != nil {
+				tt.initCPU(cpu)
+			}
+
+			err := cpu.Run()
+			if err != nil {
+				t.Fatalf("error running cpu: %v", err)
+			}
+
+			tt.check(t, cpu)
+		})
+	}
+}
+
+func TestZ80Interrupts(t *testing.T) {
+	tests := []struct {
+		name          string
+		interruptMode byte
+		nmi           bool
+		wantVector    types.Word
+	}{
+		{name: "mode 1", interruptMode: 1, wantVector: 0x0038},
+		{name: "mode 2", interruptMode: 2, wantVector: 0x0300},
+		{name: "NMI", nmi: true, wantVector: 0x0066},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewWithVariant(ZilogZ80)
+			cpu.stackPointer = 0x1000
+			cpu.programCounter = 0x0100
+			cpu.interruptEnabled = true
+			cpu.halted = true
+			cpu.z80.iff2 = true
+			cpu.z80.interruptMode = tt.interruptMode
+			cpu.z80.interruptVector = 0x20
+			cpu.Bus.WriteByteAt(0x2010, 0x00) // Mode 2 vector table entry at I:0x10
+			cpu.Bus.WriteByteAt(0x2011, 0x03)
+
+			if tt.nmi {
+				cpu.NMI()
+			} else {
+				cpu.interruptPending = true
+				cpu.interruptInstruction = 0x10
+			}
+
+			serviced, err := cpu.serviceZ80Interrupts()
+			if err != nil {
+				t.Fatalf("error servicing interrupts: %v", err)
+			}
+			if !serviced {
+				t.Fatalf("serviced = false, want true")
+			}
+
+			if cpu.programCounter != tt.wantVector {
+				t.Errorf("programCounter = 0x%04X, want 0x%04X", cpu.programCounter, tt.wantVector)
+			}
+			if cpu.interruptEnabled {
+				t.Errorf("interruptEnabled = true, want false")
+			}
+			if cpu.halted {
+				t.Errorf("halted = true, want the interrupt to wake the CPU")
+			}
+			if tt.nmi != cpu.z80.iff2 {
+				t.Errorf("iff2 = %v, want %v (preserved only by NMI)", cpu.z80.iff2, tt.nmi)
+			}
+		})
+	}
+}
+
+func TestZ80NMIWakesHalt(t *testing.T) {
+	cpu := NewWithVariant(ZilogZ80)
+	cpu.Load([]byte{
+		0x31, 0x00, 0x10, // 0000 LD SP,0x1000
+		0x76, // 0003 HALT
+	})
+	cpu.Bus.WriteByteAt(0x0066, 0x76) // 0066 HALT, in the NMI routine
+
+	err := cpu.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	cpu.NMI()
+	err = cpu.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if cpu.programCounter != 0x0067 {
+		t.Errorf("programCounter = 0x%04X after Run() with an NMI, want 0x0067 past the HALT at 0x0066", cpu.programCounter)
+	}
+	if returnAddress, _ := cpu.pop(); returnAddress != 0x0004 {
+		t.Errorf("return address = 0x%04X, want 0x0004", returnAddress)
+	}
+}
@@ -0,0 +1,87 @@
+package debugger
+
+import "sync"
+
+// Console status bits, as read from the status port.
+const (
+	ConsoleInputReady  = 1 << 0 // A byte is waiting to be read from the data port
+	ConsoleOutputReady = 1 << 1 // The data port is ready for a byte, which it always is
+)
+
+// maxOutput is the amount of console output kept for display.
+const maxOutput = 64 * 1024
+
+// Console is a simple console device for programs being debugged, shown in
+// the debugger's console pane.  Bytes typed while the program is running are
+// read from its data port, and bytes written to it are shown.
+//
+// Example:
+//
+//	console := debugger.NewConsole(0x00, 0x01) // Status on port 0x00, data on 0x01
+//	cpu.Attach(console, 0x00, 0x01)
+type Console struct {
+	statusPort byte
+	dataPort   byte
+
+	mutex  sync.Mutex
+	input  []byte
+	output []byte
+}
+
+// NewConsole returns a console with its status and data on the given ports.
+func NewConsole(statusPort, dataPort byte) *Console {
+	return &Console{statusPort: statusPort, dataPort: dataPort}
+}
+
+// Type queues input for the program to read.
+func (console *Console) Type(input []byte) {
+	console.mutex.Lock()
+	defer console.mutex.Unlock()
+
+	console.input = append(console.input, input...)
+}
+
+// Output returns the console output kept so far, the last 64K written.
+func (console *Console) Output() []byte {
+	console.mutex.Lock()
+	defer console.mutex.Unlock()
+
+	return append([]byte(nil), console.output...)
+}
+
+// ReadPort reads the status, or the next byte of input, which is 0x00 if
+// there's none.
+func (console *Console) ReadPort(port byte) byte {
+	console.mutex.Lock()
+	defer console.mutex.Unlock()
+
+	if port == console.statusPort {
+		status := byte(ConsoleOutputReady)
+		if len(console.input) > 0 {
+			status |= ConsoleInputReady
+		}
+		return status
+	}
+
+	if len(console.input) == 0 {
+		return 0x00
+	}
+	value := console.input[0]
+	console.input = console.input[1:]
+	return value
+}
+
+// WritePort writes a byte of output.  Writes to the status port are ignored.
+func (console *Console) WritePort(port byte, value byte) {
+	if port != console.dataPort {
+		return
+	}
+
+	console.mutex.Lock()
+	defer console.mutex.Unlock()
+
+	console.output = append(console.output, value)
+	if len(console.output) > maxOutput {
+		console.output = console.output[len(console.output)-maxOutput:]
+	}
+}
@@ -0,0 +1,38 @@
+package debugger
+
+import "testing"
+
+func TestConsole(t *testing.T) {
+	console := NewConsole(0x10, 0x11)
+
+	if got := console.ReadPort(0x10); got != ConsoleOutputReady {
+		t.Errorf("status = 0x%02X with no input, want 0x%02X", got, ConsoleOutputReady)
+	}
+	if got := console.ReadPort(0x11); got != 0x00 {
+		t.Errorf("data = 0x%02X with no input, want 0x00", got)
+	}
+
+	console.Type([]byte("ab"))
+	for _, want := range []byte("ab") {
+		if got := console.ReadPort(0x10); got != ConsoleOutputReady|ConsoleInputReady {
+			t.Errorf("status = 0x%02X with input, want 0x%02X", got, ConsoleOutputReady|ConsoleInputReady)
+		}
+		if got := console.ReadPort(0x11); got != want {
+			t.Errorf("data = %q, want %q", got, want)
+		}
+	}
+
+	console.WritePort(0x10, 'x') // Ignored
+	console.WritePort(0x11, 'y')
+	if got := string(console.Output()); got != "y" {
+		t.Errorf("output = %q, want %q", got, "y")
+	}
+
+	for i := 0; i < maxOutput; i++ {
+		console.WritePort(0x11, 'z')
+	}
+	output := console.Output()
+	if len(output) != maxOutput || output[0] != 'z' {
+		t.Errorf("kept %d bytes of output starting %q, want the last %d", len(output), output[0], maxOutput)
+	}
+}
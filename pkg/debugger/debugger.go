@@ -0,0 +1,209 @@
+package debugger
+
+import (
+	"fmt"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Debugger is a full-screen debugger for a CPU, with panes for the registers
+// and flags, the disassembly around the program counter, memory, the stack
+// and a console.  It's driven by the keys passed to HandleInput:
+//
+//	s, space   step one instruction
+//	c          continue until a breakpoint, HLT or Ctrl-C
+//	b          toggle a breakpoint at the cursor
+//	up, down   move the cursor through the disassembly
+//	PgUp, PgDn scroll the memory pane
+//	h          show the memory at HL
+//	k          switch the memory pane to the call stack, and back
+//	q          quit
+//
+// While the CPU is running, keys other than Ctrl-C are typed into the console.
+//
+// Example:
+//
+//	debugger := debugger.New(cpu, console)
+//	debugger.HandleInput(keys)
+//	err := debugger.Run(1000)
+//	debugger.Draw(os.Stdout, width, height)
+type Debugger struct {
+	CPU     *cpu.CPU
+	Console *Console
+
+	breakpoints map[types.Word]bool
+	running     bool
+	quit        bool
+	message     string
+
+	cursor  types.Word   // Address of the disassembly line the cursor is on
+	listing types.Word   // Address of the first line of the disassembly pane
+	lines   []types.Word // Addresses of the disassembly lines last drawn
+	memory  types.Word   // Address of the first line of the memory pane
+	calls   bool         // Whether the call stack is shown in place of memory
+}
+
+// New returns a debugger for processor, stopped at its program counter.
+// console may be nil if the program has no console.
+func New(processor *cpu.CPU, console *Console) *Debugger {
+	return &Debugger{
+		CPU:         processor,
+		Console:     console,
+		breakpoints: make(map[types.Word]bool),
+		cursor:      processor.ProgramCounter(),
+		listing:     processor.ProgramCounter(),
+	}
+}
+
+// ToggleBreakpoint sets a breakpoint at address, or clears the one there.
+func (debugger *Debugger) ToggleBreakpoint(address types.Word) {
+	if debugger.breakpoints[address] {
+		delete(debugger.breakpoints, address)
+		return
+	}
+	debugger.breakpoints[address] = true
+}
+
+// Breakpoint reports whether there's a breakpoint at address.
+func (debugger *Debugger) Breakpoint(address types.Word) bool {
+	return debugger.breakpoints[address]
+}
+
+// Running reports whether the CPU is running, rather than stopped for
+// stepping.
+func (debugger *Debugger) Running() bool {
+	return debugger.running
+}
+
+// Continue runs the CPU from the program counter, with each call to Run, until
+// it reaches a breakpoint or halts.
+func (debugger *Debugger) Continue() {
+	if debugger.CPU.Halted() {
+		debugger.message = "CPU is halted"
+		return
+	}
+	debugger.running = true
+	debugger.message = ""
+}
+
+// Stop stops the CPU running.
+func (debugger *Debugger) Stop() {
+	debugger.running = false
+	debugger.cursor = debugger.CPU.ProgramCounter()
+	debugger.message = "Stopped at " + debugger.CPU.Describe(debugger.CPU.ProgramCounter())
+}
+
+// Quit reports whether the user has asked to quit.
+func (debugger *Debugger) Quit() bool {
+	return debugger.quit
+}
+
+// Step executes one instruction.
+func (debugger *Debugger) Step() error {
+	if debugger.CPU.Halted() {
+		debugger.message = "CPU is halted"
+		return nil
+	}
+
+	address := debugger.CPU.ProgramCounter()
+	err := debugger.CPU.Step()
+	debugger.cursor = debugger.CPU.ProgramCounter()
+	if err != nil {
+		debugger.running = false
+		debugger.message = fmt.Sprintf("%s: %v", debugger.CPU.Describe(address), err)
+		return err
+	}
+	if debugger.CPU.Halted() {
+		debugger.running = false
+		debugger.message = "Halted at " + debugger.CPU.Describe(debugger.CPU.ProgramCounter()-1)
+	}
+
+	return nil
+}
+
+// Run executes up to instructions instructions while the CPU is running,
+// stopping at a breakpoint, HLT or an error.  It does nothing while the CPU is
+// stopped.
+func (debugger *Debugger) Run(instructions int) error {
+	for i := 0; i < instructions && debugger.running; i++ {
+		err := debugger.Step()
+		if err != nil {
+			return err
+		}
+
+		programCounter := debugger.CPU.ProgramCounter()
+		if debugger.running && debugger.breakpoints[programCounter] {
+			debugger.running = false
+			debugger.message = "Breakpoint at " + debugger.CPU.Describe(programCounter)
+		}
+	}
+
+	return nil
+}
+
+// HandleInput handles keys read from the terminal.
+func (debugger *Debugger) HandleInput(input []byte) error {
+	for len(input) > 0 {
+		if debugger.running {
+			if input[0] == ctrlC {
+				debugger.Stop()
+			} else if debugger.Console != nil {
+				debugger.Console.Type(input[:1])
+			}
+			input = input[1:]
+			continue
+		}
+
+		var key key
+		key, input = nextKey(input)
+		err := debugger.handleKey(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleKey carries out the command for a key pressed while the CPU is stopped.
+func (debugger *Debugger) handleKey(key key) error {
+	switch key {
+	case 's', ' ':
+		return debugger.Step()
+	case 'c':
+		debugger.Continue()
+	case 'b':
+		debugger.ToggleBreakpoint(debugger.cursor)
+	case keyUp:
+		debugger.cursor = debugger.previousLine()
+	case keyDown:
+		debugger.cursor += types.Word(debugger.disassemble(debugger.cursor).length)
+	case keyPageUp:
+		debugger.memory -= memoryPage
+	case keyPageDown:
+		debugger.memory += memoryPage
+	case 'h':
+		debugger.memory = types.Word(debugger.CPU.H)<<8 | types.Word(debugger.CPU.L)
+		debugger.calls = false
+	case 'k':
+		debugger.calls = !debugger.calls
+	case 'q', ctrlC:
+		debugger.quit = true
+	}
+
+	return nil
+}
+
+// previousLine returns the address of the disassembly line above the cursor.
+// Above the first line listed, where the instruction boundaries aren't known,
+// it's the address before the cursor.
+func (debugger *Debugger) previousLine() types.Word {
+	for i, address := range debugger.lines {
+		if address == debugger.cursor && i > 0 {
+			return debugger.lines[i-1]
+		}
+	}
+
+	return debugger.cursor - 1
+}
@@ -0,0 +1,172 @@
+package debugger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// newTestDebugger returns a debugger for a program that prints "Hi", then
+// echoes a byte typed into the console and halts.
+func newTestDebugger(t *testing.T) *Debugger {
+	program := []byte{
+		0x3E, 0x48, // 0000 MVI A,'H'
+		0xD3, 0x01, // 0002 OUT 0x01
+		0x3E, 0x69, // 0004 MVI A,'i'
+		0xD3, 0x01, // 0006 OUT 0x01
+		0xDB, 0x00, // 0008 IN 0x00
+		0xE6, 0x01, // 000A ANI 0x01
+		0xCA, 0x08, 0x00, // 000C JZ 0x0008
+		0xDB, 0x01, // 000F IN 0x01
+		0xD3, 0x01, // 0011 OUT 0x01
+		0x76, // 0013 HLT
+	}
+
+	processor := cpu.New()
+	err := processor.Load(program)
+	if err != nil {
+		t.Fatalf("could not load program: %v", err)
+	}
+	console := NewConsole(0x00, 0x01)
+	processor.Attach(console, 0x00, 0x01)
+
+	return New(processor, console)
+}
+
+func TestStep(t *testing.T) {
+	debugger := newTestDebugger(t)
+
+	err := debugger.HandleInput([]byte("s "))
+	if err != nil {
+		t.Fatalf("could not step: %v", err)
+	}
+	if debugger.CPU.ProgramCounter() != 0x0004 {
+		t.Errorf("program counter = 0x%04X after two steps, want 0x0004", debugger.CPU.ProgramCounter())
+	}
+	if got := string(debugger.Console.Output()); got != "H" {
+		t.Errorf("console output = %q, want %q", got, "H")
+	}
+}
+
+func TestContinue(t *testing.T) {
+	debugger := newTestDebugger(t)
+	debugger.ToggleBreakpoint(0x0006)
+
+	debugger.HandleInput([]byte("c"))
+	if !debugger.Running() {
+		t.Fatalf("Running() = false after c, want true")
+	}
+	debugger.Run(100)
+	if debugger.Running() || debugger.CPU.ProgramCounter() != 0x0006 {
+		t.Fatalf("Running() = %v, program counter = 0x%04X, want false at the breakpoint at 0x0006",
+			debugger.Running(), debugger.CPU.ProgramCounter())
+	}
+	if !strings.Contains(debugger.statusLine(), "Breakpoint at 0x0006") {
+		t.Errorf("status line = %q, want it to report the breakpoint", debugger.statusLine())
+	}
+
+	// Continuing from a breakpoint leaves it, then waits for input
+	debugger.HandleInput([]byte("c"))
+	debugger.Run(100)
+	if !debugger.Running() {
+		t.Fatalf("Running() = false while waiting for input, want true")
+	}
+
+	debugger.HandleInput([]byte("q")) // Typed into the console, as the CPU is running
+	debugger.Run(100)
+	if debugger.Running() || !debugger.CPU.Halted() {
+		t.Errorf("Running() = %v, Halted() = %v after input, want false, true", debugger.Running(), debugger.CPU.Halted())
+	}
+	if debugger.Quit() {
+		t.Errorf("Quit() = true after typing q into the console, want false")
+	}
+	if got := string(debugger.Console.Output()); got != "Hiq" {
+		t.Errorf("console output = %q, want %q", got, "Hiq")
+	}
+	if !strings.Contains(debugger.statusLine(), "Halted at 0x0013") {
+		t.Errorf("status line = %q, want it to report the halt", debugger.statusLine())
+	}
+
+	debugger.HandleInput([]byte("c"))
+	if debugger.Running() {
+		t.Errorf("Running() = true after continuing a halted CPU, want false")
+	}
+}
+
+func TestStop(t *testing.T) {
+	debugger := newTestDebugger(t)
+	debugger.HandleInput([]byte("c"))
+	debugger.Run(100)
+
+	debugger.HandleInput([]byte{ctrlC})
+	if debugger.Running() {
+		t.Fatalf("Running() = true after Ctrl-C, want false")
+	}
+	if got := debugger.CPU.ProgramCounter(); got < 0x0008 || got > 0x000C {
+		t.Errorf("program counter = 0x%04X, want it in the input loop", got)
+	}
+
+	debugger.HandleInput([]byte("q"))
+	if !debugger.Quit() {
+		t.Errorf("Quit() = false after q, want true")
+	}
+}
+
+func TestCursor(t *testing.T) {
+	debugger := newTestDebugger(t)
+	debugger.Render(MinWidth, MinHeight)
+
+	tests := []struct {
+		keys string
+		want types.Word
+	}{
+		{keys: "\033[B", want: 0x0002},
+		{keys: "\033[B\033OB", want: 0x0006},
+		{keys: "\033[A", want: 0x0004},
+		{keys: "\033[A\033[A", want: 0x0000},
+		{keys: "\033[A", want: 0xFFFF},
+	}
+
+	for _, test := range tests {
+		debugger.HandleInput([]byte(test.keys))
+		debugger.Render(MinWidth, MinHeight)
+		if debugger.cursor != test.want {
+			t.Errorf("cursor = 0x%04X after %q, want 0x%04X", debugger.cursor, test.keys, test.want)
+		}
+	}
+
+	debugger.HandleInput([]byte("\033[B\033[Bb"))
+	if !debugger.Breakpoint(0x0002) {
+		t.Errorf("Breakpoint(0x0002) = false after b, want true")
+	}
+	debugger.HandleInput([]byte("b"))
+	if debugger.Breakpoint(0x0002) {
+		t.Errorf("Breakpoint(0x0002) = true after a second b, want false")
+	}
+}
+
+func TestNextKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		want     key
+		wantRest string
+	}{
+		{input: "s", want: 's', wantRest: ""},
+		{input: "cq", want: 'c', wantRest: "q"},
+		{input: "\033[A", want: keyUp, wantRest: ""},
+		{input: "\033OBs", want: keyDown, wantRest: "s"},
+		{input: "\033[5~", want: keyPageUp, wantRest: ""},
+		{input: "\033[6~c", want: keyPageDown, wantRest: "c"},
+		{input: "\033[1;5C", want: keyUnknown, wantRest: ""},
+		{input: "\033", want: escape, wantRest: ""},
+	}
+
+	for _, test := range tests {
+		got, rest := nextKey([]byte(test.input))
+		if got != test.want || string(rest) != test.wantRest {
+			t.Errorf("nextKey(%q) = %d, %q, want %d, %q", test.input, got, rest, test.want, test.wantRest)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package debugger
+
+// key is a key read from the terminal: a byte, or one of the special keys
+// sent as an escape sequence.
+type key int
+
+const (
+	keyUp key = 0x100 + iota
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyUnknown
+)
+
+const (
+	ctrlC  = 0x03
+	escape = 0x1B
+)
+
+// escapeSequences maps the escape sequences of the special keys, after the
+// escape, to their key.
+var escapeSequences = map[string]key{
+	"[A":  keyUp,
+	"OA":  keyUp,
+	"[B":  keyDown,
+	"OB":  keyDown,
+	"[5~": keyPageUp,
+	"[6~": keyPageDown,
+}
+
+// nextKey returns the first key in input, and the rest of input.  An escape
+// sequence for a key that isn't handled is returned as keyUnknown.
+func nextKey(input []byte) (key, []byte) {
+	if input[0] != escape || len(input) == 1 {
+		return key(input[0]), input[1:]
+	}
+
+	// A sequence is an escape, a [ or O, any parameters and a final letter or ~
+	end := 2
+	for end < len(input) && (input[end] >= '0' && input[end] <= '9' || input[end] == ';') {
+		end++
+	}
+	if end < len(input) {
+		end++
+	}
+	if end > len(input) {
+		end = len(input)
+	}
+
+	sequence, ok := escapeSequences[string(input[1:end])]
+	if !ok {
+		return keyUnknown, input[end:]
+	}
+
+	return sequence, input[end:]
+}
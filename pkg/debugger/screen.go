@@ -0,0 +1,349 @@
+package debugger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// The smallest terminal the debugger can be drawn in.
+const (
+	MinWidth  = 80
+	MinHeight = 24
+)
+
+const (
+	registerRows = 5 // Rows of the registers and stack panes, including their titles
+	memoryWidth  = 8 // Bytes shown on each line of the memory pane
+	memoryPage   = 0x100
+)
+
+// line is a disassembled instruction.
+type line struct {
+	raw    string
+	text   string
+	length int
+}
+
+// disassemble disassembles the instruction at address.  An instruction that
+// can't be disassembled is shown as a DB of its first byte.
+func (debugger *Debugger) disassemble(address types.Word) line {
+	text, length, err := debugger.CPU.Disassemble(address)
+	if err != nil {
+		value, err := debugger.CPU.Bus.ReadByteAt(address)
+		if err != nil {
+			return line{raw: "??", text: "??", length: 1}
+		}
+		return line{raw: fmt.Sprintf("%02X", value), text: fmt.Sprintf("DB 0x%02X", value), length: 1}
+	}
+
+	raw := make([]string, length)
+	for i := range raw {
+		value, _ := debugger.CPU.Bus.ReadByteAt(address + types.Word(i))
+		raw[i] = fmt.Sprintf("%02X", value)
+	}
+
+	return line{raw: strings.Join(raw, " "), text: text, length: length}
+}
+
+// Render returns the screen as lines of plain text, width characters wide.
+func (debugger *Debugger) Render(width, height int) []string {
+	if width < MinWidth || height < MinHeight {
+		return []string{fmt.Sprintf("The terminal must be at least %dx%d", MinWidth, MinHeight)}
+	}
+
+	left := width / 2
+	right := width - left - 1
+	consoleRows := (height - registerRows - 1) / 3
+	middleRows := height - registerRows - consoleRows - 1
+
+	var screen []string
+	screen = append(screen, join(debugger.registersPane(left), debugger.stackPane(right), left, right)...)
+	memory := debugger.memoryPane(right, middleRows)
+	if debugger.calls {
+		memory = debugger.callsPane(right, middleRows)
+	}
+	screen = append(screen, join(debugger.disassemblyPane(left, middleRows), memory, left, right)...)
+	screen = append(screen, debugger.consolePane(width, consoleRows)...)
+	screen = append(screen, pad(debugger.statusLine(), width))
+
+	return screen
+}
+
+// Draw draws the screen to a terminal, from its top left corner.
+func (debugger *Debugger) Draw(w io.Writer, width, height int) error {
+	var screen bytes.Buffer
+	screen.WriteString("\033[H")
+	for i, text := range debugger.Render(width, height) {
+		if i > 0 {
+			screen.WriteString("\r\n")
+		}
+		screen.WriteString(text)
+		screen.WriteString("\033[K")
+	}
+	screen.WriteString("\033[J")
+
+	_, err := w.Write(screen.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not draw screen: %v", err)
+	}
+
+	return nil
+}
+
+func (debugger *Debugger) registersPane(width int) []string {
+	cpu := debugger.CPU
+	flags := cpu.Flags()
+
+	return []string{
+		title("Registers", width),
+		fmt.Sprintf(" A  %02X    S=%d Z=%d AC=%d P=%d C=%d", cpu.A, bit(flags.Sign), bit(flags.Zero), bit(flags.AuxCarry), bit(flags.Parity), bit(flags.Carry)),
+		fmt.Sprintf(" BC %02X%02X  DE %02X%02X  HL %02X%02X", cpu.B, cpu.C, cpu.D, cpu.E, cpu.H, cpu.L),
+		fmt.Sprintf(" SP %04X  PC %04X  INTE %d  HLT %d", cpu.StackPointer(), cpu.ProgramCounter(), bit(cpu.InterruptsEnabled()), bit(cpu.Halted())),
+		fmt.Sprintf(" %v  %d cycles", cpu.Variant(), cpu.Cycles()),
+	}
+}
+
+// stackPane shows the words at the top of the stack, from the stack pointer up.
+// With symbols, words near a label, such as return addresses, are described by
+// it.
+func (debugger *Debugger) stackPane(width int) []string {
+	pane := []string{title("Stack", width)}
+	address := debugger.CPU.StackPointer()
+	for i := 1; i < registerRows; i++ {
+		low, lowErr := debugger.CPU.Bus.ReadByteAt(address)
+		high, highErr := debugger.CPU.Bus.ReadByteAt(address + 1)
+		if lowErr != nil || highErr != nil {
+			pane = append(pane, fmt.Sprintf(" %04X  ????", address))
+			address += 2
+			continue
+		}
+
+		word := types.Word(high)<<8 | types.Word(low)
+		text := fmt.Sprintf(" %04X  %04X", address, word)
+		if debugger.CPU.Symbols != nil {
+			if description := debugger.CPU.Describe(word); description != fmt.Sprintf("0x%04X", word) {
+				text += "  " + description
+			}
+		}
+		pane = append(pane, text)
+		address += 2
+	}
+
+	return pane
+}
+
+// disassemblyPane lists the instructions from the top of the pane, scrolling
+// it to keep the cursor in view.  Each line is marked with a * if it has a
+// breakpoint, and a > if it's at the program counter or a = if it's at the
+// cursor.  With symbols, lines show their labels, and the title shows where
+// the cursor is.
+func (debugger *Debugger) disassemblyPane(width, rows int) []string {
+	debugger.scrollListing(rows - 1)
+
+	name := "Disassembly"
+	if debugger.CPU.Symbols != nil {
+		name += " " + debugger.CPU.Describe(debugger.cursor)
+	}
+
+	pane := []string{title(name, width)}
+	for _, address := range debugger.lines {
+		breakpoint, marker := ' ', ' '
+		if debugger.breakpoints[address] {
+			breakpoint = '*'
+		}
+		if address == debugger.cursor {
+			marker = '='
+		}
+		if address == debugger.CPU.ProgramCounter() {
+			marker = '>'
+		}
+
+		line := debugger.disassemble(address)
+		text := line.text
+		if debugger.CPU.Symbols != nil {
+			if label, ok := debugger.CPU.Symbols.Label(address); ok {
+				text = label + ": " + text
+			}
+		}
+		pane = append(pane, fmt.Sprintf("%c%c%04X  %-9s %s", breakpoint, marker, address, line.raw, text))
+	}
+
+	return pane
+}
+
+// scrollListing lists rows lines of disassembly from the top of the pane,
+// scrolling down a line if the cursor is just past the bottom, or to the
+// cursor if it's anywhere else out of view.
+func (debugger *Debugger) scrollListing(rows int) {
+	debugger.lines = debugger.listFrom(debugger.listing, rows)
+	for _, address := range debugger.lines {
+		if address == debugger.cursor {
+			return
+		}
+	}
+
+	last := debugger.lines[len(debugger.lines)-1]
+	if last+types.Word(debugger.disassemble(last).length) == debugger.cursor && rows > 1 {
+		debugger.listing = debugger.lines[1]
+	} else {
+		debugger.listing = debugger.cursor
+	}
+	debugger.lines = debugger.listFrom(debugger.listing, rows)
+}
+
+// listFrom returns the addresses of rows instructions starting at address.
+func (debugger *Debugger) listFrom(address types.Word, rows int) []types.Word {
+	lines := make([]types.Word, rows)
+	for i := range lines {
+		lines[i] = address
+		address += types.Word(debugger.disassemble(address).length)
+	}
+
+	return lines
+}
+
+func (debugger *Debugger) memoryPane(width, rows int) []string {
+	pane := []string{title("Memory", width)}
+	address := debugger.memory
+	for i := 1; i < rows; i++ {
+		var hex, text strings.Builder
+		for j := 0; j < memoryWidth; j++ {
+			value, err := debugger.CPU.Bus.ReadByteAt(address + types.Word(j))
+			if err != nil {
+				hex.WriteString(" ??")
+				text.WriteByte(' ')
+				continue
+			}
+			fmt.Fprintf(&hex, " %02X", value)
+			if value >= 0x20 && value < 0x7F {
+				text.WriteByte(value)
+			} else {
+				text.WriteByte('.')
+			}
+		}
+		pane = append(pane, fmt.Sprintf("%04X %s  %s", address, hex.String(), text.String()))
+		address += memoryWidth
+	}
+
+	return pane
+}
+
+// callsPane shows the CPU's CallStack, innermost call first, and the last
+// return that didn't match its call.
+func (debugger *Debugger) callsPane(width, rows int) []string {
+	pane := make([]string, 1, rows)
+	stack := debugger.CPU.CallStack
+	if stack == nil {
+		pane[0] = title("Calls", width)
+		pane = append(pane, " No call stack")
+		return pane[:rows]
+	}
+
+	count, mismatches := stack.Mismatches()
+	pane[0] = title("Calls", width)
+	if count > 0 {
+		pane[0] = title(fmt.Sprintf("Calls (%d bad returns)", count), width)
+	}
+
+	calls := stack.Calls()
+	if len(calls) == 0 {
+		pane = append(pane, " No calls")
+	}
+	for i := len(calls) - 1; i >= 0 && len(pane) < rows-1; i-- {
+		call := calls[i]
+		from := "from"
+		if call.Interrupt {
+			from = "interrupting"
+		}
+		pane = append(pane, fmt.Sprintf(" %s %s %s", debugger.CPU.Describe(call.To), from, debugger.CPU.Describe(call.From)))
+	}
+	if count > 0 {
+		last := mismatches[len(mismatches)-1]
+		pane = append(pane, fmt.Sprintf(" Bad return at %s to %s", debugger.CPU.Describe(last.PC), debugger.CPU.Describe(last.To)))
+	}
+
+	return pane[:rows] // Blank lines fill the rest of the pane
+}
+
+// consolePane shows the last lines of console output, wrapped to the width of
+// the screen.
+func (debugger *Debugger) consolePane(width, rows int) []string {
+	var lines []string
+	if debugger.Console != nil {
+		output := strings.ReplaceAll(string(debugger.Console.Output()), "\r", "")
+		for _, text := range strings.Split(output, "\n") {
+			for len(text) > width {
+				lines = append(lines, text[:width])
+				text = text[width:]
+			}
+			lines = append(lines, text)
+		}
+	}
+	if len(lines) > rows-1 {
+		lines = lines[len(lines)-(rows-1):]
+	}
+	for len(lines) < rows-1 {
+		lines = append(lines, "")
+	}
+	for i := range lines {
+		lines[i] = pad(lines[i], width)
+	}
+
+	return append([]string{title("Console", width)}, lines...)
+}
+
+func (debugger *Debugger) statusLine() string {
+	if debugger.running {
+		return " RUNNING | Ctrl-C stop, other keys are typed into the console"
+	}
+
+	status := " STOPPED"
+	if debugger.message != "" {
+		status += ": " + debugger.message
+	}
+
+	return status + " | s step  c continue  b breakpoint  up/down cursor  PgUp/PgDn memory  h memory at HL  k calls  q quit"
+}
+
+// title returns a pane's title line.
+func title(name string, width int) string {
+	return pad("-- "+name+" "+strings.Repeat("-", width), width)
+}
+
+// join places two panes side by side, separated by a bar.
+func join(left, right []string, leftWidth, rightWidth int) []string {
+	joined := make([]string, len(left))
+	for i := range left {
+		joined[i] = pad(left[i], leftWidth) + "|" + pad(right[i], rightWidth)
+	}
+
+	return joined
+}
+
+// pad pads or truncates text to width characters, replacing anything but
+// printable ASCII so it can't disturb the terminal.
+func pad(text string, width int) string {
+	padded := []byte(text)
+	for i, value := range padded {
+		if value < 0x20 || value >= 0x7F {
+			padded[i] = '.'
+		}
+	}
+	if len(padded) > width {
+		return string(padded[:width])
+	}
+
+	return string(padded) + strings.Repeat(" ", width-len(padded))
+}
+
+func bit(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}
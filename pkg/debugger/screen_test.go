@@ -0,0 +1,184 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/symbols"
+)
+
+func TestRender(t *testing.T) {
+	debugger := newTestDebugger(t)
+	debugger.ToggleBreakpoint(0x0006)
+	debugger.HandleInput([]byte("ss\033[B"))
+	debugger.CPU.H, debugger.CPU.L = 0x00, 0x08
+	debugger.HandleInput([]byte("h"))
+
+	for _, size := range [][2]int{{MinWidth, MinHeight}, {132, 50}} {
+		screen := debugger.Render(size[0], size[1])
+		if len(screen) != size[1] {
+			t.Errorf("rendered %d lines at %dx%d, want %d", len(screen), size[0], size[1], size[1])
+		}
+		for i, line := range screen {
+			if len(line) != size[0] {
+				t.Errorf("line %d is %d characters wide at %dx%d, want %d", i, len(line), size[0], size[1], size[0])
+			}
+		}
+
+		text := strings.Join(screen, "\n")
+		for _, want := range []string{
+			" A  48    S=0 Z=0 AC=0 P=0 C=0",
+			" SP 0000  PC 0004  INTE 0  HLT 0",
+			"  0000  3E 48     MVI A,0x48",
+			" >0004  3E 69     MVI A,0x69",
+			"*=0006  D3 01     OUT 0x01",
+			"|0008  DB 00 E6 01 CA 08 00 DB  ........",
+			"-- Console ---",
+			"\nH ",
+			"STOPPED",
+		} {
+			if !strings.Contains(text, want) {
+				t.Errorf("screen at %dx%d doesn't contain %q:\n%s", size[0], size[1], want, text)
+			}
+		}
+	}
+}
+
+func TestRenderSymbols(t *testing.T) {
+	debugger := newTestDebugger(t)
+	table := symbols.New()
+	table.AddLabel("WAIT", 0x0008)
+	table.AddLine(0x0008, symbols.Location{File: "echo.asm", Line: 5})
+	debugger.CPU.Symbols = table
+	debugger.cursor = 0x0008
+	debugger.ToggleBreakpoint(0x0008)
+	debugger.CPU.SetStackPointer(0x0100)
+	debugger.CPU.Bus.WriteByteAt(0x0100, 0x0B)
+
+	text := strings.Join(debugger.Render(MinWidth, MinHeight), "\n")
+	for _, want := range []string{
+		"-- Disassembly WAIT (echo.asm:5) --",
+		" 0100  000B  WAIT+3 ",
+		" 0102  0000 ",
+		"*=0008  DB 00     WAIT: IN 0x00",
+		"000C  CA 08 00  JZ WAIT",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("screen doesn't contain %q:\n%s", want, text)
+		}
+	}
+
+	debugger.HandleInput([]byte("c"))
+	debugger.Run(100)
+	if !strings.Contains(debugger.statusLine(), "Breakpoint at WAIT (echo.asm:5)") {
+		t.Errorf("status line = %q, want it to report the breakpoint by label", debugger.statusLine())
+	}
+}
+
+func TestRenderCalls(t *testing.T) {
+	processor := cpu.New()
+	processor.Load([]byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0xCD, 0x07, 0x00, // 0003 CALL 0x0007
+		0x76,             // 0006 HLT
+		0x21, 0x0C, 0x00, // 0007 LXI H,0x000C
+		0xE5, // 000A PUSH H
+		0xC9, // 000B RET
+		0x76, // 000C HLT
+	})
+	debugger := New(processor, nil)
+	debugger.HandleInput([]byte("k"))
+
+	text := strings.Join(debugger.Render(MinWidth, MinHeight), "\n")
+	if !strings.Contains(text, "-- Calls --") || !strings.Contains(text, " No call stack") {
+		t.Errorf("screen doesn't show there's no call stack:\n%s", text)
+	}
+
+	processor.CallStack = cpu.NewCallStack()
+	debugger.HandleInput([]byte("ss"))
+	text = strings.Join(debugger.Render(MinWidth, MinHeight), "\n")
+	if !strings.Contains(text, " 0x0007 from 0x0003") {
+		t.Errorf("screen doesn't show the call:\n%s", text)
+	}
+
+	debugger.HandleInput([]byte("sss"))
+	text = strings.Join(debugger.Render(MinWidth, MinHeight), "\n")
+	for _, want := range []string{"-- Calls (1 bad returns) --", " 0x0007 from 0x0003", " Bad return at 0x000B to 0x000C"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("screen doesn't contain %q:\n%s", want, text)
+		}
+	}
+
+	debugger.HandleInput([]byte("k"))
+	text = strings.Join(debugger.Render(MinWidth, MinHeight), "\n")
+	if !strings.Contains(text, "-- Memory --") {
+		t.Errorf("screen doesn't show memory after switching back:\n%s", text)
+	}
+}
+
+func TestRenderTooSmall(t *testing.T) {
+	debugger := newTestDebugger(t)
+	screen := debugger.Render(MinWidth-1, MinHeight)
+	if len(screen) != 1 || !strings.Contains(screen[0], "at least 80x24") {
+		t.Errorf("Render() = %q, want a message that the terminal is too small", screen)
+	}
+}
+
+func TestScroll(t *testing.T) {
+	debugger := newTestDebugger(t)
+
+	// Stepping past the bottom of the listing scrolls it
+	rows := MinHeight - registerRows - (MinHeight-registerRows-1)/3 - 1 - 1
+	for i := 0; i < rows; i++ {
+		debugger.HandleInput([]byte("\033[B"))
+		debugger.Render(MinWidth, MinHeight)
+	}
+	if debugger.listing != 0x0002 {
+		t.Errorf("listing starts at 0x%04X after moving past the bottom, want 0x0002", debugger.listing)
+	}
+
+	// Jumping elsewhere lists from the cursor
+	debugger.cursor = 0x1000
+	debugger.Render(MinWidth, MinHeight)
+	if debugger.listing != 0x1000 {
+		t.Errorf("listing starts at 0x%04X after jumping to 0x1000, want 0x1000", debugger.listing)
+	}
+}
+
+func TestConsoleWrap(t *testing.T) {
+	debugger := newTestDebugger(t)
+	for _, value := range []byte(strings.Repeat("x", MinWidth+5) + "\r\nend\x07") {
+		debugger.Console.WritePort(0x01, value)
+	}
+
+	screen := debugger.Render(MinWidth, MinHeight)
+	var console []string
+	for i, line := range screen {
+		if strings.HasPrefix(line, "-- Console") {
+			console = screen[i+1:]
+		}
+	}
+	want := []string{strings.Repeat("x", MinWidth), "xxxxx", "end."}
+	for i := range want {
+		if strings.TrimRight(console[i], " ") != want[i] {
+			t.Errorf("console line %d = %q, want %q", i, console[i], want[i])
+		}
+	}
+}
+
+func TestDraw(t *testing.T) {
+	debugger := newTestDebugger(t)
+
+	var buffer bytes.Buffer
+	err := debugger.Draw(&buffer, MinWidth, MinHeight)
+	if err != nil {
+		t.Fatalf("could not draw: %v", err)
+	}
+
+	got := buffer.String()
+	if !strings.HasPrefix(got, "\033[H") || strings.Count(got, "\r\n") != MinHeight-1 {
+		t.Errorf("Draw() wrote %q, want the cursor homed and %d lines", got, MinHeight)
+	}
+}
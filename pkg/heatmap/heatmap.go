@@ -0,0 +1,201 @@
+package heatmap
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Counts is how many times an address, or a region of them, was read, written
+// and fetched as part of an instruction.
+type Counts struct {
+	Reads   uint64
+	Writes  uint64
+	Fetches uint64
+}
+
+// Total returns the number of accesses of any kind.
+func (counts Counts) Total() uint64 {
+	return counts.Reads + counts.Writes + counts.Fetches
+}
+
+// Class returns whether the accesses counted were to code, data or both.
+func (counts Counts) Class() Class {
+	data := counts.Reads+counts.Writes > 0
+	switch {
+	case counts.Fetches > 0 && data:
+		return Mixed
+	case counts.Fetches > 0:
+		return Code
+	case data:
+		return Data
+	}
+
+	return Unused
+}
+
+func (counts *Counts) add(other Counts) {
+	counts.Reads += other.Reads
+	counts.Writes += other.Writes
+	counts.Fetches += other.Fetches
+}
+
+// Class is what an address was used for.
+type Class int
+
+const (
+	Unused Class = iota // Never accessed
+	Code                // Only fetched as part of an instruction
+	Data                // Only read or written
+	Mixed               // Both, as with self-modifying code or tables among code
+)
+
+var classNames = map[Class]string{
+	Unused: "unused",
+	Code:   "code",
+	Data:   "data",
+	Mixed:  "mixed",
+}
+
+func (class Class) String() string {
+	if name, ok := classNames[class]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Class(%d)", int(class))
+}
+
+// Heatmap wraps a Bus, counting the reads, writes and instruction fetches of
+// each address to show a program's memory access patterns.  The CPU fetches
+// through it as a cpu.Fetcher, and traces, disassembly and coverage peek
+// through it as a cpu.Peeker without being counted.
+//
+// Other reads through it are counted, so tools that aren't part of the
+// program, such as memory dumps, should be given the wrapped Bus.
+//
+// Example:
+//
+//	heat := heatmap.New(processor.Bus)
+//	processor.Bus = heat
+//	err := processor.Run()
+//	processor.Bus = heat.Bus
+//	heat.WriteReport(os.Stdout, 10)
+type Heatmap struct {
+	Bus cpu.Bus
+
+	counts []Counts
+}
+
+// New returns a heatmap of the accesses to bus.
+func New(bus cpu.Bus) *Heatmap {
+	return &Heatmap{Bus: bus, counts: make([]Counts, 0x10000)}
+}
+
+// ReadByteAt counts a read of address, and reads it from the Bus.
+func (heatmap *Heatmap) ReadByteAt(address types.Word) (byte, error) {
+	heatmap.counts[address].Reads++
+	return heatmap.Bus.ReadByteAt(address)
+}
+
+// WriteByteAt counts a write to address, and writes it to the Bus.
+func (heatmap *Heatmap) WriteByteAt(address types.Word, data byte) error {
+	heatmap.counts[address].Writes++
+	return heatmap.Bus.WriteByteAt(address, data)
+}
+
+// FetchByteAt counts a fetch of address as part of an instruction, and reads
+// it from the Bus.
+func (heatmap *Heatmap) FetchByteAt(address types.Word) (byte, error) {
+	heatmap.counts[address].Fetches++
+	return heatmap.Bus.ReadByteAt(address)
+}
+
+// PeekByteAt reads address from the Bus without counting it, for tools that
+// aren't part of the program.
+func (heatmap *Heatmap) PeekByteAt(address types.Word) (byte, error) {
+	if peeker, ok := heatmap.Bus.(cpu.Peeker); ok {
+		return peeker.PeekByteAt(address)
+	}
+
+	return heatmap.Bus.ReadByteAt(address)
+}
+
+// Counts returns the accesses to address.
+func (heatmap *Heatmap) Counts(address types.Word) Counts {
+	return heatmap.counts[address]
+}
+
+// Total returns the accesses to all addresses.
+func (heatmap *Heatmap) Total() Counts {
+	var total Counts
+	for _, counts := range heatmap.counts {
+		total.add(counts)
+	}
+
+	return total
+}
+
+// Reset clears the counts, to measure part of a run.
+func (heatmap *Heatmap) Reset() {
+	clear(heatmap.counts)
+}
+
+// Region is a range of addresses and the accesses to them.
+type Region struct {
+	Start types.Word
+	Size  int
+	Counts
+}
+
+// End returns the last address in the region.
+func (region Region) End() types.Word {
+	return region.Start + types.Word(region.Size-1)
+}
+
+// Hottest returns up to limit regions of size bytes that were accessed, most
+// accesses first.  The size must divide 64K, such as 256.
+func (heatmap *Heatmap) Hottest(size, limit int) ([]Region, error) {
+	if size <= 0 || 0x10000%size != 0 {
+		return nil, fmt.Errorf("region size %d does not divide 64K", size)
+	}
+
+	var regions []Region
+	for start := 0; start < 0x10000; start += size {
+		region := Region{Start: types.Word(start), Size: size}
+		for _, counts := range heatmap.counts[start : start+size] {
+			region.add(counts)
+		}
+		if region.Total() > 0 {
+			regions = append(regions, region)
+		}
+	}
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].Total() > regions[j].Total()
+	})
+
+	return regions[:min(limit, len(regions))], nil
+}
+
+// Spans returns the runs of addresses used for the same Class, in address
+// order, leaving out those that weren't used.
+func (heatmap *Heatmap) Spans() []Region {
+	var spans []Region
+	for address, counts := range heatmap.counts {
+		class := counts.Class()
+		if class == Unused {
+			continue
+		}
+
+		last := len(spans) - 1
+		if last >= 0 && spans[last].Class() == class && int(spans[last].Start)+spans[last].Size == address {
+			spans[last].Size++
+			spans[last].add(counts)
+			continue
+		}
+		spans = append(spans, Region{Start: types.Word(address), Size: 1, Counts: counts})
+	}
+
+	return spans
+}
@@ -0,0 +1,241 @@
+package heatmap
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"image/png"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/coverage"
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// run runs a program that adds up a table of 4 bytes at 0x0100 into a total
+// at 0x0200, and patches its own MVI before running it.
+func run(t *testing.T) *Heatmap {
+	t.Helper()
+
+	return runWith(t, func(*cpu.CPU) {})
+}
+
+// runWith runs the program of run, after setup attaches any tools to the CPU.
+func runWith(t *testing.T, setup func(processor *cpu.CPU)) *Heatmap {
+	t.Helper()
+
+	processor := cpu.New()
+	processor.Load([]byte{
+		0x21, 0x00, 0x01, // 0000 LXI H,0x0100
+		0x0E, 0x04, // 0003 MVI C,4
+		0xAF,             // 0005 XRA A
+		0x86,             // 0006 LOOP: ADD M
+		0x23,             // 0007 INX H
+		0x0D,             // 0008 DCR C
+		0xC2, 0x06, 0x00, // 0009 JNZ LOOP
+		0x32, 0x00, 0x02, // 000C STA 0x0200
+		0x32, 0x13, 0x00, // 000F STA 0x0013
+		0x3E, 0x00, // 0012 MVI A,0 (patched)
+		0x76, // 0014 HLT
+	})
+	processor.Bus.WriteByteAt(0x0100, 1)
+	processor.Bus.WriteByteAt(0x0101, 2)
+	processor.Bus.WriteByteAt(0x0102, 3)
+	processor.Bus.WriteByteAt(0x0103, 4)
+
+	setup(processor)
+	heat := New(processor.Bus)
+	processor.Bus = heat
+	err := processor.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	processor.Bus = heat.Bus
+
+	return heat
+}
+
+func TestHeatmap(t *testing.T) {
+	heat := run(t)
+
+	tests := []struct {
+		address   types.Word
+		want      Counts
+		wantClass Class
+	}{
+		{address: 0x0000, want: Counts{Fetches: 1}, wantClass: Code},
+		{address: 0x0006, want: Counts{Fetches: 4}, wantClass: Code},
+		{address: 0x000A, want: Counts{Fetches: 4}, wantClass: Code},
+		{address: 0x0013, want: Counts{Writes: 1, Fetches: 1}, wantClass: Mixed},
+		{address: 0x0101, want: Counts{Reads: 1}, wantClass: Data},
+		{address: 0x0200, want: Counts{Writes: 1}, wantClass: Data},
+		{address: 0x0300, want: Counts{}, wantClass: Unused},
+	}
+	for _, test := range tests {
+		got := heat.Counts(test.address)
+		if got != test.want {
+			t.Errorf("Counts(0x%04X) = %+v, want %+v", test.address, got, test.want)
+		}
+		if got.Class() != test.wantClass {
+			t.Errorf("Counts(0x%04X).Class() = %v, want %v", test.address, got.Class(), test.wantClass)
+		}
+	}
+
+	if got, want := heat.Total(), (Counts{Reads: 4, Writes: 2, Fetches: 39}); got != want {
+		t.Errorf("Total() = %+v, want %+v", got, want)
+	}
+
+	hottest, err := heat.Hottest(256, 2)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	wantHottest := []Region{
+		{Start: 0x0000, Size: 256, Counts: Counts{Writes: 1, Fetches: 39}},
+		{Start: 0x0100, Size: 256, Counts: Counts{Reads: 4}},
+	}
+	if !reflect.DeepEqual(hottest, wantHottest) {
+		t.Errorf("Hottest(256, 2) = %+v, want %+v", hottest, wantHottest)
+	}
+	if _, err := heat.Hottest(300, 2); err == nil {
+		t.Errorf("expected an error for a region size that doesn't divide 64K, but got none")
+	}
+
+	wantSpans := []Region{
+		{Start: 0x0000, Size: 19, Counts: Counts{Fetches: 37}},
+		{Start: 0x0013, Size: 1, Counts: Counts{Writes: 1, Fetches: 1}},
+		{Start: 0x0014, Size: 1, Counts: Counts{Fetches: 1}},
+		{Start: 0x0100, Size: 4, Counts: Counts{Reads: 4}},
+		{Start: 0x0200, Size: 1, Counts: Counts{Writes: 1}},
+	}
+	if got := heat.Spans(); !reflect.DeepEqual(got, wantSpans) {
+		t.Errorf("Spans() = %+v, want %+v", got, wantSpans)
+	}
+
+	heat.Reset()
+	if got := heat.Total(); got != (Counts{}) {
+		t.Errorf("Total() = %+v after Reset(), want none", got)
+	}
+}
+
+func TestHeatmapIgnoresTools(t *testing.T) {
+	want := run(t)
+	heat := runWith(t, func(processor *cpu.CPU) {
+		processor.Trace = io.Discard
+		processor.Profiler = coverage.New(processor)
+	})
+
+	if got := heat.Total(); got != want.Total() {
+		t.Errorf("Total() = %+v with a trace and coverage, want %+v", got, want.Total())
+	}
+	if got := heat.Spans(); !reflect.DeepEqual(got, want.Spans()) {
+		t.Errorf("Spans() = %+v with a trace and coverage, want %+v", got, want.Spans())
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	heat := run(t)
+
+	var report bytes.Buffer
+	err := heat.WriteReport(&report, 2)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	want := `Memory: 4 reads, 2 writes, 39 fetches
+Code: 20 bytes, data: 5 bytes, mixed: 1 bytes
+
+PAGE           READS  WRITES  FETCHES  CLASS
+0x0000-0x00FF  0      1       39       mixed
+0x0100-0x01FF  4      0       0        data
+
+SPAN           BYTES  CLASS
+0x0000-0x0012  19     code
+0x0013-0x0013  1      mixed
+0x0014-0x0014  1      code
+0x0100-0x0103  4      data
+0x0200-0x0200  1      data
+`
+	if report.String() != want {
+		t.Errorf("WriteReport() wrote:\n%s\nwant:\n%s", report.String(), want)
+	}
+}
+
+// failingWriter fails every write after the first limit bytes.
+type failingWriter struct {
+	limit int
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (writer *failingWriter) Write(data []byte) (int, error) {
+	if len(data) > writer.limit {
+		written := writer.limit
+		writer.limit = 0
+		return written, errWriteFailed
+	}
+	writer.limit -= len(data)
+
+	return len(data), nil
+}
+
+func TestWriteReportErrors(t *testing.T) {
+	heat := run(t)
+
+	for _, limit := range []int{0, 10, 100, 300} {
+		if err := heat.WriteReport(&failingWriter{limit: limit}, 2); !errors.Is(err, errWriteFailed) {
+			t.Errorf("WriteReport() after %d bytes = %v, want %v", limit, err, errWriteFailed)
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	heat := run(t)
+
+	var buffer bytes.Buffer
+	err := heat.WritePNG(&buffer, 2)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	img, err := png.Decode(&buffer)
+	if err != nil {
+		t.Fatalf("could not decode heatmap: %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != 512 || size.Y != 512 {
+		t.Fatalf("heatmap is %v, want 512x512", size)
+	}
+
+	tests := []struct {
+		address types.Word
+		want    color.RGBA
+	}{
+		{address: 0x0006, want: color.RGBA{G: 0xFF, A: 0xFF}},          // The most accesses
+		{address: 0x0000, want: color.RGBA{G: 0x92, A: 0xFF}},          // A single fetch
+		{address: 0x0013, want: color.RGBA{R: 0x92, G: 0x92, A: 0xFF}}, // Patched code
+		{address: 0x0100, want: color.RGBA{B: 0x92, A: 0xFF}},          // A data read
+		{address: 0x0300, want: color.RGBA{A: 0xFF}},                   // Unused
+		{address: 0xFFFF, want: color.RGBA{A: 0xFF}},                   // The last address
+	}
+	for _, test := range tests {
+		x, y := int(test.address%256)*2+1, int(test.address/256)*2+1
+		if got := color.RGBAModel.Convert(img.At(x, y)); got != test.want {
+			t.Errorf("pixel for 0x%04X = %v, want %v", test.address, got, test.want)
+		}
+	}
+}
+
+func TestHeatmapBusErrors(t *testing.T) {
+	heat := New(&memory.Memory{Data: make([]byte, 0x10)})
+
+	if _, err := heat.ReadByteAt(0x0010); err == nil {
+		t.Errorf("expected an error reading past the end of memory, but got none")
+	}
+	if err := heat.WriteByteAt(0x0010, 0); err == nil {
+		t.Errorf("expected an error writing past the end of memory, but got none")
+	}
+	if _, err := heat.FetchByteAt(0x0010); err == nil {
+		t.Errorf("expected an error fetching past the end of memory, but got none")
+	}
+}
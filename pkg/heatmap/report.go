@@ -0,0 +1,117 @@
+package heatmap
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// regionSize is the size of the regions in reports: a page of 256 bytes.
+const regionSize = 256
+
+// WriteReport writes the total accesses, the bytes used for code and data, up
+// to limit of the hottest 256 byte pages, and the spans of code and data.
+//
+// Example:
+//
+//	Memory: 40 reads, 20 writes, 1051 fetches
+//	Code: 23 bytes, data: 4 bytes, mixed: 0 bytes
+//
+//	PAGE           READS  WRITES  FETCHES  CLASS
+//	0x0000-0x00FF  0      0       1051     code
+//	0x0F00-0x0FFF  40     20      0        data
+//
+//	SPAN           BYTES  CLASS
+//	0x0000-0x0016  23     code
+//	0x0FFC-0x0FFF  4      data
+func (heatmap *Heatmap) WriteReport(w io.Writer, limit int) error {
+	// The buffer keeps the first error writing to w, returned by Flush
+	buffer := bufio.NewWriter(w)
+	total := heatmap.Total()
+	fmt.Fprintf(buffer, "Memory: %d reads, %d writes, %d fetches\n", total.Reads, total.Writes, total.Fetches)
+
+	bytes := map[Class]int{}
+	for _, counts := range heatmap.counts {
+		bytes[counts.Class()]++
+	}
+	fmt.Fprintf(buffer, "Code: %d bytes, data: %d bytes, mixed: %d bytes\n\n", bytes[Code], bytes[Data], bytes[Mixed])
+
+	regions, err := heatmap.Hottest(regionSize, limit)
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PAGE\tREADS\tWRITES\tFETCHES\tCLASS")
+	for _, region := range regions {
+		fmt.Fprintf(writer, "0x%04X-0x%04X\t%d\t%d\t%d\t%v\n", region.Start, region.End(), region.Reads, region.Writes, region.Fetches, region.Class())
+	}
+	writer.Flush()
+
+	fmt.Fprintln(buffer)
+	writer = tabwriter.NewWriter(buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SPAN\tBYTES\tCLASS")
+	for _, span := range heatmap.Spans() {
+		fmt.Fprintf(writer, "0x%04X-0x%04X\t%d\t%v\n", span.Start, span.End(), span.Size, span.Class())
+	}
+	writer.Flush()
+
+	return buffer.Flush()
+}
+
+// Image returns the 64K address space as a 256 by 256 heatmap, with a row for
+// each page of 256 bytes.  Fetches are green, reads blue and writes red, each
+// brighter for more accesses on a log scale, so code shows as green and data
+// as blue and red.  Addresses never accessed are black.
+func (heatmap *Heatmap) Image() *image.RGBA {
+	var most uint64
+	for _, counts := range heatmap.counts {
+		most = max(most, counts.Reads, counts.Writes, counts.Fetches)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for address, counts := range heatmap.counts {
+		img.SetRGBA(address%256, address/256, color.RGBA{
+			R: intensity(counts.Writes, most),
+			G: intensity(counts.Fetches, most),
+			B: intensity(counts.Reads, most),
+			A: 0xFF,
+		})
+	}
+
+	return img
+}
+
+// intensity scales count against the most accesses on a log scale, keeping a
+// single access visible.
+func intensity(count, most uint64) uint8 {
+	if count == 0 {
+		return 0
+	}
+
+	const dimmest = 64
+	scale := math.Log1p(float64(count)) / math.Log1p(float64(most))
+	return uint8(dimmest + scale*(0xFF-dimmest))
+}
+
+// WritePNG writes the heatmap Image as a PNG, with each address scale pixels
+// square.
+func (heatmap *Heatmap) WritePNG(w io.Writer, scale int) error {
+	img := heatmap.Image()
+	if scale <= 1 {
+		return png.Encode(w, img)
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, 256*scale, 256*scale))
+	for y := 0; y < 256*scale; y++ {
+		for x := 0; x < 256*scale; x++ {
+			scaled.SetRGBA(x, y, img.RGBAAt(x/scale, y/scale))
+		}
+	}
+
+	return png.Encode(w, scaled)
+}
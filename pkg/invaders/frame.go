@@ -0,0 +1,43 @@
+package invaders
+
+import (
+	"image"
+	"image/color"
+)
+
+// The framebuffer holds 224 lines of 256 pixels, one bit per pixel with the
+// least significant bit first.  The monitor is mounted rotated 90 degrees
+// anticlockwise, so the picture the player sees is 224 pixels wide and 256
+// high.
+const (
+	lineWidth = 256
+	lines     = 224
+
+	Width  = lines     // Width of the picture as the player sees it
+	Height = lineWidth // Height of the picture as the player sees it
+)
+
+// Palette is the palette of the frames: black, then white.
+var Palette = color.Palette{color.Black, color.White}
+
+// Frame renders the framebuffer as the player sees it, upright and 224x256.
+func (machine *Machine) Frame() *image.Paletted {
+	frame := image.NewPaletted(image.Rect(0, 0, Width, Height), Palette)
+	video := machine.memory.data[videoRAM : videoRAM+lines*lineWidth/8]
+
+	for line := 0; line < lines; line++ {
+		for column := 0; column < lineWidth/8; column++ {
+			value := video[line*lineWidth/8+column]
+			for bit := 0; bit < 8; bit++ {
+				if value&(1<<bit) == 0 {
+					continue
+				}
+				// Each line is a column of the picture, drawn from the bottom up
+				x, y := line, lineWidth-1-(column*8+bit)
+				frame.Pix[y*frame.Stride+x] = 1
+			}
+		}
+	}
+
+	return frame
+}
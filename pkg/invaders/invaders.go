@@ -0,0 +1,328 @@
+package invaders
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Timing of the board: a 2 MHz 8080, with the video refreshing at 60 Hz.
+const (
+	ClockHz         = 2_000_000
+	FramesPerSecond = 60
+	CyclesPerFrame  = ClockHz / FramesPerSecond
+)
+
+// Interrupts raised by the video hardware.
+const (
+	midScreenInterrupt = 0xCF // RST 1, when the beam reaches the middle of the screen
+	vblankInterrupt    = 0xD7 // RST 2, when the beam reaches the end of the screen
+)
+
+// Memory map of the board.
+const (
+	ROMSize   = 0x2000 // 0x0000 to 0x1FFF
+	ramStart  = 0x2000 // Work RAM from 0x2000, then video RAM from 0x2400 to 0x3FFF
+	videoRAM  = 0x2400
+	addressed = 0x4000 // The address decoding ignores A14 and A15, so memory repeats every 16K
+)
+
+// romFiles are the names of the four 2K ROMs in the MAME "invaders" set, in
+// the order they're mapped from 0x0000.
+var romFiles = []string{"invaders.h", "invaders.g", "invaders.f", "invaders.e"}
+
+// Input is one of the cabinet's switches.
+type Input int
+
+const (
+	Coin Input = iota
+	Player1Start
+	Player2Start
+	Player1Fire
+	Player1Left
+	Player1Right
+	Player2Fire
+	Player2Left
+	Player2Right
+	Tilt
+)
+
+// inputBits gives the input port and bit read for each switch.
+var inputBits = [...]struct {
+	port byte
+	bit  byte
+}{
+	Coin:         {1, 1 << 0},
+	Player2Start: {1, 1 << 1},
+	Player1Start: {1, 1 << 2},
+	Player1Fire:  {1, 1 << 4},
+	Player1Left:  {1, 1 << 5},
+	Player1Right: {1, 1 << 6},
+	Tilt:         {2, 1 << 2},
+	Player2Fire:  {2, 1 << 4},
+	Player2Left:  {2, 1 << 5},
+	Player2Right: {2, 1 << 6},
+}
+
+// Config holds the settings of the board's DIP switches.
+type Config struct {
+	// Ships is the number of ships per game, 3 to 6.  It defaults to 3.
+	Ships int
+
+	// ExtraShipAt1000 awards the extra ship at 1000 points rather than 1500.
+	ExtraShipAt1000 bool
+
+	// HideCoinInfo hides the coin information on the demo screen.
+	HideCoinInfo bool
+}
+
+// Machine is the Taito Space Invaders board: an 8080 with 8K of ROM and 8K of
+// RAM, 7K of which is a 1-bit framebuffer, a hardware shift register for
+// drawing sprites at any bit offset, and the cabinet's switches.  The video
+// hardware raises RST 1 half way through each frame and RST 2 at the end.
+//
+// The ROMs aren't included, and must be supplied by the user.
+//
+// Example:
+//
+//	rom, err := invaders.LoadROM("roms/invaders")
+//	machine, err := invaders.New(rom, invaders.Config{})
+//	machine.OnFrame = func(frame image.Image) { window.Show(frame) }
+//	for range time.Tick(time.Second / invaders.FramesPerSecond) {
+//		err = machine.RunFrame()
+//	}
+type Machine struct {
+	// CPU is the board's processor.
+	CPU *cpu.CPU
+
+	// OnFrame, when set, is called at the end of each frame with the
+	// framebuffer, as returned by Frame.
+	OnFrame func(frame image.Image)
+
+	// OnSound, when set, is called when the sound latches on output port 3 or
+	// 5 change, with the port and the new value.  Each bit triggers one of the
+	// cabinet's sounds.
+	OnSound func(port byte, value byte)
+
+	memory *Memory
+	dips   [3]byte // DIP switch bits read from each input port
+	inputs atomic.Uint32
+
+	shift       types.Word // Shift register
+	shiftOffset byte
+	sound       [2]byte // Output ports 3 and 5
+
+	cycles uint64 // Clock states into the current frame
+	frames uint64
+}
+
+// New returns a board running rom, which must be the 8K of program ROM, with
+// its DIP switches set by config.
+func New(rom []byte, config Config) (*Machine, error) {
+	if len(rom) != ROMSize {
+		return nil, fmt.Errorf("invalid ROM size %d (must be %d bytes)", len(rom), ROMSize)
+	}
+	if config.Ships == 0 {
+		config.Ships = 3
+	}
+	if config.Ships < 3 || config.Ships > 6 {
+		return nil, fmt.Errorf("invalid number of ships %d (must be 3 to 6)", config.Ships)
+	}
+
+	machine := &Machine{
+		CPU:    cpu.New(),
+		memory: &Memory{},
+	}
+	copy(machine.memory.data[:], rom)
+	machine.CPU.Bus = machine.memory
+
+	machine.dips[0] = 0b0000_1110 // Bits 1 to 3 of port 0 are always set
+	machine.dips[1] = 0b0000_1000 // Bit 3 of port 1 is always set
+	machine.dips[2] = byte(config.Ships - 3)
+	if config.ExtraShipAt1000 {
+		machine.dips[2] |= 1 << 3
+	}
+	if config.HideCoinInfo {
+		machine.dips[2] |= 1 << 7
+	}
+
+	machine.CPU.Attach(board{machine}, 0, 1, 2, 3, 4, 5, 6)
+
+	return machine, nil
+}
+
+// LoadROM reads the program ROM from path: either a single 8K file, or a
+// directory holding the four 2K ROMs of the MAME "invaders" set (invaders.h,
+// invaders.g, invaders.f and invaders.e).
+func LoadROM(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load ROM: %v", err)
+	}
+
+	if !info.IsDir() {
+		rom, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not load ROM: %v", err)
+		}
+		return rom, nil
+	}
+
+	var rom []byte
+	for _, name := range romFiles {
+		data, err := os.ReadFile(filepath.Join(path, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("could not load ROM: %s not found in %s", name, path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not load ROM: %v", err)
+		}
+		if len(data) != ROMSize/len(romFiles) {
+			return nil, fmt.Errorf("could not load ROM: %s is %d bytes, want %d", name, len(data), ROMSize/len(romFiles))
+		}
+		rom = append(rom, data...)
+	}
+
+	return rom, nil
+}
+
+// SetInput presses or releases one of the cabinet's switches.  It's safe to
+// call from any goroutine.
+func (machine *Machine) SetInput(input Input, pressed bool) {
+	bit := uint32(1) << input
+	for {
+		old := machine.inputs.Load()
+		updated := old &^ bit
+		if pressed {
+			updated |= bit
+		}
+		if machine.inputs.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+// Frames returns the number of frames run.
+func (machine *Machine) Frames() uint64 {
+	return machine.frames
+}
+
+// RunFrame runs the CPU until the end of the current frame.
+func (machine *Machine) RunFrame() error {
+	frame := machine.frames
+	for machine.frames == frame {
+		if machine.CPU.Halted() && !machine.CPU.InterruptsEnabled() {
+			return fmt.Errorf("cpu halted at 0x%04X", machine.CPU.ProgramCounter())
+		}
+
+		err := machine.CPU.Step()
+		if err != nil {
+			return fmt.Errorf("could not step cpu: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// readInput returns the value of an input port: its DIP switches and pressed
+// switches.
+func (machine *Machine) readInput(port byte) byte {
+	value := machine.dips[port]
+	inputs := machine.inputs.Load()
+	for input, bits := range inputBits {
+		if bits.port == port && inputs&(1<<input) != 0 {
+			value |= bits.bit
+		}
+	}
+
+	return value
+}
+
+// board is the I/O device for the board's ports:
+//
+//	IN 0-2  DIP switches and inputs
+//	IN 3    shift register result
+//	OUT 2   shift register result offset
+//	OUT 3   sound latch 1
+//	OUT 4   shift register data
+//	OUT 5   sound latch 2
+//	OUT 6   watchdog, which is ignored
+type board struct {
+	machine *Machine
+}
+
+func (board board) ReadPort(port byte) byte {
+	machine := board.machine
+	if port == 3 {
+		return byte(machine.shift >> (8 - machine.shiftOffset))
+	}
+	if port > 3 {
+		return 0
+	}
+
+	return machine.readInput(port)
+}
+
+func (board board) WritePort(port byte, value byte) {
+	machine := board.machine
+	switch port {
+	case 2:
+		machine.shiftOffset = value & 0b111
+	case 4:
+		machine.shift = types.Word(value)<<8 | machine.shift>>8
+	case 3, 5:
+		latch := &machine.sound[port/4] // Port 3 is latch 0, port 5 latch 1
+		if *latch != value && machine.OnSound != nil {
+			machine.OnSound(port, value)
+		}
+		*latch = value
+	}
+}
+
+// Tick raises the mid-screen and end of screen interrupts as the beam reaches
+// them, and ends the frame at the end of the screen.
+func (board board) Tick(cycles uint64) {
+	machine := board.machine
+	before := machine.cycles
+	machine.cycles += cycles
+
+	if before < CyclesPerFrame/2 && machine.cycles >= CyclesPerFrame/2 {
+		machine.CPU.Interrupt(midScreenInterrupt)
+	}
+	if machine.cycles >= CyclesPerFrame {
+		machine.cycles -= CyclesPerFrame
+		machine.frames++
+		machine.CPU.Interrupt(vblankInterrupt)
+		if machine.OnFrame != nil {
+			machine.OnFrame(machine.Frame())
+		}
+	}
+}
+
+// Memory is the board's memory: ROM, then RAM, repeated every 16K.  Writes to
+// ROM are ignored.
+type Memory struct {
+	data [addressed]byte
+}
+
+// ReadByteAt reads a byte from ROM or RAM.
+func (memory *Memory) ReadByteAt(address types.Word) (byte, error) {
+	return memory.data[address%addressed], nil
+}
+
+// WriteByteAt writes a byte to RAM.
+func (memory *Memory) WriteByteAt(address types.Word, data byte) error {
+	address %= addressed
+	if address >= ramStart {
+		memory.data[address] = data
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package invaders
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// testROM returns a ROM whose interrupt handlers count the mid-screen and end
+// of screen interrupts at 0x2000 and 0x2001.
+func testROM() []byte {
+	rom := make([]byte, ROMSize)
+	copy(rom[0x0000:], []byte{
+		0x31, 0x00, 0x24, // 0000 LXI SP,0x2400
+		0xFB,             // 0003 EI
+		0xC3, 0x04, 0x00, // 0004 JMP 0x0004
+	})
+	copy(rom[0x0008:], []byte{0xC3, 0x20, 0x00}) // 0008 JMP 0x0020
+	copy(rom[0x0010:], []byte{0xC3, 0x30, 0x00}) // 0010 JMP 0x0030
+	copy(rom[0x0020:], []byte{
+		0x3A, 0x00, 0x20, // 0020 LDA 0x2000
+		0x3C,             // 0023 INR A
+		0x32, 0x00, 0x20, // 0024 STA 0x2000
+		0xFB, // 0027 EI
+		0xC9, // 0028 RET
+	})
+	copy(rom[0x0030:], []byte{
+		0x3A, 0x01, 0x20, // 0030 LDA 0x2001
+		0x3C,             // 0033 INR A
+		0x32, 0x01, 0x20, // 0034 STA 0x2001
+		0xFB, // 0037 EI
+		0xC9, // 0038 RET
+	})
+
+	return rom
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		romSize int
+		ships   int
+		wantErr bool
+	}{
+		{romSize: ROMSize, ships: 0, wantErr: false},
+		{romSize: ROMSize, ships: 3, wantErr: false},
+		{romSize: ROMSize, ships: 6, wantErr: false},
+		{romSize: ROMSize, ships: 2, wantErr: true},
+		{romSize: ROMSize, ships: 7, wantErr: true},
+		{romSize: ROMSize - 1, ships: 3, wantErr: true},
+		{romSize: 0, ships: 3, wantErr: true},
+	}
+
+	for _, test := range tests {
+		_, err := New(make([]byte, test.romSize), Config{Ships: test.ships})
+		if test.wantErr && err == nil {
+			t.Errorf("expected an error for a %d byte ROM and %d ships, but got none", test.romSize, test.ships)
+		}
+		if !test.wantErr && err != nil {
+			t.Errorf("did not expect an error for a %d byte ROM and %d ships, but got: %v", test.romSize, test.ships, err)
+		}
+	}
+}
+
+func TestRunFrame(t *testing.T) {
+	machine, err := New(testROM(), Config{})
+	if err != nil {
+		t.Fatalf("could not create machine: %v", err)
+	}
+
+	var frames int
+	machine.OnFrame = func(frame image.Image) {
+		frames++
+		if frame.Bounds() != image.Rect(0, 0, Width, Height) {
+			t.Errorf("frame bounds = %v, want %v", frame.Bounds(), image.Rect(0, 0, Width, Height))
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		err := machine.RunFrame()
+		if err != nil {
+			t.Fatalf("could not run frame %d: %v", i, err)
+		}
+	}
+
+	if machine.Frames() != 3 || frames != 3 {
+		t.Errorf("ran %d frames, with %d OnFrame calls, want 3", machine.Frames(), frames)
+	}
+	if cycles := machine.CPU.Cycles(); cycles < 3*CyclesPerFrame || cycles > 3*CyclesPerFrame+20 {
+		t.Errorf("ran %d cycles, want about %d", cycles, 3*CyclesPerFrame)
+	}
+
+	// The last end of screen interrupt is taken at the start of the next frame
+	midScreen, _ := machine.memory.ReadByteAt(0x2000)
+	vblank, _ := machine.memory.ReadByteAt(0x2001)
+	if midScreen != 3 || vblank != 2 {
+		t.Errorf("counted %d mid-screen and %d end of screen interrupts, want 3 and 2", midScreen, vblank)
+	}
+}
+
+func TestShiftRegister(t *testing.T) {
+	machine, _ := New(testROM(), Config{})
+	device := board{machine}
+	device.WritePort(4, 0xAB)
+	device.WritePort(4, 0xCD) // The register holds 0xCDAB
+
+	tests := []struct {
+		offset byte
+		want   byte
+	}{
+		{offset: 0, want: 0xCD},
+		{offset: 1, want: 0x9B},
+		{offset: 4, want: 0xDA},
+		{offset: 7, want: 0xD5},
+		{offset: 8, want: 0xCD}, // Only the low 3 bits are used
+	}
+
+	for _, test := range tests {
+		device.WritePort(2, test.offset)
+		got := device.ReadPort(3)
+		if got != test.want {
+			t.Errorf("IN 3 with offset %d = 0x%02X, want 0x%02X", test.offset, got, test.want)
+		}
+	}
+}
+
+func TestInputs(t *testing.T) {
+	machine, _ := New(testROM(), Config{Ships: 5, ExtraShipAt1000: true, HideCoinInfo: true})
+	device := board{machine}
+
+	tests := []struct {
+		input   Input
+		pressed bool
+		want    [3]byte
+	}{
+		{input: Coin, pressed: false, want: [3]byte{0x0E, 0x08, 0x8A}},
+		{input: Coin, pressed: true, want: [3]byte{0x0E, 0x09, 0x8A}},
+		{input: Player1Fire, pressed: true, want: [3]byte{0x0E, 0x19, 0x8A}},
+		{input: Tilt, pressed: true, want: [3]byte{0x0E, 0x19, 0x8E}},
+		{input: Player2Right, pressed: true, want: [3]byte{0x0E, 0x19, 0xCE}},
+		{input: Coin, pressed: false, want: [3]byte{0x0E, 0x18, 0xCE}},
+		{input: Tilt, pressed: false, want: [3]byte{0x0E, 0x18, 0xCA}},
+	}
+
+	for _, test := range tests {
+		machine.SetInput(test.input, test.pressed)
+		for port, want := range test.want {
+			got := device.ReadPort(byte(port))
+			if got != want {
+				t.Errorf("after setting input %d to %v, IN %d = 0x%02X, want 0x%02X", test.input, test.pressed, port, got, want)
+			}
+		}
+	}
+}
+
+func TestSound(t *testing.T) {
+	machine, _ := New(testROM(), Config{})
+	device := board{machine}
+
+	var got [][2]byte
+	machine.OnSound = func(port byte, value byte) {
+		got = append(got, [2]byte{port, value})
+	}
+
+	device.WritePort(3, 0x01)
+	device.WritePort(3, 0x01) // Unchanged
+	device.WritePort(5, 0x01)
+	device.WritePort(3, 0x00)
+	device.WritePort(6, 0xFF) // Watchdog
+
+	want := [][2]byte{{3, 0x01}, {5, 0x01}, {3, 0x00}}
+	if len(got) != len(want) {
+		t.Fatalf("OnSound called with %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("OnSound called with %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	machine, _ := New(testROM(), Config{})
+	memory := machine.memory
+
+	tests := []struct {
+		address types.Word
+		write   byte
+		want    byte
+	}{
+		{address: 0x0000, write: 0x55, want: 0x31}, // ROM
+		{address: 0x1FFF, write: 0x55, want: 0x00},
+		{address: 0x2000, write: 0x66, want: 0x66},
+		{address: 0x3FFF, write: 0x77, want: 0x77},
+		{address: 0x4000, write: 0x88, want: 0x31}, // Mirror of ROM
+		{address: 0x6001, write: 0x99, want: 0x99}, // Mirror of RAM
+		{address: 0xE001, write: 0xAA, want: 0xAA},
+	}
+
+	for _, test := range tests {
+		memory.WriteByteAt(test.address, test.write)
+		got, err := memory.ReadByteAt(test.address)
+		if err != nil {
+			t.Fatalf("error reading address 0x%04X: %v", test.address, err)
+		}
+		if got != test.want {
+			t.Errorf("address 0x%04X = 0x%02X after writing 0x%02X, want 0x%02X", test.address, got, test.write, test.want)
+		}
+	}
+
+	got, _ := memory.ReadByteAt(0x2001)
+	if got != 0xAA {
+		t.Errorf("address 0x2001 = 0x%02X after writing its mirror, want 0xAA", got)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	machine, _ := New(testROM(), Config{})
+	machine.memory.WriteByteAt(videoRAM, 0b0000_0001)       // Line 0, pixel 0
+	machine.memory.WriteByteAt(videoRAM+32+31, 0b1000_0000) // Line 1, pixel 255
+	machine.memory.WriteByteAt(0x3FFF, 0b1000_0000)         // Line 223, pixel 255
+
+	frame := machine.Frame()
+	set := map[image.Point]bool{{0, 255}: true, {1, 0}: true, {223, 0}: true}
+
+	for y := 0; y < Height; y++ {
+		for x := 0; x < Width; x++ {
+			got := frame.ColorIndexAt(x, y) == 1
+			if got != set[image.Point{x, y}] {
+				t.Errorf("pixel (%d, %d) set = %v, want %v", x, y, got, !got)
+			}
+		}
+	}
+}
+
+func TestLoadROM(t *testing.T) {
+	dir := t.TempDir()
+	want := testROM()
+	for i, name := range romFiles {
+		err := os.WriteFile(filepath.Join(dir, name), want[i*0x800:(i+1)*0x800], 0o644)
+		if err != nil {
+			t.Fatalf("could not write %s: %v", name, err)
+		}
+	}
+	single := filepath.Join(t.TempDir(), "invaders.rom")
+	os.WriteFile(single, want, 0o644)
+
+	for _, path := range []string{dir, single} {
+		got, err := LoadROM(path)
+		if err != nil {
+			t.Errorf("did not expect an error loading %s, but got: %v", path, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("LoadROM(%s) did not return the ROM", path)
+		}
+	}
+
+	os.WriteFile(filepath.Join(dir, "invaders.f"), []byte{0x00}, 0o644)
+	_, err := LoadROM(dir)
+	if err == nil {
+		t.Errorf("expected an error loading a short ROM, but got none")
+	}
+
+	os.Remove(filepath.Join(dir, "invaders.e"))
+	_, err = LoadROM(dir)
+	if err == nil {
+		t.Errorf("expected an error loading a missing ROM, but got none")
+	}
+}
@@ -0,0 +1,104 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Intel HEX record types.
+const (
+	hexData                   = 0x00
+	hexEndOfFile              = 0x01
+	hexExtendedSegmentAddress = 0x02
+	hexStartSegmentAddress    = 0x03
+	hexExtendedLinearAddress  = 0x04
+	hexStartLinearAddress     = 0x05
+)
+
+// ParseHex parses Intel HEX records, returning the data as segments, with
+// consecutive records merged, and the start address if there's a start
+// address record.  Reading stops at the end of file record.
+//
+// Example:
+//
+//	segments, start, err := loader.ParseHex([]byte(":03010000C3000138\n:00000001FF\n"))
+//	// segments is []Segment{{Address: 0x0100, Data: []byte{0xC3, 0x00, 0x01}}}, start is nil
+func ParseHex(data []byte) ([]Segment, *types.Word, error) {
+	var segments []Segment
+	var start *types.Word
+	var base int // From the extended address records
+
+	for number, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] != ':' {
+			return nil, nil, fmt.Errorf("invalid HEX record on line %d (must start with ':')", number+1)
+		}
+
+		record := make([]byte, hex.DecodedLen(len(line)-1))
+		_, err := hex.Decode(record, line[1:])
+		if err != nil || len(record) < 5 || len(record) != 5+int(record[0]) {
+			return nil, nil, fmt.Errorf("invalid HEX record on line %d", number+1)
+		}
+
+		var checksum byte
+		for _, value := range record {
+			checksum += value
+		}
+		if checksum != 0 {
+			return nil, nil, fmt.Errorf("invalid HEX record checksum on line %d", number+1)
+		}
+
+		address := int(record[1])<<8 | int(record[2])
+		recordType, payload := record[3], record[4:len(record)-1]
+
+		switch recordType {
+		case hexData:
+			address += base
+			if address+len(payload) > 0x10000 {
+				return nil, nil, fmt.Errorf("HEX record on line %d is past the end of memory", number+1)
+			}
+			last := len(segments) - 1
+			if last >= 0 && int(segments[last].Address)+len(segments[last].Data) == address {
+				segments[last].Data = append(segments[last].Data, payload...)
+			} else {
+				segments = append(segments, Segment{Address: types.Word(address), Data: append([]byte(nil), payload...)})
+			}
+		case hexEndOfFile:
+			return segments, start, nil
+		case hexExtendedSegmentAddress, hexExtendedLinearAddress:
+			if len(payload) != 2 {
+				return nil, nil, fmt.Errorf("invalid extended address record on line %d", number+1)
+			}
+			base = int(payload[0])<<8 | int(payload[1])
+			if recordType == hexExtendedSegmentAddress {
+				base <<= 4
+			} else {
+				base <<= 16
+			}
+		case hexStartSegmentAddress, hexStartLinearAddress:
+			if len(payload) != 4 {
+				return nil, nil, fmt.Errorf("invalid start address record on line %d", number+1)
+			}
+			high, low := int(payload[0])<<8|int(payload[1]), int(payload[2])<<8|int(payload[3])
+			entry := high<<16 | low
+			if recordType == hexStartSegmentAddress {
+				entry = high<<4 + low
+			}
+			if entry > 0xFFFF {
+				return nil, nil, fmt.Errorf("start address 0x%X on line %d is past the end of memory", entry, number+1)
+			}
+			word := types.Word(entry)
+			start = &word
+		default:
+			return nil, nil, fmt.Errorf("unknown HEX record type 0x%02X on line %d", recordType, number+1)
+		}
+	}
+
+	return segments, start, nil
+}
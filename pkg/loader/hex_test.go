@@ -0,0 +1,102 @@
+package loader
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestParseHex(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantSegments []Segment
+		wantStart    int // -1 for none
+		wantErr      bool
+	}{
+		{
+			name:         "single record",
+			input:        ":03010000C3000138\n:00000001FF\n",
+			wantSegments: []Segment{{Address: 0x0100, Data: []byte{0xC3, 0x00, 0x01}}},
+			wantStart:    -1,
+		},
+		{
+			name:         "consecutive records merged",
+			input:        ":020000003E2A96\r\n:010002007687\r\n",
+			wantSegments: []Segment{{Address: 0x0000, Data: []byte{0x3E, 0x2A, 0x76}}},
+			wantStart:    -1,
+		},
+		{
+			name:  "separate records",
+			input: ":010000007689\n:011000007679\n",
+			wantSegments: []Segment{
+				{Address: 0x0000, Data: []byte{0x76}},
+				{Address: 0x1000, Data: []byte{0x76}},
+			},
+			wantStart: -1,
+		},
+		{
+			name:         "start linear address",
+			input:        ":010000007689\n:0400000500000100F6\n:00000001FF\n",
+			wantSegments: []Segment{{Address: 0x0000, Data: []byte{0x76}}},
+			wantStart:    0x0100,
+		},
+		{
+			name:         "start segment address",
+			input:        ":0400000300100004E5\n",
+			wantSegments: nil,
+			wantStart:    0x0104,
+		},
+		{
+			name:         "extended segment address",
+			input:        ":020000020010EC\n:010000007689\n",
+			wantSegments: []Segment{{Address: 0x0100, Data: []byte{0x76}}},
+			wantStart:    -1,
+		},
+		{
+			name:         "records after end of file ignored",
+			input:        ":00000001FF\n:010000007689\n",
+			wantSegments: nil,
+			wantStart:    -1,
+		},
+		{name: "missing colon", input: "010000007689\n", wantErr: true},
+		{name: "bad checksum", input: ":010000007688\n", wantErr: true},
+		{name: "bad length", input: ":020000007688\n", wantErr: true},
+		{name: "not hex", input: ":01000000ZZ89\n", wantErr: true},
+		{name: "unknown type", input: ":00000006FA\n", wantErr: true},
+		{name: "past end of memory", input: ":02FFFF0000000000\n", wantErr: true},
+		{name: "extended linear address", input: ":020000040001F9\n:010000007689\n", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			segments, start, err := ParseHex([]byte(test.input))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect an error, but got: %v", err)
+			}
+
+			if len(segments) != len(test.wantSegments) {
+				t.Fatalf("segments = %v, want %v", segments, test.wantSegments)
+			}
+			for i, want := range test.wantSegments {
+				if segments[i].Address != want.Address || !bytes.Equal(segments[i].Data, want.Data) {
+					t.Errorf("segment %d = %v, want %v", i, segments[i], want)
+				}
+			}
+
+			if test.wantStart < 0 && start != nil {
+				t.Errorf("start = 0x%04X, want none", *start)
+			}
+			if test.wantStart >= 0 && (start == nil || *start != types.Word(test.wantStart)) {
+				t.Errorf("start = %v, want 0x%04X", start, test.wantStart)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/symbols"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Format is the format of a program file.
+type Format int
+
+const (
+	Auto     Format = iota // Detected from the file's extension and contents
+	Assembly               // Assembly language source, assembled with go8080assembler
+	Binary                 // A memory image
+	IntelHex               // Intel HEX records, each giving its own address
+	CPM                    // A CP/M .com program, loaded and started at 0x0100
+)
+
+// CPMAddress is the address CP/M programs are loaded and started at, the start
+// of the transient program area.
+const CPMAddress = 0x0100
+
+var formatNames = map[Format]string{
+	Auto:     "auto",
+	Assembly: "asm",
+	Binary:   "bin",
+	IntelHex: "hex",
+	CPM:      "com",
+}
+
+func (format Format) String() string {
+	if name, ok := formatNames[format]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Format(%d)", int(format))
+}
+
+// ParseFormat returns the format named by name: auto, asm, bin, hex or com.
+func ParseFormat(name string) (Format, error) {
+	for format, formatName := range formatNames {
+		if strings.EqualFold(name, formatName) {
+			return format, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown format %q (must be auto, asm, bin, hex or com)", name)
+}
+
+// Detect returns the format of a program file from its extension, or if that's
+// not one it knows, from whether data looks like Intel HEX records.
+func Detect(path string, data []byte) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".asm", ".s":
+		return Assembly
+	case ".hex", ".ihx":
+		return IntelHex
+	case ".com":
+		return CPM
+	case ".bin", ".rom":
+		return Binary
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == ':' {
+		_, _, err := ParseHex(data)
+		if err == nil {
+			return IntelHex
+		}
+	}
+
+	return Binary
+}
+
+// Segment is a run of bytes loaded at an address.
+type Segment struct {
+	Address types.Word
+	Data    []byte
+}
+
+// Program is a program read from a file, ready to load into memory.
+type Program struct {
+	Format   Format
+	Segments []Segment
+
+	// Entry is the address the program starts at: a HEX file's start address
+	// if it has one, 0x0100 for a CP/M program, or otherwise the address of
+	// its first byte.
+	Entry types.Word
+
+	// Symbols holds the labels and source lines of an assembly language
+	// program, or is nil for other formats or if they couldn't be read.
+	Symbols *symbols.Table
+}
+
+// Read reads the program in the file at path.  Assembly language and binary
+// programs are loaded at address, Intel HEX programs at the addresses in their
+// records and CP/M programs at 0x0100.
+func Read(path string, format Format, address types.Word) (*Program, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read program: %v", err)
+	}
+	if format == Auto {
+		format = Detect(path, data)
+	}
+
+	return Parse(filepath.Base(path), data, format, address)
+}
+
+// Parse parses a program in the given format, which mustn't be Auto.  name
+// names the file in the source locations of an assembly language program.
+func Parse(name string, data []byte, format Format, address types.Word) (*Program, error) {
+	program := &Program{Format: format, Entry: address}
+
+	switch format {
+	case Assembly:
+		code, table, err := symbols.Assemble(name, string(data), address)
+		if err != nil {
+			return nil, err
+		}
+		program.Segments = []Segment{{Address: address, Data: code}}
+		program.Symbols = table
+	case Binary:
+		program.Segments = []Segment{{Address: address, Data: data}}
+	case CPM:
+		program.Entry = CPMAddress
+		program.Segments = []Segment{{Address: CPMAddress, Data: data}}
+	case IntelHex:
+		segments, start, err := ParseHex(data)
+		if err != nil {
+			return nil, err
+		}
+		program.Segments = segments
+		if start != nil {
+			program.Entry = *start
+		} else if len(segments) > 0 {
+			program.Entry = segments[0].Address
+		}
+	default:
+		return nil, fmt.Errorf("invalid format %v", format)
+	}
+
+	for _, segment := range program.Segments {
+		if int(segment.Address)+len(segment.Data) > 0x10000 {
+			return nil, fmt.Errorf("program of %d bytes at address 0x%04X is past the end of memory", len(segment.Data), segment.Address)
+		}
+	}
+
+	return program, nil
+}
+
+// Load writes the program into bus.
+func (program *Program) Load(bus cpu.Bus) error {
+	for _, segment := range program.Segments {
+		for i, value := range segment.Data {
+			address := segment.Address + types.Word(i)
+			err := bus.WriteByteAt(address, value)
+			if err != nil {
+				return fmt.Errorf("could not load byte at address 0x%04X: %v", address, err)
+			}
+		}
+	}
+
+	return nil
+}
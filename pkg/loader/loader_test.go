@@ -0,0 +1,144 @@
+package loader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Format
+		wantErr bool
+	}{
+		{name: "auto", want: Auto},
+		{name: "asm", want: Assembly},
+		{name: "BIN", want: Binary},
+		{name: "hex", want: IntelHex},
+		{name: "com", want: CPM},
+		{name: "elf", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseFormat(test.name)
+		if test.wantErr && err == nil {
+			t.Errorf("expected an error for %q, but got none", test.name)
+		}
+		if !test.wantErr && (err != nil || got != test.want) {
+			t.Errorf("ParseFormat(%q) = %v, %v, want %v", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		path string
+		data string
+		want Format
+	}{
+		{path: "prog.asm", data: "HLT", want: Assembly},
+		{path: "PROG.COM", data: "\x76", want: CPM},
+		{path: "prog.hex", data: "", want: IntelHex},
+		{path: "prog.ihx", data: "", want: IntelHex},
+		{path: "prog.bin", data: ":00000001FF", want: Binary},
+		{path: "prog", data: ":00000001FF\n", want: IntelHex},
+		{path: "prog.out", data: "  :010000007689\n", want: IntelHex},
+		{path: "prog", data: ":not hex", want: Binary},
+		{path: "prog", data: "\x3E\x2A\x76", want: Binary},
+	}
+
+	for _, test := range tests {
+		got := Detect(test.path, []byte(test.data))
+		if got != test.want {
+			t.Errorf("Detect(%q, %q) = %v, want %v", test.path, test.data, got, test.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		format    Format
+		address   types.Word
+		wantEntry types.Word
+		wantAt    types.Word
+		wantData  []byte
+		wantErr   bool
+	}{
+		{name: "binary", data: "\x3E\x2A\x76", format: Binary, address: 0x0200, wantEntry: 0x0200, wantAt: 0x0200, wantData: []byte{0x3E, 0x2A, 0x76}},
+		{name: "CP/M", data: "\x76", format: CPM, address: 0x0200, wantEntry: 0x0100, wantAt: 0x0100, wantData: []byte{0x76}},
+		{name: "assembly", data: "HLT", format: Assembly, address: 0x0010, wantEntry: 0x0010, wantAt: 0x0010, wantData: []byte{0x76}},
+		{name: "hex", data: ":011000007679\n", format: IntelHex, address: 0x0200, wantEntry: 0x1000, wantAt: 0x1000, wantData: []byte{0x76}},
+		{name: "hex start", data: ":011000007679\n:0400000500000100F6\n", format: IntelHex, wantEntry: 0x0100, wantAt: 0x1000, wantData: []byte{0x76}},
+		{name: "invalid hex", data: ":01", format: IntelHex, wantErr: true},
+		{name: "past end of memory", data: "\x00\x00", format: Binary, address: 0xFFFF, wantErr: true},
+		{name: "auto", data: "\x76", format: Auto, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			program, err := Parse("prog", []byte(test.data), test.format, test.address)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect an error, but got: %v", err)
+			}
+
+			if program.Entry != test.wantEntry {
+				t.Errorf("entry = 0x%04X, want 0x%04X", program.Entry, test.wantEntry)
+			}
+			if (program.Symbols != nil) != (test.format == Assembly) {
+				t.Errorf("symbols = %v, want them only for assembly language", program.Symbols)
+			}
+			if len(program.Segments) != 1 || program.Segments[0].Address != test.wantAt || !bytes.Equal(program.Segments[0].Data, test.wantData) {
+				t.Errorf("segments = %v, want % X at 0x%04X", program.Segments, test.wantData, test.wantAt)
+			}
+		})
+	}
+}
+
+func TestReadAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prog.hex")
+	err := os.WriteFile(path, []byte(":020000003E2A96\n:011000007679\n:00000001FF\n"), 0o644)
+	if err != nil {
+		t.Fatalf("could not write program: %v", err)
+	}
+
+	program, err := Read(path, Auto, 0x0000)
+	if err != nil {
+		t.Fatalf("could not read program: %v", err)
+	}
+	if program.Format != IntelHex {
+		t.Errorf("format = %v, want %v", program.Format, IntelHex)
+	}
+
+	bus := memory.New()
+	err = program.Load(bus)
+	if err != nil {
+		t.Fatalf("could not load program: %v", err)
+	}
+	if bus.Data[0x0000] != 0x3E || bus.Data[0x0001] != 0x2A || bus.Data[0x1000] != 0x76 {
+		t.Errorf("memory = % X ... %02X, want 3E 2A ... 76", bus.Data[0:2], bus.Data[0x1000])
+	}
+
+	_, err = Read(filepath.Join(t.TempDir(), "missing.bin"), Auto, 0x0000)
+	if err == nil {
+		t.Errorf("expected an error reading a missing file, but got none")
+	}
+
+	program = &Program{Segments: []Segment{{Address: 0xFFFF, Data: []byte{0x76}}}}
+	err = program.Load(memory.New()) // memory.New has no byte at 0xFFFF
+	if err == nil {
+		t.Errorf("expected an error loading past the end of memory, but got none")
+	}
+}
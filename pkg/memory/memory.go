@@ -7,6 +7,22 @@ import (
 	"github.com/lukepeterson/go8080cpu/pkg/types"
 )
 
+// AddressError is returned accessing an address past the end of memory.
+type AddressError struct {
+	Address types.Word
+	Size    int
+	Write   bool
+}
+
+func (err *AddressError) Error() string {
+	access := "read from"
+	if err.Write {
+		access = "write to"
+	}
+
+	return fmt.Sprintf("could not %s address 0x%04X (out of bounds as memory size is 0x%04X)", access, err.Address, err.Size)
+}
+
 type Memory struct {
 	Data []byte
 }
@@ -22,7 +38,7 @@ func New() *Memory {
 // ReadByteAt reads a byte from the specified memory location
 func (memory Memory) ReadByteAt(address types.Word) (byte, error) {
 	if int(address) >= len(memory.Data) {
-		return 0, fmt.Errorf("could not read from address 0x%04X (out of bounds as memory size is 0x%04X)", address, len(memory.Data))
+		return 0, &AddressError{Address: address, Size: len(memory.Data)}
 	}
 
 	return memory.Data[address], nil
@@ -31,7 +47,7 @@ func (memory Memory) ReadByteAt(address types.Word) (byte, error) {
 // WriteByteTo writes a byte to the specified memory location
 func (memory *Memory) WriteByteAt(address types.Word, data byte) error {
 	if int(address) >= len(memory.Data) {
-		return fmt.Errorf("could not write to address 0x%04X (out of bounds as memory size is 0x%04X)", address, len(memory.Data))
+		return &AddressError{Address: address, Size: len(memory.Data), Write: true}
 	}
 
 	memory.Data[address] = data
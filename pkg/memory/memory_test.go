@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/lukepeterson/go8080cpu/pkg/types"
@@ -71,3 +72,24 @@ func TestWriteByteAt(t *testing.T) {
 		}
 	}
 }
+
+func TestAddressError(t *testing.T) {
+	memory := Memory{Data: make([]byte, 4)}
+
+	_, err := memory.ReadByteAt(0x0010)
+	var addressErr *AddressError
+	if !errors.As(err, &addressErr) {
+		t.Fatalf("expected an AddressError reading, but got: %v", err)
+	}
+	if addressErr.Address != 0x0010 || addressErr.Size != 4 || addressErr.Write {
+		t.Errorf("read error = %+v, want address 0x0010, size 4 and a read", addressErr)
+	}
+
+	err = memory.WriteByteAt(0x0004, 0xAA)
+	if !errors.As(err, &addressErr) {
+		t.Fatalf("expected an AddressError writing, but got: %v", err)
+	}
+	if addressErr.Address != 0x0004 || !addressErr.Write {
+		t.Errorf("write error = %+v, want address 0x0004 and a write", addressErr)
+	}
+}
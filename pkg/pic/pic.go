@@ -0,0 +1,362 @@
+package pic
+
+// Initialization command word 1 bits, written to the even port with bit 4 set.
+const (
+	icw1NeedICW4 = 1 << 0 // IC4 - ICW4 follows
+	icw1Single   = 1 << 1 // SNGL - no slaves, so no ICW3
+	icw1Interval = 1 << 2 // ADI - CALL addresses are 4 bytes apart rather than 8
+	icw1Level    = 1 << 3 // LTIM - level triggered rather than edge triggered
+	icw1         = 1 << 4
+)
+
+// Initialization command word 4 bits.
+const (
+	icw4AutoEOI = 1 << 1 // AEOI - the in-service bit is cleared when the interrupt is acknowledged
+)
+
+// Operation command word 2 commands, in bits 7 to 5 (R, SL and EOI).
+const (
+	ocw2RotateAutoEOIClear = 0b000
+	ocw2NonSpecificEOI     = 0b001
+	ocw2NoOperation        = 0b010
+	ocw2SpecificEOI        = 0b011
+	ocw2RotateAutoEOISet   = 0b100
+	ocw2RotateNonSpecific  = 0b101
+	ocw2SetPriority        = 0b110
+	ocw2RotateSpecific     = 0b111
+)
+
+// Operation command word 3 bits, written to the even port with bit 3 set.
+const (
+	ocw3ReadISR        = 1 << 0 // RIS - read the in-service register rather than the request register
+	ocw3ReadRegister   = 1 << 1 // RR - RIS is valid
+	ocw3Poll           = 1 << 2 // P - the next read is a poll
+	ocw3               = 1 << 3
+	ocw3SpecialMask    = 1 << 5 // SMM
+	ocw3SetSpecialMask = 1 << 6 // ESMM - SMM is valid
+)
+
+const callOpCode = 0xCD // CALL a16
+
+// initState tracks which initialization command word the odd port expects next.
+type initState int
+
+const (
+	uninitialized initState = iota
+	expectICW2
+	expectICW3
+	expectICW4
+	ready
+)
+
+// PIC emulates an Intel 8259A programmable interrupt controller in 8080/8085
+// mode: eight prioritized interrupt request lines, IR0 to IR7, each answered
+// with a three byte CALL to its own service routine.
+//
+// The 8259A decodes a single address line (A0), so it occupies two ports: the
+// even port, where bit 0 of the port number is clear, takes ICW1, OCW2 and
+// OCW3 and reads the request or in-service register; the odd port takes the
+// rest of the initialization command words and the interrupt mask (OCW1), and
+// reads the mask.  Only a single controller is emulated, so the cascade
+// configuration in ICW3 is accepted and ignored, as is the 8086 mode bit in
+// ICW4.
+//
+// Example:
+//
+//	controller := pic.New()
+//	cpu.Attach(controller, 0x20, 0x21)
+//	cpu.SetInterruptController(controller)
+//	serial.OnRxReady = func() { controller.Request(3) }
+type PIC struct {
+	init initState
+	icw1 byte
+	icw2 byte // High byte of the CALL addresses
+	icw4 byte
+
+	irr byte // Interrupt request register
+	isr byte // In-service register
+	imr byte // Interrupt mask register
+
+	lines          byte // Levels of the IR inputs, for edge detection
+	lowest         int  // Lowest priority level, 7 until rotated
+	rotateAutoEOI  bool
+	specialMask    bool
+	readISR        bool
+	poll           bool
+	acknowledgeSeq int // Bytes of the CALL supplied so far
+	acknowledged   int // Level being acknowledged
+}
+
+// New returns an uninitialized PIC, which requests no interrupts until it's
+// been programmed with ICW1 and ICW2.
+func New() *PIC {
+	return &PIC{lowest: 7}
+}
+
+// SetLine drives an IR input high (requesting an interrupt) or low.  In edge
+// triggered mode a low to high transition latches the request until it's
+// acknowledged; in level triggered mode the request follows the line.
+func (pic *PIC) SetLine(line int, high bool) {
+	bit := byte(1) << line
+	rising := high && pic.lines&bit == 0
+
+	if high {
+		pic.lines |= bit
+	} else {
+		pic.lines &^= bit
+	}
+
+	switch {
+	case pic.icw1&icw1Level != 0 && high:
+		pic.irr |= bit
+	case pic.icw1&icw1Level != 0:
+		pic.irr &^= bit
+	case rising:
+		pic.irr |= bit
+	}
+}
+
+// Request pulses an IR input, as an edge triggered peripheral does to request
+// an interrupt.  In level triggered mode, use SetLine to hold the line high
+// until the interrupt is serviced instead.
+func (pic *PIC) Request(line int) {
+	pic.SetLine(line, true)
+	if pic.icw1&icw1Level == 0 {
+		pic.SetLine(line, false)
+	}
+}
+
+// Pending reports whether the INT output is active: an unmasked request has
+// higher priority than every interrupt in service.
+func (pic *PIC) Pending() bool {
+	_, ok := pic.highestRequest()
+	return ok
+}
+
+// Acknowledge supplies the next byte of the CALL instruction for the highest
+// priority request: the CALL opcode on the first INTA cycle, when the request
+// moves into service, then the low and high bytes of its address.
+func (pic *PIC) Acknowledge() byte {
+	switch pic.acknowledgeSeq {
+	case 0:
+		pic.acknowledgeSeq = 1
+		level, ok := pic.highestRequest()
+		if ok {
+			pic.setInService(level)
+		} else {
+			level = 7 // Spurious interrupt, which calls IR7 without setting it in service
+		}
+		pic.acknowledged = level
+		return callOpCode
+	case 1:
+		pic.acknowledgeSeq = 2
+		return pic.vectorLow(pic.acknowledged)
+	default:
+		pic.acknowledgeSeq = 0
+		if pic.icw4&icw4AutoEOI != 0 {
+			pic.endOfInterrupt(pic.acknowledged, pic.rotateAutoEOI)
+		}
+		return pic.icw2
+	}
+}
+
+// ReadPort reads the interrupt mask from the odd port, and the request or
+// in-service register, as selected by OCW3, or the poll word from the even port.
+func (pic *PIC) ReadPort(port byte) byte {
+	if port&1 == 1 {
+		return pic.imr
+	}
+
+	if pic.poll {
+		pic.poll = false
+		level, ok := pic.highestRequest()
+		if !ok {
+			return 0
+		}
+		pic.setInService(level)
+		return 0x80 | byte(level)
+	}
+
+	if pic.readISR {
+		return pic.isr
+	}
+	return pic.irr
+}
+
+// WritePort writes an initialization or operation command word.
+func (pic *PIC) WritePort(port byte, value byte) {
+	if port&1 == 0 {
+		switch {
+		case value&icw1 != 0:
+			pic.writeICW1(value)
+		case value&ocw3 != 0:
+			pic.writeOCW3(value)
+		default:
+			pic.writeOCW2(value)
+		}
+		return
+	}
+
+	switch pic.init {
+	case expectICW2:
+		pic.icw2 = value
+		pic.init = pic.nextAfterICW2()
+	case expectICW3:
+		pic.init = ready
+		if pic.icw1&icw1NeedICW4 != 0 {
+			pic.init = expectICW4
+		}
+	case expectICW4:
+		pic.icw4 = value
+		pic.init = ready
+	case ready:
+		pic.imr = value // OCW1
+	}
+}
+
+// writeICW1 starts the initialization sequence, clearing the mask, the
+// in-service register and any special modes.
+func (pic *PIC) writeICW1(value byte) {
+	pic.icw1 = value
+	pic.icw4 = 0
+	pic.init = expectICW2
+
+	pic.imr, pic.isr = 0, 0
+	pic.lowest = 7
+	pic.rotateAutoEOI, pic.specialMask = false, false
+	pic.readISR, pic.poll = false, false
+	pic.acknowledgeSeq = 0
+
+	// The edge sense circuit is reset, so in edge triggered mode a request needs
+	// a new rising edge
+	pic.irr = 0
+	if value&icw1Level != 0 {
+		pic.irr = pic.lines
+	}
+}
+
+// nextAfterICW2 returns the initialization command word expected after ICW2.
+func (pic *PIC) nextAfterICW2() initState {
+	switch {
+	case pic.icw1&icw1Single == 0:
+		return expectICW3
+	case pic.icw1&icw1NeedICW4 != 0:
+		return expectICW4
+	default:
+		return ready
+	}
+}
+
+// writeOCW2 handles the end of interrupt and priority rotation commands.
+func (pic *PIC) writeOCW2(value byte) {
+	level := int(value & 0b111)
+
+	switch value >> 5 {
+	case ocw2NonSpecificEOI:
+		if current, ok := pic.highestInService(); ok {
+			pic.endOfInterrupt(current, false)
+		}
+	case ocw2RotateNonSpecific:
+		if current, ok := pic.highestInService(); ok {
+			pic.endOfInterrupt(current, true)
+		}
+	case ocw2SpecificEOI:
+		pic.endOfInterrupt(level, false)
+	case ocw2RotateSpecific:
+		pic.endOfInterrupt(level, true)
+	case ocw2RotateAutoEOISet:
+		pic.rotateAutoEOI = true
+	case ocw2RotateAutoEOIClear:
+		pic.rotateAutoEOI = false
+	case ocw2SetPriority:
+		pic.lowest = level
+	case ocw2NoOperation:
+	}
+}
+
+// writeOCW3 selects the register read from the even port, issues a poll
+// command, or sets or resets special mask mode.
+func (pic *PIC) writeOCW3(value byte) {
+	if value&ocw3SetSpecialMask != 0 {
+		pic.specialMask = value&ocw3SpecialMask != 0
+	}
+	if value&ocw3ReadRegister != 0 {
+		pic.readISR = value&ocw3ReadISR != 0
+	}
+	pic.poll = value&ocw3Poll != 0
+}
+
+// setInService moves a request into service.  In level triggered mode the
+// request remains until the line goes low.
+func (pic *PIC) setInService(level int) {
+	bit := byte(1) << level
+	pic.isr |= bit
+	if pic.icw1&icw1Level == 0 {
+		pic.irr &^= bit
+	}
+}
+
+// endOfInterrupt clears level from the in-service register, making it the
+// lowest priority level if rotate is set.
+func (pic *PIC) endOfInterrupt(level int, rotate bool) {
+	pic.isr &^= 1 << level
+	if rotate {
+		pic.lowest = level
+	}
+}
+
+// priority returns the IR level with the given priority, where 0 is the highest.
+func (pic *PIC) priority(rank int) int {
+	return (pic.lowest + 1 + rank) % 8
+}
+
+// highestInService returns the highest priority level in service.
+func (pic *PIC) highestInService() (int, bool) {
+	for rank := 0; rank < 8; rank++ {
+		level := pic.priority(rank)
+		if pic.isr&(1<<level) != 0 {
+			return level, true
+		}
+	}
+
+	return 0, false
+}
+
+// highestRequest returns the highest priority unmasked request that can
+// interrupt the levels in service.  In special mask mode, a level in service
+// only blocks further requests at that level, rather than at every lower
+// priority level too.
+func (pic *PIC) highestRequest() (int, bool) {
+	if pic.init != ready {
+		return 0, false
+	}
+
+	requests := pic.irr &^ pic.imr
+	if pic.specialMask {
+		requests &^= pic.isr
+	}
+
+	for rank := 0; rank < 8; rank++ {
+		level := pic.priority(rank)
+		bit := byte(1) << level
+		if pic.isr&bit != 0 && !pic.specialMask {
+			return 0, false
+		}
+		if requests&bit != 0 {
+			return level, true
+		}
+	}
+
+	return 0, false
+}
+
+// vectorLow returns the low byte of the CALL address for level: the address
+// bits from ICW1 with the level in bits 4 to 2 at an interval of 4, or in bits
+// 5 to 3 at an interval of 8.
+func (pic *PIC) vectorLow(level int) byte {
+	if pic.icw1&icw1Interval != 0 {
+		return pic.icw1&0b1110_0000 | byte(level)<<2
+	}
+
+	return pic.icw1&0b1100_0000 | byte(level)<<3
+}
@@ -0,0 +1,317 @@
+package pic
+
+import (
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+const (
+	evenPort = 0x20
+	oddPort  = 0x21
+
+	icw1Edge  = icw1 | icw1Single | icw1Interval // Edge triggered, no slaves, interval of 4, no ICW4
+	icw1Edge8 = icw1 | icw1Single                // As icw1Edge, with an interval of 8
+)
+
+// newPIC returns a PIC initialized with icw1, a vector address page of 0x01
+// and all lines unmasked.
+func newPIC(icw1 byte) *PIC {
+	pic := New()
+	pic.WritePort(evenPort, icw1)
+	pic.WritePort(oddPort, 0x01)
+	return pic
+}
+
+// acknowledge runs the three INTA cycles, returning the CALL's address.
+func acknowledge(t *testing.T, pic *PIC) uint16 {
+	t.Helper()
+
+	if opCode := pic.Acknowledge(); opCode != callOpCode {
+		t.Fatalf("first INTA = 0x%02X, want CALL (0x%02X)", opCode, callOpCode)
+	}
+	low := pic.Acknowledge()
+	high := pic.Acknowledge()
+	return uint16(high)<<8 | uint16(low)
+}
+
+func TestInitialization(t *testing.T) {
+	pic := New()
+	pic.Request(0)
+	if pic.Pending() {
+		t.Errorf("Pending() = true before initialization, want false")
+	}
+
+	// ICW1 needing ICW3 (cascaded) and ICW4, then ICW2, ICW3, ICW4 and OCW1
+	pic.WritePort(evenPort, icw1|icw1NeedICW4)
+	for _, value := range []byte{0x10, 0x00, icw4AutoEOI, 0b1111_1110} {
+		if pic.Pending() {
+			t.Errorf("Pending() = true during initialization, want false")
+		}
+		pic.WritePort(oddPort, value)
+	}
+
+	if got := pic.ReadPort(oddPort); got != 0b1111_1110 {
+		t.Errorf("mask = 0b%08b, want 0b11111110", got)
+	}
+	if pic.Pending() {
+		t.Errorf("Pending() = true for a request made before initialization, want false")
+	}
+
+	pic.Request(0)
+	if address := acknowledge(t, pic); address != 0x1000 {
+		t.Errorf("CALL address = 0x%04X, want 0x1000", address)
+	}
+	if pic.isr != 0 {
+		t.Errorf("ISR = 0b%08b with automatic EOI, want 0", pic.isr)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	tests := []struct {
+		icw1  byte
+		level int
+		want  uint16
+	}{
+		{icw1Edge, 0, 0x0100},
+		{icw1Edge, 3, 0x010C},
+		{icw1Edge, 7, 0x011C},
+		{icw1Edge | 0xE0, 5, 0x01F4},
+		{icw1Edge8, 0, 0x0100},
+		{icw1Edge8, 3, 0x0118},
+		{icw1Edge8, 7, 0x0138},
+		{icw1Edge8 | 0xC0, 2, 0x01D0},
+		{icw1Edge8 | 0x20, 2, 0x0110}, // Bit 5 isn't part of the address at an interval of 8
+	}
+
+	for _, test := range tests {
+		pic := newPIC(test.icw1)
+		pic.Request(test.level)
+
+		if address := acknowledge(t, pic); address != test.want {
+			t.Errorf("ICW1 0x%02X IR%d CALL address = 0x%04X, want 0x%04X", test.icw1, test.level, address, test.want)
+		}
+	}
+}
+
+func TestPriority(t *testing.T) {
+	pic := newPIC(icw1Edge)
+	pic.Request(5)
+	pic.Request(2)
+
+	if address := acknowledge(t, pic); address != 0x0108 {
+		t.Errorf("CALL address = 0x%04X, want IR2 (0x0108) first", address)
+	}
+	if pic.Pending() {
+		t.Errorf("Pending() = true for IR5 with IR2 in service, want false")
+	}
+
+	pic.Request(1)
+	if address := acknowledge(t, pic); address != 0x0104 {
+		t.Errorf("CALL address = 0x%04X, want IR1 (0x0104) to nest inside IR2", address)
+	}
+
+	pic.WritePort(evenPort, ocw2NonSpecificEOI<<5) // Ends IR1
+	pic.WritePort(evenPort, ocw2NonSpecificEOI<<5) // Ends IR2
+	if address := acknowledge(t, pic); address != 0x0114 {
+		t.Errorf("CALL address = 0x%04X, want IR5 (0x0114) after EOI", address)
+	}
+}
+
+func TestSpecificEOI(t *testing.T) {
+	pic := newPIC(icw1Edge)
+	pic.Request(3)
+	pic.Request(6)
+	acknowledge(t, pic)
+
+	pic.WritePort(evenPort, ocw2SpecificEOI<<5|6) // Not in service, so no effect
+	if pic.Pending() {
+		t.Errorf("Pending() = true after EOI of a level not in service, want false")
+	}
+
+	pic.WritePort(evenPort, ocw2SpecificEOI<<5|3)
+	if !pic.Pending() {
+		t.Errorf("Pending() = false after EOI of IR3, want IR6 pending")
+	}
+}
+
+func TestMask(t *testing.T) {
+	pic := newPIC(icw1Edge)
+	pic.WritePort(oddPort, 0b0000_0100) // Mask IR2
+	pic.Request(2)
+
+	if pic.Pending() {
+		t.Errorf("Pending() = true for a masked request, want false")
+	}
+
+	pic.WritePort(oddPort, 0)
+	if address := acknowledge(t, pic); address != 0x0108 {
+		t.Errorf("CALL address = 0x%04X, want IR2 (0x0108) once unmasked", address)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	pic := newPIC(icw1Edge)
+	pic.Request(2)
+	acknowledge(t, pic)
+	pic.WritePort(evenPort, ocw2RotateNonSpecific<<5) // IR2 becomes the lowest priority
+
+	pic.Request(2)
+	pic.Request(6)
+	if address := acknowledge(t, pic); address != 0x0118 {
+		t.Errorf("CALL address = 0x%04X, want IR6 (0x0118) ahead of IR2 after rotation", address)
+	}
+	pic.WritePort(evenPort, ocw2NonSpecificEOI<<5)
+
+	pic.WritePort(evenPort, ocw2SetPriority<<5|7) // IR0 is the highest priority again
+	pic.Request(6)
+	if address := acknowledge(t, pic); address != 0x0108 {
+		t.Errorf("CALL address = 0x%04X, want IR2 (0x0108) after setting priority", address)
+	}
+}
+
+func TestSpecialMask(t *testing.T) {
+	pic := newPIC(icw1Edge)
+	pic.Request(1)
+	acknowledge(t, pic)
+
+	pic.Request(4)
+	if pic.Pending() {
+		t.Errorf("Pending() = true for IR4 with IR1 in service, want false")
+	}
+
+	pic.WritePort(oddPort, 0b0000_0010)                              // Mask IR1
+	pic.WritePort(evenPort, ocw3|ocw3SetSpecialMask|ocw3SpecialMask) // Special mask mode
+	if address := acknowledge(t, pic); address != 0x0110 {
+		t.Errorf("CALL address = 0x%04X, want IR4 (0x0110) in special mask mode", address)
+	}
+}
+
+func TestReadRegisters(t *testing.T) {
+	pic := newPIC(icw1Edge)
+	pic.Request(3)
+	pic.Request(7)
+
+	if got := pic.ReadPort(evenPort); got != 0b1000_1000 {
+		t.Errorf("IRR = 0b%08b, want 0b10001000", got)
+	}
+
+	acknowledge(t, pic)
+	pic.WritePort(evenPort, ocw3|ocw3ReadRegister|ocw3ReadISR)
+	if got := pic.ReadPort(evenPort); got != 0b0000_1000 {
+		t.Errorf("ISR = 0b%08b, want 0b00001000", got)
+	}
+
+	pic.WritePort(evenPort, ocw3|ocw3ReadRegister)
+	if got := pic.ReadPort(evenPort); got != 0b1000_0000 {
+		t.Errorf("IRR = 0b%08b, want 0b10000000", got)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	pic := newPIC(icw1Edge)
+	pic.WritePort(evenPort, ocw3|ocw3Poll)
+	if got := pic.ReadPort(evenPort); got != 0 {
+		t.Errorf("poll = 0x%02X with no requests, want 0", got)
+	}
+
+	pic.Request(6)
+	pic.WritePort(evenPort, ocw3|ocw3Poll)
+	if got := pic.ReadPort(evenPort); got != 0x86 {
+		t.Errorf("poll = 0x%02X, want 0x86 (interrupt on IR6)", got)
+	}
+	if pic.isr != 0b0100_0000 {
+		t.Errorf("ISR = 0b%08b after polling, want IR6 in service", pic.isr)
+	}
+}
+
+func TestLevelTriggered(t *testing.T) {
+	pic := newPIC(icw1Edge | icw1Level)
+	pic.SetLine(4, true)
+	acknowledge(t, pic)
+	pic.WritePort(evenPort, ocw2NonSpecificEOI<<5)
+
+	if !pic.Pending() {
+		t.Errorf("Pending() = false with the line still high, want true")
+	}
+
+	pic.SetLine(4, false)
+	if pic.Pending() {
+		t.Errorf("Pending() = true with the line low, want false")
+	}
+}
+
+func TestSpuriousInterrupt(t *testing.T) {
+	pic := newPIC(icw1Edge)
+	if address := acknowledge(t, pic); address != 0x011C {
+		t.Errorf("CALL address = 0x%04X, want IR7 (0x011C) with no request", address)
+	}
+	if pic.isr != 0 {
+		t.Errorf("ISR = 0b%08b after a spurious interrupt, want 0", pic.isr)
+	}
+}
+
+func TestInterruptCPU(t *testing.T) {
+	// The main program initializes the PIC and spins until both handlers have
+	// run.  Each handler records its IR level at (HL), then sends a
+	// non-specific EOI.
+	program := make([]byte, 0x220)
+	copy(program[0x0000:], []byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0x21, 0x00, 0x03, // 0003 LXI H,0x0300
+		0x3E, icw1Edge, // 0006 MVI A,icw1Edge
+		0xD3, evenPort, // 0008 OUT evenPort
+		0x3E, 0x01, // 000A MVI A,0x01
+		0xD3, oddPort, // 000C OUT oddPort
+		0x3E, 0x00, // 000E MVI A,0x00
+		0xD3, oddPort, // 0010 OUT oddPort
+		0xFB,       // 0012 EI
+		0x78,       // 0013 MOV A,B
+		0xFE, 0x02, // 0014 CPI 0x02
+		0xC2, 0x13, 0x00, // 0016 JNZ 0x0013
+		0x76, // 0019 HLT
+	})
+	copy(program[0x0104:], []byte{0xC3, 0x00, 0x02}) // 0104 JMP 0x0200 (IR1)
+	copy(program[0x0110:], []byte{0xC3, 0x10, 0x02}) // 0110 JMP 0x0210 (IR4)
+	for address, level := range map[int]byte{0x0200: 1, 0x0210: 4} {
+		copy(program[address:], []byte{
+			0xF5,        // PUSH PSW
+			0x36, level, // MVI M,level
+			0x23,       // INX H
+			0x04,       // INR B
+			0x3E, 0x20, // MVI A,0x20 (non-specific EOI)
+			0xD3, evenPort, // OUT evenPort
+			0xF1, // POP PSW
+			0xFB, // EI
+			0xC9, // RET
+		})
+	}
+
+	pic := New()
+	goCPU := cpu.New()
+	goCPU.Attach(pic, evenPort, oddPort)
+	goCPU.SetInterruptController(pic)
+	goCPU.Load(program)
+
+	for i := 0; i < 8; i++ { // Initialize the PIC
+		goCPU.Step()
+	}
+	pic.Request(4)
+	pic.Request(1)
+
+	err := goCPU.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	for i, want := range []byte{1, 4} {
+		got, _ := goCPU.Bus.ReadByteAt(0x0300 + types.Word(i))
+		if got != want {
+			t.Errorf("handler %d was for IR%d, want IR%d", i, got, want)
+		}
+	}
+	if pic.isr != 0 {
+		t.Errorf("ISR = 0b%08b after both handlers, want 0", pic.isr)
+	}
+}
@@ -0,0 +1,414 @@
+package pit
+
+// Control word fields, as written to the control port.
+const (
+	selectReadBack = 0b11 // Counter select value for the 8254 read-back command
+
+	accessLatch   = 0b00 // Counter latch command
+	accessLSB     = 0b01 // Read/write the least significant byte only
+	accessMSB     = 0b10 // Read/write the most significant byte only
+	accessLSBThen = 0b11 // Read/write the least then most significant byte
+)
+
+// counter holds the state of one of the three 16-bit down counters.
+type counter struct {
+	mode   byte
+	bcd    bool
+	access byte
+
+	reload  uint16 // Count register, written by the CPU
+	count   uint16 // Counting element
+	counted bool   // A count has been loaded, and the counter is counting
+
+	writeMSB    bool // The next write is the MSB of a two byte count
+	writeLow    byte
+	readMSB     bool // The next read is the MSB of a two byte count
+	loadPending bool // The count register is copied to the counting element on the next clock
+	nullCount   bool // A count has been written, but not yet loaded
+
+	latched       bool
+	latch         uint16
+	statusLatched bool
+	status        byte
+
+	gate   bool
+	output bool
+	strobe bool // Mode 4 and 5 strobe is low, and goes high on the next clock
+}
+
+// PIT emulates an Intel 8253 programmable interval timer, including the 8254's
+// read-back command: three independent 16-bit down counters, each counting in
+// binary or BCD in one of six modes.
+//
+// It occupies four ports, decoded from the two least significant bits of the
+// port number: counters 0 to 2, then the control word.  The counters are
+// clocked by the CPU: their input clock runs at inputHz, derived from the
+// clock states the CPU executes at cpuHz.  All gates are high unless set
+// otherwise, as they are when tied high on most boards.
+//
+// Example:
+//
+//	timer := pit.New(2_000_000, 2_000_000)
+//	timer.OnOutput = func(counter int, high bool) {
+//		if counter == 0 && high {
+//			cpu.Interrupt(0xFF) // RST 7 when counter 0 fires
+//		}
+//	}
+//	cpu.Attach(timer, 0x40, 0x41, 0x42, 0x43)
+type PIT struct {
+	// OnOutput, when set, is called each time a counter's OUT pin changes
+	// level, for example to raise an interrupt or generate a tone.
+	OnOutput func(counter int, high bool)
+
+	counters [3]counter
+
+	cpuHz   float64
+	inputHz float64
+	pulses  float64 // Fraction of an input clock pulse carried over between ticks
+}
+
+// New returns a PIT whose counters are clocked at inputHz, driven by a CPU
+// running at cpuHz.
+func New(cpuHz, inputHz float64) *PIT {
+	pit := &PIT{cpuHz: cpuHz, inputHz: inputHz}
+	for i := range pit.counters {
+		pit.counters[i].gate = true
+		pit.counters[i].access = accessLSBThen
+	}
+
+	return pit
+}
+
+// Output returns the level of a counter's OUT pin.
+func (pit *PIT) Output(counter int) bool {
+	return pit.counters[counter].output
+}
+
+// Frequency returns the frequency of a counter's output in mode 3 (the square
+// wave generator), or 0 in any other mode or before a count has been loaded.
+func (pit *PIT) Frequency(counter int) float64 {
+	c := &pit.counters[counter]
+	if c.mode != 3 || !c.counted {
+		return 0
+	}
+
+	return pit.inputHz / float64(c.period(c.reload))
+}
+
+// SetGate sets the level of a counter's GATE input.  In modes 0 and 4 a low
+// gate pauses counting; in modes 1 and 5 a rising edge triggers the counter;
+// and in modes 2 and 3 a low gate holds OUT high, with a rising edge
+// restarting the count.
+func (pit *PIT) SetGate(counter int, high bool) {
+	c := &pit.counters[counter]
+	rising := high && !c.gate
+	c.gate = high
+
+	switch c.mode {
+	case 1, 5:
+		if rising {
+			c.loadPending = true
+		}
+	case 2, 3:
+		if !high {
+			pit.setOutput(counter, true)
+		} else if rising {
+			c.loadPending = true
+		}
+	}
+}
+
+// ReadPort reads a counter's count, or its latched count or status.  Reading
+// the control port returns 0xFF, as nothing drives the data bus.
+func (pit *PIT) ReadPort(port byte) byte {
+	index := int(port & 0b11)
+	if index == 3 {
+		return 0xFF
+	}
+	c := &pit.counters[index]
+
+	if c.statusLatched {
+		c.statusLatched = false
+		return c.status
+	}
+
+	value := c.count
+	if c.latched {
+		value = c.latch
+	}
+
+	var result byte
+	switch c.access {
+	case accessLSB:
+		result = byte(value)
+		c.latched = false
+	case accessMSB:
+		result = byte(value >> 8)
+		c.latched = false
+	default:
+		if c.readMSB {
+			result = byte(value >> 8)
+			c.latched = false
+		} else {
+			result = byte(value)
+		}
+		c.readMSB = !c.readMSB
+	}
+
+	return result
+}
+
+// WritePort writes a byte of a counter's count, or a control word.
+func (pit *PIT) WritePort(port byte, value byte) {
+	index := int(port & 0b11)
+	if index == 3 {
+		pit.writeControl(value)
+		return
+	}
+	c := &pit.counters[index]
+
+	switch c.access {
+	case accessLSB:
+		pit.writeCount(index, uint16(value))
+	case accessMSB:
+		pit.writeCount(index, uint16(value)<<8)
+	default:
+		if c.writeMSB {
+			pit.writeCount(index, uint16(value)<<8|uint16(c.writeLow))
+		} else {
+			c.writeLow = value
+			if c.mode == 0 {
+				pit.setOutput(index, false) // Writing the first byte stops mode 0 counting
+				c.counted = false
+			}
+		}
+		c.writeMSB = !c.writeMSB
+	}
+}
+
+// writeControl handles a control word: programming a counter's mode, latching
+// its count, or (when the counter select bits are both set) the 8254
+// read-back command.
+func (pit *PIT) writeControl(value byte) {
+	selected := value >> 6
+	access := value >> 4 & 0b11
+
+	if selected == selectReadBack {
+		for i := range pit.counters {
+			if value&(1<<(i+1)) == 0 {
+				continue
+			}
+			c := &pit.counters[i]
+			if value&(1<<5) == 0 && !c.latched { // Latch count, active low
+				c.latched, c.latch, c.readMSB = true, c.count, false
+			}
+			if value&(1<<4) == 0 && !c.statusLatched { // Latch status, active low
+				c.statusLatched, c.status = true, c.statusByte()
+			}
+		}
+		return
+	}
+
+	c := &pit.counters[selected]
+	if access == accessLatch {
+		if !c.latched {
+			c.latched, c.latch, c.readMSB = true, c.count, false
+		}
+		return
+	}
+
+	mode := value >> 1 & 0b111
+	if mode > 5 {
+		mode -= 4 // Modes 6 and 7 are aliases of 2 and 3
+	}
+
+	c.mode = mode
+	c.bcd = value&1 == 1
+	c.access = access
+	c.writeMSB, c.readMSB = false, false
+	c.latched, c.statusLatched = false, false
+	c.counted, c.loadPending, c.strobe = false, false, false
+	c.nullCount = true
+	pit.setOutput(int(selected), mode != 0)
+}
+
+// writeCount stores a newly written count in the count register.  It's loaded
+// into the counting element on the next clock, except in modes 2 and 3 while
+// counting, where the new count takes effect at the end of the current period,
+// and in modes 1 and 5, which wait for the gate to trigger them.
+func (pit *PIT) writeCount(index int, value uint16) {
+	c := &pit.counters[index]
+	c.reload = value
+	c.nullCount = true
+
+	switch c.mode {
+	case 0:
+		pit.setOutput(index, false)
+		c.loadPending = true
+	case 2, 3:
+		if !c.counted {
+			c.loadPending = true
+		}
+	case 4:
+		c.loadPending = true
+	}
+}
+
+// Tick advances the counters by the input clock pulses that occur during the
+// given number of CPU clock states.
+func (pit *PIT) Tick(cycles uint64) {
+	if pit.cpuHz <= 0 {
+		return
+	}
+
+	pit.pulses += float64(cycles) * pit.inputHz / pit.cpuHz
+	for ; pit.pulses >= 1; pit.pulses-- {
+		for i := range pit.counters {
+			pit.clock(i)
+		}
+	}
+}
+
+// clock applies a single input clock pulse to a counter.
+func (pit *PIT) clock(index int) {
+	c := &pit.counters[index]
+
+	if c.strobe {
+		c.strobe = false
+		pit.setOutput(index, true)
+	}
+
+	if c.loadPending {
+		c.loadPending = false
+		c.count = c.reload
+		c.counted = true
+		c.nullCount = false
+		switch c.mode {
+		case 1:
+			pit.setOutput(index, false)
+		case 2, 3:
+			pit.setOutput(index, true)
+		}
+		return
+	}
+
+	if !c.counted {
+		return
+	}
+	if !c.gate && (c.mode == 0 || c.mode == 2 || c.mode == 3 || c.mode == 4) {
+		return
+	}
+
+	c.count = c.decrement(c.count)
+
+	switch c.mode {
+	case 0: // Interrupt on terminal count
+		if c.count == 0 {
+			pit.setOutput(index, true)
+		}
+	case 1: // Hardware retriggerable one-shot
+		if c.count == 0 {
+			pit.setOutput(index, true)
+			c.counted = false
+		}
+	case 2: // Rate generator
+		switch c.count {
+		case 1:
+			pit.setOutput(index, false)
+		case 0:
+			c.count = c.reload
+			c.nullCount = false
+			pit.setOutput(index, true)
+		}
+	case 3: // Square wave generator
+		period := c.period(c.reload)
+		if c.count == 0 {
+			c.count = c.reload
+			c.nullCount = false
+			pit.setOutput(index, true)
+		} else {
+			pit.setOutput(index, c.remaining(c.count) > period/2)
+		}
+	case 4, 5: // Software and hardware triggered strobe
+		if c.count == 0 {
+			pit.setOutput(index, false)
+			c.strobe = true
+			c.counted = false
+		}
+	}
+}
+
+// setOutput sets a counter's OUT pin, calling OnOutput if it changes.
+func (pit *PIT) setOutput(index int, high bool) {
+	c := &pit.counters[index]
+	if c.output == high {
+		return
+	}
+
+	c.output = high
+	if pit.OnOutput != nil {
+		pit.OnOutput(index, high)
+	}
+}
+
+// statusByte returns the 8254 status byte: OUT, null count, access, mode and BCD.
+func (c *counter) statusByte() byte {
+	var status byte
+	if c.output {
+		status |= 1 << 7
+	}
+	if c.nullCount {
+		status |= 1 << 6
+	}
+	status |= c.access<<4 | c.mode<<1
+	if c.bcd {
+		status |= 1
+	}
+
+	return status
+}
+
+// decrement returns value minus one, wrapping from 0 to 0xFFFF in binary, or
+// to 9999 in BCD.
+func (c *counter) decrement(value uint16) uint16 {
+	if !c.bcd {
+		return value - 1
+	}
+	if value == 0 {
+		return 0x9999
+	}
+
+	// Borrow from the next digit up for each trailing zero digit
+	for digit := 0; digit < 4; digit++ {
+		shift := digit * 4
+		if value>>shift&0xF != 0 {
+			return value - 1<<shift
+		}
+		value |= 0x9 << shift
+	}
+
+	return value
+}
+
+// remaining returns the number of clock pulses left in the current period for
+// a count, accounting for BCD.
+func (c *counter) remaining(value uint16) int {
+	if !c.bcd {
+		return int(value)
+	}
+
+	return int(value>>12&0xF)*1000 + int(value>>8&0xF)*100 + int(value>>4&0xF)*10 + int(value&0xF)
+}
+
+// period returns the number of clock pulses in a count, where a count of 0 is
+// the largest possible: 65536 in binary or 10000 in BCD.
+func (c *counter) period(value uint16) int {
+	if value != 0 {
+		return c.remaining(value)
+	}
+	if c.bcd {
+		return 10000
+	}
+
+	return 65536
+}
@@ -0,0 +1,269 @@
+package pit
+
+import (
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+)
+
+const (
+	counter0Port = 0x40
+	counter2Port = 0x42
+	controlPort  = 0x43
+)
+
+// program writes a control word selecting counter, mode and two byte access,
+// followed by count.
+func program(pit *PIT, counter int, mode byte, bcd bool, count uint16) {
+	control := byte(counter)<<6 | accessLSBThen<<4 | mode<<1
+	if bcd {
+		control |= 1
+	}
+	pit.WritePort(controlPort, control)
+	pit.WritePort(counter0Port+byte(counter), byte(count))
+	pit.WritePort(counter0Port+byte(counter), byte(count>>8))
+}
+
+// trace clocks the PIT one pulse at a time, returning the level of a counter's
+// output after each pulse as H or L.
+func trace(pit *PIT, counter int, pulses int) string {
+	levels := make([]byte, pulses)
+	for i := range levels {
+		pit.Tick(1)
+		levels[i] = 'L'
+		if pit.Output(counter) {
+			levels[i] = 'H'
+		}
+	}
+
+	return string(levels)
+}
+
+func TestModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    byte
+		count   uint16
+		trigger bool
+		want    string
+	}{
+		{"interrupt on terminal count", 0, 3, false, "LLLHHH"},
+		{"hardware retriggerable one-shot", 1, 2, true, "LLHHH"},
+		{"rate generator", 2, 3, false, "HHLHHLHHL"},
+		{"square wave even count", 3, 4, false, "HHLLHHLLH"},
+		{"square wave odd count", 3, 5, false, "HHHLLHHHLL"},
+		{"software triggered strobe", 4, 2, false, "HHLHHH"},
+		{"hardware triggered strobe", 5, 2, true, "HHLHHH"},
+		{"mode 6 is mode 2", 6, 3, false, "HHLHHL"},
+		{"mode 7 is mode 3", 7, 4, false, "HHLLHH"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pit := New(1, 1)
+			program(pit, 2, test.mode, false, test.count)
+			if test.trigger {
+				pit.SetGate(2, false)
+				pit.SetGate(2, true)
+			}
+
+			got := trace(pit, 2, len(test.want))
+			if got != test.want {
+				t.Errorf("output = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUntriggered(t *testing.T) {
+	for _, mode := range []byte{1, 5} {
+		pit := New(1, 1)
+		program(pit, 0, mode, false, 2)
+
+		if got := trace(pit, 0, 5); got != "HHHHH" {
+			t.Errorf("mode %d output = %s without a gate trigger, want HHHHH", mode, got)
+		}
+	}
+}
+
+func TestGate(t *testing.T) {
+	pit := New(1, 1)
+	program(pit, 0, 0, false, 3)
+
+	pit.Tick(1) // Loads the count
+	pit.SetGate(0, false)
+	if got := trace(pit, 0, 5); got != "LLLLL" {
+		t.Errorf("output = %s with the gate low, want counting paused", got)
+	}
+
+	pit.SetGate(0, true)
+	if got := trace(pit, 0, 3); got != "LLH" {
+		t.Errorf("output = %s with the gate high again, want LLH", got)
+	}
+}
+
+func TestBCD(t *testing.T) {
+	tests := []struct {
+		count uint16
+		want  uint16
+	}{
+		{0x0010, 0x0009},
+		{0x1000, 0x0999},
+		{0x0001, 0x0000},
+		{0x0000, 0x9999},
+		{0x0456, 0x0455},
+	}
+
+	for _, test := range tests {
+		pit := New(1, 1)
+		program(pit, 1, 0, true, test.count)
+		pit.Tick(2) // Loads the count, then decrements it
+
+		low := pit.ReadPort(counter0Port + 1)
+		high := pit.ReadPort(counter0Port + 1)
+		got := uint16(high)<<8 | uint16(low)
+		if got != test.want {
+			t.Errorf("count 0x%04X decremented to 0x%04X, want 0x%04X", test.count, got, test.want)
+		}
+	}
+}
+
+func TestLatch(t *testing.T) {
+	pit := New(1, 1)
+	program(pit, 0, 2, false, 0x1234)
+	pit.Tick(1)
+
+	pit.WritePort(controlPort, 0b00_00_0000) // Latch counter 0
+	pit.Tick(0x30)
+	pit.WritePort(controlPort, 0b00_00_0000) // Ignored while the first latch is unread
+
+	low := pit.ReadPort(counter0Port)
+	high := pit.ReadPort(counter0Port)
+	if low != 0x34 || high != 0x12 {
+		t.Errorf("latched count = 0x%02X%02X, want 0x1234", high, low)
+	}
+
+	low = pit.ReadPort(counter0Port)
+	high = pit.ReadPort(counter0Port)
+	if low != 0x04 || high != 0x12 {
+		t.Errorf("count = 0x%02X%02X after reading the latch, want 0x1204", high, low)
+	}
+}
+
+func TestByteAccess(t *testing.T) {
+	pit := New(1, 1)
+	pit.WritePort(controlPort, 0b10_10_0100) // Counter 2, MSB only, mode 2
+	pit.WritePort(counter2Port, 0x02)
+	pit.Tick(1)
+
+	if got := pit.ReadPort(counter2Port); got != 0x02 {
+		t.Errorf("MSB = 0x%02X, want 0x02", got)
+	}
+	if got := pit.ReadPort(counter2Port); got != 0x02 {
+		t.Errorf("MSB read again = 0x%02X, want 0x02", got)
+	}
+
+	pit.WritePort(controlPort, 0b10_01_0000) // Counter 2, LSB only, mode 0
+	pit.WritePort(counter2Port, 0x09)
+	pit.Tick(3)
+	if got := pit.ReadPort(counter2Port); got != 0x07 {
+		t.Errorf("LSB = 0x%02X, want 0x07", got)
+	}
+}
+
+func TestReadBack(t *testing.T) {
+	pit := New(1, 1)
+	program(pit, 1, 3, true, 0x0100)
+
+	pit.WritePort(controlPort, 0b11_10_0100) // Latch status of counter 1
+	if got, want := pit.ReadPort(counter0Port+1), byte(0b1_1_11_011_1); got != want {
+		t.Errorf("status = 0b%08b before loading, want 0b%08b", got, want)
+	}
+
+	pit.Tick(1)
+	pit.WritePort(controlPort, 0b11_00_0100) // Latch status and count of counter 1
+	if got, want := pit.ReadPort(counter0Port+1), byte(0b1_0_11_011_1); got != want {
+		t.Errorf("status = 0b%08b after loading, want 0b%08b", got, want)
+	}
+	low := pit.ReadPort(counter0Port + 1)
+	high := pit.ReadPort(counter0Port + 1)
+	if low != 0x00 || high != 0x01 {
+		t.Errorf("count = 0x%02X%02X, want 0x0100", high, low)
+	}
+
+	if got := pit.ReadPort(controlPort); got != 0xFF {
+		t.Errorf("control port read = 0x%02X, want 0xFF", got)
+	}
+}
+
+func TestFrequency(t *testing.T) {
+	pit := New(2_000_000, 1_193_182)
+	if got := pit.Frequency(0); got != 0 {
+		t.Errorf("Frequency() = %v before programming, want 0", got)
+	}
+
+	program(pit, 0, 3, false, 0)
+	pit.Tick(4)
+	if got, want := pit.Frequency(0), 1_193_182.0/65536; got != want {
+		t.Errorf("Frequency() = %v, want %v", got, want)
+	}
+}
+
+func TestClockRatio(t *testing.T) {
+	pit := New(2_000_000, 500_000) // One input pulse for every four clock states
+	program(pit, 0, 0, false, 10)
+
+	pit.Tick(43) // 10 pulses, plus 3 clock states towards the next
+	if pit.Output(0) {
+		t.Errorf("output high after 10 pulses, want low until the count reaches 0")
+	}
+
+	pit.Tick(1)
+	if !pit.Output(0) {
+		t.Errorf("output low after 11 pulses, want high")
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	// The program sets counter 0 to interrupt on terminal count, enables
+	// interrupts and spins until RST 7 halts the CPU.
+	program := append([]byte{
+		0x31, 0x00, 0x10, // LXI SP 0x1000
+		0x3E, 0b00_11_0000, // MVI A (counter 0, LSB then MSB, mode 0)
+		0xD3, controlPort, // OUT controlPort
+		0x3E, 0xE8, // MVI A 0xE8
+		0xD3, counter0Port, // OUT counter0Port
+		0x3E, 0x03, // MVI A 0x03
+		0xD3, counter0Port, // OUT counter0Port
+		0xFB,             // EI
+		0x04,             // 0010: INR B
+		0xC3, 0x10, 0x00, // 0011: JMP 0x0010
+	}, make([]byte, 0x38-0x14)...)
+	program = append(program,
+		0x76, // 0038: HLT
+	)
+
+	pit := New(2_000_000, 2_000_000)
+	goCPU := cpu.New()
+	var fired int
+	pit.OnOutput = func(counter int, high bool) {
+		if counter == 0 && high {
+			fired++
+			goCPU.Interrupt(0xFF) // RST 7
+		}
+	}
+	goCPU.Attach(pit, counter0Port, counter0Port+1, counter2Port, controlPort)
+	goCPU.Load(program)
+
+	err := goCPU.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	if fired != 1 {
+		t.Errorf("counter 0 fired %d times, want 1", fired)
+	}
+	if cycles := goCPU.Cycles(); cycles < 1000 || cycles > 1100 {
+		t.Errorf("cycles = %d, want the interrupt after about 1000 clock states", cycles)
+	}
+}
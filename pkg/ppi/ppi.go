@@ -0,0 +1,386 @@
+package ppi
+
+// Port identifies one of the PPI's three 8-bit ports.
+type Port int
+
+const (
+	PortA Port = iota
+	PortB
+	PortC
+)
+
+// Mode definition control word bits, written to the control port with bit 7 set.
+const (
+	controlModeSet     = 1 << 7
+	controlAMode       = 0b11 << 5 // Group A mode: 0, 1, or 2 when bit 6 is set
+	controlAInput      = 1 << 4
+	controlCUpperInput = 1 << 3
+	controlBMode       = 1 << 2 // Group B mode: 0 or 1
+	controlBInput      = 1 << 1
+	controlCLowerInput = 1 << 0
+)
+
+// Port C bits used for handshaking by ports A and B in modes 1 and 2.
+const (
+	pcINTRB = 1 << 0 // Port B interrupt request
+	pcIBFB  = 1 << 1 // Port B input buffer full (mode 1 input)
+	pcOBFB  = 1 << 1 // Port B output buffer full, active low (mode 1 output)
+	pcINTEB = 1 << 2 // Port B STB or ACK, and its interrupt enable
+	pcINTRA = 1 << 3 // Port A interrupt request
+	pcINTEA = 1 << 4 // Port A STB, and its input interrupt enable (INTE 2 in mode 2)
+	pcIBFA  = 1 << 5 // Port A input buffer full
+	pcACKA  = 1 << 6 // Port A ACK, and its output interrupt enable (INTE 1 in mode 2)
+	pcOBFA  = 1 << 7 // Port A output buffer full, active low
+)
+
+// group holds the handshake state of port A (group A) or port B (group B).
+type group struct {
+	inputLatch byte // Data strobed in by the peripheral
+	ibf        bool // Input buffer full
+	obf        bool // Output buffer full
+	inteIn     bool // Interrupt enable for input
+	inteOut    bool // Interrupt enable for output
+}
+
+// PPI emulates an Intel 8255 programmable peripheral interface: three 8-bit
+// parallel ports, A, B and C, with port A in mode 0 (basic I/O), 1 (strobed
+// I/O) or 2 (strobed bidirectional I/O), and port B in mode 0 or 1.  In modes 1
+// and 2, port C carries the handshake signals, and the rest of its bits remain
+// ordinary I/O.
+//
+// It occupies four ports, decoded from the two least significant bits of the
+// port number: ports A, B and C, then the control word.  The host drives the
+// input pins with SetInput, or by supplying them on demand from OnRead (to scan
+// a keypad against the columns selected by an output port, for example), and
+// observes the output pins through OnOutput.  In modes 1 and 2 the peripheral's
+// side of the handshake is Strobe and Acknowledge.
+//
+// Example:
+//
+//	parallel := ppi.New()
+//	parallel.OnOutput = func(port ppi.Port, value byte) {
+//		if port == ppi.PortB {
+//			leds.Show(value)
+//		}
+//	}
+//	parallel.SetInput(ppi.PortA, switches)
+//	cpu.Attach(parallel, 0x80, 0x81, 0x82, 0x83)
+type PPI struct {
+	// OnOutput, when set, is called each time the value on a port's output
+	// pins changes, with the new value.  Bits that are inputs are zero.
+	OnOutput func(port Port, value byte)
+
+	// OnRead, when set, is called when the CPU reads a port with input pins,
+	// and returns the levels of the pins, in place of those set by SetInput.
+	OnRead func(port Port) byte
+
+	// OnInterrupt, when set, is called each time the INTR output of port A or
+	// port B goes high, as if it were wired to an interrupt input.
+	OnInterrupt func(port Port)
+
+	control byte
+	latches [3]byte // Output latches for ports A, B and C
+	inputs  [3]byte // Input pin levels set by SetInput
+	groups  [2]group
+}
+
+// New returns a PPI in its reset state, with every port an input in mode 0.
+func New() *PPI {
+	ppi := &PPI{}
+	ppi.WritePort(3, controlModeSet|controlAInput|controlCUpperInput|controlBInput|controlCLowerInput)
+
+	return ppi
+}
+
+// SetInput sets the levels of a port's input pins.  Bits that are outputs are
+// ignored.
+func (ppi *PPI) SetInput(port Port, value byte) {
+	ppi.inputs[port] = value
+}
+
+// Output returns the value on a port's output pins.  Bits that are inputs are zero.
+func (ppi *PPI) Output(port Port) byte {
+	switch port {
+	case PortA:
+		if ppi.control&controlAInput != 0 && ppi.modeA() != 2 {
+			return 0 // Mode 2 is bidirectional, so the direction bit is ignored
+		}
+		return ppi.latches[PortA]
+	case PortB:
+		if ppi.control&controlBInput != 0 {
+			return 0
+		}
+		return ppi.latches[PortB]
+	default:
+		outputs := ^ppi.cInputMask() &^ ppi.handshakeMask()
+		return ppi.latches[PortC]&outputs | ppi.handshakeOutputs()
+	}
+}
+
+// Strobe latches value into port A or B, as a peripheral does by pulsing STB in
+// mode 1 input or mode 2.  It sets IBF, and INTR if input interrupts are enabled.
+func (ppi *PPI) Strobe(port Port, value byte) {
+	if port == PortC || !ppi.strobedInput(port) {
+		return
+	}
+
+	ppi.update(func() {
+		ppi.groups[port].inputLatch = value
+		ppi.groups[port].ibf = true
+	})
+}
+
+// Acknowledge returns the data written to port A or B, as a peripheral reads it
+// by pulsing ACK in mode 1 output or mode 2.  It clears OBF, and sets INTR if
+// output interrupts are enabled.
+func (ppi *PPI) Acknowledge(port Port) byte {
+	if port == PortC || !ppi.strobedOutput(port) {
+		return 0
+	}
+
+	ppi.update(func() {
+		ppi.groups[port].obf = false
+	})
+	return ppi.latches[port]
+}
+
+// ReadPort reads port A, B or C.  Reading the control port returns 0xFF, as
+// the 8255 doesn't allow the control word to be read back.
+func (ppi *PPI) ReadPort(port byte) byte {
+	switch Port(port & 0b11) {
+	case PortA:
+		return ppi.readData(PortA, ppi.control&controlAInput != 0)
+	case PortB:
+		return ppi.readData(PortB, ppi.control&controlBInput != 0)
+	case PortC:
+		handshake := ppi.handshakeMask()
+		inputs := ppi.cInputMask() &^ handshake
+		value := ppi.latches[PortC] &^ inputs &^ handshake
+		if inputs != 0 {
+			value |= ppi.readPins(PortC) & inputs
+		}
+		return value | ppi.status()
+	}
+
+	return 0xFF
+}
+
+// readData reads port A or B: the input latch when strobed, otherwise the
+// input pins or, for an output port, the output latch.
+func (ppi *PPI) readData(port Port, input bool) byte {
+	if ppi.strobedInput(port) {
+		var value byte
+		ppi.update(func() {
+			value = ppi.groups[port].inputLatch
+			ppi.groups[port].ibf = false
+		})
+		return value
+	}
+	if input {
+		return ppi.readPins(port)
+	}
+
+	return ppi.latches[port]
+}
+
+// readPins returns the levels of a port's input pins.
+func (ppi *PPI) readPins(port Port) byte {
+	if ppi.OnRead != nil {
+		return ppi.OnRead(port)
+	}
+
+	return ppi.inputs[port]
+}
+
+// WritePort writes to port A, B or C, or writes a control word: either a mode
+// definition or, when bit 7 is clear, a port C bit set/reset.
+func (ppi *PPI) WritePort(port byte, value byte) {
+	switch index := Port(port & 0b11); index {
+	case PortA, PortB:
+		ppi.update(func() {
+			ppi.latches[index] = value
+			if ppi.strobedOutput(index) {
+				ppi.groups[index].obf = true // Also clears INTR
+			}
+		})
+	case PortC:
+		ppi.update(func() {
+			ppi.latches[PortC] = value
+		})
+	default:
+		if value&controlModeSet != 0 {
+			ppi.update(func() {
+				ppi.control = value
+				ppi.latches = [3]byte{}
+				ppi.groups = [2]group{}
+			})
+			return
+		}
+		ppi.setResetBit(int(value>>1&0b111), value&1 == 1)
+	}
+}
+
+// setResetBit sets or resets a single bit of port C.  A bit used for an
+// interrupt enable in modes 1 and 2 sets that enable instead.
+func (ppi *PPI) setResetBit(bit int, set bool) {
+	ppi.update(func() {
+		mask := byte(1) << bit
+		switch {
+		case mask == pcINTEB && ppi.control&controlBMode != 0:
+			ppi.groups[PortB].inteIn, ppi.groups[PortB].inteOut = set, set
+		case mask == pcINTEA && ppi.strobedInput(PortA):
+			ppi.groups[PortA].inteIn = set
+		case mask == pcACKA && ppi.strobedOutput(PortA):
+			ppi.groups[PortA].inteOut = set
+		case set:
+			ppi.latches[PortC] |= mask
+		default:
+			ppi.latches[PortC] &^= mask
+		}
+	})
+}
+
+// update applies change, then calls OnOutput for each port whose output pins
+// changed, and OnInterrupt for each INTR output that went high.
+func (ppi *PPI) update(change func()) {
+	var before [3]byte
+	for port := range before {
+		before[port] = ppi.Output(Port(port))
+	}
+	intrBefore := [2]bool{ppi.intr(PortA), ppi.intr(PortB)}
+
+	change()
+
+	if ppi.OnOutput != nil {
+		for port := range before {
+			if value := ppi.Output(Port(port)); value != before[port] {
+				ppi.OnOutput(Port(port), value)
+			}
+		}
+	}
+	if ppi.OnInterrupt != nil {
+		for port, was := range intrBefore {
+			if !was && ppi.intr(Port(port)) {
+				ppi.OnInterrupt(Port(port))
+			}
+		}
+	}
+}
+
+// modeA returns the mode of group A: 0, 1 or 2.
+func (ppi *PPI) modeA() int {
+	mode := int(ppi.control & controlAMode >> 5)
+	if mode > 2 {
+		mode = 2 // Bit 5 is ignored when bit 6 selects mode 2
+	}
+
+	return mode
+}
+
+// strobedInput reports whether port A or B latches input with STB, in mode 1
+// input or mode 2.
+func (ppi *PPI) strobedInput(port Port) bool {
+	if port == PortA {
+		return ppi.modeA() == 2 || ppi.modeA() == 1 && ppi.control&controlAInput != 0
+	}
+
+	return ppi.control&controlBMode != 0 && ppi.control&controlBInput != 0
+}
+
+// strobedOutput reports whether port A or B hands output over with OBF and
+// ACK, in mode 1 output or mode 2.
+func (ppi *PPI) strobedOutput(port Port) bool {
+	if port == PortA {
+		return ppi.modeA() == 2 || ppi.modeA() == 1 && ppi.control&controlAInput == 0
+	}
+
+	return ppi.control&controlBMode != 0 && ppi.control&controlBInput == 0
+}
+
+// intr returns the level of the INTR output of port A or B.
+func (ppi *PPI) intr(port Port) bool {
+	group := &ppi.groups[port]
+
+	return ppi.strobedInput(port) && group.inteIn && group.ibf ||
+		ppi.strobedOutput(port) && group.inteOut && !group.obf
+}
+
+// cInputMask returns the port C bits set as inputs by the mode definition.
+func (ppi *PPI) cInputMask() byte {
+	var mask byte
+	if ppi.control&controlCUpperInput != 0 {
+		mask |= 0xF0
+	}
+	if ppi.control&controlCLowerInput != 0 {
+		mask |= 0x0F
+	}
+
+	return mask
+}
+
+// handshakeMask returns the port C bits used for handshaking.
+func (ppi *PPI) handshakeMask() byte {
+	var mask byte
+	switch {
+	case ppi.modeA() == 2:
+		mask |= pcINTRA | pcINTEA | pcIBFA | pcACKA | pcOBFA
+	case ppi.strobedInput(PortA):
+		mask |= pcINTRA | pcINTEA | pcIBFA
+	case ppi.strobedOutput(PortA):
+		mask |= pcINTRA | pcACKA | pcOBFA
+	}
+	if ppi.control&controlBMode != 0 {
+		mask |= pcINTRB | pcIBFB | pcINTEB
+	}
+
+	return mask
+}
+
+// handshakeOutputs returns the levels of the handshake signals the PPI drives
+// on port C: IBF, OBF (active low) and INTR.
+func (ppi *PPI) handshakeOutputs() byte {
+	var value byte
+	a, b := &ppi.groups[PortA], &ppi.groups[PortB]
+
+	if ppi.strobedInput(PortA) && a.ibf {
+		value |= pcIBFA
+	}
+	if ppi.strobedOutput(PortA) && !a.obf {
+		value |= pcOBFA
+	}
+	if ppi.intr(PortA) {
+		value |= pcINTRA
+	}
+
+	if ppi.strobedInput(PortB) && b.ibf {
+		value |= pcIBFB
+	}
+	if ppi.strobedOutput(PortB) && !b.obf {
+		value |= pcOBFB
+	}
+	if ppi.intr(PortB) {
+		value |= pcINTRB
+	}
+
+	return value
+}
+
+// status returns the handshake bits of port C as the CPU reads them: the
+// handshake outputs, with each interrupt enable in place of the STB or ACK
+// input it shares a bit with.
+func (ppi *PPI) status() byte {
+	value := ppi.handshakeOutputs()
+	a, b := &ppi.groups[PortA], &ppi.groups[PortB]
+
+	if ppi.strobedInput(PortA) && a.inteIn {
+		value |= pcINTEA
+	}
+	if ppi.strobedOutput(PortA) && a.inteOut {
+		value |= pcACKA
+	}
+	if ppi.control&controlBMode != 0 && (b.inteIn || b.inteOut) {
+		value |= pcINTEB
+	}
+
+	return value
+}
@@ -0,0 +1,236 @@
+package ppi
+
+import (
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+const (
+	portA       = 0x80
+	portB       = 0x81
+	portC       = 0x82
+	controlPort = 0x83
+)
+
+// output records the values passed to OnOutput.
+type output struct {
+	port  Port
+	value byte
+}
+
+func TestMode0(t *testing.T) {
+	ppi := New()
+	var outputs []output
+	ppi.OnOutput = func(port Port, value byte) { outputs = append(outputs, output{port, value}) }
+
+	ppi.WritePort(controlPort, controlModeSet|controlBInput|controlCLowerInput) // A and upper C out, B and lower C in
+	ppi.SetInput(PortB, 0x5A)
+	ppi.SetInput(PortC, 0xFF)
+
+	ppi.WritePort(portA, 0x42)
+	ppi.WritePort(portA, 0x42) // Unchanged, so no output
+	ppi.WritePort(portC, 0xA5)
+
+	if got := ppi.ReadPort(portB); got != 0x5A {
+		t.Errorf("port B = 0x%02X, want 0x5A", got)
+	}
+	if got := ppi.ReadPort(portA); got != 0x42 {
+		t.Errorf("port A = 0x%02X, want the output latch 0x42", got)
+	}
+	if got := ppi.ReadPort(portC); got != 0xAF {
+		t.Errorf("port C = 0x%02X, want 0xAF (upper output latch, lower input pins)", got)
+	}
+	if got := ppi.Output(PortB); got != 0 {
+		t.Errorf("Output(PortB) = 0x%02X for an input port, want 0", got)
+	}
+
+	want := []output{{PortA, 0x42}, {PortC, 0xA0}}
+	if len(outputs) != len(want) {
+		t.Fatalf("outputs = %v, want %v", outputs, want)
+	}
+	for i := range want {
+		if outputs[i] != want[i] {
+			t.Errorf("output %d = %v, want %v", i, outputs[i], want[i])
+		}
+	}
+}
+
+func TestModeSetResetsOutputs(t *testing.T) {
+	ppi := New()
+	ppi.WritePort(controlPort, controlModeSet)
+	ppi.WritePort(portA, 0xFF)
+	ppi.WritePort(portC, 0xFF)
+
+	ppi.WritePort(controlPort, controlModeSet)
+	for _, port := range []Port{PortA, PortB, PortC} {
+		if got := ppi.Output(port); got != 0 {
+			t.Errorf("Output(%d) = 0x%02X after a mode set, want 0", port, got)
+		}
+	}
+}
+
+func TestBitSetReset(t *testing.T) {
+	tests := []struct {
+		control byte
+		want    byte
+	}{
+		{0b0000_1111, 0b1000_0000}, // Set PC7
+		{0b0000_0001, 0b1000_0001}, // Set PC0
+		{0b0000_0101, 0b1000_0101}, // Set PC2
+		{0b0000_1110, 0b0000_0101}, // Reset PC7
+		{0b0111_0000, 0b0000_0100}, // Bits 6 to 4 are ignored, so reset PC0
+	}
+
+	ppi := New()
+	ppi.WritePort(controlPort, controlModeSet)
+	for _, test := range tests {
+		ppi.WritePort(controlPort, test.control)
+		if got := ppi.Output(PortC); got != test.want {
+			t.Errorf("control 0b%08b: port C = 0b%08b, want 0b%08b", test.control, got, test.want)
+		}
+	}
+}
+
+func TestMode1Input(t *testing.T) {
+	ppi := New()
+	var interrupts []Port
+	ppi.OnInterrupt = func(port Port) { interrupts = append(interrupts, port) }
+
+	ppi.WritePort(controlPort, controlModeSet|1<<5|controlAInput|controlBMode|controlBInput)
+	ppi.WritePort(controlPort, 4<<1|1) // Set INTE A (PC4)
+
+	ppi.Strobe(PortA, 0x31)
+	if got := ppi.ReadPort(portC); got != pcIBFA|pcINTEA|pcINTRA {
+		t.Errorf("port C = 0b%08b after strobing port A, want IBF, INTE and INTR", got)
+	}
+	if len(interrupts) != 1 || interrupts[0] != PortA {
+		t.Errorf("interrupts = %v, want [PortA]", interrupts)
+	}
+
+	if got := ppi.ReadPort(portA); got != 0x31 {
+		t.Errorf("port A = 0x%02X, want 0x31", got)
+	}
+	if got := ppi.ReadPort(portC); got != pcINTEA {
+		t.Errorf("port C = 0b%08b after reading port A, want only INTE", got)
+	}
+
+	ppi.Strobe(PortB, 0x32) // INTE B is clear, so no interrupt
+	if got := ppi.ReadPort(portC); got != pcINTEA|pcIBFB {
+		t.Errorf("port C = 0b%08b after strobing port B, want IBF B", got)
+	}
+	if len(interrupts) != 1 {
+		t.Errorf("interrupts = %v, want no interrupt for port B", interrupts)
+	}
+}
+
+func TestMode1Output(t *testing.T) {
+	ppi := New()
+	var interrupts []Port
+	ppi.OnInterrupt = func(port Port) { interrupts = append(interrupts, port) }
+
+	ppi.WritePort(controlPort, controlModeSet|1<<5|controlBMode)
+	if got := ppi.ReadPort(portC); got != pcOBFA|pcOBFB {
+		t.Errorf("port C = 0b%08b after the mode set, want OBF A and B inactive (high)", got)
+	}
+
+	ppi.WritePort(controlPort, 2<<1|1) // Set INTE B (PC2), raising INTR with the buffer empty
+	ppi.WritePort(portB, 0x77)
+	if got := ppi.ReadPort(portC); got != pcOBFA|pcINTEB {
+		t.Errorf("port C = 0b%08b after writing port B, want OBF B active (low) and INTR clear", got)
+	}
+
+	if got := ppi.Acknowledge(PortB); got != 0x77 {
+		t.Errorf("Acknowledge(PortB) = 0x%02X, want 0x77", got)
+	}
+	if got := ppi.ReadPort(portC); got != pcOBFA|pcOBFB|pcINTEB|pcINTRB {
+		t.Errorf("port C = 0b%08b after ACK, want OBF B inactive and INTR set", got)
+	}
+
+	want := []Port{PortB, PortB}
+	if len(interrupts) != len(want) || interrupts[0] != PortB || interrupts[1] != PortB {
+		t.Errorf("interrupts = %v, want %v", interrupts, want)
+	}
+}
+
+func TestMode2(t *testing.T) {
+	ppi := New()
+	ppi.WritePort(controlPort, controlModeSet|0b10<<5|controlCLowerInput)
+	ppi.WritePort(controlPort, 6<<1|1) // INTE 1 (PC6)
+	ppi.WritePort(controlPort, 4<<1|1) // INTE 2 (PC4)
+	ppi.SetInput(PortC, 0x05)
+
+	ppi.WritePort(portA, 0x12)
+	if got := ppi.ReadPort(portC); got != pcACKA|pcINTEA|0x05 {
+		t.Errorf("port C = 0b%08b after writing, want OBF active and both INTE", got)
+	}
+
+	ppi.Strobe(PortA, 0x34)
+	if got := ppi.ReadPort(portC); got != pcACKA|pcINTEA|pcIBFA|pcINTRA|0x05 {
+		t.Errorf("port C = 0b%08b after strobing, want IBF and INTR", got)
+	}
+	if got := ppi.Acknowledge(PortA); got != 0x12 {
+		t.Errorf("Acknowledge(PortA) = 0x%02X, want 0x12", got)
+	}
+	if got := ppi.ReadPort(portA); got != 0x34 {
+		t.Errorf("port A = 0x%02X, want 0x34", got)
+	}
+	if got := ppi.ReadPort(portC); got != pcOBFA|pcACKA|pcINTEA|pcINTRA|0x05 {
+		t.Errorf("port C = 0b%08b after both transfers, want OBF inactive and INTR for output", got)
+	}
+}
+
+func TestKeypadScan(t *testing.T) {
+	// A 4x4 keypad, with the column driven low on the upper half of port C and
+	// the rows read from port A.  The program scans each column in turn,
+	// storing the rows read at 0x0100 onwards.
+	program := make([]byte, 0x0100)
+	copy(program, []byte{
+		0x3E, 0x90, // 0000 MVI A,0x90 (A in, C out, mode 0)
+		0xD3, controlPort, // 0002 OUT controlPort
+		0x21, 0x00, 0x01, // 0004 LXI H,0x0100
+		0x06, 0xEF, // 0007 MVI B,0xEF
+		0x78,        // 0009 MOV A,B
+		0xD3, portC, // 000A OUT portC
+		0xDB, portA, // 000C IN portA
+		0x77,             // 000E MOV M,A
+		0x23,             // 000F INX H
+		0x78,             // 0010 MOV A,B
+		0x07,             // 0011 RLC
+		0x47,             // 0012 MOV B,A
+		0xDA, 0x09, 0x00, // 0013 JC 0x0009
+		0x76, // 0016 HLT
+	})
+
+	ppi := New()
+	pressed := map[int]int{1: 2, 3: 0} // Column: row
+	ppi.OnRead = func(port Port) byte {
+		rows := byte(0xFF)
+		for column := 0; column < 4; column++ {
+			if ppi.Output(PortC)&(0x10<<column) != 0 {
+				continue
+			}
+			if row, ok := pressed[column]; ok {
+				rows &^= 1 << row
+			}
+		}
+		return rows
+	}
+
+	goCPU := cpu.New()
+	goCPU.Attach(ppi, portA, portB, portC, controlPort)
+	goCPU.Load(program)
+
+	err := goCPU.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	for column, want := range []byte{0xFF, 0xFB, 0xFF, 0xFE} {
+		got, _ := goCPU.Bus.ReadByteAt(0x0100 + types.Word(column))
+		if got != want {
+			t.Errorf("column %d rows = 0b%08b, want 0b%08b", column, got, want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+
+	"github.com/lukepeterson/go8080cpu/pkg/symbols"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Field numbers of the profile.proto messages that go tool pprof reads.
+const (
+	profileSampleType   = 1
+	profileSample       = 2
+	profileMapping      = 3
+	profileLocation     = 4
+	profileFunction     = 5
+	profileStringTable  = 6
+	profilePeriodType   = 11
+	profilePeriod       = 12
+	valueTypeType       = 1
+	valueTypeUnit       = 2
+	sampleLocationID    = 1
+	sampleValue         = 2
+	mappingID           = 1
+	mappingMemoryLimit  = 3
+	mappingFilename     = 5
+	mappingHasFunctions = 7
+	mappingHasFilenames = 8
+	mappingHasLines     = 9
+	locationID          = 1
+	locationMappingID   = 2
+	locationAddress     = 3
+	locationLine        = 4
+	lineFunctionID      = 1
+	lineLine            = 2
+	functionID          = 1
+	functionName        = 2
+	functionSystemName  = 3
+	functionFilename    = 4
+	functionStartLine   = 5
+)
+
+// location is an address executed in a routine.
+type location struct {
+	address  types.Word
+	function types.Word
+}
+
+// WritePprof writes the profile gzipped in the protocol buffer format of
+// pprof, with instructions and cycles sampled for each chain of calls, so it
+// can be explored with go tool pprof.  Routines are named by their labels, and
+// placed on their source lines if the CPU's Symbols is a symbols.Table.
+//
+// Example:
+//
+//	file, _ := os.Create("cpu.pb.gz")
+//	profiler.WritePprof(file)
+//	file.Close()
+//	// go tool pprof -top cpu.pb.gz
+func (profiler *Profiler) WritePprof(w io.Writer) error {
+	var table *symbols.Table
+	if t, ok := profiler.processor.Symbols.(*symbols.Table); ok {
+		table = t
+	}
+
+	strings := []string{""}
+	stringIDs := map[string]uint64{"": 0}
+	str := func(text string) uint64 {
+		id, ok := stringIDs[text]
+		if !ok {
+			id = uint64(len(strings))
+			stringIDs[text] = id
+			strings = append(strings, text)
+		}
+		return id
+	}
+
+	var profile encoder
+	valueType := func(field int, kind, unit string) {
+		profile.message(field, func(message *encoder) {
+			message.uint64(valueTypeType, str(kind))
+			message.uint64(valueTypeUnit, str(unit))
+		})
+	}
+	valueType(profileSampleType, "instructions", "count")
+	valueType(profileSampleType, "cycles", "count")
+
+	// Samples are written in a fixed order so the same run gives the same file
+	keys := make([]sample, 0, len(profiler.samples))
+	for key := range profiler.samples {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].stack != keys[j].stack {
+			return keys[i].stack < keys[j].stack
+		}
+		return keys[i].address < keys[j].address
+	})
+
+	var locations []location
+	locationIDs := map[location]uint64{}
+	locate := func(address, function types.Word) uint64 {
+		key := location{address: address, function: function}
+		id, ok := locationIDs[key]
+		if !ok {
+			locations = append(locations, key)
+			id = uint64(len(locations))
+			locationIDs[key] = id
+		}
+		return id
+	}
+
+	for _, key := range keys {
+		calls := profiler.stacks[key.stack]
+		chain := profiler.chain(key.stack)
+
+		// Locations go innermost first: the address executed, then the calls
+		ids := []uint64{locate(key.address, chain[len(chain)-1])}
+		for i := len(calls) - 1; i >= 0; i-- {
+			ids = append(ids, locate(calls[i].From, chain[i]))
+		}
+		counts := profiler.samples[key]
+		profile.message(profileSample, func(message *encoder) {
+			message.packed(sampleLocationID, ids)
+			message.packed(sampleValue, []uint64{counts.Instructions, counts.Cycles})
+		})
+	}
+
+	profile.message(profileMapping, func(message *encoder) {
+		message.uint64(mappingID, 1)
+		message.uint64(mappingMemoryLimit, 0x10000)
+		message.uint64(mappingFilename, str("memory"))
+		message.bool(mappingHasFunctions, true)
+		message.bool(mappingHasFilenames, table != nil)
+		message.bool(mappingHasLines, table != nil)
+	})
+
+	var functions []types.Word
+	functionIDs := map[types.Word]uint64{}
+	for i, location := range locations {
+		if _, ok := functionIDs[location.function]; !ok {
+			functions = append(functions, location.function)
+			functionIDs[location.function] = uint64(len(functions))
+		}
+
+		line := uint64(0)
+		if table != nil {
+			if source, ok := table.Line(location.address); ok {
+				line = uint64(source.Line)
+			}
+		}
+		profile.message(profileLocation, func(message *encoder) {
+			message.uint64(locationID, uint64(i+1))
+			message.uint64(locationMappingID, 1)
+			message.uint64(locationAddress, uint64(location.address))
+			message.message(locationLine, func(message *encoder) {
+				message.uint64(lineFunctionID, functionIDs[location.function])
+				message.uint64(lineLine, line)
+			})
+		})
+	}
+
+	for i, address := range functions {
+		name := str(profiler.name(address))
+		var file, line uint64
+		if table != nil {
+			if source, ok := table.Line(address); ok {
+				file, line = str(source.File), uint64(source.Line)
+			}
+		}
+		profile.message(profileFunction, func(message *encoder) {
+			message.uint64(functionID, uint64(i+1))
+			message.uint64(functionName, name)
+			message.uint64(functionSystemName, name)
+			message.uint64(functionFilename, file)
+			message.uint64(functionStartLine, line)
+		})
+	}
+
+	valueType(profilePeriodType, "cycles", "count")
+	profile.uint64(profilePeriod, 1)
+
+	// The string table goes last, once everything has added its strings
+	for _, text := range strings {
+		profile.bytes(profileStringTable, []byte(text))
+	}
+
+	writer := gzip.NewWriter(w)
+	_, err := writer.Write(profile.data)
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// encoder encodes a protocol buffer message, leaving out fields with zero
+// values as proto3 does.
+type encoder struct {
+	data []byte
+}
+
+func (e *encoder) varint(value uint64) {
+	for value >= 0x80 {
+		e.data = append(e.data, byte(value)|0x80)
+		value >>= 7
+	}
+	e.data = append(e.data, byte(value))
+}
+
+// key encodes a field number with its wire type: 0 for a varint or 2 for bytes.
+func (e *encoder) key(field int, wireType uint64) {
+	e.varint(uint64(field)<<3 | wireType)
+}
+
+func (e *encoder) uint64(field int, value uint64) {
+	if value == 0 {
+		return
+	}
+	e.key(field, 0)
+	e.varint(value)
+}
+
+func (e *encoder) bool(field int, value bool) {
+	if value {
+		e.uint64(field, 1)
+	}
+}
+
+// bytes encodes a string or bytes field, even an empty one, as the string
+// table has to start with one.
+func (e *encoder) bytes(field int, value []byte) {
+	e.key(field, 2)
+	e.varint(uint64(len(value)))
+	e.data = append(e.data, value...)
+}
+
+// packed encodes a repeated integer field.
+func (e *encoder) packed(field int, values []uint64) {
+	var packed encoder
+	for _, value := range values {
+		packed.varint(value)
+	}
+	e.bytes(field, packed.data)
+}
+
+// message encodes a field holding the message encoded by encode.
+func (e *encoder) message(field int, encode func(message *encoder)) {
+	var message encoder
+	encode(&message)
+	e.bytes(field, message.data)
+}
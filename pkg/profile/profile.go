@@ -0,0 +1,258 @@
+package profile
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Counts is how many instructions were executed and the clock states they
+// took.
+type Counts struct {
+	Instructions uint64
+	Cycles       uint64
+}
+
+func (counts *Counts) add(other Counts) {
+	counts.Instructions += other.Instructions
+	counts.Cycles += other.Cycles
+}
+
+// sample is an address executed with a chain of calls on the stack, the index
+// of the chain in Profiler.stacks.
+type sample struct {
+	stack   int
+	address types.Word
+}
+
+// Profiler counts the instructions executed and clock states taken at each
+// address, along with the chain of calls on the shadow CallStack when they
+// were, so it can say where a program spends its time by address and by
+// routine.  It implements cpu.Profiler.
+//
+// Example:
+//
+//	profiler := profile.New(processor)
+//	processor.Profiler = profiler
+//	err := processor.Run()
+//	profiler.WriteFlat(os.Stdout)
+//	profiler.WritePprof(file) // For go tool pprof
+type Profiler struct {
+	processor *cpu.CPU
+	root      types.Word // Where profiling started, naming the outermost routine
+
+	hits    []Counts
+	total   Counts
+	samples map[sample]*Counts
+	entries map[types.Word]uint64 // Calls to each routine
+
+	// The calls on the stack before the instruction being executed, and the
+	// top and depth of the CallStack they were taken at
+	stack    []cpu.Call
+	stackID  int
+	top      cpu.Call
+	depth    int
+	stacks   [][]cpu.Call
+	stackIDs map[string]int
+}
+
+// New returns a profiler for processor, whose routines are named with its
+// Symbols.  It gives processor a CallStack if it has none, to attribute time to
+// the routines that called where it was spent.
+func New(processor *cpu.CPU) *Profiler {
+	if processor.CallStack == nil {
+		processor.CallStack = cpu.NewCallStack()
+	}
+
+	profiler := &Profiler{
+		processor: processor,
+		root:      processor.ProgramCounter(),
+		hits:      make([]Counts, 0x10000),
+		samples:   map[sample]*Counts{},
+		entries:   map[types.Word]uint64{},
+		stacks:    [][]cpu.Call{nil},
+		stackIDs:  map[string]int{"": 0},
+	}
+	profiler.refresh()
+
+	return profiler
+}
+
+// Executed counts the instruction at address taking cycles clock states,
+// against the calls on the stack before it was executed.
+func (profiler *Profiler) Executed(address types.Word, cycles uint64) {
+	counts := Counts{Instructions: 1, Cycles: cycles}
+	key := sample{stack: profiler.stackID, address: address}
+	if profiler.samples[key] == nil {
+		profiler.samples[key] = &Counts{}
+	}
+	profiler.samples[key].add(counts)
+	profiler.hits[address].add(counts)
+	profiler.total.add(counts)
+	profiler.refresh()
+}
+
+// Hits returns the counts for the instruction at address.
+func (profiler *Profiler) Hits(address types.Word) Counts {
+	return profiler.hits[address]
+}
+
+// Total returns the counts for the whole run.
+func (profiler *Profiler) Total() Counts {
+	return profiler.total
+}
+
+// refresh takes the calls on the CallStack if they've changed since the last
+// instruction, counting calls to the routines they add.
+func (profiler *Profiler) refresh() {
+	stack := profiler.processor.CallStack
+	if stack == nil {
+		return
+	}
+	top, _ := stack.Top()
+	if top == profiler.top && stack.Depth() == profiler.depth {
+		return
+	}
+
+	calls := stack.Calls()
+	kept := 0
+	for kept < len(calls) && kept < len(profiler.stack) && calls[kept] == profiler.stack[kept] {
+		kept++
+	}
+	for _, call := range calls[kept:] {
+		profiler.entries[call.To]++
+	}
+
+	key := make([]byte, 0, len(calls)*4)
+	for _, call := range calls {
+		key = append(key, byte(call.From), byte(call.From>>8), byte(call.To), byte(call.To>>8))
+	}
+	id, ok := profiler.stackIDs[string(key)]
+	if !ok {
+		id = len(profiler.stacks)
+		profiler.stackIDs[string(key)] = id
+		profiler.stacks = append(profiler.stacks, calls)
+	}
+
+	profiler.stack = calls
+	profiler.stackID = id
+	profiler.top = top
+	profiler.depth = len(calls)
+}
+
+// Edge is the time spent in a routine called from another.
+type Edge struct {
+	Address types.Word // The caller or routine called
+	Name    string
+	Counts
+}
+
+// Function is a routine: the address called and the time spent in it.  Self is
+// the time spent in its own instructions, and Total includes the routines it
+// called.  The outermost routine is the one profiling started in.
+type Function struct {
+	Address types.Word
+	Name    string
+	Calls   uint64
+	Self    Counts
+	Total   Counts
+	Callers []Edge // Most time first
+	Callees []Edge // Most time first
+}
+
+// Functions returns the routines executed, most time in total first.
+func (profiler *Profiler) Functions() []Function {
+	functions := map[types.Word]*Function{}
+	callers := map[[2]types.Word]*Counts{}
+	function := func(address types.Word) *Function {
+		if functions[address] == nil {
+			functions[address] = &Function{
+				Address: address,
+				Name:    profiler.name(address),
+				Calls:   profiler.entries[address],
+			}
+		}
+		return functions[address]
+	}
+
+	for key, counts := range profiler.samples {
+		chain := profiler.chain(key.stack)
+		function(chain[len(chain)-1]).Self.add(*counts)
+
+		seen := map[types.Word]bool{}
+		edges := map[[2]types.Word]bool{}
+		for i, address := range chain {
+			if !seen[address] {
+				seen[address] = true
+				function(address).Total.add(*counts)
+			}
+			if i == 0 {
+				continue
+			}
+			edge := [2]types.Word{chain[i-1], address}
+			if !edges[edge] {
+				edges[edge] = true
+				if callers[edge] == nil {
+					callers[edge] = &Counts{}
+				}
+				callers[edge].add(*counts)
+			}
+		}
+	}
+
+	for edge, counts := range callers {
+		caller, callee := function(edge[0]), function(edge[1])
+		caller.Callees = append(caller.Callees, Edge{Address: callee.Address, Name: callee.Name, Counts: *counts})
+		callee.Callers = append(callee.Callers, Edge{Address: caller.Address, Name: caller.Name, Counts: *counts})
+	}
+
+	sorted := make([]Function, 0, len(functions))
+	for _, function := range functions {
+		sortEdges(function.Callers)
+		sortEdges(function.Callees)
+		sorted = append(sorted, *function)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Total.Cycles != sorted[j].Total.Cycles {
+			return sorted[i].Total.Cycles > sorted[j].Total.Cycles
+		}
+		return sorted[i].Address < sorted[j].Address
+	})
+
+	return sorted
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Cycles != edges[j].Cycles {
+			return edges[i].Cycles > edges[j].Cycles
+		}
+		return edges[i].Address < edges[j].Address
+	})
+}
+
+// chain returns the routines in a chain of calls, outermost first.
+func (profiler *Profiler) chain(id int) []types.Word {
+	calls := profiler.stacks[id]
+	chain := make([]types.Word, 0, len(calls)+1)
+	chain = append(chain, profiler.root)
+	for _, call := range calls {
+		chain = append(chain, call.To)
+	}
+
+	return chain
+}
+
+// name names the routine at address with its label, or as a number if it has
+// none.
+func (profiler *Profiler) name(address types.Word) string {
+	if profiler.processor.Symbols != nil {
+		if label, ok := profiler.processor.Symbols.Label(address); ok {
+			return label
+		}
+	}
+
+	return fmt.Sprintf("0x%04X", address)
+}
@@ -0,0 +1,280 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/symbols"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// run profiles a program that calls DELAY, which counts C down from 5, for each
+// count of B down from 10.
+func run(t *testing.T) *Profiler {
+	t.Helper()
+
+	program := make([]byte, 0x20)
+	copy(program[0x00:], []byte{
+		0x31, 0x00, 0x10, // 0000 LXI SP,0x1000
+		0x06, 0x0A, // 0003 MVI B,10
+		0xCD, 0x10, 0x00, // 0005 CALL DELAY
+		0x05,             // 0008 DCR B
+		0xC2, 0x05, 0x00, // 0009 JNZ 0x0005
+		0x76, // 000C HLT
+	})
+	copy(program[0x10:], []byte{
+		0x0E, 0x05, // 0010 DELAY: MVI C,5
+		0x0D,             // 0012 DCR C
+		0xC2, 0x12, 0x00, // 0013 JNZ 0x0012
+		0xC9, // 0016 RET
+	})
+
+	processor := cpu.New()
+	processor.Load(program)
+	table := symbols.New()
+	table.AddLabel("START", 0x0000)
+	table.AddLabel("DELAY", 0x0010)
+	table.AddLine(0x0012, symbols.Location{File: "delay.asm", Line: 3})
+	processor.Symbols = table
+
+	profiler := New(processor)
+	processor.Profiler = profiler
+	err := processor.Run()
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+
+	return profiler
+}
+
+func TestProfiler(t *testing.T) {
+	profiler := run(t)
+
+	tests := []struct {
+		address types.Word
+		want    Counts
+	}{
+		{address: 0x0005, want: Counts{Instructions: 10, Cycles: 170}},
+		{address: 0x0012, want: Counts{Instructions: 50, Cycles: 250}},
+		{address: 0x0016, want: Counts{Instructions: 10, Cycles: 100}},
+		{address: 0x000C, want: Counts{Instructions: 1, Cycles: 7}},
+		{address: 0x0020, want: Counts{}},
+	}
+	for _, test := range tests {
+		if got := profiler.Hits(test.address); got != test.want {
+			t.Errorf("Hits(0x%04X) = %+v, want %+v", test.address, got, test.want)
+		}
+	}
+	if got, want := profiler.Total(), (Counts{Instructions: 153, Cycles: 1264}); got != want {
+		t.Errorf("Total() = %+v, want %+v", got, want)
+	}
+
+	// Calls count against the caller, and returns against the routine called
+	want := []Function{
+		{
+			Address: 0x0000,
+			Name:    "START",
+			Self:    Counts{Instructions: 33, Cycles: 344},
+			Total:   Counts{Instructions: 153, Cycles: 1264},
+			Callees: []Edge{{Address: 0x0010, Name: "DELAY", Counts: Counts{Instructions: 120, Cycles: 920}}},
+		},
+		{
+			Address: 0x0010,
+			Name:    "DELAY",
+			Calls:   10,
+			Self:    Counts{Instructions: 120, Cycles: 920},
+			Total:   Counts{Instructions: 120, Cycles: 920},
+			Callers: []Edge{{Address: 0x0000, Name: "START", Counts: Counts{Instructions: 120, Cycles: 920}}},
+		},
+	}
+	if got := profiler.Functions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Functions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	profiler := run(t)
+
+	var flat bytes.Buffer
+	err := profiler.WriteFlat(&flat)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	for _, want := range []string{
+		"Flat profile: 153 instructions, 1264 cycles",
+		"DELAY    10     920          72.8%   920           72.8%\n",
+		"0x0013   50        500     39.6%  JNZ 0x0012     DELAY+3",
+		"0x0012   50        250     19.8%  DCR C          DELAY+2 (delay.asm:3)",
+	} {
+		if !strings.Contains(flat.String(), want) {
+			t.Errorf("flat profile does not contain %q:\n%s", want, flat.String())
+		}
+	}
+
+	var graph bytes.Buffer
+	err = profiler.WriteCallGraph(&graph)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	want := `Call graph: 1264 cycles
+
+START: 0 calls, 344 cycles self (27.2%), 1264 in total (100.0%)
+  calls DELAY: 920 cycles (72.8%)
+
+DELAY: 10 calls, 920 cycles self (72.8%), 920 in total (72.8%)
+  called from START: 920 cycles (72.8%)
+`
+	if graph.String() != want {
+		t.Errorf("call graph = %q, want %q", graph.String(), want)
+	}
+}
+
+// failingWriter fails every write after the first limit bytes.
+type failingWriter struct {
+	limit int
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (writer *failingWriter) Write(data []byte) (int, error) {
+	if len(data) > writer.limit {
+		written := writer.limit
+		writer.limit = 0
+		return written, errWriteFailed
+	}
+	writer.limit -= len(data)
+
+	return len(data), nil
+}
+
+func TestWriteReportsErrors(t *testing.T) {
+	profiler := run(t)
+
+	for _, limit := range []int{0, 10, 100} {
+		if err := profiler.WriteFlat(&failingWriter{limit: limit}); !errors.Is(err, errWriteFailed) {
+			t.Errorf("WriteFlat() after %d bytes = %v, want %v", limit, err, errWriteFailed)
+		}
+		if err := profiler.WriteCallGraph(&failingWriter{limit: limit}); !errors.Is(err, errWriteFailed) {
+			t.Errorf("WriteCallGraph() after %d bytes = %v, want %v", limit, err, errWriteFailed)
+		}
+	}
+}
+
+func TestWritePprof(t *testing.T) {
+	profiler := run(t)
+
+	var buffer bytes.Buffer
+	err := profiler.WritePprof(&buffer)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	reader, err := gzip.NewReader(&buffer)
+	if err != nil {
+		t.Fatalf("profile is not gzipped: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("could not decompress profile: %v", err)
+	}
+
+	var strs []string
+	var totals [2]uint64
+	deepest := 0
+	for _, field := range fields(t, data) {
+		switch field.number {
+		case profileStringTable:
+			strs = append(strs, string(field.value))
+		case profileSample:
+			for _, field := range fields(t, field.value) {
+				switch field.number {
+				case sampleLocationID:
+					deepest = max(deepest, len(varints(t, field.value)))
+				case sampleValue:
+					values := varints(t, field.value)
+					totals[0] += values[0]
+					totals[1] += values[1]
+				}
+			}
+		}
+	}
+
+	if totals != [2]uint64{153, 1264} {
+		t.Errorf("samples total %v instructions and cycles, want [153 1264]", totals)
+	}
+	if deepest != 2 {
+		t.Errorf("deepest sample has %d locations, want 2", deepest)
+	}
+	if len(strs) == 0 || strs[0] != "" {
+		t.Errorf("string table = %q, want it to start with an empty string", strs)
+	}
+	for _, want := range []string{"cycles", "instructions", "START", "DELAY"} {
+		found := false
+		for _, str := range strs {
+			found = found || str == want
+		}
+		if !found {
+			t.Errorf("string table = %q, want it to contain %q", strs, want)
+		}
+	}
+}
+
+// field is a field of a protocol buffer message: its number, and its bytes or
+// encoded varint.
+type field struct {
+	number int
+	value  []byte
+}
+
+// fields decodes the fields of a protocol buffer message.
+func fields(t *testing.T, data []byte) []field {
+	var decoded []field
+	for len(data) > 0 {
+		key := varint(t, &data)
+		var value []byte
+		switch key & 7 {
+		case 0:
+			start := data
+			varint(t, &data)
+			value = start[:len(start)-len(data)]
+		case 2:
+			length := varint(t, &data)
+			value, data = data[:length], data[length:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		decoded = append(decoded, field{number: int(key >> 3), value: value})
+	}
+
+	return decoded
+}
+
+// varints decodes packed varints.
+func varints(t *testing.T, data []byte) []uint64 {
+	var values []uint64
+	for len(data) > 0 {
+		values = append(values, varint(t, &data))
+	}
+
+	return values
+}
+
+func varint(t *testing.T, data *[]byte) uint64 {
+	var value uint64
+	for shift := 0; len(*data) > 0; shift += 7 {
+		b := (*data)[0]
+		*data = (*data)[1:]
+		value |= uint64(b&0x7F) << shift
+		if b < 0x80 {
+			return value
+		}
+	}
+	t.Fatalf("truncated varint")
+
+	return 0
+}
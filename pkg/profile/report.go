@@ -0,0 +1,114 @@
+package profile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// WriteFlat writes a flat profile: the time spent in each routine, then at
+// each address executed, most cycles first.
+//
+// Example:
+//
+//	Flat profile: 1510 instructions, 12580 cycles
+//
+//	ROUTINE  CALLS  SELF CYCLES  SELF %  TOTAL CYCLES  TOTAL %
+//	DELAY    10     12480        99.2%   12480         99.2%
+//	0x0000   0      100          0.8%    12580         100.0%
+//
+//	ADDRESS  EXECUTED  CYCLES  %      INSTRUCTION  LOCATION
+//	0x0104   1000      5000    39.7%  DCR B        DELAY+4
+func (profiler *Profiler) WriteFlat(w io.Writer) error {
+	// The buffer keeps the first error writing to w, returned by Flush
+	buffer := bufio.NewWriter(w)
+	fmt.Fprintf(buffer, "Flat profile: %d instructions, %d cycles\n\n", profiler.total.Instructions, profiler.total.Cycles)
+
+	writer := tabwriter.NewWriter(buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ROUTINE\tCALLS\tSELF CYCLES\tSELF %\tTOTAL CYCLES\tTOTAL %")
+	functions := profiler.Functions()
+	sort.SliceStable(functions, func(i, j int) bool {
+		return functions[i].Self.Cycles > functions[j].Self.Cycles
+	})
+	for _, function := range functions {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%s\t%d\t%s\n",
+			function.Name, function.Calls, function.Self.Cycles, profiler.percent(function.Self.Cycles),
+			function.Total.Cycles, profiler.percent(function.Total.Cycles))
+	}
+	writer.Flush()
+
+	fmt.Fprintln(buffer)
+	writer = tabwriter.NewWriter(buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ADDRESS\tEXECUTED\tCYCLES\t%\tINSTRUCTION\tLOCATION")
+	for _, address := range profiler.addresses() {
+		hits := profiler.hits[address]
+		text, _, err := profiler.processor.Disassemble(address)
+		if err != nil {
+			text = "?"
+		}
+		fmt.Fprintf(writer, "0x%04X\t%d\t%d\t%s\t%s\t%s\n",
+			address, hits.Instructions, hits.Cycles, profiler.percent(hits.Cycles), text, profiler.processor.Describe(address))
+	}
+	writer.Flush()
+
+	return buffer.Flush()
+}
+
+// WriteCallGraph writes the time spent in each routine, most in total first,
+// with the routines that called it and the time spent in those it called.
+//
+// Example:
+//
+//	Call graph: 12580 cycles
+//
+//	0x0000: 0 calls, 100 cycles self (0.8%), 12580 in total (100.0%)
+//	  calls DELAY: 12480 cycles (99.2%)
+//
+//	DELAY: 10 calls, 12480 cycles self (99.2%), 12480 in total (99.2%)
+//	  called from 0x0000: 12480 cycles (99.2%)
+func (profiler *Profiler) WriteCallGraph(w io.Writer) error {
+	// The buffer keeps the first error writing to w, returned by Flush
+	buffer := bufio.NewWriter(w)
+	fmt.Fprintf(buffer, "Call graph: %d cycles\n", profiler.total.Cycles)
+	for _, function := range profiler.Functions() {
+		fmt.Fprintf(buffer, "\n%s: %d calls, %d cycles self (%s), %d in total (%s)\n",
+			function.Name, function.Calls, function.Self.Cycles, profiler.percent(function.Self.Cycles),
+			function.Total.Cycles, profiler.percent(function.Total.Cycles))
+		for _, caller := range function.Callers {
+			fmt.Fprintf(buffer, "  called from %s: %d cycles (%s)\n", caller.Name, caller.Cycles, profiler.percent(caller.Cycles))
+		}
+		for _, callee := range function.Callees {
+			fmt.Fprintf(buffer, "  calls %s: %d cycles (%s)\n", callee.Name, callee.Cycles, profiler.percent(callee.Cycles))
+		}
+	}
+
+	return buffer.Flush()
+}
+
+// addresses returns the addresses executed, most cycles first.
+func (profiler *Profiler) addresses() []types.Word {
+	var addresses []types.Word
+	for address, hits := range profiler.hits {
+		if hits.Instructions > 0 {
+			addresses = append(addresses, types.Word(address))
+		}
+	}
+	sort.SliceStable(addresses, func(i, j int) bool {
+		return profiler.hits[addresses[i]].Cycles > profiler.hits[addresses[j]].Cycles
+	})
+
+	return addresses
+}
+
+// percent returns cycles as a percentage of the cycles in the run.
+func (profiler *Profiler) percent(cycles uint64) string {
+	if profiler.total.Cycles == 0 {
+		return "0.0%"
+	}
+
+	return fmt.Sprintf("%.1f%%", float64(cycles)*100/float64(profiler.total.Cycles))
+}
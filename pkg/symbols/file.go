@@ -0,0 +1,239 @@
+package symbols
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Read reads the symbols in a file written by another assembler or linker.
+// Listings (.lst and .prn) are read by ParseListing, and anything else, such
+// as .sym and .map files, by ParseSymbols.
+//
+// Example:
+//
+//	table, err := symbols.Read("prog.sym")
+//	cpu.Symbols = table
+func Read(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read symbols: %v", err)
+	}
+
+	var table *Table
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".lst", ".prn":
+		table = ParseListing(filepath.Base(path), data)
+	default:
+		table = ParseSymbols(data)
+	}
+	if len(table.labels) == 0 && len(table.lines) == 0 {
+		return nil, fmt.Errorf("no symbols found in %s", path)
+	}
+
+	return table, nil
+}
+
+// ParseSymbols reads a list of symbols and their addresses, as written to .sym
+// and .map files.  Each line holds one or more pairs of an address and a name
+// in either order, such as "0100 START 0103 LOOP", "START 0100H" or "START EQU
+// 0100H".  Addresses are hex, with an optional 0x or $ prefix or H suffix.
+// Lines that aren't all symbols, such as headings, are skipped.
+func ParseSymbols(data []byte) *Table {
+	table := New()
+	for _, text := range strings.Split(string(data), "\n") {
+		for _, symbol := range parsePairs(strings.Fields(text)) {
+			table.AddLabel(symbol.name, symbol.address)
+		}
+	}
+
+	return table
+}
+
+// ParseListing reads the labels and the line of each instruction in an
+// assembler's listing, where each line of code starts with its address and
+// the bytes assembled, then the source.  Lines may also start with a line
+// number, if the listing numbers its first lines.  Labels are names followed
+// by a colon, or defined with EQU, at the start of the source.  file names the
+// listing in the table's locations, so the listing stands in for the source.
+// Lines without code are read as by ParseSymbols, for the symbol table at the
+// end of some listings.
+func ParseListing(file string, data []byte) *Table {
+	lines := strings.Split(string(data), "\n")
+	numbered := hasLineNumbers(lines)
+
+	table := New()
+	for number, text := range lines {
+		text, _, _ = strings.Cut(text, ";")
+		fields := strings.Fields(text)
+		if numbered && len(fields) > 1 && isDecimal(fields[0]) {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 || !isListingAddress(fields[0]) {
+			for _, symbol := range parsePairs(fields) {
+				table.AddLabel(symbol.name, symbol.address)
+			}
+			continue
+		}
+		address, _ := parseAddress(fields[0])
+
+		// The bytes assembled come before the source.  An equate may be marked
+		// with an equals sign.
+		source := fields[1:]
+		code := false
+		for len(source) > 0 && (isBytes(source[0]) || source[0] == "=") {
+			code = code || source[0] != "="
+			source = source[1:]
+		}
+
+		switch {
+		case len(source) > 0 && strings.HasSuffix(source[0], ":") && isName(source[0][:len(source[0])-1]):
+			table.AddLabel(source[0][:len(source[0])-1], address)
+		case len(source) > 1 && isName(source[0]) && (strings.EqualFold(source[1], "EQU") || source[1] == "="):
+			table.AddLabel(source[0], address)
+			continue
+		case !code:
+			for _, symbol := range parsePairs(fields) {
+				table.AddLabel(symbol.name, symbol.address)
+			}
+		}
+		if code {
+			table.AddLine(address, Location{File: file, Line: number + 1})
+		}
+	}
+
+	return table
+}
+
+// hasLineNumbers returns whether a listing's lines start with a line number,
+// before the address.  As a four digit line number can't be told apart from an
+// address, a line number of another length must start one of the lines.
+func hasLineNumbers(lines []string) bool {
+	for _, text := range lines {
+		fields := strings.Fields(text)
+		if len(fields) > 1 && isDecimal(fields[0]) && len(fields[0]) != 4 && isListingAddress(fields[1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parsePairs returns the symbols on a line of a symbol file, or none if the
+// line isn't all pairs of an address and a name.  An address followed by
+// something that could be a name or an address is taken as the address.
+func parsePairs(fields []string) []symbol {
+	var words []string
+	for _, field := range fields {
+		if field != "=" && !strings.EqualFold(field, "EQU") {
+			words = append(words, field)
+		}
+	}
+	if len(words) == 0 || len(words)%2 != 0 {
+		return nil
+	}
+
+	var symbols []symbol
+	for i := 0; i < len(words); i += 2 {
+		first, second := words[i], strings.TrimSuffix(words[i+1], ":")
+		if address, err := parseAddress(first); err == nil && isName(second) {
+			symbols = append(symbols, symbol{name: second, address: address})
+			continue
+		}
+
+		first = strings.TrimSuffix(first, ":")
+		if address, err := parseAddress(words[i+1]); err == nil && isName(first) {
+			symbols = append(symbols, symbol{name: first, address: address})
+			continue
+		}
+
+		return nil
+	}
+
+	return symbols
+}
+
+// parseAddress parses a hex address, with an optional 0x or $ prefix or H
+// suffix.  A ' or " after it, marking it relocatable or external in some
+// assemblers' output, is ignored.
+func parseAddress(text string) (types.Word, error) {
+	text = strings.TrimRight(text, `'"`)
+	switch {
+	case strings.HasPrefix(text, "0x"), strings.HasPrefix(text, "0X"):
+		text = text[2:]
+	case strings.HasPrefix(text, "$"):
+		text = text[1:]
+	case strings.HasSuffix(text, "H"), strings.HasSuffix(text, "h"):
+		text = text[:len(text)-1]
+	}
+
+	// ParseUint would also accept a sign or underscores
+	for _, char := range text {
+		if !isHexDigit(char) {
+			return 0, fmt.Errorf("invalid address %q", text)
+		}
+	}
+	address, err := strconv.ParseUint(text, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", text)
+	}
+
+	return types.Word(address), nil
+}
+
+// isListingAddress returns whether a field of a listing is the address at the
+// start of a line: four hex digits, optionally marked as relocatable.
+func isListingAddress(field string) bool {
+	field = strings.TrimRight(field, `'"`)
+	if len(field) != 4 {
+		return false
+	}
+	_, err := parseAddress(field)
+	return err == nil
+}
+
+// isBytes returns whether a field of a listing is bytes assembled: pairs of
+// hex digits, optionally marked as relocatable.
+func isBytes(field string) bool {
+	field = strings.TrimRight(field, `'"`)
+	if len(field) == 0 || len(field)%2 != 0 {
+		return false
+	}
+	for _, char := range field {
+		if !isHexDigit(char) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isName returns whether text can be a symbol's name: letters, digits and
+// _?@.$, not starting with a digit.
+func isName(text string) bool {
+	if text == "" || text[0] >= '0' && text[0] <= '9' {
+		return false
+	}
+	for _, char := range text {
+		isLetter := char >= 'A' && char <= 'Z' || char >= 'a' && char <= 'z'
+		isDigit := char >= '0' && char <= '9'
+		if !isLetter && !isDigit && !strings.ContainsRune("_?@.$", char) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDecimal(text string) bool {
+	_, err := strconv.ParseUint(text, 10, 32)
+	return err == nil
+}
+
+func isHexDigit(char rune) bool {
+	return char >= '0' && char <= '9' || char >= 'A' && char <= 'F' || char >= 'a' && char <= 'f'
+}
@@ -0,0 +1,181 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestParseSymbols(t *testing.T) {
+	data := []byte(`Symbol table of prog.com
+
+0100 START	0103 LOOP	FF00 BIOS
+DONE 0110H
+CONOUT EQU $0009
+PRINT = 0x0005
+BEEF 0120
+CAFE FEED
+0130' RELOC
+Total 3 errors
+`)
+
+	want := map[string]types.Word{
+		"START":  0x0100,
+		"LOOP":   0x0103,
+		"BIOS":   0xFF00,
+		"DONE":   0x0110,
+		"CONOUT": 0x0009,
+		"PRINT":  0x0005,
+		"BEEF":   0x0120,
+		"FEED":   0xCAFE, // An address first wins when both could be either
+		"RELOC":  0x0130,
+	}
+
+	table := ParseSymbols(data)
+	for name, address := range want {
+		if got, ok := table.Address(name); !ok || got != address {
+			t.Errorf("Address(%q) = 0x%04X, %v, want 0x%04X", name, got, ok, address)
+		}
+	}
+	for _, name := range []string{"Symbol", "table", "Total", "errors", "CAFE"} {
+		if _, ok := table.Address(name); ok {
+			t.Errorf("Address(%q) found a label, want none", name)
+		}
+	}
+}
+
+func TestParseListing(t *testing.T) {
+	tests := []struct {
+		name       string
+		listing    string
+		wantLabels map[string]types.Word
+		wantLines  map[types.Word]int
+	}{
+		{
+			name: "CP/M ASM",
+			listing: `
+0005 =         BDOS    EQU     5
+0100                   ORG     100H
+0100 0E09      START:  MVI     C,9     ; Print string
+0102 110901            LXI     D,MSG
+0105 CD0500            CALL    BDOS
+0108 C9                RET
+0109 4869240D  MSG:    DB      'Hi$'
+`,
+			wantLabels: map[string]types.Word{"BDOS": 0x0005, "START": 0x0100, "MSG": 0x0109},
+			wantLines:  map[types.Word]int{0x0100: 4, 0x0102: 5, 0x0105: 6, 0x0108: 7, 0x0109: 8},
+		},
+		{
+			name: "line numbers and relocatable addresses",
+			listing: `
+     1                          .8080
+     2  0000'   3E 01       START:  MVI A,1
+     3  0002'   3C          LOOP:   INR A
+     4  0003'   C2 0002'            JNZ LOOP
+     5                              END
+
+Symbols:
+0000'   START           0002'   LOOP
+`,
+			wantLabels: map[string]types.Word{"START": 0x0000, "LOOP": 0x0002},
+			wantLines:  map[types.Word]int{0x0000: 3, 0x0002: 4, 0x0003: 5},
+		},
+		{
+			name: "label on its own line",
+			listing: `
+1000                  DONE:
+1000  76                      HLT
+`,
+			wantLabels: map[string]types.Word{"DONE": 0x1000},
+			wantLines:  map[types.Word]int{0x1000: 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			table := ParseListing("prog.lst", []byte(test.listing))
+			if len(table.labels) != len(test.wantLabels) {
+				t.Errorf("read %d labels, want %d: %v", len(table.labels), len(test.wantLabels), table.labels)
+			}
+			for name, address := range test.wantLabels {
+				if got, ok := table.Address(name); !ok || got != address {
+					t.Errorf("Address(%q) = 0x%04X, %v, want 0x%04X", name, got, ok, address)
+				}
+			}
+
+			if len(table.lines) != len(test.wantLines) {
+				t.Errorf("read %d lines, want %d: %v", len(table.lines), len(test.wantLines), table.lines)
+			}
+			for address, line := range test.wantLines {
+				location, ok := table.Line(address)
+				if !ok || location != (Location{File: "prog.lst", Line: line}) {
+					t.Errorf("Line(0x%04X) = %v, %v, want prog.lst:%d", address, location, ok, line)
+				}
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"prog.sym":  "0100 START\n",
+		"prog.prn":  "0100 76        START:  HLT\n",
+		"empty.map": "Link map\n",
+	}
+	for name, data := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644)
+		if err != nil {
+			t.Fatalf("could not write %s: %v", name, err)
+		}
+	}
+
+	table, err := Read(filepath.Join(dir, "prog.sym"))
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if got := table.Describe(0x0100); got != "START" {
+		t.Errorf("Describe(0x0100) = %q reading prog.sym, want %q", got, "START")
+	}
+
+	table, err = Read(filepath.Join(dir, "prog.prn"))
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if got := table.Describe(0x0100); got != "START (prog.prn:1)" {
+		t.Errorf("Describe(0x0100) = %q reading prog.prn, want %q", got, "START (prog.prn:1)")
+	}
+
+	for _, name := range []string{"empty.map", "missing.sym"} {
+		_, err := Read(filepath.Join(dir, name))
+		if err == nil {
+			t.Errorf("expected an error reading %s, but got none", name)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	table := New()
+	table.AddLabel("START", 0x0100)
+	table.AddLabel("LOOP", 0x0103)
+
+	other := New()
+	other.AddLabel("LOOP", 0x0105)
+	other.AddLabel("BDOS", 0x0005)
+	other.AddLine(0x0105, Location{File: "prog.lst", Line: 9})
+
+	table.Merge(other)
+	tests := map[types.Word]string{
+		0x0005: "BDOS",
+		0x0100: "START",
+		0x0103: "START+3",
+		0x0105: "LOOP (prog.lst:9)",
+	}
+	for address, want := range tests {
+		if got := table.Describe(address); got != want {
+			t.Errorf("Describe(0x%04X) = %q, want %q", address, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package symbols
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lukepeterson/go8080assembler/pkg/assembler"
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// lengths holds the length in bytes of each 8080 mnemonic.
+var lengths = func() map[string]int {
+	lengths := make(map[string]int)
+	processor := cpu.New()
+	for opCode := 0; opCode < 256; opCode++ {
+		instruction, _ := processor.Lookup(byte(opCode))
+		mnemonic, _, _ := strings.Cut(instruction.Mnemonic, " ")
+		if mnemonic != "" {
+			lengths[mnemonic] = instruction.Length
+		}
+	}
+
+	return lengths
+}()
+
+// Assemble assembles source with go8080assembler, returning the program and a
+// table of its labels and lines, placed at origin.  file names the source in
+// the table's locations.  The table is nil if the source can't be read by
+// FromSource, or doesn't match the program assembled.
+func Assemble(file, source string, origin types.Word) ([]byte, *Table, error) {
+	program, err := assembler.New(source).Assemble()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not assemble program: %v", err)
+	}
+
+	table, size, err := FromSource(file, source, origin)
+	if err != nil || size != len(program) {
+		return program, nil, nil
+	}
+
+	return program, table, nil
+}
+
+// FromSource reads the labels and the line of each instruction in assembly
+// language source, without assembling it, returning them with the size of the
+// program in bytes.  Instructions are placed from origin, with the lengths of
+// 8080 instructions.  Labels are names followed by a colon at the start of a
+// line, and comments start with a semicolon.
+func FromSource(file, source string, origin types.Word) (*Table, int, error) {
+	table := New()
+	address := int(origin)
+
+	for number, text := range strings.Split(source, "\n") {
+		text, _, _ = strings.Cut(text, ";")
+		text = strings.TrimSpace(text)
+
+		// A label ends with a colon, and may be followed by an instruction
+		if label, instruction, found := strings.Cut(text, ":"); found {
+			label = strings.TrimSpace(label)
+			if label == "" || strings.ContainsAny(label, " \t,") {
+				return nil, 0, fmt.Errorf("invalid label %q on line %d of %s", label, number+1, file)
+			}
+			table.AddLabel(label, types.Word(address))
+			text = strings.TrimSpace(instruction)
+		}
+		if text == "" {
+			continue
+		}
+
+		mnemonic, _, _ := strings.Cut(text, " ")
+		mnemonic, _, _ = strings.Cut(mnemonic, "\t")
+		length, ok := lengths[strings.ToUpper(mnemonic)]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown instruction %q on line %d of %s", mnemonic, number+1, file)
+		}
+		if address+length > 0x10000 {
+			return nil, 0, fmt.Errorf("instruction on line %d of %s is past the end of memory", number+1, file)
+		}
+
+		table.AddLine(types.Word(address), Location{File: file, Line: number + 1})
+		address += length
+	}
+
+	return table, address - int(origin), nil
+}
@@ -0,0 +1,166 @@
+package symbols
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Location is a line of a source file.
+type Location struct {
+	File string
+	Line int
+}
+
+func (location Location) String() string {
+	return fmt.Sprintf("%s:%d", location.File, location.Line)
+}
+
+// symbol is a label and its address.
+type symbol struct {
+	name    string
+	address types.Word
+}
+
+// Table holds a program's labels and the source line of each instruction, to
+// describe addresses as a label and line rather than a number.  It implements
+// cpu.Symbols.
+//
+// Example:
+//
+//	program, table, err := symbols.Assemble("prog.asm", source, 0x0000)
+//	cpu.Symbols = table
+//	fmt.Println(table.Describe(0x0012)) // LOOP+3 (prog.asm:17)
+type Table struct {
+	labels  map[string]types.Word
+	ordered []symbol // Sorted by address, then name
+	lines   map[types.Word]Location
+}
+
+// New returns an empty table.
+func New() *Table {
+	return &Table{
+		labels: make(map[string]types.Word),
+		lines:  make(map[types.Word]Location),
+	}
+}
+
+// AddLabel adds a label at address, replacing any label of the same name.
+func (table *Table) AddLabel(name string, address types.Word) {
+	if old, ok := table.labels[name]; ok {
+		for i, symbol := range table.ordered {
+			if symbol.name == name && symbol.address == old {
+				table.ordered = append(table.ordered[:i], table.ordered[i+1:]...)
+				break
+			}
+		}
+	}
+	table.labels[name] = address
+
+	i := sort.Search(len(table.ordered), func(i int) bool {
+		other := table.ordered[i]
+		return other.address > address || other.address == address && other.name > name
+	})
+	table.ordered = append(table.ordered, symbol{})
+	copy(table.ordered[i+1:], table.ordered[i:])
+	table.ordered[i] = symbol{name: name, address: address}
+}
+
+// AddLine records the source line of the instruction at address.
+func (table *Table) AddLine(address types.Word, location Location) {
+	table.lines[address] = location
+}
+
+// Merge adds the labels and lines of other to table, replacing any of the same
+// name or address.
+func (table *Table) Merge(other *Table) {
+	for _, symbol := range other.ordered {
+		table.AddLabel(symbol.name, symbol.address)
+	}
+	for address, location := range other.lines {
+		table.lines[address] = location
+	}
+}
+
+// Address returns the address of a label.  Labels are matched ignoring case,
+// as they are by the assembler.
+func (table *Table) Address(name string) (types.Word, bool) {
+	if address, ok := table.labels[name]; ok {
+		return address, true
+	}
+	for label, address := range table.labels {
+		if strings.EqualFold(label, name) {
+			return address, true
+		}
+	}
+
+	return 0, false
+}
+
+// Label returns the label at address, if there is one.  Of several labels at
+// the same address, the first in alphabetical order is returned.
+func (table *Table) Label(address types.Word) (string, bool) {
+	name, offset, ok := table.Nearest(address)
+	if !ok || offset != 0 {
+		return "", false
+	}
+
+	return name, true
+}
+
+// Nearest returns the closest label at or below address, and address's offset
+// from it.
+func (table *Table) Nearest(address types.Word) (string, int, bool) {
+	i := sort.Search(len(table.ordered), func(i int) bool {
+		return table.ordered[i].address > address
+	})
+	if i == 0 {
+		return "", 0, false
+	}
+
+	// Go back to the first label at the closest address below
+	nearest := table.ordered[i-1]
+	for i > 1 && table.ordered[i-2].address == nearest.address {
+		i--
+		nearest = table.ordered[i-1]
+	}
+
+	return nearest.name, int(address - nearest.address), true
+}
+
+// Line returns the source line of the instruction at address.
+func (table *Table) Line(address types.Word) (Location, bool) {
+	location, ok := table.lines[address]
+	return location, ok
+}
+
+// Instructions returns the addresses of the instructions with source lines,
+// in order.
+func (table *Table) Instructions() []types.Word {
+	addresses := make([]types.Word, 0, len(table.lines))
+	for address := range table.lines {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+
+	return addresses
+}
+
+// Describe describes address by its nearest label and its source line, such
+// as LOOP+3 (prog.asm:17), falling back to its number for either.
+func (table *Table) Describe(address types.Word) string {
+	description := fmt.Sprintf("0x%04X", address)
+	if name, offset, ok := table.Nearest(address); ok {
+		description = name
+		if offset != 0 {
+			description = fmt.Sprintf("%s+%d", name, offset)
+		}
+	}
+	if location, ok := table.Line(address); ok {
+		description += " (" + location.String() + ")"
+	}
+
+	return description
+}
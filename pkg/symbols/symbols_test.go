@@ -0,0 +1,140 @@
+package symbols
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+func TestTable(t *testing.T) {
+	table := New()
+	table.AddLabel("START", 0x0000)
+	table.AddLabel("LOOP", 0x0010)
+	table.AddLabel("AGAIN", 0x0010)
+	table.AddLabel("END", 0x0020)
+	table.AddLine(0x0010, Location{File: "prog.asm", Line: 5})
+	table.AddLine(0x0013, Location{File: "prog.asm", Line: 6})
+
+	tests := []struct {
+		address      types.Word
+		wantLabel    string
+		wantDescribe string
+	}{
+		{address: 0x0000, wantLabel: "START", wantDescribe: "START"},
+		{address: 0x0001, wantLabel: "", wantDescribe: "START+1"},
+		{address: 0x0010, wantLabel: "AGAIN", wantDescribe: "AGAIN (prog.asm:5)"},
+		{address: 0x0013, wantLabel: "", wantDescribe: "AGAIN+3 (prog.asm:6)"},
+		{address: 0x0020, wantLabel: "END", wantDescribe: "END"},
+		{address: 0xFFFF, wantLabel: "", wantDescribe: "END+65503"},
+	}
+
+	for _, test := range tests {
+		label, ok := table.Label(test.address)
+		if label != test.wantLabel || ok != (test.wantLabel != "") {
+			t.Errorf("Label(0x%04X) = %q, %v, want %q", test.address, label, ok, test.wantLabel)
+		}
+		if got := table.Describe(test.address); got != test.wantDescribe {
+			t.Errorf("Describe(0x%04X) = %q, want %q", test.address, got, test.wantDescribe)
+		}
+	}
+
+	if address, ok := table.Address("loop"); !ok || address != 0x0010 {
+		t.Errorf("Address(%q) = 0x%04X, %v, want 0x0010", "loop", address, ok)
+	}
+	if _, ok := table.Address("MISSING"); ok {
+		t.Errorf("Address(%q) found a label, want none", "MISSING")
+	}
+
+	if got, want := table.Instructions(), []types.Word{0x0010, 0x0013}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Instructions() = %v, want %v", got, want)
+	}
+
+	// Moving a label removes it from its old address
+	table.AddLabel("START", 0x0030)
+	if got := table.Describe(0x0005); got != "0x0005" {
+		t.Errorf("Describe(0x0005) = %q after moving START, want %q", got, "0x0005")
+	}
+	if got := table.Describe(0x0031); got != "START+1" {
+		t.Errorf("Describe(0x0031) = %q after moving START, want %q", got, "START+1")
+	}
+}
+
+func TestFromSource(t *testing.T) {
+	source := `
+		; Counts down from 5
+		START:	MVI B, 5
+		LOOP:	DCR B	; Count down
+				JNZ LOOP
+		DONE:
+				LXI H, 0x1234
+				HLT
+	`
+
+	table, size, err := FromSource("count.asm", source, 0x0100)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if size != 10 {
+		t.Errorf("size = %d, want 10", size)
+	}
+
+	labels := map[string]types.Word{"START": 0x0100, "LOOP": 0x0102, "DONE": 0x0106}
+	for name, want := range labels {
+		if got, ok := table.Address(name); !ok || got != want {
+			t.Errorf("Address(%q) = 0x%04X, %v, want 0x%04X", name, got, ok, want)
+		}
+	}
+
+	lines := map[types.Word]int{0x0100: 3, 0x0102: 4, 0x0103: 5, 0x0106: 7, 0x0109: 8}
+	for address, want := range lines {
+		location, ok := table.Line(address)
+		if !ok || location != (Location{File: "count.asm", Line: want}) {
+			t.Errorf("Line(0x%04X) = %v, %v, want count.asm:%d", address, location, ok, want)
+		}
+	}
+	if _, ok := table.Line(0x0101); ok {
+		t.Errorf("Line(0x0101) found a line in the middle of an instruction, want none")
+	}
+}
+
+func TestFromSourceErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "unknown instruction", source: "FOO A"},
+		{name: "empty label", source: ": HLT"},
+		{name: "label with spaces", source: "MVI A, B: HLT"},
+	}
+
+	for _, test := range tests {
+		_, _, err := FromSource("prog.asm", test.source, 0x0000)
+		if err == nil {
+			t.Errorf("expected an error for %s, but got none", test.name)
+		}
+	}
+
+	_, _, err := FromSource("prog.asm", "LXI H, 0x0000", 0xFFFE)
+	if err == nil {
+		t.Errorf("expected an error for an instruction past the end of memory, but got none")
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	program, table, err := Assemble("prog.asm", "START: MVI A, 0x01\nJMP START\n", 0x0000)
+	if err != nil {
+		t.Fatalf("did not expect an error, but got: %v", err)
+	}
+	if len(program) != 5 {
+		t.Errorf("program is %d bytes, want 5", len(program))
+	}
+	if table == nil || table.Describe(0x0002) != "START+2 (prog.asm:2)" {
+		t.Errorf("symbols describe 0x0002 as %q, want %q", table.Describe(0x0002), "START+2 (prog.asm:2)")
+	}
+
+	_, _, err = Assemble("prog.asm", "FOO A", 0x0000)
+	if err == nil {
+		t.Errorf("expected an error assembling an unknown instruction, but got none")
+	}
+}
@@ -0,0 +1,345 @@
+package usart
+
+import (
+	"bufio"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+// Status register bits, as read from the control port.
+const (
+	StatusTxReady     = 1 << 0 // TxRDY - the transmit buffer can take another character
+	StatusRxReady     = 1 << 1 // RxRDY - a received character is waiting to be read
+	StatusTxEmpty     = 1 << 2 // TxEMPTY - the transmitter has nothing left to send
+	StatusParityError = 1 << 3 // PE
+	StatusOverrun     = 1 << 4 // OE - a character arrived before the last was read
+	StatusFraming     = 1 << 5 // FE
+	StatusSyncDetect  = 1 << 6 // SYNDET
+	StatusDSR         = 1 << 7 // Data set ready input
+)
+
+// Command instruction bits, as written to the control port after the mode.
+const (
+	CommandTxEnable      = 1 << 0 // TxEN
+	CommandDTR           = 1 << 1 // Data terminal ready output
+	CommandRxEnable      = 1 << 2 // RxE
+	CommandSendBreak     = 1 << 3 // SBRK
+	CommandErrorReset    = 1 << 4 // ER - clears PE, OE and FE
+	CommandRTS           = 1 << 5 // Request to send output
+	CommandInternalReset = 1 << 6 // IR - the next control write is a mode instruction
+	CommandEnterHunt     = 1 << 7 // EH - synchronous mode only
+)
+
+// controlState tracks what the next write to the control port means.
+type controlState int
+
+const (
+	expectMode controlState = iota
+	expectSync1
+	expectSync2
+	expectCommand
+)
+
+// USART emulates an Intel 8251 programmable communication interface, with its
+// receiver and transmitter connected to the host through an io.Reader and an
+// io.Writer.
+//
+// The 8251 decodes a single address line (C/D), so it occupies two ports: the
+// data port, where bit 0 of the port number is clear, and the control and
+// status port, where it's set.  Characters take as long to send and receive as
+// they would at the configured baud rate, measured in CPU clock states.
+//
+// Example:
+//
+//	serial := usart.New(2_000_000, 9600)
+//	serial.Connect(os.Stdin, os.Stdout)
+//	cpu.Attach(serial, 0x10, 0x11)
+type USART struct {
+	// OnRxReady, when set, is called each time a received character becomes
+	// ready to read, as if the RxRDY pin were wired to an interrupt input.
+	OnRxReady func()
+
+	clockHz  float64
+	baudRate int
+
+	control   controlState
+	mode      byte
+	command   byte
+	syncChars [2]byte
+	status    byte
+
+	charCycles uint64 // Clock states taken to send or receive one character
+
+	txHolding  byte
+	txFull     bool
+	txShift    byte
+	txShifting bool
+	txTimer    uint64
+
+	rxBuffer byte
+	rxTimer  uint64
+
+	dsr bool
+	cts bool
+
+	mutex    sync.Mutex
+	incoming []byte
+	writer   io.Writer
+}
+
+// New returns a USART paced for a CPU running at clockHz, transferring
+// characters at baudRate bits per second.  A baudRate of 0 transfers a
+// character every instruction.
+func New(clockHz float64, baudRate int) *USART {
+	usart := &USART{
+		clockHz:  clockHz,
+		baudRate: baudRate,
+		cts:      true,
+	}
+	usart.reset()
+
+	return usart
+}
+
+// reset puts the USART into its power on state, waiting for a mode instruction.
+func (usart *USART) reset() {
+	usart.control = expectMode
+	usart.command = 0
+	usart.status = StatusTxReady | StatusTxEmpty
+	usart.txFull, usart.txShifting = false, false
+	usart.updateCharCycles()
+}
+
+// Connect connects the receiver to reader and the transmitter to writer.
+// Either may be nil.  The reader is read from a separate goroutine, so it can
+// block, as os.Stdin or a net.Conn does, without stalling the CPU.
+func (usart *USART) Connect(reader io.Reader, writer io.Writer) {
+	usart.mutex.Lock()
+	usart.writer = writer
+	usart.mutex.Unlock()
+
+	if reader == nil {
+		return
+	}
+	go func() {
+		buffered := bufio.NewReader(reader)
+		for {
+			value, err := buffered.ReadByte()
+			if err != nil {
+				return
+			}
+			usart.Receive(value)
+		}
+	}()
+}
+
+// Receive queues value as if it had arrived on the serial input.  Queued
+// characters are received one at a time, at the configured baud rate.
+func (usart *USART) Receive(value byte) {
+	usart.mutex.Lock()
+	usart.incoming = append(usart.incoming, value)
+	usart.mutex.Unlock()
+}
+
+// SetDSR sets the data set ready input, reported in bit 7 of the status.
+func (usart *USART) SetDSR(active bool) {
+	usart.dsr = active
+}
+
+// SetCTS sets the clear to send input.  The transmitter only starts sending a
+// character while CTS is active; it's active until set otherwise.
+func (usart *USART) SetCTS(active bool) {
+	usart.cts = active
+}
+
+// DTR returns the data terminal ready output, set by the command instruction.
+func (usart *USART) DTR() bool {
+	return usart.command&CommandDTR != 0
+}
+
+// RTS returns the request to send output, set by the command instruction.
+func (usart *USART) RTS() bool {
+	return usart.command&CommandRTS != 0
+}
+
+// ReadPort returns the received character from the data port, or the status
+// from the control port.
+func (usart *USART) ReadPort(port byte) byte {
+	if port&1 == 1 {
+		status := usart.status
+		if usart.dsr {
+			status |= StatusDSR
+		}
+		return status
+	}
+
+	usart.status &^= StatusRxReady
+	return usart.rxBuffer
+}
+
+// WritePort writes a character to transmit to the data port, or a mode, sync
+// character or command instruction to the control port.
+func (usart *USART) WritePort(port byte, value byte) {
+	if port&1 == 0 {
+		usart.txHolding = value
+		usart.txFull = true
+		usart.status &^= StatusTxReady | StatusTxEmpty
+		return
+	}
+
+	switch usart.control {
+	case expectMode:
+		usart.mode = value
+		usart.control = expectCommand
+		if usart.synchronous() {
+			usart.control = expectSync1
+		}
+		usart.updateCharCycles()
+	case expectSync1:
+		usart.syncChars[0] = value
+		usart.control = expectSync2
+		if usart.mode&(1<<7) != 0 { // Single sync character
+			usart.control = expectCommand
+		}
+	case expectSync2:
+		usart.syncChars[1] = value
+		usart.control = expectCommand
+	case expectCommand:
+		if value&CommandInternalReset != 0 {
+			usart.reset()
+			return
+		}
+		usart.command = value
+		if value&CommandErrorReset != 0 {
+			usart.status &^= StatusParityError | StatusOverrun | StatusFraming
+		}
+	}
+}
+
+// Tick advances the transmitter and receiver by the given number of clock states.
+func (usart *USART) Tick(cycles uint64) {
+	usart.tickTransmitter(cycles)
+	usart.tickReceiver(cycles)
+}
+
+// tickTransmitter moves a character from the holding buffer into the shift
+// register when the transmitter's enabled, and sends it to the host once it
+// has been shifted out.
+func (usart *USART) tickTransmitter(cycles uint64) {
+	if usart.txShifting {
+		usart.txTimer += cycles
+		if usart.txTimer < usart.charCycles {
+			return
+		}
+
+		usart.txShifting = false
+		usart.mutex.Lock()
+		writer := usart.writer
+		usart.mutex.Unlock()
+		if writer != nil {
+			writer.Write([]byte{usart.txShift & usart.dataMask()})
+		}
+	}
+
+	if usart.txFull && usart.command&CommandTxEnable != 0 && usart.cts {
+		usart.txShift = usart.txHolding
+		usart.txFull = false
+		usart.txShifting = true
+		usart.txTimer = 0
+		usart.status |= StatusTxReady
+	}
+
+	if !usart.txFull && !usart.txShifting {
+		usart.status |= StatusTxEmpty
+	}
+}
+
+// tickReceiver receives the next queued character once a character time has
+// passed since the last, setting an overrun error if the last hasn't been read.
+func (usart *USART) tickReceiver(cycles uint64) {
+	usart.rxTimer += cycles
+	if usart.rxTimer < usart.charCycles || usart.command&CommandRxEnable == 0 {
+		return
+	}
+
+	usart.mutex.Lock()
+	if len(usart.incoming) == 0 {
+		usart.mutex.Unlock()
+		return
+	}
+	value := usart.incoming[0]
+	usart.incoming = usart.incoming[1:]
+	usart.mutex.Unlock()
+
+	usart.rxTimer = 0
+	if usart.status&StatusRxReady != 0 {
+		usart.status |= StatusOverrun
+	}
+	if !usart.parityValid(value) {
+		usart.status |= StatusParityError
+	}
+	usart.rxBuffer = value & usart.dataMask()
+	usart.status |= StatusRxReady
+
+	if usart.OnRxReady != nil {
+		usart.OnRxReady()
+	}
+}
+
+// synchronous reports whether the mode selects synchronous operation, which is
+// when the baud rate factor bits are both clear.
+func (usart *USART) synchronous() bool {
+	return usart.mode&0b11 == 0
+}
+
+// characterLength returns the number of data bits per character, 5 to 8.
+func (usart *USART) characterLength() int {
+	return 5 + int(usart.mode>>2&0b11)
+}
+
+// parityEnabled reports whether the mode adds a parity bit to each character.
+func (usart *USART) parityEnabled() bool {
+	return usart.mode&(1<<4) != 0
+}
+
+// dataMask returns a mask of the data bits in a character.
+func (usart *USART) dataMask() byte {
+	return byte(1<<usart.characterLength() - 1)
+}
+
+// parityValid checks the parity of a character from the host.  When the
+// character length leaves room in the byte, the bit above the data bits is
+// taken as the parity bit, as sent by a terminal set to 7E1 or 7O1.
+func (usart *USART) parityValid(value byte) bool {
+	length := usart.characterLength()
+	if !usart.parityEnabled() || length == 8 {
+		return true
+	}
+
+	ones := bits.OnesCount8(value & byte(1<<(length+1)-1))
+	even := usart.mode&(1<<5) != 0
+	return (ones%2 == 0) == even
+}
+
+// updateCharCycles works out how many clock states a character takes to send
+// or receive from the mode and baud rate: a start bit, the data bits, an
+// optional parity bit and the stop bits in asynchronous mode, or just the data
+// and parity bits in synchronous mode.
+func (usart *USART) updateCharCycles() {
+	if usart.baudRate <= 0 {
+		usart.charCycles = 0
+		return
+	}
+
+	bitsPerChar := float64(usart.characterLength())
+	if usart.parityEnabled() {
+		bitsPerChar++
+	}
+	if !usart.synchronous() {
+		stopBits := []float64{1, 1, 1.5, 2}[usart.mode>>6]
+		bitsPerChar += 1 + stopBits
+	}
+
+	usart.charCycles = uint64(usart.clockHz / float64(usart.baudRate) * bitsPerChar)
+}
@@ -0,0 +1,290 @@
+package usart
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+)
+
+const (
+	dataPort    = 0x10
+	controlPort = 0x11
+
+	mode8N1  = 0b0100_1110 // 1 stop bit, no parity, 8 data bits, x16 baud factor
+	mode7E1  = 0b0111_1010 // 1 stop bit, even parity, 7 data bits, x16 baud factor
+	modeSync = 0b0000_1100 // Two sync characters, no parity, 8 data bits
+
+	commandRun = CommandTxEnable | CommandDTR | CommandRxEnable | CommandErrorReset | CommandRTS
+)
+
+// newUSART returns a USART at 2 MHz and 9600 baud, programmed with mode and
+// commandRun, and writing to output.
+func newUSART(mode byte, output *bytes.Buffer) *USART {
+	usart := New(2_000_000, 9600)
+	usart.Connect(nil, output)
+	usart.WritePort(controlPort, mode)
+	usart.WritePort(controlPort, commandRun)
+	return usart
+}
+
+func TestTransmit(t *testing.T) {
+	var output bytes.Buffer
+	usart := newUSART(mode8N1, &output)
+	charCycles := uint64(2083) // 2 MHz / 9600 baud * 10 bits (start bit, 8 data bits and a stop bit)
+
+	usart.WritePort(dataPort, 'A')
+	if status := usart.ReadPort(controlPort); status&(StatusTxReady|StatusTxEmpty) != 0 {
+		t.Errorf("status = 0b%08b after writing, want TxRDY and TxEMPTY clear", status)
+	}
+
+	usart.Tick(4) // Moves the character into the shift register
+	if status := usart.ReadPort(controlPort); status&StatusTxReady == 0 || status&StatusTxEmpty != 0 {
+		t.Errorf("status = 0b%08b while shifting, want TxRDY set and TxEMPTY clear", status)
+	}
+
+	usart.Tick(charCycles - 1)
+	if output.Len() != 0 {
+		t.Errorf("output = %q before a character time has passed, want nothing", output.String())
+	}
+
+	usart.Tick(1)
+	if output.String() != "A" {
+		t.Errorf("output = %q, want %q", output.String(), "A")
+	}
+	if status := usart.ReadPort(controlPort); status&StatusTxEmpty == 0 {
+		t.Errorf("status = 0b%08b after sending, want TxEMPTY set", status)
+	}
+}
+
+func TestTransmitterDisabled(t *testing.T) {
+	var output bytes.Buffer
+	usart := newUSART(mode8N1, &output)
+	usart.WritePort(controlPort, CommandRxEnable)
+
+	usart.WritePort(dataPort, 'A')
+	usart.Tick(10_000)
+
+	if output.Len() != 0 {
+		t.Errorf("output = %q with the transmitter disabled, want nothing", output.String())
+	}
+	if status := usart.ReadPort(controlPort); status&StatusTxReady != 0 {
+		t.Errorf("status = 0b%08b, want TxRDY clear", status)
+	}
+}
+
+func TestReceive(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       byte
+		command    byte
+		input      []byte
+		wantData   byte
+		wantStatus byte
+	}{
+		{
+			name:       "character received",
+			mode:       mode8N1,
+			input:      []byte{'x'},
+			wantData:   'x',
+			wantStatus: StatusRxReady,
+		},
+		{
+			name:       "overrun when the last character wasn't read",
+			mode:       mode8N1,
+			input:      []byte{'x', 'y'},
+			wantData:   'y',
+			wantStatus: StatusRxReady | StatusOverrun,
+		},
+		{
+			name:       "even parity",
+			mode:       mode7E1,
+			input:      []byte{'A'}, // 0x41 has two bits set, so the parity bit is clear
+			wantData:   'A',
+			wantStatus: StatusRxReady,
+		},
+		{
+			name:       "parity error",
+			mode:       mode7E1,
+			input:      []byte{'A' | 0x80},
+			wantData:   'A',
+			wantStatus: StatusRxReady | StatusParityError,
+		},
+		{
+			name:       "receiver disabled",
+			mode:       mode8N1,
+			command:    CommandTxEnable,
+			input:      []byte{'x'},
+			wantStatus: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usart := newUSART(tt.mode, nil)
+			if tt.command != 0 {
+				usart.WritePort(controlPort, tt.command)
+			}
+
+			for _, value := range tt.input {
+				usart.Receive(value)
+				usart.Tick(10_000)
+			}
+
+			status := usart.ReadPort(controlPort) &^ (StatusTxReady | StatusTxEmpty)
+			if status != tt.wantStatus {
+				t.Errorf("status = 0b%08b, want 0b%08b", status, tt.wantStatus)
+			}
+			if tt.wantStatus&StatusRxReady == 0 {
+				return
+			}
+
+			if data := usart.ReadPort(dataPort); data != tt.wantData {
+				t.Errorf("data = 0x%02X, want 0x%02X", data, tt.wantData)
+			}
+			if status := usart.ReadPort(controlPort); status&StatusRxReady != 0 {
+				t.Errorf("status = 0b%08b after reading, want RxRDY clear", status)
+			}
+		})
+	}
+}
+
+func TestReceiveTiming(t *testing.T) {
+	usart := newUSART(mode8N1, nil)
+	usart.Tick(10_000)
+	usart.Receive('x')
+	usart.Receive('y')
+
+	usart.Tick(1)
+	usart.ReadPort(dataPort)
+	usart.Tick(2_000)
+	if status := usart.ReadPort(controlPort); status&StatusRxReady != 0 {
+		t.Errorf("status = 0b%08b before a character time has passed, want RxRDY clear", status)
+	}
+
+	usart.Tick(100)
+	if data := usart.ReadPort(dataPort); data != 'y' {
+		t.Errorf("data = 0x%02X, want 'y'", data)
+	}
+}
+
+func TestErrorReset(t *testing.T) {
+	usart := newUSART(mode8N1, nil)
+	usart.Receive('x')
+	usart.Tick(10_000)
+	usart.Receive('y')
+	usart.Tick(10_000)
+
+	usart.WritePort(controlPort, commandRun)
+
+	if status := usart.ReadPort(controlPort); status&StatusOverrun != 0 {
+		t.Errorf("status = 0b%08b, want OE cleared", status)
+	}
+}
+
+func TestInternalReset(t *testing.T) {
+	usart := newUSART(mode8N1, nil)
+	usart.WritePort(controlPort, CommandInternalReset)
+	usart.WritePort(controlPort, mode7E1) // The next control write is a mode again
+
+	if usart.mode != mode7E1 {
+		t.Errorf("mode = 0b%08b, want 0b%08b", usart.mode, mode7E1)
+	}
+	if usart.DTR() || usart.RTS() {
+		t.Errorf("DTR = %v, RTS = %v after reset, want false", usart.DTR(), usart.RTS())
+	}
+}
+
+func TestSynchronousMode(t *testing.T) {
+	usart := New(2_000_000, 9600)
+	usart.WritePort(controlPort, modeSync)
+	usart.WritePort(controlPort, 0x16)
+	usart.WritePort(controlPort, 0x16)
+	usart.WritePort(controlPort, commandRun)
+
+	if usart.syncChars != [2]byte{0x16, 0x16} {
+		t.Errorf("sync characters = %v, want [0x16 0x16]", usart.syncChars)
+	}
+	if !usart.DTR() || !usart.RTS() {
+		t.Errorf("DTR = %v, RTS = %v, want true", usart.DTR(), usart.RTS())
+	}
+}
+
+func TestDSR(t *testing.T) {
+	usart := New(2_000_000, 9600)
+	usart.SetDSR(true)
+
+	if status := usart.ReadPort(controlPort); status&StatusDSR == 0 {
+		t.Errorf("status = 0b%08b, want DSR set", status)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	// The program initialises the USART, waits for a character, echoes it,
+	// and then halts once it has been sent.
+	program := []byte{
+		0x3E, mode8N1, // MVI A mode8N1
+		0xD3, controlPort, // OUT controlPort
+		0x3E, commandRun, // MVI A commandRun
+		0xD3, controlPort, // OUT controlPort
+		0xDB, controlPort, // 0008: IN controlPort
+		0xE6, StatusRxReady, // ANI StatusRxReady
+		0xCA, 0x08, 0x00, // JZ 0x0008
+		0xDB, dataPort, // IN dataPort
+		0xD3, dataPort, // OUT dataPort
+		0xDB, controlPort, // 0013: IN controlPort
+		0xE6, StatusTxEmpty, // ANI StatusTxEmpty
+		0xCA, 0x13, 0x00, // JZ 0x0013
+		0x76, // HLT
+	}
+
+	var output bytes.Buffer
+	usart := New(2_000_000, 9600)
+	usart.Connect(strings.NewReader("h"), &output)
+
+	goCPU := cpu.New()
+	goCPU.Attach(usart, dataPort, controlPort)
+	goCPU.Load(program)
+
+	err := goCPU.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	if output.String() != "h" {
+		t.Errorf("output = %q, want %q", output.String(), "h")
+	}
+}
+
+func TestInterruptOnReceive(t *testing.T) {
+	// The program enables interrupts and spins until RST 7 reads the character.
+	program := append([]byte{
+		0x31, 0x00, 0x10, // LXI SP 0x1000
+		0x3E, mode8N1, // MVI A mode8N1
+		0xD3, controlPort, // OUT controlPort
+		0x3E, commandRun, // MVI A commandRun
+		0xD3, controlPort, // OUT controlPort
+		0xFB,             // EI
+		0xC3, 0x0C, 0x00, // 000C: JMP 0x000C
+	}, make([]byte, 0x38-0x0F)...)
+	program = append(program,
+		0xDB, dataPort, // 0038: IN dataPort
+		0x76, // HLT
+	)
+
+	usart := New(2_000_000, 9600)
+	goCPU := cpu.New()
+	usart.OnRxReady = func() { goCPU.Interrupt(0xFF) } // RST 7
+	goCPU.Attach(usart, dataPort, controlPort)
+	goCPU.Load(program)
+	usart.Receive('z')
+
+	err := goCPU.Run()
+	if err != nil {
+		t.Fatalf("error running cpu: %v", err)
+	}
+
+	if usart.ReadPort(controlPort)&StatusRxReady != 0 {
+		t.Errorf("RxRDY set, want the interrupt handler to have read the character")
+	}
+}
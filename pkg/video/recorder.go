@@ -0,0 +1,121 @@
+package video
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// Recorder captures a frame from a framebuffer every interval clock states,
+// keeping them to write as an animated GIF.  It's a clocked device with no
+// ports, so it's attached to the CPU without any.
+//
+// Example:
+//
+//	recorder := video.NewRecorder(framebuffer, clock.Intel8080Hz/60) // 60 frames a second
+//	recorder.MaxFrames = 600
+//	cpu.Attach(recorder)
+//	err := cpu.Run()
+//	err = recorder.WriteGIF(file, time.Second/60)
+type Recorder struct {
+	// OnFrame, when set, is called with each frame as it's captured.
+	OnFrame func(frame *image.Paletted)
+
+	// MaxFrames limits the number of frames kept, keeping the latest.  It
+	// defaults to 0, which keeps every frame.
+	MaxFrames int
+
+	framebuffer *Framebuffer
+	interval    uint64
+	cycles      uint64 // Clock states since the last frame
+	frames      []*image.Paletted
+
+	err error
+}
+
+// NewRecorder returns a recorder capturing a frame from framebuffer every
+// interval clock states.
+func NewRecorder(framebuffer *Framebuffer, interval uint64) *Recorder {
+	if interval == 0 {
+		interval = 1
+	}
+
+	return &Recorder{framebuffer: framebuffer, interval: interval}
+}
+
+// Frames returns the frames kept so far.
+func (recorder *Recorder) Frames() []*image.Paletted {
+	return recorder.frames
+}
+
+// Err returns the last error capturing a frame.
+func (recorder *Recorder) Err() error {
+	return recorder.err
+}
+
+// Capture captures a frame now, as if the interval had passed.
+func (recorder *Recorder) Capture() {
+	recorder.cycles = 0
+
+	frame, err := recorder.framebuffer.Frame()
+	if err != nil {
+		recorder.err = err
+		return
+	}
+
+	recorder.frames = append(recorder.frames, frame)
+	if recorder.MaxFrames > 0 && len(recorder.frames) > recorder.MaxFrames {
+		recorder.frames = recorder.frames[len(recorder.frames)-recorder.MaxFrames:]
+	}
+	if recorder.OnFrame != nil {
+		recorder.OnFrame(frame)
+	}
+}
+
+// Tick captures a frame each time interval clock states have passed.
+func (recorder *Recorder) Tick(cycles uint64) {
+	recorder.cycles += cycles
+	if recorder.cycles >= recorder.interval {
+		excess := recorder.cycles - recorder.interval
+		recorder.Capture()
+		recorder.cycles = excess % recorder.interval
+	}
+}
+
+// ReadPort reads 0xFF, as the recorder has no ports.
+func (recorder *Recorder) ReadPort(port byte) byte {
+	return 0xFF
+}
+
+// WritePort does nothing, as the recorder has no ports.
+func (recorder *Recorder) WritePort(port byte, value byte) {}
+
+// WriteGIF writes the frames kept so far to w as an animated GIF, showing each
+// for delay, which is rounded to the GIF's resolution of 10ms.
+func (recorder *Recorder) WriteGIF(w io.Writer, delay time.Duration) error {
+	if len(recorder.frames) == 0 {
+		return fmt.Errorf("could not write GIF: no frames captured")
+	}
+
+	hundredths := int((delay + 5*time.Millisecond) / (10 * time.Millisecond))
+	if hundredths < 1 {
+		hundredths = 1
+	}
+
+	animation := &gif.GIF{
+		Image: recorder.frames,
+		Delay: make([]int, len(recorder.frames)),
+	}
+	for i := range animation.Delay {
+		animation.Delay[i] = hundredths
+	}
+
+	err := gif.EncodeAll(w, animation)
+	if err != nil {
+		return fmt.Errorf("could not write GIF: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package video
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"testing"
+	"time"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+)
+
+func TestRecorder(t *testing.T) {
+	bus := memory.New()
+	framebuffer, _ := New(bus, Config{Width: 8, Height: 1})
+	recorder := NewRecorder(framebuffer, 100)
+
+	var captured int
+	recorder.OnFrame = func(frame *image.Paletted) { captured++ }
+
+	tests := []struct {
+		cycles uint64
+		want   int
+	}{
+		{cycles: 99, want: 0},
+		{cycles: 1, want: 1},
+		{cycles: 50, want: 1},
+		{cycles: 60, want: 2}, // The 10 cycles over count towards the next frame
+		{cycles: 89, want: 2},
+		{cycles: 1, want: 3},
+	}
+
+	for i, test := range tests {
+		recorder.Tick(test.cycles)
+		if captured != test.want || len(recorder.Frames()) != test.want {
+			t.Errorf("after tick %d, captured %d frames and kept %d, want %d", i, captured, len(recorder.Frames()), test.want)
+		}
+	}
+}
+
+func TestMaxFrames(t *testing.T) {
+	bus := memory.New()
+	framebuffer, _ := New(bus, Config{Width: 8, Height: 1})
+	recorder := NewRecorder(framebuffer, 1)
+	recorder.MaxFrames = 2
+
+	for i := byte(1); i <= 3; i++ {
+		bus.Data[0] = i
+		recorder.Tick(1)
+	}
+
+	frames := recorder.Frames()
+	if len(frames) != 2 {
+		t.Fatalf("kept %d frames, want 2", len(frames))
+	}
+	if frames[0].Pix[0] != 0 || frames[0].Pix[1] != 1 || frames[1].Pix[0] != 1 || frames[1].Pix[1] != 1 {
+		t.Errorf("kept frames %v and %v, want the last two", frames[0].Pix, frames[1].Pix)
+	}
+}
+
+func TestRecorderError(t *testing.T) {
+	framebuffer, _ := New(memory.New(), Config{Address: 0xFFFE, Width: 8, Height: 2})
+	recorder := NewRecorder(framebuffer, 1)
+	recorder.Tick(1)
+
+	if recorder.Err() == nil {
+		t.Errorf("expected an error capturing a frame, but got none")
+	}
+	if len(recorder.Frames()) != 0 {
+		t.Errorf("kept %d frames, want 0", len(recorder.Frames()))
+	}
+}
+
+func TestWriteGIF(t *testing.T) {
+	framebuffer, _ := New(memory.New(), Config{Width: 8, Height: 8})
+	recorder := NewRecorder(framebuffer, 1)
+
+	var buffer bytes.Buffer
+	err := recorder.WriteGIF(&buffer, time.Second/60)
+	if err == nil {
+		t.Errorf("expected an error writing a GIF with no frames, but got none")
+	}
+
+	recorder.Capture()
+	recorder.Capture()
+	err = recorder.WriteGIF(&buffer, time.Second/60)
+	if err != nil {
+		t.Fatalf("could not write GIF: %v", err)
+	}
+
+	decoded, err := gif.DecodeAll(&buffer)
+	if err != nil {
+		t.Fatalf("could not decode GIF: %v", err)
+	}
+	if len(decoded.Image) != 2 {
+		t.Errorf("GIF has %d frames, want 2", len(decoded.Image))
+	}
+	for i, delay := range decoded.Delay {
+		if delay != 2 {
+			t.Errorf("frame %d delay = %d, want 2", i, delay)
+		}
+	}
+}
+
+func TestRecordCPU(t *testing.T) {
+	// Draws a pixel further along the line each time round the loop
+	program := []byte{
+		0x21, 0x00, 0x10, // 0000 LXI H,0x1000
+		0x3E, 0x01, // 0003 MVI A,0x01
+		0x77,             // 0005 MOV M,A
+		0x07,             // 0006 RLC
+		0xD2, 0x05, 0x00, // 0007 JNC 0x0005
+		0x76, // 000A HLT
+	}
+
+	processor := cpu.New()
+	err := processor.Load(program)
+	if err != nil {
+		t.Fatalf("could not load program: %v", err)
+	}
+
+	framebuffer, _ := New(processor.Bus, Config{Address: 0x1000, Width: 8, Height: 1})
+	recorder := NewRecorder(framebuffer, 21) // Once round the loop: MOV, RLC and JNC
+	processor.Attach(recorder)
+
+	err = processor.Run()
+	if err != nil {
+		t.Fatalf("could not run program: %v", err)
+	}
+
+	var got []string
+	for _, frame := range recorder.Frames() {
+		got = append(got, render(frame))
+	}
+	want := []string{"#.......", ".#......", "..#.....", "...#....", "....#...", ".....#..", "......#.", ".......#"}
+	if len(got) < len(want) {
+		t.Fatalf("captured %v, want at least %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("frame %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package video
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/lukepeterson/go8080cpu/pkg/cpu"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// Rotation is the clockwise rotation applied to the bitmap, for machines whose
+// monitor is mounted on its side.
+type Rotation int
+
+const (
+	Rotate0   Rotation = iota // Upright
+	Rotate90                  // A quarter turn clockwise
+	Rotate180                 // Upside down
+	Rotate270                 // A quarter turn anticlockwise
+)
+
+// Palette is the default palette: black for clear bits and white for set bits.
+var Palette = color.Palette{color.Black, color.White}
+
+// Config describes how a bitmap is laid out in memory and how it's shown.
+type Config struct {
+	// Address is the address of the first byte of the bitmap.
+	Address types.Word
+
+	// Width and Height are the size of the bitmap in pixels, as laid out in
+	// memory before it's rotated.
+	Width, Height int
+
+	// Stride is the number of bytes from the start of one line to the next.  It
+	// defaults to Width/8, rounded up.
+	Stride int
+
+	// MSBFirst takes the leftmost pixel of each byte from bit 7 rather than
+	// bit 0.
+	MSBFirst bool
+
+	// Rotation rotates the bitmap clockwise.
+	Rotation Rotation
+
+	// Palette holds the colours of clear and set bits.  It defaults to Palette.
+	Palette color.Palette
+}
+
+// Framebuffer renders a 1 bit per pixel bitmap held in a Bus's memory, such as
+// a video RAM.
+//
+// Example:
+//
+//	framebuffer, err := video.New(cpu.Bus, video.Config{ // Space Invaders
+//		Address:  0x2400,
+//		Width:    256,
+//		Height:   224,
+//		Rotation: video.Rotate270,
+//	})
+//	err = framebuffer.WritePNG(file)
+type Framebuffer struct {
+	bus    cpu.Bus
+	config Config
+}
+
+// New returns a framebuffer rendering the bitmap described by config from bus.
+func New(bus cpu.Bus, config Config) (*Framebuffer, error) {
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("invalid bitmap size %dx%d", config.Width, config.Height)
+	}
+	if config.Stride == 0 {
+		config.Stride = (config.Width + 7) / 8
+	}
+	if config.Stride*8 < config.Width {
+		return nil, fmt.Errorf("invalid stride %d (must be at least %d bytes for a width of %d)", config.Stride, (config.Width+7)/8, config.Width)
+	}
+	if int(config.Address)+config.Stride*(config.Height-1)+(config.Width+7)/8 > 0x10000 {
+		return nil, fmt.Errorf("invalid bitmap at address 0x%04X (past the end of memory)", config.Address)
+	}
+	if config.Rotation < Rotate0 || config.Rotation > Rotate270 {
+		return nil, fmt.Errorf("invalid rotation %d", config.Rotation)
+	}
+	if config.Palette == nil {
+		config.Palette = Palette
+	}
+	if len(config.Palette) != 2 {
+		return nil, fmt.Errorf("invalid palette of %d colours (must be 2)", len(config.Palette))
+	}
+
+	return &Framebuffer{bus: bus, config: config}, nil
+}
+
+// Bounds returns the bounds of the frames, after rotation.
+func (framebuffer *Framebuffer) Bounds() image.Rectangle {
+	width, height := framebuffer.config.Width, framebuffer.config.Height
+	if framebuffer.config.Rotation == Rotate90 || framebuffer.config.Rotation == Rotate270 {
+		width, height = height, width
+	}
+
+	return image.Rect(0, 0, width, height)
+}
+
+// Frame renders the bitmap as it is now.
+func (framebuffer *Framebuffer) Frame() (*image.Paletted, error) {
+	config := framebuffer.config
+	frame := image.NewPaletted(framebuffer.Bounds(), config.Palette)
+
+	for line := 0; line < config.Height; line++ {
+		for column := 0; column < (config.Width+7)/8; column++ {
+			address := config.Address + types.Word(line*config.Stride+column)
+			value, err := framebuffer.bus.ReadByteAt(address)
+			if err != nil {
+				return nil, fmt.Errorf("could not read bitmap at address 0x%04X: %v", address, err)
+			}
+
+			for bit := 0; bit < 8 && column*8+bit < config.Width; bit++ {
+				mask := byte(1) << bit
+				if config.MSBFirst {
+					mask = 0x80 >> bit
+				}
+				if value&mask == 0 {
+					continue
+				}
+				x, y := framebuffer.rotate(column*8+bit, line)
+				frame.Pix[y*frame.Stride+x] = 1
+			}
+		}
+	}
+
+	return frame, nil
+}
+
+// rotate maps a pixel of the bitmap to its position in the frame.
+func (framebuffer *Framebuffer) rotate(x, y int) (int, int) {
+	width, height := framebuffer.config.Width, framebuffer.config.Height
+	switch framebuffer.config.Rotation {
+	case Rotate90:
+		return height - 1 - y, x
+	case Rotate180:
+		return width - 1 - x, height - 1 - y
+	case Rotate270:
+		return y, width - 1 - x
+	}
+
+	return x, y
+}
+
+// WritePNG writes the bitmap as it is now to w as a PNG image.
+func (framebuffer *Framebuffer) WritePNG(w io.Writer) error {
+	frame, err := framebuffer.Frame()
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(w, frame)
+	if err != nil {
+		return fmt.Errorf("could not write PNG: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package video
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/lukepeterson/go8080cpu/pkg/invaders"
+	"github.com/lukepeterson/go8080cpu/pkg/memory"
+	"github.com/lukepeterson/go8080cpu/pkg/types"
+)
+
+// render returns a frame as a line of '#' and '.' per row.
+func render(frame image.Image) string {
+	var lines []string
+	bounds := frame.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		var line strings.Builder
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if r, _, _, _ := frame.At(x, y).RGBA(); r != 0 {
+				line.WriteByte('#')
+			} else {
+				line.WriteByte('.')
+			}
+		}
+		lines = append(lines, line.String())
+	}
+
+	return strings.Join(lines, "/")
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "valid", config: Config{Width: 256, Height: 224}, wantErr: false},
+		{name: "zero size", config: Config{Width: 0, Height: 224}, wantErr: true},
+		{name: "negative size", config: Config{Width: 8, Height: -1}, wantErr: true},
+		{name: "short stride", config: Config{Width: 16, Height: 1, Stride: 1}, wantErr: true},
+		{name: "end of memory", config: Config{Address: 0xFFFE, Width: 16, Height: 1}, wantErr: false},
+		{name: "past end of memory", config: Config{Address: 0xFFFF, Width: 16, Height: 1}, wantErr: true},
+		{name: "rotation", config: Config{Width: 8, Height: 8, Rotation: 4}, wantErr: true},
+		{name: "palette", config: Config{Width: 8, Height: 8, Palette: color.Palette{color.Black}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(memory.New(), test.config)
+			if test.wantErr && err == nil {
+				t.Errorf("expected an error, but got none")
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("did not expect an error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestFrame(t *testing.T) {
+	// A 3x2 bitmap, with one byte per line:
+	//
+	//	##.
+	//	..#
+	bitmap := []byte{0b0000_0011, 0b0000_0100}
+
+	tests := []struct {
+		name   string
+		config Config
+		data   []byte
+		want   string
+	}{
+		{name: "upright", config: Config{}, data: bitmap, want: "##./..#"},
+		{name: "90", config: Config{Rotation: Rotate90}, data: bitmap, want: ".#/.#/#."},
+		{name: "180", config: Config{Rotation: Rotate180}, data: bitmap, want: "#../.##"},
+		{name: "270", config: Config{Rotation: Rotate270}, data: bitmap, want: ".#/#./#."},
+		{name: "MSB first", config: Config{MSBFirst: true}, data: []byte{0b1100_0000, 0b0010_0000}, want: "##./..#"},
+		{name: "stride", config: Config{Stride: 2}, data: []byte{0b0000_0011, 0xFF, 0b0000_0100}, want: "##./..#"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bus := memory.New()
+			copy(bus.Data[0x1000:], test.data)
+
+			test.config.Address, test.config.Width, test.config.Height = 0x1000, 3, 2
+			framebuffer, err := New(bus, test.config)
+			if err != nil {
+				t.Fatalf("could not create framebuffer: %v", err)
+			}
+			frame, err := framebuffer.Frame()
+			if err != nil {
+				t.Fatalf("could not render frame: %v", err)
+			}
+
+			got := render(frame)
+			if got != test.want {
+				t.Errorf("frame = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPalette(t *testing.T) {
+	bus := memory.New()
+	bus.Data[0] = 0b0000_0001
+	green := color.RGBA{G: 0xFF, A: 0xFF}
+
+	framebuffer, _ := New(bus, Config{Width: 2, Height: 1, Palette: color.Palette{color.Black, green}})
+	frame, _ := framebuffer.Frame()
+	if frame.At(0, 0) != green || frame.At(1, 0) != color.Black {
+		t.Errorf("pixels = %v and %v, want %v and %v", frame.At(0, 0), frame.At(1, 0), green, color.Black)
+	}
+}
+
+func TestFrameError(t *testing.T) {
+	// memory.New's last byte is 0xFFFE, so the second line can't be read
+	framebuffer, _ := New(memory.New(), Config{Address: 0xFFFE, Width: 8, Height: 2})
+	_, err := framebuffer.Frame()
+	if err == nil {
+		t.Errorf("expected an error reading past the end of memory, but got none")
+	}
+}
+
+func TestInvaders(t *testing.T) {
+	machine, err := invaders.New(make([]byte, invaders.ROMSize), invaders.Config{})
+	if err != nil {
+		t.Fatalf("could not create machine: %v", err)
+	}
+	for i := 0; i < 0x1C00; i++ {
+		machine.CPU.Bus.WriteByteAt(types.Word(0x2400+i), byte(i*37))
+	}
+
+	framebuffer, err := New(machine.CPU.Bus, Config{Address: 0x2400, Width: 256, Height: 224, Rotation: Rotate270})
+	if err != nil {
+		t.Fatalf("could not create framebuffer: %v", err)
+	}
+	got, _ := framebuffer.Frame()
+	want := machine.Frame()
+
+	if got.Bounds() != want.Bounds() || !bytes.Equal(got.Pix, want.Pix) {
+		t.Errorf("frame does not match the Space Invaders frame")
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	bus := memory.New()
+	bus.Data[0] = 0b0000_0101
+
+	framebuffer, _ := New(bus, Config{Width: 3, Height: 1, Rotation: Rotate90})
+	var buffer bytes.Buffer
+	err := framebuffer.WritePNG(&buffer)
+	if err != nil {
+		t.Fatalf("could not write PNG: %v", err)
+	}
+
+	decoded, err := png.Decode(&buffer)
+	if err != nil {
+		t.Fatalf("could not decode PNG: %v", err)
+	}
+	if got := render(decoded); got != "#/./#" {
+		t.Errorf("PNG = %s, want #/./#", got)
+	}
+}